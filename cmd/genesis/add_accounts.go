@@ -0,0 +1,47 @@
+package genesis
+
+import (
+	"github.com/spf13/cobra"
+
+	kvgenesis "github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// AddAccountsCmd funds the accounts of a file in a genesis
+func AddAccountsCmd() *cobra.Command {
+	var skipExisting bool
+
+	cmd := &cobra.Command{
+		Use:   "add-accounts path/to/genesis.json path/to/accounts.yaml",
+		Short: "Fund accounts in a genesis, creating them if they don't exist",
+		Long: `Funds the accounts listed in a yaml or json file. Addresses are kava bech32 or 0x evm addresses.
+
+Accounts that don't exist are created with the next free account numbers. 0x addresses are created as an EthAccount,
+unless eth: false. Accounts with a vesting schedule are created as a periodic vesting account, whose vesting starts at
+the genesis time unless start is set. Existing accounts have the coins added to their balance, or are left as is with
+--skip-existing. The bank supply of every denom is increased to match, unless it's empty & calculated by x/bank.
+
+  accounts:
+    - address: kava1fy5zeuutmxzwcx5hncu5q83ug3zcqmxcpwrjsn
+      coins: 1000000000ukava,1000000hard
+    - address: 0x6767114FFAA17C6439D7AEA480738B982CE63A02
+      coins: 1000000000ukava
+    - address: kava1...
+      coins: 1000000ukava
+      vesting:
+        start: 2024-01-01T00:00:00Z
+        periods:
+          - { length: 720h, coins: 500000ukava }
+          - { length: 720h, coins: 500000ukava }`,
+		Example:      `kvtool genesis add-accounts export.json test-wallets.yaml --skip-existing`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+	}
+	editor := newGenesisEditor(cmd)
+	cmd.RunE = func(_ *cobra.Command, args []string) error {
+		return editor.run(args[0], &kvgenesis.AddAccountsTransformer{File: args[1], SkipExisting: skipExisting})
+	}
+
+	cmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "leave existing accounts as is, instead of adding the coins to their balance")
+
+	return cmd
+}
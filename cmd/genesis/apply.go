@@ -0,0 +1,82 @@
+package genesis
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kava-labs/kvtool/config/generate"
+	kvgenesis "github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// ApplyCmd edits a genesis with the steps of a recipe file
+func ApplyCmd() *cobra.Command {
+	var genesisPath string
+	var outPath string
+	var skipValidation bool
+
+	cmd := &cobra.Command{
+		Use:   "apply path/to/recipe.yaml",
+		Short: "Edit a genesis with the steps of a recipe",
+		Long: fmt.Sprintf(`Applies the steps of a yaml recipe to a genesis in order & checks the result with the kava app's
+ValidateGenesis. Each step is a map of the step name to its options. Relative paths are relative to the recipe.
+
+  genesis: export.json          # the genesis to edit, or --genesis
+  out: mirrornet-genesis.json   # where the result is written, or --out. defaults to the genesis
+  validate: false               # only genesis files of the kava version kvtool is built with can be validated
+  steps:
+    - set-chain-id: { chainId: kavamirror_2221-1 }
+    - replace-validators: { keysDir: keys, keyPrefix: priv_validator_key_, minPower: 0.67, persistentPower: true }
+    - inject-god-committee: { member: kava1... }   # member defaults to the templates' committee key
+    - set-voting-period: { period: 30s }
+    - grant-balance: { address: kava1..., coins: 1000000ukava }
+    - add-accounts: { file: accounts.yaml, skipExisting: true }   # see kvtool genesis add-accounts
+    - set: { path: app_state.swap.params.swap_fee, value: "0.001" }
+
+Steps: %v`, kvgenesis.TransformerNames()),
+		Example:      `kvtool genesis apply mirrornet.yaml --genesis export.json --out genesis.json`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			recipe, err := kvgenesis.LoadRecipe(args[0])
+			if err != nil {
+				return err
+			}
+			if genesisPath != "" {
+				recipe.Genesis = genesisPath
+			}
+			if recipe.Genesis == "" {
+				return fmt.Errorf("no genesis to edit, set genesis in the recipe or use --genesis")
+			}
+			if outPath != "" {
+				recipe.Out = outPath
+			}
+			if recipe.Out == "" {
+				recipe.Out = recipe.Genesis
+			}
+			for _, step := range recipe.Steps {
+				if committee, ok := step.Transformer.(*kvgenesis.InjectGodCommitteeTransformer); ok && committee.Member == "" {
+					if committee.Member, err = generate.CommitteeMemberAddress(); err != nil {
+						return err
+					}
+				}
+			}
+
+			pipeline := recipe.Pipeline()
+			if skipValidation {
+				pipeline.Validate = false
+			}
+			if err := pipeline.RunFile(recipe.Genesis, recipe.Out); err != nil {
+				return err
+			}
+			fmt.Printf("applied %d steps, genesis saved to %s\n", len(recipe.Steps), recipe.Out)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&genesisPath, "genesis", "", "genesis file to edit. overrides the recipe's genesis")
+	cmd.Flags().StringVarP(&outPath, "out", "o", "", "path the result is written to. overrides the recipe's out")
+	cmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "don't check the result with the kava app's ValidateGenesis")
+
+	return cmd
+}
@@ -0,0 +1,89 @@
+package genesis
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	kvgenesis "github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// BuildCmd builds the genesis of the kava master template
+func BuildCmd() *cobra.Command {
+	repoDir := kvtoolRepoDir()
+	builder := kvgenesis.NewBuilder(repoDir, filepath.Join(repoDir, "config/templates/kava/master/initstate/.kava"))
+	var genesisTime string
+	var replaceAccountKeys bool
+
+	cmd := &cobra.Command{
+		Use:   "build",
+		Short: "Build the genesis.json & gentx of a kava template",
+		Long: `Build the genesis.json & gentx of a kava template.
+
+The genesis starts from the default state of the kava version kvtool is built with, then:
+* funds the accounts in config/common/addresses.json & creates the validator's gentx
+* sets app_state values from the json files in config/generate/genesis. The file name is the path in app_state.
+  Files may reference account addresses as variables, eg. $whale.
+* validates the result with the kava app's genesis validation
+
+The validator's consensus & node keys are read from the destination home directory, which must already exist.
+Only config/genesis.json & config/gentx are written. app.toml, config.toml & client.toml are not modified.
+
+To build on a genesis from a different kava version, pass the output of 'kava init' as --base-genesis.
+The result is not validated in that case, use 'kava validate-genesis' instead.`,
+		Example: `# regenerate the master template
+kvtool genesis build
+
+# generate the ibc chain's genesis
+kvtool genesis build --chain-id kavalocalnet_8889-2 --denom uatom --skip-incentives --dest ./config/templates/ibcchain/master/initstate/.kava`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			t, err := time.Parse(time.RFC3339Nano, genesisTime)
+			if err != nil {
+				return fmt.Errorf("invalid --genesis-time: %w", err)
+			}
+			builder.GenesisTime = t
+			if _, err := os.Stat(builder.FragmentsDir); err != nil {
+				return fmt.Errorf("genesis fragments not found, build requires a checkout of the kvtool repo: %w", err)
+			}
+
+			result, err := builder.Build()
+			if err != nil {
+				return err
+			}
+			if err := result.WriteHome(builder.HomeDir); err != nil {
+				return err
+			}
+			fmt.Printf("wrote genesis for %s to %s\n", builder.ChainID, builder.HomeDir)
+
+			if replaceAccountKeys {
+				if err := replaceKeyring(builder); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&builder.ChainID, "chain-id", builder.ChainID, "chain id of the genesis")
+	cmd.Flags().StringVar(&builder.Denom, "denom", builder.Denom, "primary denom of the chain. ukava is replaced with it throughout the genesis")
+	cmd.Flags().StringVar(&builder.HomeDir, "dest", builder.HomeDir, "kava home directory to write the genesis to")
+	cmd.Flags().BoolVar(&builder.SkipIncentives, "skip-incentives", false, "don't set the incentive params")
+	cmd.Flags().StringVar(&builder.BaseGenesisPath, "base-genesis", "", "genesis file to build on instead of kvtool's default kava genesis")
+	cmd.Flags().StringVar(&genesisTime, "genesis-time", builder.GenesisTime.Format(time.RFC3339Nano), "genesis time in RFC3339 format")
+	cmd.Flags().BoolVar(&replaceAccountKeys, "replace-account-keys", false, "replace the keyring-test of the destination with keys of the genesis accounts. This always results in a diff because creation time is baked into the key files.")
+
+	return cmd
+}
+
+// replaceKeyring removes the keyring of the builder's home directory & recreates it with the genesis accounts' keys
+func replaceKeyring(builder kvgenesis.Builder) error {
+	fmt.Println("replacing existing account keys")
+	if err := os.RemoveAll(filepath.Join(builder.HomeDir, "keyring-test")); err != nil {
+		return err
+	}
+	return builder.WriteKeyring(builder.HomeDir)
+}
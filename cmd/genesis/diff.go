@@ -0,0 +1,66 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	kvgenesis "github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// DiffCmd reports the differences between two genesis files
+func DiffCmd() *cobra.Command {
+	var (
+		ignore []string
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff a.json b.json",
+		Short: "Report the differences between two genesis or export files, module by module",
+		Long: `Compares two genesis or export files & reports what changed from a to b, module by module:
+changed params, supply by denom, the lengths of lists like accounts (including accounts by type), staking validators,
+committees & the other fields of each module that changed. The tendermint validator set & fields outside of app_state,
+like chain_id & consensus_params, are compared too.
+
+Modules are decoded with the kava app codec, so equivalent state like an omitted default value isn't reported.
+Modules the codec rejects, eg. those of another kava version, are compared as json.
+Volatile fields are ignored with --ignore, a dot separated path like initial_height or app_state.mint.minter.
+genesis_time is always ignored, unless --ignore= is passed to compare everything.`,
+		Example: `kvtool genesis diff export.json updated-genesis.json
+kvtool genesis diff export-v1.json export-v2.json --ignore initial_height,app_state.distribution --output json`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			diff, err := kvgenesis.DiffFiles(args[0], args[1], diffIgnore(ignore, cmd.Flags().Changed("ignore")))
+			if err != nil {
+				return err
+			}
+			switch output {
+			case "text":
+				return diff.WriteText(os.Stdout)
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(diff)
+			default:
+				return fmt.Errorf("unknown output %q, must be text or json", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&ignore, "ignore", nil, fmt.Sprintf("dot separated paths that aren't compared, in addition to %v. pass --ignore= to compare everything.", kvgenesis.DefaultDiffIgnore))
+	cmd.Flags().StringVar(&output, "output", "text", "output format, text or json")
+
+	return cmd
+}
+
+// diffIgnore returns the --ignore paths merged with the default ones. Only an explicitly empty --ignore= drops them.
+func diffIgnore(ignore []string, changed bool) []string {
+	if changed && len(ignore) == 0 {
+		return nil
+	}
+	return append(append([]string{}, kvgenesis.DefaultDiffIgnore...), ignore...)
+}
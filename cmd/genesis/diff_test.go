@@ -0,0 +1,37 @@
+package genesis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffIgnore(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ignore   []string
+		changed  bool
+		expected []string
+	}{
+		{
+			name:     "default",
+			expected: []string{"genesis_time"},
+		},
+		{
+			name:     "merged with the default",
+			ignore:   []string{"app_state.mint", "initial_height"},
+			changed:  true,
+			expected: []string{"genesis_time", "app_state.mint", "initial_height"},
+		},
+		{
+			name:    "compare everything",
+			changed: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, diffIgnore(tc.ignore, tc.changed))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package genesis
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	kvgenesis "github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// defaultEditOutFile is where edited genesis files are written by default, so the input is never overwritten unless asked
+const defaultEditOutFile = "updated-genesis.json"
+
+// genesisEditor is shared by the commands that edit an existing genesis file. It loads the genesis, applies transformers
+// with a genesis pipeline & writes the result to --out.
+//
+// Genesis files are edited as raw json so files from other kava versions keep the fields kvtool doesn't know about.
+type genesisEditor struct {
+	outFile string
+}
+
+// newGenesisEditor registers the --out flag of an edit command
+func newGenesisEditor(cmd *cobra.Command) *genesisEditor {
+	editor := &genesisEditor{}
+	cmd.Flags().StringVarP(&editor.outFile, "out", "o", defaultEditOutFile, "path the edited genesis is written to. may be the input file to edit it in place.")
+	return editor
+}
+
+// run applies the transformers to the genesis at path & writes the result to --out
+func (e *genesisEditor) run(path string, transformers ...kvgenesis.GenesisTransformer) error {
+	pipeline := kvgenesis.Pipeline{Transformers: transformers}
+	if err := pipeline.RunFile(path, e.outFile); err != nil {
+		return err
+	}
+	fmt.Printf("genesis saved to %s\n", e.outFile)
+	return nil
+}
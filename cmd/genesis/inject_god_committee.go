@@ -0,0 +1,41 @@
+package genesis
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/kava-labs/kvtool/config/generate"
+	kvgenesis "github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// InjectGodCommitteeCmd adds a committee that can pass any proposal to a genesis
+func InjectGodCommitteeCmd() *cobra.Command {
+	var member string
+
+	cmd := &cobra.Command{
+		Use:   "inject-god-committee path/to/genesis.json",
+		Short: "Add a committee that can pass any proposal to a genesis",
+		Long: `Adds a member committee with the God Permission & a single member to a genesis, so proposals like software
+upgrades can be passed by one vote. The member's account is created & funded if it doesn't exist.
+The committee's id is one more than the highest existing committee id.
+
+The member defaults to the committee key of the kvtool templates' keyrings.`,
+		Example: `kvtool genesis inject-god-committee export.json
+kvtool genesis inject-god-committee export.json --member kava1fy5zeuutmxzwcx5hncu5q83ug3zcqmxcpwrjsn`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+	}
+	editor := newGenesisEditor(cmd)
+	cmd.RunE = func(_ *cobra.Command, args []string) error {
+		if member == "" {
+			var err error
+			if member, err = generate.CommitteeMemberAddress(); err != nil {
+				return err
+			}
+		}
+		return editor.run(args[0], &kvgenesis.InjectGodCommitteeTransformer{Member: member})
+	}
+
+	cmd.Flags().StringVar(&member, "member", "", "kava address of the sole committee member. defaults to the templates' committee key")
+
+	return cmd
+}
@@ -0,0 +1,72 @@
+package genesis
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	kvgenesis "github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// ReplaceValidatorsCmd replaces the validators of an exported genesis with local validator keys
+func ReplaceValidatorsCmd() *cobra.Command {
+	var chainID string
+	var minPowerPercent float64
+	var persistentPower bool
+	var keysDir string
+	var keyPrefix string
+
+	cmd := &cobra.Command{
+		Use:   "replace-validators path/to/genesis.json",
+		Short: "Replace the validators of an exported genesis with local validator keys",
+		Long: `Takes an exported genesis.json & a directory of indexed priv_validator_key.json files & replaces the
+consensus keys of the genesis validators with them, from highest power to lowest. Any other validators are left as is.
+The consensus addresses are also updated in x/staking, x/slashing & x/distribution & the genesis time is set to now.
+
+--min-power gives the replaced validators enough power to hold at least that share of the total.
+Note that x/staking reverts the power to that of the validators' delegations after the first block, unless
+--persistent-power is used. It bonds the tokens backing the increase to each validator's self delegation (or its
+largest delegation) & funds the bonded pool & supply to match, so the validators keep their share.`,
+		Example: `# replace the top validators of a mainnet export with keys/priv_validator_key_0.json, ...
+kvtool genesis replace-validators export.json --chain-id kavamirror_2221-1 --min-power 0.67
+
+# keep the controlling share of power after the first block
+kvtool genesis replace-validators export.json --chain-id kavamirror_2221-1 --min-power 0.67 --persistent-power`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+	}
+	editor := newGenesisEditor(cmd)
+	cmd.RunE = func(_ *cobra.Command, args []string) error {
+		gen, err := kvgenesis.ReadRawGenesis(args[0])
+		if err != nil {
+			return err
+		}
+		currentChainID, err := gen.ChainID()
+		if err != nil {
+			return err
+		}
+
+		var transformers []kvgenesis.GenesisTransformer
+		if chainID == "" || chainID == currentChainID {
+			fmt.Println("WARNING: the output will have the same chain id. This can put the new chain at risk of replay attacks.")
+			fmt.Println("Consider changing the chain id with the --chain-id flag.")
+		} else {
+			transformers = append(transformers, &kvgenesis.SetChainIDTransformer{ChainID: chainID})
+		}
+		transformers = append(transformers, &kvgenesis.ReplaceValidatorsTransformer{
+			KeysDir:         keysDir,
+			KeyPrefix:       keyPrefix,
+			MinPower:        minPowerPercent,
+			PersistentPower: persistentPower,
+		})
+		return editor.run(args[0], transformers...)
+	}
+
+	cmd.Flags().StringVar(&chainID, "chain-id", "", "chain id of the output genesis")
+	cmd.Flags().Float64Var(&minPowerPercent, "min-power", 0, "minimum share of the total power given to the replaced validators, 0 <= x < 1")
+	cmd.Flags().BoolVar(&persistentPower, "persistent-power", false, "bond tokens backing the --min-power increase so it isn't reverted after the first block")
+	cmd.Flags().StringVarP(&keysDir, "keys-dir", "d", "keys/", "directory containing the new validator keys")
+	cmd.Flags().StringVarP(&keyPrefix, "key-prefix", "p", kvgenesis.DefaultValidatorKeyPrefix, "file prefix of the validator keys. keys are named <prefix><index>.json, starting from index 0")
+
+	return cmd
+}
@@ -0,0 +1,42 @@
+package genesis
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+// kvtoolRepoDir is the root of the kvtool repo that kvtool was installed from, or a checkout of the repo in the kvtool
+// home when kvtool is built without one
+func kvtoolRepoDir() string {
+	if generate.ConfigTemplatesDir != "" {
+		return filepath.Join(generate.ConfigTemplatesDir, "../..")
+	}
+	home, err := generate.KvtoolHome()
+	if err != nil {
+		return "kvtool"
+	}
+	return filepath.Join(home, "kvtool")
+}
+
+// Cmd is the CLI command for building & editing kava genesis files
+func Cmd() *cobra.Command {
+	genesisCmd := &cobra.Command{
+		Use:   "genesis",
+		Short: "Build and edit kava genesis files",
+	}
+
+	genesisCmd.AddCommand(BuildCmd())
+	genesisCmd.AddCommand(ReplaceValidatorsCmd())
+	genesisCmd.AddCommand(InjectGodCommitteeCmd())
+	genesisCmd.AddCommand(SetVotingPeriodCmd())
+	genesisCmd.AddCommand(SetChainIDCmd())
+	genesisCmd.AddCommand(AddAccountsCmd())
+	genesisCmd.AddCommand(ApplyCmd())
+	genesisCmd.AddCommand(DiffCmd())
+	genesisCmd.AddCommand(ValidateCmd())
+
+	return genesisCmd
+}
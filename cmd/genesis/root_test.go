@@ -0,0 +1,24 @@
+package genesis
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+func TestKvtoolRepoDir(t *testing.T) {
+	templatesDir := generate.ConfigTemplatesDir
+	t.Cleanup(func() { generate.ConfigTemplatesDir = templatesDir })
+
+	generate.ConfigTemplatesDir = "/src/kvtool/config/templates"
+	require.Equal(t, "/src/kvtool", kvtoolRepoDir())
+
+	// without a checkout, the repo is expected in the kvtool home rather than relative to the working directory
+	generate.ConfigTemplatesDir = ""
+	home := t.TempDir()
+	t.Setenv(generate.KvtoolHomeEnv, home)
+	require.Equal(t, filepath.Join(home, "kvtool"), kvtoolRepoDir())
+}
@@ -0,0 +1,47 @@
+package genesis
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	kvgenesis "github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// SetVotingPeriodCmd sets the x/gov voting period of a genesis
+func SetVotingPeriodCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "set-voting-period path/to/genesis.json duration",
+		Short:        "Set the x/gov voting period of a genesis",
+		Long:         `Sets the x/gov voting period of a genesis. The duration is in go format, eg. 30s or 1h30m.`,
+		Example:      `kvtool genesis set-voting-period export.json 30s`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+	}
+	editor := newGenesisEditor(cmd)
+	cmd.RunE = func(_ *cobra.Command, args []string) error {
+		period, err := time.ParseDuration(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid voting period: %w", err)
+		}
+		return editor.run(args[0], &kvgenesis.SetVotingPeriodTransformer{Period: period})
+	}
+	return cmd
+}
+
+// SetChainIDCmd sets the chain id of a genesis
+func SetChainIDCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "set-chain-id path/to/genesis.json chain-id",
+		Short:        "Set the chain id of a genesis",
+		Example:      `kvtool genesis set-chain-id export.json kavamirror_2221-1`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+	}
+	editor := newGenesisEditor(cmd)
+	cmd.RunE = func(_ *cobra.Command, args []string) error {
+		return editor.run(args[0], &kvgenesis.SetChainIDTransformer{ChainID: args[1]})
+	}
+	return cmd
+}
@@ -0,0 +1,79 @@
+package genesis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	kvgenesis "github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// ValidateCmd runs the genesis checks on a genesis file
+func ValidateCmd() *cobra.Command {
+	var (
+		skip      []string
+		keysDir   string
+		keyPrefix string
+	)
+
+	var checks []string
+	for _, c := range kvgenesis.GenesisChecks() {
+		checks = append(checks, fmt.Sprintf("  %-11s %s", c.Name, c.Description))
+	}
+
+	cmd := &cobra.Command{
+		Use:   "validate path/to/genesis.json",
+		Short: "Check a genesis is valid & consistent before starting a chain with it",
+		Long: `Runs sanity checks on a genesis file & reports every failure, instead of a node crashing on the first one:
+
+` + strings.Join(checks, "\n") + `
+
+The modules check uses the kava version kvtool is built with, skip it for genesis files of other kava versions.
+With --keys-dir, the consensus keys the nodes will run with must be genesis validators, so the chain produces blocks.`,
+		Example: `kvtool genesis validate updated-genesis.json
+kvtool genesis validate export.json --skip modules --keys-dir keys/`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			gen, err := kvgenesis.ReadRawGenesis(args[0])
+			if err != nil {
+				return err
+			}
+			opts := kvgenesis.CheckOptions{Skip: skip}
+			if keysDir != "" {
+				if opts.ValidatorKeys, err = kvgenesis.LoadValidatorKeys(keysDir, keyPrefix); err != nil {
+					return err
+				}
+			}
+
+			results, err := kvgenesis.CheckGenesis(gen, opts)
+			if err != nil {
+				return err
+			}
+			for _, result := range results {
+				switch {
+				case result.Skipped:
+					fmt.Printf("SKIP %s\n", result.Name)
+				case result.Err != nil:
+					fmt.Printf("FAIL %s\n", result.Name)
+					for _, line := range strings.Split(result.Err.Error(), "\n") {
+						fmt.Printf("     %s\n", line)
+					}
+				default:
+					fmt.Printf("PASS %s\n", result.Name)
+				}
+			}
+			if err := kvgenesis.CheckErr(results); err != nil {
+				return fmt.Errorf("%s is invalid", args[0])
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&skip, "skip", nil, "names of checks that aren't run")
+	cmd.Flags().StringVarP(&keysDir, "keys-dir", "d", "", "directory of the consensus keys the nodes will run with, checked to be genesis validators")
+	cmd.Flags().StringVarP(&keyPrefix, "key-prefix", "p", kvgenesis.DefaultValidatorKeyPrefix, "file prefix of the validator keys. keys are named <prefix><index>.json, starting from index 0")
+
+	return cmd
+}
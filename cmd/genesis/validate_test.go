@@ -0,0 +1,50 @@
+package genesis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCmdSkip(t *testing.T) {
+	const testGenesisPath = "../../config/generate/genesis/testdata/test_genesis.json"
+
+	testCases := []struct {
+		name   string
+		args   []string
+		errMsg string
+	}{
+		{
+			name:   "all checks",
+			args:   []string{testGenesisPath},
+			errMsg: testGenesisPath + " is invalid",
+		},
+		{
+			// the modules of the test genesis are from another kava version
+			name: "skip modules",
+			args: []string{testGenesisPath, "--skip", "modules"},
+		},
+		{
+			name: "skip several checks",
+			args: []string{testGenesisPath, "--skip", "modules,denoms"},
+		},
+		{
+			name:   "unknown check",
+			args:   []string{testGenesisPath, "--skip", "modules,module"},
+			errMsg: `unknown genesis check "module"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := ValidateCmd()
+			cmd.SetArgs(tc.args)
+			err := cmd.Execute()
+			if tc.errMsg != "" {
+				require.EqualError(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
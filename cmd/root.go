@@ -6,6 +6,7 @@ import (
 	"github.com/kava-labs/kava/app"
 	"github.com/spf13/cobra"
 
+	"github.com/kava-labs/kvtool/cmd/genesis"
 	"github.com/kava-labs/kvtool/cmd/testnet"
 )
 
@@ -26,6 +27,7 @@ func Execute() error {
 	var cdc *codec.LegacyAmino = app.MakeEncodingConfig().Amino
 
 	rootCmd.AddCommand(EstimateBlockHeightCmd())
+	rootCmd.AddCommand(genesis.Cmd())
 	rootCmd.AddCommand(InflationRootCmd())
 	rootCmd.AddCommand(MaccAddrCmd())
 	rootCmd.AddCommand(NodeKeysCmd(cdc))
@@ -0,0 +1,480 @@
+package testnet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// apiPort is the container port of a kava node's rest api, which serves the grpc gateway
+const apiPort = 1317
+
+const (
+	// assertBefore assertions run once the upgrade proposal has passed, before the chain halts
+	assertBefore = "before"
+	// assertAfter assertions run once the upgraded chain has produced a block
+	assertAfter = "after"
+	// assertBoth assertions run before the halt & after the restart
+	assertBoth = "both"
+)
+
+// UpgradeAssertions is a file of queries that are checked around automated chain upgrades
+type UpgradeAssertions struct {
+	Assertions []UpgradeAssertion `yaml:"assertions"`
+}
+
+// UpgradeAssertion is a query & the checks made on its json result.
+// Exactly one of Query & Grpc is required. All checks that are set must pass.
+type UpgradeAssertion struct {
+	Name string `yaml:"name"`
+	// Query are the arguments of a kava cli query, eg. "q evmutil params". --output json is appended.
+	Query string `yaml:"query"`
+	// Grpc is a grpc gateway path served by the kava node's api, eg. /cosmos/gov/v1beta1/params/voting
+	Grpc string `yaml:"grpc"`
+	// Path is a JSONPath selecting the checked value from the result, eg. $.params.conversion_pairs[0].denom.
+	// The whole result is checked when it is empty.
+	Path string `yaml:"path"`
+	// When is before, after or both. Defaults to after.
+	When string `yaml:"when"`
+	// Upgrade limits the assertion to the upgrade with this name. It runs for every upgrade when empty.
+	Upgrade string `yaml:"upgrade"`
+
+	// Equals is the expected value. Scalars are compared by their string form, so 10 matches "10".
+	Equals interface{} `yaml:"equals"`
+	// Exists checks whether the path matches a value
+	Exists *bool `yaml:"exists"`
+	// Contains checks the value's json contains a substring
+	Contains string `yaml:"contains"`
+	// Changed compares the value after the upgrade with the value before it. Requires when: both.
+	// The value before the upgrade is only recorded, the other checks are made after the upgrade.
+	Changed *bool `yaml:"changed"`
+}
+
+// LoadUpgradeAssertions reads, decodes & validates an upgrade assertions file
+func LoadUpgradeAssertions(path string) (UpgradeAssertions, error) {
+	var assertions UpgradeAssertions
+
+	f, err := os.Open(path)
+	if err != nil {
+		return assertions, fmt.Errorf("failed to read upgrade assertions: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&assertions); err != nil {
+		return assertions, fmt.Errorf("failed to parse upgrade assertions %s: %w", path, err)
+	}
+	if err := assertions.Validate(); err != nil {
+		return assertions, fmt.Errorf("invalid upgrade assertions %s:\n%w", path, err)
+	}
+	return assertions, nil
+}
+
+// Validate checks every assertion has a single query & at least one check
+func (a UpgradeAssertions) Validate() error {
+	var errs []error
+
+	if len(a.Assertions) == 0 {
+		errs = append(errs, fmt.Errorf("assertions: at least one assertion is required"))
+	}
+	for i, assertion := range a.Assertions {
+		field := fmt.Sprintf("assertions[%d]", i)
+		if assertion.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name: required", field))
+		}
+		if (assertion.Query == "") == (assertion.Grpc == "") {
+			errs = append(errs, fmt.Errorf("%s: exactly one of query or grpc is required", field))
+		}
+		if assertion.Grpc != "" && !strings.HasPrefix(assertion.Grpc, "/") {
+			errs = append(errs, fmt.Errorf("%s.grpc: must be a path starting with /, found %q", field, assertion.Grpc))
+		}
+		if _, err := parseJSONPath(assertion.Path); err != nil {
+			errs = append(errs, fmt.Errorf("%s.path: %w", field, err))
+		}
+		switch assertion.when() {
+		case assertBefore, assertAfter, assertBoth:
+		default:
+			errs = append(errs, fmt.Errorf("%s.when: must be before, after or both, found %q", field, assertion.When))
+		}
+		if assertion.Equals == nil && assertion.Exists == nil && assertion.Contains == "" && assertion.Changed == nil {
+			errs = append(errs, fmt.Errorf("%s: at least one of equals, exists, contains or changed is required", field))
+		}
+		if assertion.Changed != nil && assertion.when() != assertBoth {
+			errs = append(errs, fmt.Errorf("%s.changed: requires when: both", field))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (a UpgradeAssertion) when() string {
+	if a.When == "" {
+		return assertAfter
+	}
+	return a.When
+}
+
+// runsFor returns true if the assertion is checked at phase of the upgrade
+func (a UpgradeAssertion) runsFor(upgrade, phase string) bool {
+	if a.Upgrade != "" && a.Upgrade != upgrade {
+		return false
+	}
+	return a.when() == assertBoth || a.when() == phase
+}
+
+// assertionResult is the outcome of checking an assertion at one phase of an upgrade
+type assertionResult struct {
+	Name    string
+	Upgrade string
+	Phase   string
+	Passed  bool
+	Message string
+}
+
+// assertionRunner checks upgrade assertions & collects their results
+type assertionRunner struct {
+	assertions []UpgradeAssertion
+	results    []assertionResult
+	// beforeValues are the values selected before the halt, keyed by upgrade then assertion index. They are used by changed.
+	beforeValues map[string]map[int]interface{}
+}
+
+func newAssertionRunner(assertions []UpgradeAssertion) *assertionRunner {
+	return &assertionRunner{
+		assertions:   assertions,
+		beforeValues: map[string]map[int]interface{}{},
+	}
+}
+
+// check runs every assertion of phase for an upgrade. Failures are recorded, not returned, so all assertions are reported.
+func (r *assertionRunner) check(ctx context.Context, upgrade, phase string) {
+	if r == nil {
+		return
+	}
+	for i, assertion := range r.assertions {
+		if !assertion.runsFor(upgrade, phase) {
+			continue
+		}
+		result := assertionResult{Name: assertion.Name, Upgrade: upgrade, Phase: phase}
+		value, found, err := queryAssertion(ctx, assertion)
+		if err != nil {
+			result.Message = err.Error()
+		} else {
+			if phase == assertBefore {
+				if r.beforeValues[upgrade] == nil {
+					r.beforeValues[upgrade] = map[int]interface{}{}
+				}
+				r.beforeValues[upgrade][i] = value
+			}
+			before, hasBefore := r.beforeValues[upgrade][i]
+			result.Message = evaluateAssertion(assertion, phase, value, found, before, hasBefore)
+		}
+		result.Passed = result.Message == ""
+		if result.Passed {
+			result.Message = "ok"
+		}
+		fmt.Printf("upgrade assertion %q (%s %s): %s\n", assertion.Name, phase, upgrade, result.Message)
+		r.results = append(r.results, result)
+	}
+}
+
+// failed returns the number of failed assertions
+func (r *assertionRunner) failed() int {
+	failed := 0
+	for _, result := range r.results {
+		if !result.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// report writes a table of every assertion result & returns an error if any assertion failed
+func (r *assertionRunner) report(w io.Writer) error {
+	if r == nil || len(r.results) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tUPGRADE\tPHASE\tASSERTION\tMESSAGE\t")
+	for _, result := range r.results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", status, result.Upgrade, result.Phase, result.Name, result.Message)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if failed := r.failed(); failed > 0 {
+		return fmt.Errorf("%d of %d upgrade assertions failed", failed, len(r.results))
+	}
+	return nil
+}
+
+// queryAssertion runs the assertion's query & selects the value at its path.
+// found is false if the path doesn't match a value.
+func queryAssertion(ctx context.Context, assertion UpgradeAssertion) (interface{}, bool, error) {
+	var bz []byte
+	var err error
+	if assertion.Query != "" {
+		args := append(strings.Fields(assertion.Query), "--output", "json")
+		bz, err = kavaCliOutput(ctx, args...)
+	} else {
+		bz, err = queryGrpcGateway(ctx, assertion.Grpc)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("query failed: %w", err)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(bz, &result); err != nil {
+		return nil, false, fmt.Errorf("query result is not json: %w", err)
+	}
+	path, err := parseJSONPath(assertion.Path)
+	if err != nil {
+		return nil, false, err
+	}
+	value, found := path.selectFrom(result)
+	return value, found, nil
+}
+
+// queryGrpcGateway gets a grpc gateway path from the api of the kava node published on the host
+func queryGrpcGateway(ctx context.Context, path string) ([]byte, error) {
+	compose, err := loadComposeFile(generatedPath("docker-compose.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	port, ok := compose.Services[DockerServiceKavaNode].hostPort(apiPort)
+	if !ok {
+		return nil, fmt.Errorf("%s does not publish the api port %d", DockerServiceKavaNode, apiPort)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://localhost:%d%s", port, path), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	bz, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s: %s", path, res.Status, strings.TrimSpace(string(bz)))
+	}
+	return bz, nil
+}
+
+// evaluateAssertion returns a description of the first failed check, or "" if all checks passed
+func evaluateAssertion(assertion UpgradeAssertion, phase string, value interface{}, found bool, before interface{}, hasBefore bool) string {
+	if assertion.Changed != nil && phase == assertBefore {
+		return ""
+	}
+	if assertion.Exists != nil && *assertion.Exists != found {
+		if found {
+			return fmt.Sprintf("expected no value at %s, found %s", assertion.Path, formatJSON(value))
+		}
+		return fmt.Sprintf("expected a value at %s", assertion.Path)
+	}
+	if !found {
+		if assertion.Exists != nil {
+			// the path is expected to be missing, the other checks don't apply
+			return ""
+		}
+		return fmt.Sprintf("no value at %s", assertion.Path)
+	}
+	if assertion.Equals != nil && !jsonValuesEqual(assertion.Equals, value) {
+		return fmt.Sprintf("expected %s, found %s", formatJSON(assertion.Equals), formatJSON(value))
+	}
+	if assertion.Contains != "" && !strings.Contains(formatJSON(value), assertion.Contains) {
+		return fmt.Sprintf("expected %s to contain %q", formatJSON(value), assertion.Contains)
+	}
+	if assertion.Changed != nil && phase == assertAfter {
+		if !hasBefore {
+			return "no value was recorded before the upgrade"
+		}
+		if changed := !jsonValuesEqual(before, value); changed != *assertion.Changed {
+			if changed {
+				return fmt.Sprintf("expected no change, %s changed to %s", formatJSON(before), formatJSON(value))
+			}
+			return fmt.Sprintf("expected a change, value is still %s", formatJSON(value))
+		}
+	}
+	return ""
+}
+
+// jsonValuesEqual compares values by their json representation. Scalars are compared by their string form
+// because the cli & grpc gateway encode integers as strings.
+func jsonValuesEqual(expected, actual interface{}) bool {
+	normalize := func(v interface{}) interface{} {
+		bz, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var normalized interface{}
+		if err := json.Unmarshal(bz, &normalized); err != nil {
+			return v
+		}
+		return normalized
+	}
+	expected, actual = normalize(expected), normalize(actual)
+	if isScalar(expected) && isScalar(actual) {
+		return fmt.Sprint(expected) == fmt.Sprint(actual)
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, float64, bool:
+		return true
+	}
+	return false
+}
+
+func formatJSON(v interface{}) string {
+	bz, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(bz)
+}
+
+// jsonPath is a parsed JSONPath. The supported subset is the root $, .field, ['field'], [index] & [*].
+type jsonPath []jsonPathSegment
+
+type jsonPathSegment struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses a JSONPath. The leading $ is optional & an empty path selects the whole document.
+func parseJSONPath(path string) (jsonPath, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	// allow the leading . to be omitted, eg. params.community_tax
+	if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+	var segments jsonPath
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			field := rest[:end]
+			if field == "" {
+				return nil, fmt.Errorf("empty field in %q", path)
+			}
+			if field == "*" {
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			} else {
+				segments = append(segments, jsonPathSegment{field: field})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed [ in %q", path)
+			}
+			selector := rest[1:end]
+			rest = rest[end+1:]
+			switch {
+			case selector == "*":
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				segments = append(segments, jsonPathSegment{field: selector[1 : len(selector)-1]})
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("invalid selector [%s] in %q", selector, path)
+				}
+				segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("expected . or [ at %q in %q", rest, path)
+		}
+	}
+	return segments, nil
+}
+
+// selectFrom returns the value at the path. Wildcards select a list of the matched values.
+func (p jsonPath) selectFrom(v interface{}) (interface{}, bool) {
+	if len(p) == 0 {
+		return v, true
+	}
+	segment, rest := p[0], p[1:]
+	switch {
+	case segment.wildcard:
+		var children []interface{}
+		switch node := v.(type) {
+		case []interface{}:
+			children = node
+		case map[string]interface{}:
+			// fields are visited in order so the selected list is stable between queries
+			keys := make([]string, 0, len(node))
+			for key := range node {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				children = append(children, node[key])
+			}
+		default:
+			return nil, false
+		}
+		matches := []interface{}{}
+		for _, child := range children {
+			if match, found := rest.selectFrom(child); found {
+				matches = append(matches, match)
+			}
+		}
+		return matches, true
+	case segment.isIndex:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, false
+		}
+		index := segment.index
+		if index < 0 {
+			index += len(list)
+		}
+		if index < 0 || index >= len(list) {
+			return nil, false
+		}
+		return rest.selectFrom(list[index])
+	default:
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		child, found := object[segment.field]
+		if !found {
+			return nil, false
+		}
+		return rest.selectFrom(child)
+	}
+}
@@ -0,0 +1,483 @@
+package testnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeAssertions writes an upgrade assertions file & returns its path
+func writeAssertions(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "assertions.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestLoadUpgradeAssertions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		expected []UpgradeAssertion
+		errMsgs  []string
+	}{
+		{
+			name: "valid",
+			contents: `
+assertions:
+  - name: conversion pair
+    query: q evmutil params
+    path: $.params.conversion_pairs[0].denom
+    equals: erc20/multichain/usdc
+  - name: voting period
+    grpc: /cosmos/gov/v1beta1/params/voting
+    when: both
+    upgrade: v0.26.0
+    changed: false
+    exists: true
+`,
+			expected: []UpgradeAssertion{
+				{
+					Name:   "conversion pair",
+					Query:  "q evmutil params",
+					Path:   "$.params.conversion_pairs[0].denom",
+					Equals: "erc20/multichain/usdc",
+				},
+				{
+					Name:    "voting period",
+					Grpc:    "/cosmos/gov/v1beta1/params/voting",
+					When:    assertBoth,
+					Upgrade: "v0.26.0",
+					Changed: boolPtr(false),
+					Exists:  boolPtr(true),
+				},
+			},
+		},
+		{
+			name:     "unknown field",
+			contents: "assertions:\n  - name: a\n    query: q bank total\n    equal: 1\n",
+			errMsgs:  []string{"field equal not found"},
+		},
+		{
+			name:     "no assertions",
+			contents: "assertions: []\n",
+			errMsgs:  []string{"assertions: at least one assertion is required"},
+		},
+		{
+			name:     "missing name",
+			contents: "assertions:\n  - query: q bank total\n    exists: true\n",
+			errMsgs:  []string{"assertions[0].name: required"},
+		},
+		{
+			name:     "query & grpc",
+			contents: "assertions:\n  - name: a\n    query: q bank total\n    grpc: /cosmos/bank/v1beta1/supply\n    exists: true\n",
+			errMsgs:  []string{"assertions[0]: exactly one of query or grpc is required"},
+		},
+		{
+			name:     "no query",
+			contents: "assertions:\n  - name: a\n    exists: true\n",
+			errMsgs:  []string{"assertions[0]: exactly one of query or grpc is required"},
+		},
+		{
+			name:     "relative grpc path",
+			contents: "assertions:\n  - name: a\n    grpc: cosmos/bank/v1beta1/supply\n    exists: true\n",
+			errMsgs:  []string{`assertions[0].grpc: must be a path starting with /, found "cosmos/bank/v1beta1/supply"`},
+		},
+		{
+			name:     "invalid path",
+			contents: "assertions:\n  - name: a\n    query: q bank total\n    path: $.supply[0\n    exists: true\n",
+			errMsgs:  []string{"assertions[0].path: unclosed ["},
+		},
+		{
+			name:     "invalid when",
+			contents: "assertions:\n  - name: a\n    query: q bank total\n    when: during\n    exists: true\n",
+			errMsgs:  []string{`assertions[0].when: must be before, after or both, found "during"`},
+		},
+		{
+			name:     "no checks",
+			contents: "assertions:\n  - name: a\n    query: q bank total\n",
+			errMsgs:  []string{"assertions[0]: at least one of equals, exists, contains or changed is required"},
+		},
+		{
+			name:     "changed without both",
+			contents: "assertions:\n  - name: a\n    query: q bank total\n    when: after\n    changed: true\n",
+			errMsgs:  []string{"assertions[0].changed: requires when: both"},
+		},
+		{
+			name:     "errors are combined",
+			contents: "assertions:\n  - query: q bank total\n  - name: b\n    grpc: supply\n    exists: true\n",
+			errMsgs: []string{
+				"assertions[0].name: required",
+				"assertions[0]: at least one of equals, exists, contains or changed is required",
+				"assertions[1].grpc: must be a path starting with /",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			loaded, err := LoadUpgradeAssertions(writeAssertions(t, tc.contents))
+			if len(tc.errMsgs) > 0 {
+				require.Error(t, err)
+				for _, msg := range tc.errMsgs {
+					require.ErrorContains(t, err, msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, loaded.Assertions)
+		})
+	}
+}
+
+func TestLoadUpgradeAssertionsMissingFile(t *testing.T) {
+	_, err := LoadUpgradeAssertions(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorContains(t, err, "failed to read upgrade assertions")
+}
+
+func TestUpgradeAssertionRunsFor(t *testing.T) {
+	testCases := []struct {
+		name      string
+		assertion UpgradeAssertion
+		upgrade   string
+		phase     string
+		runs      bool
+	}{
+		{"default is after", UpgradeAssertion{}, "v0.26.0", assertAfter, true},
+		{"default isn't before", UpgradeAssertion{}, "v0.26.0", assertBefore, false},
+		{"before", UpgradeAssertion{When: assertBefore}, "v0.26.0", assertBefore, true},
+		{"before isn't after", UpgradeAssertion{When: assertBefore}, "v0.26.0", assertAfter, false},
+		{"both before", UpgradeAssertion{When: assertBoth}, "v0.26.0", assertBefore, true},
+		{"both after", UpgradeAssertion{When: assertBoth}, "v0.26.0", assertAfter, true},
+		{"matching upgrade", UpgradeAssertion{Upgrade: "v0.26.0"}, "v0.26.0", assertAfter, true},
+		{"other upgrade", UpgradeAssertion{Upgrade: "v0.25.0", When: assertBoth}, "v0.26.0", assertAfter, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.runs, tc.assertion.runsFor(tc.upgrade, tc.phase))
+		})
+	}
+}
+
+func TestJSONPathSelect(t *testing.T) {
+	const doc = `{"params":{"pairs":[{"denom":"usdc","n":1},{"denom":"usdt","n":"2"}],"tax":"0.02","dotted.key":true}}`
+
+	testCases := []struct {
+		path     string
+		expected string
+		found    bool
+		errMsg   string
+	}{
+		{path: "", expected: doc, found: true},
+		{path: "$", expected: doc, found: true},
+		{path: "$.params.tax", expected: `"0.02"`, found: true},
+		{path: "params.tax", expected: `"0.02"`, found: true},
+		{path: "$.params.pairs[1].denom", expected: `"usdt"`, found: true},
+		{path: "$.params.pairs[-1].n", expected: `"2"`, found: true},
+		{path: "$.params.pairs[*].denom", expected: `["usdc","usdt"]`, found: true},
+		{path: "$.params['dotted.key']", expected: `true`, found: true},
+		{path: `$.params["tax"]`, expected: `"0.02"`, found: true},
+		{path: "$.params.pairs[2]", found: false},
+		{path: "$.params.missing", found: false},
+		{path: "$.params.tax[0]", found: false},
+		{path: "$.params.pairs.denom", found: false},
+		{path: "$..params", errMsg: "empty field"},
+		{path: "$.params[0", errMsg: "unclosed ["},
+		{path: "$.params[x]", errMsg: "invalid selector [x]"},
+		{path: "$.params.pairs[0]denom", errMsg: `expected . or [ at "denom"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			path, err := parseJSONPath(tc.path)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			var v interface{}
+			require.NoError(t, json.Unmarshal([]byte(doc), &v))
+			value, found := path.selectFrom(v)
+			require.Equal(t, tc.found, found)
+			if tc.found {
+				require.JSONEq(t, tc.expected, formatJSON(value))
+			}
+		})
+	}
+}
+
+func TestEvaluateAssertion(t *testing.T) {
+	testCases := []struct {
+		name      string
+		assertion UpgradeAssertion
+		phase     string
+		value     interface{}
+		found     bool
+		before    interface{}
+		hasBefore bool
+		expected  string
+	}{
+		{
+			name:      "equals",
+			assertion: UpgradeAssertion{Equals: "usdc"},
+			phase:     assertAfter, value: "usdc", found: true,
+		},
+		{
+			name:      "equals compares scalars by string",
+			assertion: UpgradeAssertion{Equals: 10},
+			phase:     assertAfter, value: "10", found: true,
+		},
+		{
+			name:      "equals compares objects",
+			assertion: UpgradeAssertion{Equals: map[string]interface{}{"a": 1}},
+			phase:     assertAfter, value: map[string]interface{}{"a": float64(1)}, found: true,
+		},
+		{
+			name:      "not equal",
+			assertion: UpgradeAssertion{Equals: "usdc"},
+			phase:     assertAfter, value: "usdt", found: true,
+			expected: `expected "usdc", found "usdt"`,
+		},
+		{
+			name:      "no value",
+			assertion: UpgradeAssertion{Path: "$.a", Equals: "usdc"},
+			phase:     assertAfter,
+			expected:  "no value at $.a",
+		},
+		{
+			name:      "exists",
+			assertion: UpgradeAssertion{Path: "$.a", Exists: boolPtr(true)},
+			phase:     assertAfter, value: "x", found: true,
+		},
+		{
+			name:      "expected to exist",
+			assertion: UpgradeAssertion{Path: "$.a", Exists: boolPtr(true)},
+			phase:     assertAfter,
+			expected:  "expected a value at $.a",
+		},
+		{
+			name:      "expected not to exist",
+			assertion: UpgradeAssertion{Path: "$.a", Exists: boolPtr(false)},
+			phase:     assertAfter, value: "x", found: true,
+			expected: `expected no value at $.a, found "x"`,
+		},
+		{
+			name:      "missing value skips other checks",
+			assertion: UpgradeAssertion{Path: "$.a", Exists: boolPtr(false), Equals: "x"},
+			phase:     assertAfter,
+		},
+		{
+			name:      "contains",
+			assertion: UpgradeAssertion{Contains: "usdc"},
+			phase:     assertAfter, value: []interface{}{"usdc", "usdt"}, found: true,
+		},
+		{
+			name:      "doesn't contain",
+			assertion: UpgradeAssertion{Contains: "dai"},
+			phase:     assertAfter, value: []interface{}{"usdc"}, found: true,
+			expected: `expected ["usdc"] to contain "dai"`,
+		},
+		{
+			name:      "changed is only recorded before",
+			assertion: UpgradeAssertion{When: assertBoth, Changed: boolPtr(true), Equals: "new"},
+			phase:     assertBefore, value: "old", found: true,
+		},
+		{
+			name:      "changed",
+			assertion: UpgradeAssertion{When: assertBoth, Changed: boolPtr(true)},
+			phase:     assertAfter, value: "new", found: true, before: "old", hasBefore: true,
+		},
+		{
+			name:      "expected a change",
+			assertion: UpgradeAssertion{When: assertBoth, Changed: boolPtr(true)},
+			phase:     assertAfter, value: "old", found: true, before: "old", hasBefore: true,
+			expected: `expected a change, value is still "old"`,
+		},
+		{
+			name:      "unchanged",
+			assertion: UpgradeAssertion{When: assertBoth, Changed: boolPtr(false)},
+			phase:     assertAfter, value: "1", found: true, before: float64(1), hasBefore: true,
+		},
+		{
+			name:      "expected no change",
+			assertion: UpgradeAssertion{When: assertBoth, Changed: boolPtr(false)},
+			phase:     assertAfter, value: "new", found: true, before: "old", hasBefore: true,
+			expected: `expected no change, "old" changed to "new"`,
+		},
+		{
+			name:      "changed without a value before",
+			assertion: UpgradeAssertion{When: assertBoth, Changed: boolPtr(true)},
+			phase:     assertAfter, value: "new", found: true,
+			expected: "no value was recorded before the upgrade",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			message := evaluateAssertion(tc.assertion, tc.phase, tc.value, tc.found, tc.before, tc.hasBefore)
+			require.Equal(t, tc.expected, message)
+		})
+	}
+}
+
+func TestAssertionRunnerCheck(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cosmos/gov/v1beta1/params/voting":
+			fmt.Fprint(w, `{"voting_params":{"voting_period":"600s"}}`)
+		default:
+			http.Error(w, `{"code":12,"message":"Not Implemented"}`, http.StatusNotImplemented)
+		}
+	}))
+	t.Cleanup(api.Close)
+	useChainNodeCompose(t, fmt.Sprintf("%s:%d", serverPort(t, api.URL), apiPort))
+
+	fake := useFakeRuntime(t, DockerServiceKavaNode)
+	require.NoError(t, fake.ComposeUp(context.Background(), ComposeUpOptions{}))
+	// the evmutil params change during the upgrade
+	denom := "usdc"
+	fake.ExecFunc = func(opts ExecOptions) error {
+		switch strings.Join(opts.Cmd, " ") {
+		case "kava q evmutil params --output json":
+			_, err := fmt.Fprintf(opts.Stdout, `{"params":{"conversion_pairs":[{"denom":"%s"}]}}`, denom)
+			return err
+		case "kava q bank total --output json":
+			_, err := fmt.Fprint(opts.Stdout, "not json")
+			return err
+		}
+		return fmt.Errorf("unexpected command %v", opts.Cmd)
+	}
+
+	runner := newAssertionRunner([]UpgradeAssertion{
+		{Name: "denom changed", Query: "q evmutil params", Path: "$.params.conversion_pairs[0].denom", When: assertBoth, Changed: boolPtr(true)},
+		{Name: "denom before", Query: "q evmutil params", Path: "params.conversion_pairs[0].denom", When: assertBefore, Equals: "usdc"},
+		{Name: "voting period", Grpc: "/cosmos/gov/v1beta1/params/voting", Path: "$.voting_params.voting_period", Equals: "600s"},
+		{Name: "unserved path", Grpc: "/kava/unknown", Exists: boolPtr(true)},
+		{Name: "invalid output", Query: "q bank total", Exists: boolPtr(true)},
+		{Name: "other upgrade", Query: "q evmutil params", Upgrade: "v0.25.0", Exists: boolPtr(true)},
+	})
+
+	ctx := context.Background()
+	runner.check(ctx, "v0.26.0", assertBefore)
+	denom = "usdt"
+	runner.check(ctx, "v0.26.0", assertAfter)
+
+	require.Equal(t, []assertionResult{
+		{Name: "denom changed", Upgrade: "v0.26.0", Phase: assertBefore, Passed: true, Message: "ok"},
+		{Name: "denom before", Upgrade: "v0.26.0", Phase: assertBefore, Passed: true, Message: "ok"},
+		{Name: "denom changed", Upgrade: "v0.26.0", Phase: assertAfter, Passed: true, Message: "ok"},
+		{Name: "voting period", Upgrade: "v0.26.0", Phase: assertAfter, Passed: true, Message: "ok"},
+		{
+			Name: "unserved path", Upgrade: "v0.26.0", Phase: assertAfter,
+			Message: `query failed: /kava/unknown returned 501 Not Implemented: {"code":12,"message":"Not Implemented"}`,
+		},
+		{
+			Name: "invalid output", Upgrade: "v0.26.0", Phase: assertAfter,
+			Message: "query result is not json: invalid character 'o' in literal null (expecting 'u')",
+		},
+	}, runner.results)
+	require.Equal(t, 2, runner.failed())
+}
+
+func TestAssertionRunnerCheckChangedPerUpgrade(t *testing.T) {
+	fake := useFakeRuntime(t, DockerServiceKavaNode)
+	require.NoError(t, fake.ComposeUp(context.Background(), ComposeUpOptions{}))
+	version := "1"
+	fake.ExecFunc = func(opts ExecOptions) error {
+		_, err := fmt.Fprintf(opts.Stdout, `{"version":"%s"}`, version)
+		return err
+	}
+
+	runner := newAssertionRunner([]UpgradeAssertion{
+		{Name: "version", Query: "q upgrade applied", Path: "version", When: assertBoth, Changed: boolPtr(true)},
+	})
+	ctx := context.Background()
+	runner.check(ctx, "v0.25.0", assertBefore)
+	version = "2"
+	runner.check(ctx, "v0.25.0", assertAfter)
+	// the second upgrade doesn't change the value, it must be compared with its own value before the halt
+	runner.check(ctx, "v0.26.0", assertBefore)
+	runner.check(ctx, "v0.26.0", assertAfter)
+
+	require.Len(t, runner.results, 4)
+	require.True(t, runner.results[1].Passed, runner.results[1].Message)
+	require.False(t, runner.results[3].Passed)
+	require.Equal(t, `expected a change, value is still "2"`, runner.results[3].Message)
+}
+
+func TestAssertionRunnerReport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		runner   *assertionRunner
+		expected string
+		errMsg   string
+	}{
+		{
+			name: "nil runner",
+		},
+		{
+			name:   "no results",
+			runner: newAssertionRunner(nil),
+		},
+		{
+			name: "passed",
+			runner: &assertionRunner{results: []assertionResult{
+				{Name: "denom", Upgrade: "v0.26.0", Phase: assertBefore, Passed: true, Message: "ok"},
+				{Name: "voting period", Upgrade: "v0.26.0", Phase: assertAfter, Passed: true, Message: "ok"},
+			}},
+			expected: `RESULT  UPGRADE  PHASE   ASSERTION      MESSAGE
+PASS    v0.26.0  before  denom          ok
+PASS    v0.26.0  after   voting period  ok
+`,
+		},
+		{
+			name: "failed",
+			runner: &assertionRunner{results: []assertionResult{
+				{Name: "denom", Upgrade: "v0.26.0", Phase: assertBefore, Passed: true, Message: "ok"},
+				{Name: "denom", Upgrade: "v0.26.0", Phase: assertAfter, Message: `expected "usdc", found "usdt"`},
+				{Name: "supply", Upgrade: "v0.26.0", Phase: assertAfter, Message: "no value at $.supply"},
+			}},
+			expected: `RESULT  UPGRADE  PHASE   ASSERTION  MESSAGE
+PASS    v0.26.0  before  denom      ok
+FAIL    v0.26.0  after   denom      expected "usdc", found "usdt"
+FAIL    v0.26.0  after   supply     no value at $.supply
+`,
+			errMsg: "2 of 3 upgrade assertions failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := tc.runner.report(&out)
+			if tc.errMsg != "" {
+				require.EqualError(t, err, tc.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expected, trimLines(out.String()))
+		})
+	}
+}
+
+// trimLines removes the padding tabwriter adds after the last column of each line
+func trimLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
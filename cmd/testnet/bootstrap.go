@@ -4,8 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
-	"strconv"
 	"strings"
 	"time"
 
@@ -37,11 +35,42 @@ at runtime via the 'kava.configTemplate' flag. The Kava templates contain kava c
 (including a genesis.json) that are supported with the corresponding kava docker image tag.
 
 Some templates, like "master", support overriding the image tag via the KAVA_TAG env variable.
+Each template has a template.yaml manifest describing the kava versions, image, db backends & kvtool features
+it supports. Combinations a template doesn't support are rejected before anything is generated, see
+'kvtool testnet templates list'.
+
+## Multiple validators
+By default the network runs with a single validator. Use --validators to run more. Each additional
+validator gets a fresh node key, consensus key and operator account, and is included in genesis with
+its own gentx. The validators run in the containers kavanode, kavanode2, kavanode3, etc. and have their
+host ports shifted by 100 per validator. Their operator keys are available in the keyring as
+validator2, validator3, etc.
 
 ## Database backend
 The --kava.db flag can be used to change the db_backend value in the generated configuration's app.toml.
 Note that the KAVA_TAG used must be compatible with the provided backend type.
 
+## Multiple networks
+--name runs an isolated network alongside the default network & other named networks, eg. for integration
+suites running in parallel on one host. A named network has its own generated dir, compose project &
+docker network, its containers are named <name>-<service>-1 & its host ports are offset by the first
+multiple of 1000 that doesn't conflict with the other networks, or by --port-offset. Pass the same --name
+to the other testnet commands, eg. 'kvtool testnet down --name <name>'. 'kvtool testnet ls' lists the networks.
+
+## Port conflicts
+The docker-compose.yaml of each service is merged into the generated one. Lists like ports, volumes &
+environment are combined, while conflicting values, host ports published by more than one service,
+duplicate container names & volumes mounted twice to the same path fail generation with a report of
+every conflict. With --remap-ports, conflicting host ports are published on the next free port instead.
+The remapped ports are printed & found in the generated docker-compose.yaml by the commands that query the chains.
+
+## Node settings
+--kava.config & --kava.app set a key of the config.toml & app.toml of every kava node: the validators,
+the pruning node & the ibc chains. Keys are dotted paths of the toml tables, eg. consensus.timeout_commit.
+The key must exist in the template's config & the value keeps the type of the template's value, so typos &
+settings of other kava versions are rejected before anything is started. Non-string values are toml, eg.
+true, 5000 or ["a", "b"].
+
 # IBC
 The bootstrap command supports running a secondary chain and opening an IBC channel between the
 primary Kava node and the secondary chain. To set this up, simply use the --ibc flag.
@@ -50,6 +79,21 @@ Once the two chains are started, the necessary txs are run to open a channel bet
 and a relayer is started to relay transactions between them. The primary denom of the secondary chain
 is "uatom" and it runs under the docker container named "ibcchain".
 
+## IBC topologies
+A topology file can run several counterparty chains & link any pair of them, see ibc.chains & ibc.paths below.
+Each chain runs the ibcchain template with its own chain id, denom, image or genesis in the service <name>node
+(ibcnode for the chain named ibcchain) & its host ports are shifted by 10 per chain. Kava is the chain named kava.
+Paths default to a transfer path between kava & each chain. Their type is transfer (ports transfer & version
+ics20-1 unless set), custom (srcPort, dstPort & version are required) or ica, which only opens a connection as
+the channel is opened by registering an interchain account. The relayer's config.yaml & keys are generated from
+the chains & paths, & every path is opened before the relayer is started.
+
+## Relayers
+--relayer picks the relayer implementation: rly, the go relayer (the default), or hermes. Both generate their
+config from the same chains & paths, open every path & run as a compose service, relayer & hermes respectively,
+so relayer specific behaviour can be reproduced. Hermes' keys are added with 'hermes keys add' before the paths
+are opened, from the mnemonic the go relayer's keys were created with.
+
 # Automated Chain Upgrades
 The bootstrap command supports running a chain that is then upgraded via an upgrade handler. The following
 flags are all required to run an automated software upgrade:
@@ -66,7 +110,116 @@ When these flags are defined, the chain is initially started with the --upgrade-
 As soon as the chain is configured & producing blocks, a committee proposal is submitted to update
 the chain. The committee uses First-Pass-the-Post voting so passes as soon as it gets consensus.
 The committee member account votes on the proposal and then we wait for the upgrade height to be
-reached. At that point, the chain halts and is restarted with the updated image tag.`,
+reached. At that point, the chain halts and is restarted with the updated image tag.
+
+The proposal id is read from the events of the submit proposal tx, so it doesn't matter how many proposals
+already exist.
+
+## Upgrading via governance
+Upgrades can be passed the way mainnet's are with --upgrade-via gov. A software upgrade proposal is submitted
+to x/gov with the minimum deposit, every validator key (validator, validator2, ...) votes yes & the upgrade
+waits for the proposal to pass.
+The upgrade height must leave room for the gov voting period, which is 30s in the templates.
+
+## Starting from an exported genesis
+--genesis starts the network from another genesis file, eg. an export of mainnet, instead of the template's.
+The template's config & keys are used & the genesis validators with the most power have their consensus
+keys replaced by the local validators' keys (validator, validator2, ...). Their power is increased to hold
+at least --genesis-min-power of the total so the network produces blocks without the other validators.
+x/staking reverts the power to that of the validators' delegations after the first block, unless
+--genesis-persistent-power bonds tokens backing the increase to the local validators.
+The chain-id of the genesis is used by the local cli.
+
+--genesis-god-committee adds a committee that passes any proposal to the genesis, with the local committee
+key as its only member, so upgrades can be run via the committee. Upgrading a --genesis via the committee requires it.
+With --upgrade-via gov, the local validator keys are funded & delegate enough tokens to the replaced validators
+to reach the x/gov quorum by themselves, as the other validators & delegators of the genesis don't vote.
+
+## Validating genesis
+A broken genesis otherwise only shows up when the kava node crashes. --validate-genesis runs the checks of
+'kvtool genesis validate' on the generated genesis before any containers are started & fails with every problem
+found. The local validators' consensus keys must also be genesis validators, so the network can produce blocks.
+The modules check uses the kava version kvtool is built with, skip it for templates of other versions with
+--validate-genesis-skip modules.
+
+## Upgrade assertions
+Reaching the block after the upgrade doesn't show the migrations did what was intended. --upgrade-assertions
+takes a yaml file of queries whose json results are checked once the upgrade proposal passes ("before"),
+once the upgraded chain produces a block ("after", the default) or both. A query is either kava cli query
+arguments (run with --output json) or a grpc gateway path of the kava node's api. path is a JSONPath
+($.field, ['field'], [0], [*]) selecting the checked value. A pass/fail report is printed once all
+upgrades have run & bootstrap exits non-zero if any assertion failed.
+
+  assertions:
+    - name: community tax is zeroed
+      query: q distribution params
+      path: $.community_tax
+      equals: "0.000000000000000000"
+    - name: voting period is migrated
+      grpc: /cosmos/gov/v1beta1/params/voting
+      path: $.voting_params.voting_period
+      when: both
+      changed: true
+    - name: evmutil has conversion pairs
+      query: q evmutil params
+      path: $.params.enabled_conversion_pairs[0]
+      exists: true
+      upgrade: v0.24.0    # only check this upgrade of a multi-step plan
+
+## Multi-step upgrades
+To test a chain through several sequential upgrades, describe them in a yaml file passed with --upgrade-plan.
+The chain starts with baseImageTag and each upgrade is proposed, voted on, halted at and restarted with
+its imageTag in order. Heights must be increasing and leave enough blocks for each proposal to pass.
+imageTag is required on every step, as each binary only registers the handler of its own upgrade.
+
+  baseImageTag: v0.19.2
+  via: gov                # optional, defaults to --upgrade-via
+  steps:
+    - name: v0.21.0
+      height: 15
+      imageTag: v0.21.0
+    - name: v0.23.0
+      height: 40
+      imageTag: v0.23.0
+
+# Topology files
+Instead of flags, the network can be described by a versioned yaml file passed with --topology.
+The file is validated before any configuration is generated. It can't be combined with the flags it replaces.
+
+  version: 1
+  kava:
+    template: master      # --kava.configTemplate
+    imageTag: v0.26.0     # KAVA_TAG
+    db: goleveldb         # --kava.db
+    validators: 1         # --validators
+    config:               # --kava.config
+      consensus.timeout_commit: 500ms
+    app:                  # --kava.app
+      json-rpc.api: eth,net,web3
+  pruning: false          # --pruning
+  geth: false             # --geth
+  ibc:
+    enabled: true         # --ibc
+    relayer: rly          # --relayer
+    chains:               # optional, defaults to the ibcchain template
+      - name: ibcchain
+      - name: osmo
+        chainId: osmolocal_9000-1
+        denom: uosmo
+        image: kava/kava:v0.26.0
+        genesis: osmo.json  # optional, relative to the topology file
+    paths:                # optional, defaults to a transfer path between kava & each chain
+      - { name: transfer, src: kava, dst: ibcchain }
+      - { name: ica, src: kava, dst: osmo, type: ica }
+      - { name: custom, src: osmo, dst: ibcchain, type: custom, srcPort: p, dstPort: p, version: v1, order: ordered }
+  upgrade:                # --upgrade-name, --upgrade-height, --upgrade-base-image-tag, --upgrade-via, --upgrade-assertions
+    name: v0.26.0
+    height: 15
+    baseImageTag: v0.25.0
+    assertions: assertions.yaml
+    # or, instead of name & height, a list of upgrades as in --upgrade-plan
+    # steps:
+    #   - { name: v0.26.0, height: 15, imageTag: v0.26.0 }`,
 		Example: `Run kava node with particular template:
 $ kvtool testnet bootstrap --kava.configTemplate v0.12
 
@@ -76,6 +229,19 @@ $ KAVA_TAG=master-rocksdb kvtool testnet bootstrap --kava.db rocksdb
 Run kava & another chain with open IBC channel & relayer:
 $ kvtool testnet bootstrap --ibc
 
+Run kava & another chain relayed by hermes:
+$ kvtool testnet bootstrap --ibc --relayer hermes
+
+Run kava with faster blocks & a larger mempool:
+$ kvtool testnet bootstrap --kava.config consensus.timeout_commit=500ms --kava.config mempool.size=10000
+
+Run two isolated networks side by side:
+$ kvtool testnet bootstrap --name suite-a
+$ kvtool testnet bootstrap --name suite-b --port-offset 5000
+
+Run a kava network with 4 validators:
+$ kvtool testnet bootstrap --validators 4
+
 Run a kava network with an additional pruning node:
 $ kvtool testnet bootstrap --pruning
 
@@ -87,19 +253,62 @@ $ KAVA_TAG=v0.21.0 kvtool testnet bootstrap
 
 Test a chain upgrade from v0.19.2 -> v0.21.0:
 $ KAVA_TAG=v0.21.0 kvtool testnet bootstrap --upgrade-name v0.21.0 --upgrade-height 15 --upgrade-base-image-tag v0.19.2
+
+Test a chain upgrade passed by x/gov instead of the God Committee:
+$ KAVA_TAG=v0.21.0 kvtool testnet bootstrap --upgrade-name v0.21.0 --upgrade-height 30 --upgrade-base-image-tag v0.19.2 --upgrade-via gov
+
+Check the state migrated by an upgrade:
+$ KAVA_TAG=v0.21.0 kvtool testnet bootstrap --upgrade-name v0.21.0 --upgrade-height 15 --upgrade-base-image-tag v0.19.2 --upgrade-assertions assertions.yaml
+
+Test a chain through sequential upgrades described by an upgrade plan:
+$ kvtool testnet bootstrap --kava.configTemplate v0.19 --upgrade-plan upgrades.yaml
+
+Run a mirror of mainnet from an export, with a committee to pass proposals:
+$ kvtool testnet bootstrap --genesis export.json --genesis-god-committee
+
+Check the genesis of a template before starting it:
+$ kvtool testnet bootstrap --kava.configTemplate v0.19 --validate-genesis --validate-genesis-skip modules
+
+Run the network described by a topology file:
+$ kvtool testnet bootstrap --topology topology.yaml
 `,
 		Args: cobra.NoArgs,
 		// Avoid printing usage on error, as its most likely to be caused by
 		// a configuration error leading to container errors if something fails.
 		SilenceUsage: true,
-		RunE: func(_ *cobra.Command, _ []string) error {
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			if topologyFile != "" {
+				if err := applyTopology(cmd, topologyFile); err != nil {
+					return err
+				}
+			}
 			if err := validateBootstrapFlags(); err != nil {
 				return err
 			}
+			relayer, err := newIbcRelayer(relayerFlag)
+			if err != nil {
+				return err
+			}
+			upgradePlan, err := bootstrapUpgradePlan()
+			if err != nil {
+				return err
+			}
+			var assertions *assertionRunner
+			if chainUpgradeAssertions != "" {
+				if upgradePlan == nil {
+					return fmt.Errorf("--upgrade-assertions requires an automated chain upgrade")
+				}
+				loaded, err := LoadUpgradeAssertions(chainUpgradeAssertions)
+				if err != nil {
+					return err
+				}
+				assertions = newAssertionRunner(loaded.Assertions)
+			}
 
 			// shutdown existing networks if a docker-compose.yaml already exists.
 			if _, err := os.Stat(generatedPath("docker-compose.yaml")); err == nil {
-				if err2 := dockerComposeCmd("down").Run(); err2 != nil {
+				if err2 := containerRuntime.ComposeDown(ctx); err2 != nil {
 					return err2
 				}
 			}
@@ -110,48 +319,107 @@ $ KAVA_TAG=v0.21.0 kvtool testnet bootstrap --upgrade-name v0.21.0 --upgrade-hei
 			}
 
 			// generate kava node configuration
-			if err := generate.GenerateKavaConfig(kavaConfigTemplate, generatedConfigDir, kavaDbBackend); err != nil {
+			if err := generate.GenerateKavaConfig(kavaConfigTemplate, generatedConfigDir, kavaDbBackend, remapPortsFlag); err != nil {
+				return err
+			}
+			// handle additional validators
+			if err := generate.GenerateKavaValidatorsConfig(kavaConfigTemplate, generatedConfigDir, numValidators); err != nil {
 				return err
 			}
+			// replace the template's genesis with the provided one
+			if kavaGenesisFile != "" {
+				committeeID, err := generate.UseKavaGenesis(kavaConfigTemplate, generatedConfigDir, numValidators, kavaGenesisFile, generate.ExternalGenesisOptions{
+					MinPowerPercent: kavaGenesisMinPowerPercent,
+					PersistentPower: kavaGenesisPersistentPower,
+					GodCommittee:    kavaGenesisGodCommittee,
+					// the local validator keys vote on gov upgrades, so they're given enough stake to pass them
+					GovVoters: upgradePlan != nil && upgradePlan.Via == upgradeViaGov,
+				})
+				if err != nil {
+					return err
+				}
+				if kavaGenesisGodCommittee {
+					godCommitteeID = committeeID
+				}
+			}
+			// check the genesis before any containers are started
+			if validateGenesis {
+				if err := generate.ValidateKavaGenesis(kavaConfigTemplate, generatedConfigDir, numValidators, validateGenesisSkip); err != nil {
+					return err
+				}
+				fmt.Println("kava genesis passed validation")
+			}
 			// handle pruning node configuration
 			if includePruningFlag {
-				if err := generate.GenerateKavaPruningConfig(kavaConfigTemplate, generatedConfigDir, kavaDbBackend); err != nil {
+				if err := generate.GenerateKavaPruningConfig(kavaConfigTemplate, generatedConfigDir, kavaDbBackend, remapPortsFlag); err != nil {
 					return err
 				}
 			}
 			// handle ibc configuration
 			if ibcFlag {
-				if err := generate.GenerateIbcConfigs(generatedConfigDir); err != nil {
+				if err := generate.GenerateIbcChainsConfig(generatedConfigDir, ibcTopology, remapPortsFlag); err != nil {
+					return err
+				}
+				if err := relayer.Generate(generatedConfigDir, ibcTopology); err != nil {
 					return err
 				}
 			}
 			// handle geth configuration
 			if gethFlag {
-				if err := generate.GenerateGethConfig(generatedConfigDir); err != nil {
+				if err := generate.GenerateGethConfig(generatedConfigDir, remapPortsFlag); err != nil {
 					return err
 				}
 			}
+			// apply the node settings to all kava nodes, after every node is generated
+			overrides, err := kavaNodeConfigOverrides()
+			if err != nil {
+				return err
+			}
+			if err := generate.ApplyNodeConfigOverrides(generatedConfigDir, overrides); err != nil {
+				return err
+			}
+			// the pruning node syncs from the validators, so it gets their final genesis
+			if includePruningFlag {
+				if err := generate.CopyKavaGenesisToPruningNode(kavaConfigTemplate, generatedConfigDir); err != nil {
+					return err
+				}
+			}
+			// isolate the network from the other kvtool networks
+			portOffset, err := finalizeNetwork(cmd)
+			if err != nil {
+				return err
+			}
+
+			// record how the network was configured, the chain starts with the upgrade's base image if upgrading
+			startTag := os.Getenv(kavaTagEnv)
+			if upgradePlan != nil {
+				startTag = upgradePlan.BaseImageTag
+			}
+			if err := writeNetworkInfo(bootstrapNetworkInfo(startTag, portOffset)); err != nil {
+				return err
+			}
 
 			// pull the kava image tag if not overridden to be "local"
 			kavaTagOverride := os.Getenv(kavaTagEnv)
 			if kavaTagOverride != "local" {
-				if err := dockerComposeCmd("pull").Run(); err != nil {
+				if err := containerRuntime.ComposePull(ctx); err != nil {
 					fmt.Println(err.Error())
 				}
 			}
 
-			upCmd := dockerComposeCmd("up", "-d", "--remove-orphans")
+			upOpts := ComposeUpOptions{Detach: true, RemoveOrphans: true}
 			// when doing automated chain upgrade, ensure the node starts with the desired image tag
-			// if this is empty, the docker-compose should default to intended image tag
-			if chainUpgradeBaseImageTag != "" {
-				upCmd.Env = os.Environ()
-				upCmd.Env = append(upCmd.Env, fmt.Sprintf("%s=%s", kavaTagEnv, chainUpgradeBaseImageTag))
-				fmt.Printf("starting chain with image tag %s\n", chainUpgradeBaseImageTag)
+			// if there is no upgrade, the docker-compose should default to intended image tag
+			var baseImageTag string
+			if upgradePlan != nil {
+				baseImageTag = upgradePlan.BaseImageTag
+				upOpts.Env = []string{fmt.Sprintf("%s=%s", kavaTagEnv, baseImageTag)}
+				fmt.Printf("starting chain with image tag %s\n", baseImageTag)
 			}
-			if err := upCmd.Run(); err != nil {
+			if err := containerRuntime.ComposeUp(ctx, upOpts); err != nil {
 				return fmt.Errorf(
 					"failed to start chain with image %s: %w",
-					chainUpgradeBaseImageTag,
+					baseImageTag,
 					err,
 				)
 			}
@@ -163,14 +431,15 @@ $ KAVA_TAG=v0.21.0 kvtool testnet bootstrap --upgrade-name v0.21.0 --upgrade-hei
 			}
 
 			if ibcFlag {
-				if err := setupIbcChannelAndRelayer(); err != nil {
+				if err := setupIbcChannelAndRelayer(ctx, relayer); err != nil {
 					return fmt.Errorf("failed to setup IBC channel and relayer: %w", err)
 				}
 			}
 
 			// validation of all necessary data for an automated chain upgrade is performed in validateBootstrapFlags()
-			if chainUpgradeName != "" {
-				if err := runChainUpgrade(); err != nil {
+			// & when the upgrade plan is loaded
+			if upgradePlan != nil {
+				if err := runUpgradePlan(ctx, *upgradePlan, assertions); err != nil {
 					return fmt.Errorf("failed to run chain upgrade: %w", err)
 				}
 			}
@@ -181,12 +450,28 @@ $ KAVA_TAG=v0.21.0 kvtool testnet bootstrap --upgrade-name v0.21.0 --upgrade-hei
 
 	bootstrapCmd.Flags().StringVar(&kavaConfigTemplate, "kava.configTemplate", "master", "the directory name of the template used to generating the kava config")
 	bootstrapCmd.Flags().BoolVar(&includePruningFlag, "pruning", false, "flag for running pruning node alongside kava validator")
+	bootstrapCmd.Flags().IntVar(&numValidators, "validators", 1, "number of kava validators to run. each additional validator gets fresh keys & a gentx in genesis.")
 	bootstrapCmd.Flags().BoolVar(&ibcFlag, "ibc", false, "flag for if ibc is enabled")
+	bootstrapCmd.Flags().StringVar(&relayerFlag, "relayer", relayerRly, fmt.Sprintf("the relayer of the --ibc paths, one of %v.", supportedRelayers))
 	bootstrapCmd.Flags().BoolVar(&gethFlag, "geth", false, "flag for if geth is enabled")
+	addNodeConfigFlags(bootstrapCmd)
+	addPortOffsetFlag(bootstrapCmd)
+	bootstrapCmd.Flags().StringVar(&topologyFile, "topology", "", "path to a yaml file describing the network to run. replaces the template, db, service & upgrade flags.")
+
+	// optional genesis to start from instead of the template's
+	bootstrapCmd.Flags().StringVar(&kavaGenesisFile, "genesis", "", "path to a genesis.json, eg. an export of mainnet, used instead of the template's genesis. its validators with the most power are replaced by the local validators.")
+	bootstrapCmd.Flags().BoolVar(&kavaGenesisGodCommittee, "genesis-god-committee", false, "add a committee to the --genesis that can pass any proposal, with the local committee key as its member.")
+	bootstrapCmd.Flags().Float64Var(&kavaGenesisMinPowerPercent, "genesis-min-power", generate.DefaultMinPowerPercent, "minimum share of the --genesis voting power given to the local validators, 0 <= x < 1.")
+	bootstrapCmd.Flags().BoolVar(&kavaGenesisPersistentPower, "genesis-persistent-power", false, "bond tokens to the local validators backing their --genesis-min-power, so they keep it after the first block.")
+	bootstrapCmd.Flags().BoolVar(&validateGenesis, "validate-genesis", false, "check the kava genesis with the checks of 'kvtool genesis validate' before starting any containers.")
+	bootstrapCmd.Flags().StringSliceVar(&validateGenesisSkip, "validate-genesis-skip", nil, "names of the --validate-genesis checks that aren't run, eg. modules for templates of other kava versions.")
 
 	// optional data for running an automated chain upgrade
 	bootstrapCmd.Flags().StringVar(&chainUpgradeName, "upgrade-name", "", "name of automated chain upgrade to run, if desired. the upgrade must be defined in the kava image container.")
 	bootstrapCmd.Flags().Int64Var(&chainUpgradeHeight, "upgrade-height", 0, "height of automated chain upgrade to run.")
+	bootstrapCmd.Flags().StringVar(&chainUpgradePlanFile, "upgrade-plan", "", "path to a yaml file describing a sequence of upgrades to run. replaces the other upgrade flags.")
+	bootstrapCmd.Flags().StringVar(&chainUpgradeVia, "upgrade-via", upgradeViaCommittee, "how upgrades are proposed: committee (the God Committee) or gov (an x/gov proposal voted on by all validators).")
+	bootstrapCmd.Flags().StringVar(&chainUpgradeAssertions, "upgrade-assertions", "", "path to a yaml file of queries checked before the upgrade halt & after the restart. bootstrap fails if any assertion fails.")
 	bootstrapCmd.Flags().StringVar(&chainUpgradeBaseImageTag, "upgrade-base-image-tag", "", "the kava docker image tag that will be upgraded.\nthe chain is initialized from this tag and then upgraded to the new image.\nthe binary must be compatible with the kava.configTemplate genesis.json.")
 
 	return bootstrapCmd
@@ -195,146 +480,85 @@ $ KAVA_TAG=v0.21.0 kvtool testnet bootstrap --upgrade-name v0.21.0 --upgrade-hei
 func validateBootstrapFlags() error {
 	hasUpgradeName := chainUpgradeName != ""
 	hasUpgradeBaseImageTag := chainUpgradeBaseImageTag != ""
+	// an upgrade plan describes all upgrades, so it can't be combined with the single upgrade flags
+	if chainUpgradePlanFile != "" && (hasUpgradeName || hasUpgradeBaseImageTag || chainUpgradeHeight != 0) {
+		return fmt.Errorf("--upgrade-plan cannot be used with --upgrade-name, --upgrade-height or --upgrade-base-image-tag")
+	}
 	// the upgrade flags are all or nothing. both the upgrade name and the image tag are required for
 	// an automated chain upgrade
 	if (hasUpgradeName && !hasUpgradeBaseImageTag) || (hasUpgradeBaseImageTag && !hasUpgradeName) {
 		return fmt.Errorf("automated chain upgrades require both --upgrade-name and --upgrade-base-image-tag to be defined")
 	}
-	// if running an automate chain upgrade, there must be a sufficiently high upgrade height.
-	if hasUpgradeName && chainUpgradeHeight < 10 {
-		// TODO: is 10 a sufficient height for an upgrade to occur with proposal & voting? probs not..
-		return fmt.Errorf("upgrade height must be > 10, found %d", chainUpgradeHeight)
+	// the upgrade height & --upgrade-via are checked with the rest of the plan, see UpgradePlan.Validate
+	if kavaGenesisFile != "" {
+		if _, err := os.Stat(kavaGenesisFile); err != nil {
+			return fmt.Errorf("--genesis: %w", err)
+		}
+		if kavaGenesisMinPowerPercent < 0 || kavaGenesisMinPowerPercent >= 1 {
+			return fmt.Errorf("--genesis-min-power must be >= 0 and < 1, found %v", kavaGenesisMinPowerPercent)
+		}
+	} else if kavaGenesisGodCommittee || kavaGenesisPersistentPower {
+		return fmt.Errorf("--genesis-god-committee & --genesis-persistent-power require --genesis")
+	}
+	if numValidators < 1 {
+		return fmt.Errorf("at least one validator is required, found %d", numValidators)
 	}
-	if kavaConfigTemplate == "pruning-node" {
-		return fmt.Errorf("the pruning node must be run alongside a different template, see --pruning")
+	upgradePlan, err := bootstrapUpgradePlan()
+	if err != nil {
+		return err
 	}
-	return nil
+	// a provided genesis only has a god committee if one is injected
+	if upgradePlan != nil && kavaGenesisFile != "" && upgradePlan.Via == upgradeViaCommittee && !kavaGenesisGodCommittee {
+		return fmt.Errorf("upgrading a --genesis via the committee requires --genesis-god-committee")
+	}
+	if _, err := kavaNodeConfigOverrides(); err != nil {
+		return err
+	}
+	// reject combinations the template doesn't support before anything is generated
+	template, err := generate.LoadKavaTemplate(kavaConfigTemplate)
+	if err != nil {
+		return err
+	}
+	return template.CheckUsage(generate.TemplateUsage{
+		DbBackend:  kavaDbBackend,
+		Validators: numValidators,
+		Pruning:    includePruningFlag,
+		Genesis:    kavaGenesisFile != "" || validateGenesis,
+		KavaTag:    os.Getenv(kavaTagEnv) != "" || hasUpgradeName || chainUpgradePlanFile != "" || chainUpgradePlan != nil,
+	})
 }
 
-func setupIbcChannelAndRelayer() error {
+func setupIbcChannelAndRelayer(ctx context.Context, relayer ibcRelayer) error {
 	// wait for chains to be up and running before setting up ibc
 	// wait for block 2, as waiting only for block 1 sometimes leads to client expiration problems
-	if err := waitForBlock(2, 5*time.Second, "ibcnode"); err != nil {
-		return fmt.Errorf("error waiting for ibcnode block: %w", err)
+	for _, chain := range ibcTopology.Chains {
+		if err := waitForBlock(2, 5*time.Second, chain.ServiceName()); err != nil {
+			return fmt.Errorf("error waiting for %s block: %w", chain.ServiceName(), err)
+		}
 	}
 
-	fmt.Println("Attempting to establish IBC channel connection between chains...")
-	// open the channel between kava and ibcnode
-	openConnectionCmd := exec.Command("docker", "run", "-v", fmt.Sprintf("%s:%s", generatedPath("relayer"), "/home/relayer/.relayer"), "--name", "ibc-relayer", "--rm", "--net", "generated_default", relayerImageTag, "rly", "transact", "link", "transfer", "-r", "10", "-t", "30s")
-	openConnectionCmd.Stdout = os.Stdout
-	openConnectionCmd.Stderr = os.Stderr
-	if err := openConnectionCmd.Run(); err != nil {
-		fmt.Println(err.Error())
-		return fmt.Errorf("[relayer] failed to open ibc connection")
+	if err := relayer.Link(ctx, ibcTopology); err != nil {
+		return err
 	}
 	fmt.Printf("IBC connection complete, starting relayer process...\n")
 	// setup and run the relayer
-	if err := generate.AddRelayerToNetwork(generatedConfigDir); err != nil {
+	if err := generate.AddRelayerToNetwork(generatedConfigDir, relayer.Template(), remapPortsFlag); err != nil {
 		return fmt.Errorf("could not add relayer to network: %w", err)
 	}
-	if err := dockerComposeCmd("up", "-d", "relayer").Run(); err != nil {
+	if err := containerRuntime.ComposeUp(ctx, ComposeUpOptions{Detach: true, Services: []string{relayer.ServiceName()}}); err != nil {
 		return fmt.Errorf("docker relayer up failed: %w", err)
 	}
-	// prune temp containers used to initialize ibc channel
-	pruneCmd := exec.Command("docker", "container", "prune", "-f")
-	pruneCmd.Stdout = os.Stdout
-	pruneCmd.Stderr = os.Stderr
-	if err := pruneCmd.Run(); err != nil {
-		return fmt.Errorf("error running docker container prune: %w", err)
+	// prune temp containers used to initialize ibc channel. named networks skip it, as it would remove the stopped
+	// containers of the other networks
+	if testnetName == "" {
+		if err := containerRuntime.PruneContainers(ctx); err != nil {
+			return fmt.Errorf("error running docker container prune: %w", err)
+		}
 	}
 	fmt.Println("IBC relayer ready!")
 	return nil
 }
 
-func runChainUpgrade() error {
-	fmt.Printf(
-		"configured for automated chain upgrade\n\tupgrade name: %s\n\tupgrade height: %d\n\tstarting tag: %s\n",
-		chainUpgradeName, chainUpgradeHeight, chainUpgradeBaseImageTag,
-	)
-
-	// write upgrade proposal to json file
-	upgradeJson, err := writeUpgradeProposal()
-	if err != nil {
-		return err
-	}
-
-	// submit upgrade proposal via God Committee (committee 3)
-	fmt.Println("submitting upgrade proposal")
-	cmd := fmt.Sprintf("tx committee submit-proposal 3 %s --gas auto --gas-adjustment 1.2 --gas-prices 0.05ukava --from committee -y",
-		upgradeJson,
-	)
-	if err := runKavaCli(strings.Split(cmd, " ")...); err != nil {
-		return err
-	}
-
-	// Cosmos SDK no longer has broadcast mode block and the use of "sync" mode
-	// only waits for a CheckTx response. Voting will fail with an account
-	// sequence mismatch if the proposal is not committed to a block yet even
-	// when manually specifying the account sequence.
-	// We simply retry here until it succeeds instead of getting the tx hash
-	// from the previous tx and polling for the proposal to be committed. This
-	// is much simpler.
-	b := backoff.NewExponentialBackOff()
-	b.MaxInterval = 2 * time.Second
-	b.MaxElapsedTime = 20 * time.Second
-	err = backoff.Retry(func() error {
-		// vote on the committee proposal
-		cmd = "tx committee vote 1 yes --from committee --gas auto --gas-adjustment 1.8 --gas-prices 0.05ukava -y"
-		return runKavaCli(strings.Split(cmd, " ")...)
-	}, b)
-	if err != nil {
-		return fmt.Errorf("error voting on committee proposal: %w", err)
-	}
-
-	// wait for chain halt at upgrade height
-	if err := waitForBlock(chainUpgradeHeight, time.Duration(chainUpgradeHeight)*4*time.Second, DockerServiceKavaNode); err != nil {
-		return err
-	}
-
-	fmt.Printf("chain has reached upgrade height @ %d, checking if halted\n", chainUpgradeHeight)
-
-	// Check if chain actually halted, if proposal or vote failed then it will
-	// continue to produce blocks and produce an invalid state after continuing
-	// with the new binary.
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
-	if err := waitForUpgradeHalt(ctx, chainUpgradeHeight, DockerServiceKavaNode); err != nil {
-		return fmt.Errorf("chain halt failed: %w", err)
-	}
-
-	fmt.Printf("chain has halted! restarting chain with upgraded image\n")
-
-	// this runs with the desired image because KAVA_TAG will be correctly set, or if that is unset,
-	// the docker-compose files supporting upgrades default to the desired template version.
-	if err := dockerComposeCmd("up", "--force-recreate", "-d", DockerServiceKavaNode).Run(); err != nil {
-		return err
-	}
-
-	// Ensure upgraded chain produces new blocks, at least 1.
-	// Retry since it may return an error while the container is being re-created
-	if err := mustReachHeightOrLog(chainUpgradeHeight+1, 10*time.Second, DockerServiceKavaNode); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// writeUpgradeProposal writes a proposal json to a file in the kavanode container and returns the path
-func writeUpgradeProposal() (string, error) {
-	content := fmt.Sprintf(`{
-		"@type": "/cosmos.upgrade.v1beta1.SoftwareUpgradeProposal",
-		"title": "Automated Chain Upgrade",
-		"description": "An auto-magical chain upgrade performed by kvtool.",
-		"plan": { "name": "%s", "height": "%d" }
-	}`, chainUpgradeName, chainUpgradeHeight)
-	// write the file to a location inside the container
-	return "/root/.kava/config/upgrade-proposal.json", os.WriteFile(
-		generatedPath("kava", "initstate", ".kava", "config", "upgrade-proposal.json"),
-		[]byte(content),
-		0644,
-	)
-}
-
 func waitForBlock(n int64, timeout time.Duration, chainDockerServiceName string) error {
 	b := backoff.NewExponentialBackOff()
 	b.MaxInterval = 2 * time.Second
@@ -342,7 +566,7 @@ func waitForBlock(n int64, timeout time.Duration, chainDockerServiceName string)
 	return backoff.Retry(blockGTE(chainDockerServiceName, n), b)
 }
 
-// blockGTE is a backoff operation that uses kava's CLI to query the chain for the current block number
+// blockGTE is a backoff operation that queries the chain for the current block number, see latestHeight
 // the operation fails in the following cases:
 // 1. the chain cannot be reached, 2. result cannot be parsed, 3. current height is less than desired height `n`
 func blockGTE(chainDockerServiceName string, n int64) backoff.Operation {
@@ -355,13 +579,9 @@ func blockGTE(chainDockerServiceName string, n int64) backoff.Operation {
 			return backoff.Permanent(err)
 		}
 
-		cmd := "kava status | jq -r .sync_info.latest_block_height"
-		// can't use dockerComposeCmd because Output() sets Stdout
-		out, err := exec.Command("docker", "compose", "-f", generatedPath("docker-compose.yaml"), "exec", "-T", chainDockerServiceName, "bash", "-c", cmd).Output()
-		if err != nil {
-			return fmt.Errorf("error docker exec kava status for latest_block_height: %w", err)
-		}
-		height, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+		ctx, cancel := context.WithTimeout(context.Background(), chainQueryTimeout)
+		defer cancel()
+		height, err := latestHeight(ctx, chainDockerServiceName)
 		if err != nil {
 			return err
 		}
@@ -373,24 +593,28 @@ func blockGTE(chainDockerServiceName string, n int64) backoff.Operation {
 	}
 }
 
+// upgradeHaltCheckInterval is how often waitForUpgradeHalt checks the chain hasn't produced blocks past the upgrade height
+var upgradeHaltCheckInterval = 5 * time.Second
+
 // waitForUpgradeHalt waits for the chain to halt at a specific height and
 // returns an error if the chain continues producing blocks after the specified
 // height.
 func waitForUpgradeHalt(
 	ctx context.Context,
+	upgradeName string,
 	n int64,
 	chainDockerServiceName string,
 ) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	logsCh, err := getContainerLogsChannel(ctx, DockerServiceKavaNode)
+	logsCh, err := getContainerLogsChannel(ctx, chainDockerServiceName)
 	if err != nil {
 		return fmt.Errorf("failed to monitor container logs: %w", err)
 	}
 
-	done := make(chan error)
-	defer close(done)
+	// both monitors may report, the channel is buffered so the one that loses doesn't block once the other is received
+	done := make(chan error, 2)
 
 	// Two cases to monitor for:
 	// 1. The chain halts at the upgrade height and logs the expected upgrade
@@ -399,7 +623,7 @@ func waitForUpgradeHalt(
 	//    This returns an error.
 	go func() {
 		// Monitor logs for the expected upgrade message
-		expLog := fmt.Sprintf("UPGRADE \"%s\" NEEDED", chainUpgradeName)
+		expLog := fmt.Sprintf("UPGRADE \"%s\" NEEDED", upgradeName)
 		for logLine := range logsCh {
 			// If found halt return nil to mark as done and no error
 			if strings.Contains(logLine, expLog) {
@@ -414,7 +638,7 @@ func waitForUpgradeHalt(
 			select {
 			case <-ctx.Done():
 				return
-			case <-time.After(5 * time.Second):
+			case <-time.After(upgradeHaltCheckInterval):
 				// Check if height exceeds halt height
 				atHeightFn := blockGTE(chainDockerServiceName, n+1)
 
@@ -439,24 +663,12 @@ func waitForUpgradeHalt(
 }
 
 // runKavaCli execs into the kava container and runs `kava args...`
-func runKavaCli(args ...string) error {
-	pieces := make([]string, 4, len(args)+4)
-	pieces[0] = "exec"
-	pieces[1] = "-T"
-	pieces[2] = DockerServiceKavaNode
-	pieces[3] = "kava"
-	pieces = append(pieces, args...)
-	return dockerComposeCmd(pieces...).Run()
-}
-
-func dockerComposeCmd(args ...string) *exec.Cmd {
-	// exec.Command requires all items to be in single []string variadic
-	// combine the args with the file flag & value
-	pieces := []string{"compose", "-f", generatedPath("docker-compose.yaml")}
-	pieces = append(pieces, args...)
-	fmt.Printf("run: docker compose %s\n", strings.Join(pieces, " "))
-	cmd := exec.Command("docker", pieces...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd
+func runKavaCli(ctx context.Context, args ...string) error {
+	fmt.Printf("run: kava %s\n", strings.Join(args, " "))
+	return containerRuntime.Exec(ctx, ExecOptions{
+		Service: DockerServiceKavaNode,
+		Cmd:     append([]string{"kava"}, args...),
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+	})
 }
@@ -0,0 +1,299 @@
+package testnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/kava-labs/kava/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// heightQuery is the exec fallback of latestHeight, see execLatestHeight
+const heightQuery = "exec kavanode bash -c kava status | jq -r .sync_info.latest_block_height"
+
+// useGeneratedDir points the testnet commands at a temporary generated dir for the duration of the test
+func useGeneratedDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "generated")
+	original, originalName := generatedConfigDir, testnetName
+	generatedConfigDir = dir
+	t.Cleanup(func() { generatedConfigDir, testnetName = original, originalName })
+	return dir
+}
+
+// executeTestnetCmd runs `kvtool testnet args...`
+func executeTestnetCmd(t *testing.T, args ...string) error {
+	t.Helper()
+	cmd := Cmd()
+	cmd.SetArgs(args)
+	cmd.SetOut(os.Stdout)
+	return cmd.ExecuteContext(context.Background())
+}
+
+// scriptHeight makes the fake's kava nodes report the height of the returned counter
+func scriptHeight(fake *fakeRuntime, exec func(opts ExecOptions) error) *atomic.Int64 {
+	height := &atomic.Int64{}
+	fake.ExecFunc = func(opts ExecOptions) error {
+		if len(opts.Cmd) > 0 && opts.Cmd[0] == "bash" {
+			_, err := fmt.Fprint(opts.Stdout, height.Load())
+			return err
+		}
+		if exec != nil {
+			return exec(opts)
+		}
+		return nil
+	}
+	return height
+}
+
+// withoutHeightQueries returns the calls that aren't block height queries
+func withoutHeightQueries(calls []string) []string {
+	var filtered []string
+	for _, call := range calls {
+		if call != heightQuery {
+			filtered = append(filtered, call)
+		}
+	}
+	return filtered
+}
+
+func TestBootstrapReplacesRunningNetwork(t *testing.T) {
+	dir := useGeneratedDir(t)
+	t.Setenv(kavaTagEnv, "")
+	fake := useFakeRuntime(t, DockerServiceKavaNode)
+	height := scriptHeight(fake, nil)
+	height.Store(2)
+
+	// a network from a previous bootstrap is running
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	previousCompose := "services:\n  previous:\n    image: previous\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte(previousCompose), 0644))
+
+	fake.OnCall = func(call string) {
+		compose, err := os.ReadFile(filepath.Join(dir, "docker-compose.yaml"))
+		switch call {
+		case "down":
+			// the previous network is stopped with its own compose file, before anything is generated
+			assert.NoError(t, err)
+			assert.Equal(t, previousCompose, string(compose))
+		case "pull", "up":
+			// the network is started from the generated config
+			assert.NoError(t, err)
+			assert.Contains(t, string(compose), DockerServiceKavaNode)
+			assert.NotContains(t, string(compose), "previous")
+			assert.FileExists(t, filepath.Join(dir, networkInfoFile))
+		}
+	}
+
+	require.NoError(t, executeTestnetCmd(t, "bootstrap", "--generated-dir", dir))
+
+	assert.Equal(t, []string{"down", "pull", "up"}, withoutHeightQueries(fake.Calls))
+	assert.Contains(t, fake.Calls, heightQuery, "bootstrap should wait for blocks")
+	assert.Equal(t, "running", fake.Containers[DockerServiceKavaNode].State)
+}
+
+func TestBootstrapNewNetwork(t *testing.T) {
+	dir := useGeneratedDir(t)
+	t.Setenv(kavaTagEnv, "local")
+	fake := useFakeRuntime(t, DockerServiceKavaNode)
+	scriptHeight(fake, nil).Store(2)
+
+	require.NoError(t, executeTestnetCmd(t, "bootstrap", "--generated-dir", dir, "--validators", "2"))
+
+	// nothing to shut down & a local image isn't pulled
+	assert.Equal(t, []string{"up"}, withoutHeightQueries(fake.Calls))
+	info, err := readNetworkInfo(dir)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, 2, info.Validators)
+	assert.Equal(t, "local", info.KavaTag)
+}
+
+func TestBootstrapInvalidFlagsDontTouchNetwork(t *testing.T) {
+	dir := useGeneratedDir(t)
+	fake := useFakeRuntime(t, DockerServiceKavaNode)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte("services: {}\n"), 0644))
+
+	err := executeTestnetCmd(t, "bootstrap", "--generated-dir", dir, "--upgrade-name", "v1", "--upgrade-height", "5", "--upgrade-base-image-tag", "v0")
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "steps[0].height: must be >= 10"), err.Error())
+	assert.Empty(t, fake.Calls)
+	assert.FileExists(t, filepath.Join(dir, "docker-compose.yaml"))
+}
+
+func TestBootstrapGenesisWithPruning(t *testing.T) {
+	dir := useGeneratedDir(t)
+	t.Setenv(kavaTagEnv, "local")
+	fake := useFakeRuntime(t, DockerServiceKavaNode)
+	scriptHeight(fake, nil).Store(2)
+
+	genesisFile := writeExportedGenesis(t)
+	require.NoError(t, executeTestnetCmd(t, "bootstrap", "--generated-dir", dir, "--genesis", genesisFile, "--pruning", "--validators", "2"))
+
+	// the pruning node syncs from the validators, so it must use the provided genesis with the local validators
+	validatorGenesis, err := os.ReadFile(filepath.Join(dir, "kava", "initstate", ".kava", "config", "genesis.json"))
+	require.NoError(t, err)
+	pruningGenesis, err := os.ReadFile(filepath.Join(dir, "kava-pruning", "shared", "genesis.json"))
+	require.NoError(t, err)
+	assert.Equal(t, string(validatorGenesis), string(pruningGenesis))
+	assert.Contains(t, string(pruningGenesis), "proto_2221-17000")
+}
+
+func TestBootstrapGenesisUpgradeVia(t *testing.T) {
+	genesisFile := writeExportedGenesis(t)
+	upgrade := []string{"--upgrade-name", "v1", "--upgrade-height", "20", "--upgrade-base-image-tag", "v0"}
+
+	testCases := []struct {
+		name   string
+		args   []string
+		errMsg string
+	}{
+		{
+			name:   "committee without god committee",
+			args:   []string{"--upgrade-via", "committee"},
+			errMsg: "upgrading a --genesis via the committee requires --genesis-god-committee",
+		},
+		{
+			name:   "default via without god committee",
+			args:   nil,
+			errMsg: "upgrading a --genesis via the committee requires --genesis-god-committee",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := useGeneratedDir(t)
+			fake := useFakeRuntime(t, DockerServiceKavaNode)
+
+			args := append([]string{"bootstrap", "--generated-dir", dir, "--genesis", genesisFile}, upgrade...)
+			err := executeTestnetCmd(t, append(args, tc.args...)...)
+			require.ErrorContains(t, err, tc.errMsg)
+			assert.Empty(t, fake.Calls)
+			assert.NoDirExists(t, dir)
+		})
+	}
+}
+
+func TestBootstrapGenesisUpgradeViaGov(t *testing.T) {
+	dir := useGeneratedDir(t)
+	t.Setenv(kavaTagEnv, "local")
+	fake := useFakeRuntime(t, DockerServiceKavaNode)
+	// the node halts for the upgrade once the proposal passes
+	fake.Containers[DockerServiceKavaNode] = &fakeContainer{ID: "kavanode", Files: map[string]string{}, Logs: []string{
+		"committed state",
+		`ERR UPGRADE "v1" NEEDED at height: 20`,
+	}}
+	scriptUpgrade(t, fake, 20, map[string]string{
+		"kava q gov params":             `{"deposit_params":{"min_deposit":[{"denom":"ukava","amount":"10000000"}]},"voting_params":{"voting_period":"10s"}}`,
+		"kava tx gov --help":            "Available Commands:\n  submit-legacy-proposal\n",
+		"kava tx gov submit":            fmt.Sprintf(`{"txhash":"%s","code":0}`, testSubmitTxHash),
+		"kava q tx " + testSubmitTxHash: testProposalTx("submit_proposal"),
+		"kava q gov proposal 7":         `{"status":"PROPOSAL_STATUS_PASSED"}`,
+	})
+
+	genesisFile := writeExportedGenesis(t)
+	require.NoError(t, executeTestnetCmd(t, "bootstrap", "--generated-dir", dir, "--genesis", genesisFile, "--validators", "2",
+		"--upgrade-name", "v1", "--upgrade-height", "20", "--upgrade-base-image-tag", "v0", "--upgrade-via", "gov"))
+
+	// the proposal is submitted & voted on by the local validator keys, then the validators are restarted
+	calls := withoutHeightQueries(fake.Calls)
+	assert.Contains(t, calls, "exec kavanode kava tx gov vote 7 yes --from validator --gas auto --gas-adjustment 1.5 --gas-prices 0.05ukava -y")
+	assert.Contains(t, calls, "exec kavanode kava tx gov vote 7 yes --from validator2 --gas auto --gas-adjustment 1.5 --gas-prices 0.05ukava -y")
+	assert.Equal(t, "up kavanode kavanode2", calls[len(calls)-1])
+
+	// the keys voting are funded delegators of the genesis' validator, which was replaced by a local validator
+	homeDir := filepath.Join(dir, "kava", "initstate", ".kava")
+	gen, err := gabs.ParseJSONFile(filepath.Join(homeDir, "config", "genesis.json"))
+	require.NoError(t, err)
+	operator := gen.Path("app_state.staking.validators.0.operator_address").Data()
+	kr, err := keyring.New("kava", keyring.BackendTest, homeDir, nil, app.MakeEncodingConfig().Marshaler)
+	require.NoError(t, err)
+	for _, name := range []string{"validator", "validator2"} {
+		record, err := kr.Key(name)
+		require.NoError(t, err)
+		address, err := record.GetAddress()
+		require.NoError(t, err)
+
+		delegated := false
+		for _, delegation := range gen.Path("app_state.staking.delegations").Children() {
+			if delegation.Path("delegator_address").Data() == address.String() {
+				assert.Equal(t, operator, delegation.Path("validator_address").Data())
+				delegated = true
+			}
+		}
+		assert.True(t, delegated, "%s has no delegation", name)
+		funded := false
+		for _, balance := range gen.Path("app_state.bank.balances").Children() {
+			if balance.Path("address").Data() == address.String() {
+				funded = true
+			}
+		}
+		assert.True(t, funded, "%s has no balance", name)
+	}
+}
+
+// writeExportedGenesis writes the genesis fixture with a bonded validator, like an exported genesis, & returns its path
+func writeExportedGenesis(t *testing.T) string {
+	t.Helper()
+	doc, err := gabs.ParseJSONFile(filepath.Join("..", "..", "config", "generate", "genesis", "testdata", "test_genesis.json"))
+	require.NoError(t, err)
+
+	pubKey := ed25519.GenPrivKey().PubKey()
+	validators, err := tmjson.Marshal([]tmtypes.GenesisValidator{
+		{Address: pubKey.Address(), PubKey: pubKey, Power: 100, Name: "exported"},
+	})
+	require.NoError(t, err)
+	_, err = doc.Set(json.RawMessage(validators), "validators")
+	require.NoError(t, err)
+
+	// the validator's self delegation & its distribution records, as x/staking & x/distribution export them
+	operatorAddress := secp256k1.GenPrivKey().PubKey().Address()
+	operator, self := sdk.ValAddress(operatorAddress).String(), sdk.AccAddress(operatorAddress).String()
+	staking, distribution := doc.Search("app_state", "staking"), doc.Search("app_state", "distribution")
+	require.NoError(t, staking.ArrayAppend(map[string]interface{}{
+		"operator_address": operator,
+		"consensus_pubkey": map[string]interface{}{"@type": "/cosmos.crypto.ed25519.PubKey", "key": pubKey.Bytes()},
+		"status":           "BOND_STATUS_BONDED",
+		"tokens":           "100000000",
+		"delegator_shares": "100000000.000000000000000000",
+	}, "validators"))
+	require.NoError(t, staking.ArrayAppend(map[string]interface{}{
+		"delegator_address": self, "validator_address": operator, "shares": "100000000.000000000000000000",
+	}, "delegations"))
+	require.NoError(t, staking.ArrayAppend(map[string]interface{}{"address": operator, "power": "100"}, "last_validator_powers"))
+	_, err = staking.Set("100", "last_total_power")
+	require.NoError(t, err)
+	require.NoError(t, distribution.ArrayAppend(map[string]interface{}{
+		"delegator_address": self,
+		"validator_address": operator,
+		"starting_info":     map[string]interface{}{"previous_period": "1", "stake": "100000000.000000000000000000", "height": "0"},
+	}, "delegator_starting_infos"))
+	require.NoError(t, distribution.ArrayAppend(map[string]interface{}{
+		"validator_address": operator,
+		"rewards":           map[string]interface{}{"rewards": []interface{}{}, "period": "2"},
+	}, "validator_current_rewards"))
+	require.NoError(t, distribution.ArrayAppend(map[string]interface{}{
+		"validator_address": operator,
+		"period":            "1",
+		"rewards":           map[string]interface{}{"cumulative_reward_ratio": []interface{}{}, "reference_count": 2},
+	}, "validator_historical_rewards"))
+
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, os.WriteFile(path, doc.Bytes(), 0644))
+	return path
+}
@@ -0,0 +1,203 @@
+package testnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+
+	"github.com/kava-labs/kvtool/kavaclient"
+)
+
+// grpcPort is the container port of a chain node's grpc server
+const grpcPort = 9090
+
+// chainClients are the rpc & grpc clients of the network's chain nodes, keyed by url. Each client is created once &
+// reused by every query, eg. each tick of status --watch or each poll while waiting for a block, until the network
+// changes or closeChainClients is called.
+var chainClients struct {
+	sync.Mutex
+	// network is the generated dir of the network the clients query
+	network string
+	rpc     map[string]*rpchttp.HTTP
+	grpc    map[string]*kavaclient.Client
+}
+
+// rpcClient returns the shared tendermint rpc client of rpcURL
+func rpcClient(rpcURL string) (*rpchttp.HTTP, error) {
+	chainClients.Lock()
+	defer chainClients.Unlock()
+	useNetworkClients()
+
+	if client, found := chainClients.rpc[rpcURL]; found {
+		return client, nil
+	}
+	client, err := rpchttp.New(rpcURL, "/websocket")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rpc client for %s: %w", rpcURL, err)
+	}
+	chainClients.rpc[rpcURL] = client
+	return client, nil
+}
+
+// grpcClient returns the shared grpc client of grpcURL
+func grpcClient(grpcURL string) (*kavaclient.Client, error) {
+	chainClients.Lock()
+	defer chainClients.Unlock()
+	useNetworkClients()
+
+	if client, found := chainClients.grpc[grpcURL]; found {
+		return client, nil
+	}
+	client, err := kavaclient.NewClient(grpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grpc client for %s: %w", grpcURL, err)
+	}
+	chainClients.grpc[grpcURL] = client
+	return client, nil
+}
+
+// useNetworkClients closes the clients of another network when the selected network changed. chainClients must be locked.
+func useNetworkClients() {
+	if chainClients.network == generatedConfigDir && chainClients.rpc != nil {
+		return
+	}
+	_ = closeChainClientsLocked()
+	chainClients.network = generatedConfigDir
+}
+
+// closeChainClients closes the clients of the network's chain nodes. Later queries create new clients.
+func closeChainClients() error {
+	chainClients.Lock()
+	defer chainClients.Unlock()
+	return closeChainClientsLocked()
+}
+
+// closeChainClientsLocked closes the clients, chainClients must be locked
+func closeChainClientsLocked() error {
+	var errs []error
+	for grpcURL, client := range chainClients.grpc {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close grpc client for %s: %w", grpcURL, err))
+		}
+	}
+	// the rpc clients only hold idle http connections, as their websocket is never started
+	chainClients.rpc = map[string]*rpchttp.HTTP{}
+	chainClients.grpc = map[string]*kavaclient.Client{}
+	return errors.Join(errs...)
+}
+
+// chainStatus is the sync status of a chain node
+type chainStatus struct {
+	LatestHeight    int64     `json:"latest_height"`
+	LatestBlockTime time.Time `json:"latest_block_time"`
+	CatchingUp      bool      `json:"catching_up"`
+	Peers           int       `json:"peers"`
+}
+
+// chainRpcURL returns the url of a chain service's tendermint rpc, published on the host
+func chainRpcURL(service composeService, serviceName string) (string, error) {
+	port, ok := service.hostPort(tendermintRpcPort)
+	if !ok {
+		return "", fmt.Errorf("%s does not publish a tendermint rpc port", serviceName)
+	}
+	return fmt.Sprintf("http://localhost:%d", port), nil
+}
+
+// queryChainStatus queries the status & peers of the node with its tendermint rpc at rpcURL
+func queryChainStatus(ctx context.Context, rpcURL string) (chainStatus, error) {
+	client, err := rpcClient(rpcURL)
+	if err != nil {
+		return chainStatus{}, err
+	}
+
+	status, err := client.Status(ctx)
+	if err != nil {
+		return chainStatus{}, fmt.Errorf("failed to query status: %w", err)
+	}
+	netInfo, err := client.NetInfo(ctx)
+	if err != nil {
+		return chainStatus{}, fmt.Errorf("failed to query peers: %w", err)
+	}
+
+	return chainStatus{
+		LatestHeight:    status.SyncInfo.LatestBlockHeight,
+		LatestBlockTime: status.SyncInfo.LatestBlockTime,
+		CatchingUp:      status.SyncInfo.CatchingUp,
+		Peers:           netInfo.NPeers,
+	}, nil
+}
+
+// latestHeight returns the latest block height of a chain service.
+// The tendermint rpc or grpc published on the host is queried. If neither is published or reachable,
+// `kava status` is exec'd in the container as a fallback, which requires a kava cli & jq in the image.
+func latestHeight(ctx context.Context, serviceName string) (int64, error) {
+	var errs []error
+
+	compose, err := loadComposeFile(generatedPath("docker-compose.yaml"))
+	if err != nil {
+		return 0, err
+	}
+	if service, found := compose.Services[serviceName]; found {
+		height, err := queryLatestHeight(ctx, service, serviceName)
+		if err == nil {
+			return height, nil
+		}
+		errs = append(errs, err)
+	}
+
+	height, err := execLatestHeight(ctx, serviceName)
+	if err == nil {
+		return height, nil
+	}
+	return 0, errors.Join(append(errs, err)...)
+}
+
+// queryLatestHeight queries the latest height from the tendermint rpc or grpc published on the host
+func queryLatestHeight(ctx context.Context, service composeService, serviceName string) (int64, error) {
+	if rpcURL, err := chainRpcURL(service, serviceName); err == nil {
+		client, err := rpcClient(rpcURL)
+		if err != nil {
+			return 0, err
+		}
+		status, err := client.Status(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to query %s status: %w", rpcURL, err)
+		}
+		return status.SyncInfo.LatestBlockHeight, nil
+	}
+
+	if port, ok := service.hostPort(grpcPort); ok {
+		client, err := grpcClient(fmt.Sprintf("http://localhost:%d", port))
+		if err != nil {
+			return 0, err
+		}
+		height, err := client.LatestHeight(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to query latest block over grpc: %w", err)
+		}
+		return height, nil
+	}
+
+	return 0, fmt.Errorf("%s does not publish a tendermint rpc or grpc port", serviceName)
+}
+
+// execLatestHeight runs `kava status` in the service's container to find the latest height
+func execLatestHeight(ctx context.Context, serviceName string) (int64, error) {
+	var out bytes.Buffer
+	err := containerRuntime.Exec(ctx, ExecOptions{
+		Service: serviceName,
+		Cmd:     []string{"bash", "-c", "kava status | jq -r .sync_info.latest_block_height"},
+		Stdout:  &out,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error docker exec kava status for latest_block_height: %w", err)
+	}
+	return strconv.ParseInt(strings.TrimSpace(out.String()), 10, 64)
+}
@@ -0,0 +1,226 @@
+package testnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// useChainNodeCompose writes a compose file with a kavanode publishing ports, eg. "1234:26657"
+func useChainNodeCompose(t *testing.T, ports ...string) {
+	t.Helper()
+	dir := useGeneratedDir(t)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	compose := "services:\n  kavanode:\n    image: kava/kava\n"
+	if len(ports) > 0 {
+		compose += "    ports:\n"
+		for _, port := range ports {
+			compose += fmt.Sprintf("      - %q\n", port)
+		}
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte(compose), 0644))
+}
+
+// serverPort returns the port of a server listening on the host
+func serverPort(t *testing.T, addr string) string {
+	t.Helper()
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(addr, "http://"))
+	require.NoError(t, err)
+	return port
+}
+
+// closedPort returns a host port nothing listens on
+func closedPort(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := serverPort(t, listener.Addr().String())
+	require.NoError(t, listener.Close())
+	return port
+}
+
+// newRpcServer serves the tendermint rpc status of a node at height
+func newRpcServer(t *testing.T, height int64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) || !assert.Equal(t, "status", req.Method) {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"sync_info":{"latest_block_height":"%d"}}}`, req.ID, height)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// tmServiceServer serves the latest block of a node at height
+type tmServiceServer struct {
+	tmservice.UnimplementedServiceServer
+	height int64
+}
+
+func (s *tmServiceServer) GetLatestBlock(context.Context, *tmservice.GetLatestBlockRequest) (*tmservice.GetLatestBlockResponse, error) {
+	return &tmservice.GetLatestBlockResponse{SdkBlock: &tmservice.Block{Header: tmservice.Header{Height: s.height}}}, nil
+}
+
+// newGrpcServer serves the tendermint grpc service of a node at height & returns its port
+func newGrpcServer(t *testing.T, height int64) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	tmservice.RegisterServiceServer(server, &tmServiceServer{height: height})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return serverPort(t, listener.Addr().String())
+}
+
+func TestLatestHeight(t *testing.T) {
+	testCases := []struct {
+		name string
+		// ports returns the ports published by kavanode
+		ports        func(t *testing.T) []string
+		execHeight   string
+		execErr      error
+		expected     int64
+		expectedExec bool
+		errs         []string
+	}{
+		{
+			name: "rpc",
+			ports: func(t *testing.T) []string {
+				return []string{serverPort(t, newRpcServer(t, 42).URL) + ":26657", newGrpcServer(t, 1) + ":9090"}
+			},
+			expected: 42,
+		},
+		{
+			name: "grpc without rpc",
+			ports: func(t *testing.T) []string {
+				return []string{newGrpcServer(t, 43) + ":9090"}
+			},
+			expected: 43,
+		},
+		{
+			name: "exec when the rpc is unreachable",
+			ports: func(t *testing.T) []string {
+				return []string{closedPort(t) + ":26657"}
+			},
+			execHeight:   "44\n",
+			expected:     44,
+			expectedExec: true,
+		},
+		{
+			name:         "exec without published ports",
+			ports:        func(t *testing.T) []string { return nil },
+			execHeight:   "45",
+			expected:     45,
+			expectedExec: true,
+		},
+		{
+			name: "all fail",
+			ports: func(t *testing.T) []string {
+				return []string{closedPort(t) + ":26657"}
+			},
+			execErr:      fmt.Errorf("kava: not found"),
+			expectedExec: true,
+			errs:         []string{"failed to query http://localhost:", "error docker exec kava status", "kava: not found"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			useChainNodeCompose(t, tc.ports(t)...)
+			fake := useFakeRuntime(t, DockerServiceKavaNode)
+			require.NoError(t, fake.ComposeUp(context.Background(), ComposeUpOptions{}))
+			fake.Calls = nil
+			fake.ExecFunc = func(opts ExecOptions) error {
+				if tc.execErr != nil {
+					return tc.execErr
+				}
+				_, err := fmt.Fprint(opts.Stdout, tc.execHeight)
+				return err
+			}
+
+			height, err := latestHeight(context.Background(), DockerServiceKavaNode)
+			if tc.expectedExec {
+				assert.Equal(t, []string{heightQuery}, fake.Calls)
+			} else {
+				assert.Empty(t, fake.Calls)
+			}
+			if len(tc.errs) > 0 {
+				for _, msg := range tc.errs {
+					require.ErrorContains(t, err, msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, height)
+		})
+	}
+}
+
+func TestLatestHeightReusesClients(t *testing.T) {
+	t.Cleanup(func() { require.NoError(t, closeChainClients()) })
+	rpcPort := serverPort(t, newRpcServer(t, 42).URL)
+	grpcPort := newGrpcServer(t, 43)
+	useChainNodeCompose(t, rpcPort+":26657")
+	compose, err := loadComposeFile(generatedPath("docker-compose.yaml"))
+	require.NoError(t, err)
+	grpcOnly := composeService{Ports: []interface{}{grpcPort + ":9090"}}
+
+	for i := 0; i < 3; i++ {
+		height, err := queryLatestHeight(context.Background(), compose.Services[DockerServiceKavaNode], DockerServiceKavaNode)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), height)
+		height, err = queryLatestHeight(context.Background(), grpcOnly, "ibcnode")
+		require.NoError(t, err)
+		assert.Equal(t, int64(43), height)
+	}
+	// one client per node, whatever the number of polls
+	rpc, err := rpcClient("http://localhost:" + rpcPort)
+	require.NoError(t, err)
+	grpcConn, err := grpcClient("http://localhost:" + grpcPort)
+	require.NoError(t, err)
+	assert.Len(t, chainClients.rpc, 1)
+	assert.Len(t, chainClients.grpc, 1)
+
+	// another network gets its own clients
+	useGeneratedDir(t)
+	otherRpc, err := rpcClient("http://localhost:" + rpcPort)
+	require.NoError(t, err)
+	assert.NotSame(t, rpc, otherRpc)
+	assert.Empty(t, chainClients.grpc)
+
+	// closed clients are replaced by the next query
+	_, err = grpcClient("http://localhost:" + grpcPort)
+	require.NoError(t, err)
+	require.NoError(t, closeChainClients())
+	assert.Empty(t, chainClients.rpc)
+	assert.Empty(t, chainClients.grpc)
+	reopened, err := grpcClient("http://localhost:" + grpcPort)
+	require.NoError(t, err)
+	assert.NotSame(t, grpcConn, reopened)
+}
+
+func TestStatusRejectsInvalidInterval(t *testing.T) {
+	for _, interval := range []string{"0s", "-1s"} {
+		err := executeTestnetCmd(t, "status", "--watch", "--interval", interval)
+		require.ErrorContains(t, err, "--interval must be > 0")
+	}
+}
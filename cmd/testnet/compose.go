@@ -0,0 +1,161 @@
+package testnet
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tendermintRpcPort is the container port of a chain node's tendermint rpc
+const tendermintRpcPort = 26657
+
+// composeFile is the subset of a docker-compose.yaml that kvtool inspects
+type composeFile struct {
+	// Name is the compose project name, set for networks started with --name
+	Name     string                    `yaml:"name"`
+	Services map[string]composeService `yaml:"services"`
+}
+
+// composeService is the subset of a docker compose service definition that kvtool inspects
+type composeService struct {
+	Image   string        `yaml:"image"`
+	Ports   []interface{} `yaml:"ports"`
+	Volumes []interface{} `yaml:"volumes"`
+}
+
+// loadComposeFile reads the docker-compose.yaml at path
+func loadComposeFile(path string) (composeFile, error) {
+	var compose composeFile
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return compose, fmt.Errorf("failed to read docker compose file: %w", err)
+	}
+	if err := yaml.Unmarshal(bz, &compose); err != nil {
+		return compose, fmt.Errorf("failed to parse docker compose file %s: %w", path, err)
+	}
+	return compose, nil
+}
+
+// serviceNames returns the names of all services, sorted
+func (c composeFile) serviceNames() []string {
+	names := make([]string, 0, len(c.Services))
+	for name := range c.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hostPort returns the host port that containerPort is published on.
+// Supported mappings are "<host>:<container>", "<ip>:<host>:<container>" & an optional "/<protocol>" suffix.
+func (s composeService) hostPort(containerPort int) (int, bool) {
+	for _, port := range s.Ports {
+		mapping, ok := port.(string)
+		if !ok {
+			continue
+		}
+		mapping = strings.SplitN(mapping, "/", 2)[0]
+		pieces := strings.Split(mapping, ":")
+		if len(pieces) < 2 || pieces[len(pieces)-1] != strconv.Itoa(containerPort) {
+			continue
+		}
+		hostPort, err := strconv.Atoi(pieces[len(pieces)-2])
+		if err != nil {
+			continue
+		}
+		return hostPort, true
+	}
+	return 0, false
+}
+
+// bindMount is a host path mounted into a service's container
+type bindMount struct {
+	HostPath      string
+	ContainerPath string
+	// Options are the mount options, eg. ro
+	Options string
+}
+
+// String formats the mount as a docker volume, <host path>:<container path>[:<options>]
+func (m bindMount) String() string {
+	if m.Options == "" {
+		return m.HostPath + ":" + m.ContainerPath
+	}
+	return m.HostPath + ":" + m.ContainerPath + ":" + m.Options
+}
+
+// bindMounts returns the service's short syntax volumes that mount host paths.
+// Relative host paths are resolved against composeDir, the directory of the compose file.
+func (s composeService) bindMounts(composeDir string) []bindMount {
+	var mounts []bindMount
+	for _, volume := range s.Volumes {
+		mapping, ok := volume.(string)
+		if !ok {
+			continue
+		}
+		pieces := strings.Split(mapping, ":")
+		if len(pieces) < 2 {
+			continue
+		}
+		hostPath := pieces[0]
+		// named volumes can't be mounted by path
+		if !strings.HasPrefix(hostPath, ".") && !filepath.IsAbs(hostPath) {
+			continue
+		}
+		if !filepath.IsAbs(hostPath) {
+			hostPath = filepath.Join(composeDir, hostPath)
+		}
+		mount := bindMount{HostPath: hostPath, ContainerPath: pieces[1]}
+		if len(pieces) > 2 {
+			mount.Options = pieces[2]
+		}
+		mounts = append(mounts, mount)
+	}
+	return mounts
+}
+
+// chainHome returns the home directory of a chain service's node in its container.
+// It is found from the volumes mounting the home or its config directory & defaults to /root/.kava.
+func (s composeService) chainHome() string {
+	for _, volume := range s.Volumes {
+		mapping, ok := volume.(string)
+		if !ok {
+			continue
+		}
+		pieces := strings.Split(mapping, ":")
+		if len(pieces) < 2 {
+			continue
+		}
+		containerPath := path.Clean(pieces[1])
+		if path.Base(containerPath) == "config" {
+			return path.Dir(containerPath)
+		}
+		if strings.HasPrefix(path.Base(containerPath), ".") {
+			return containerPath
+		}
+	}
+	return "/root/.kava"
+}
+
+// chainNodeNames returns the names of all chain node services, sorted
+func (c composeFile) chainNodeNames() []string {
+	var names []string
+	for _, name := range c.serviceNames() {
+		if c.Services[name].isChainNode() {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// isChainNode returns true if the service publishes a tendermint rpc, eg. kavanode, ibcnode & the pruning node
+func (s composeService) isChainNode() bool {
+	_, ok := s.hostPort(tendermintRpcPort)
+	return ok
+}
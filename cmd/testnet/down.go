@@ -1,9 +1,6 @@
 package testnet
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/spf13/cobra"
 )
 
@@ -12,13 +9,8 @@ func DownCmd() *cobra.Command {
 		Use:   "down",
 		Short: "A convenience command that runs `docker-compose down` on the generated config.",
 		Args:  cobra.NoArgs,
-		RunE: func(_ *cobra.Command, _ []string) error {
-			cmd := []string{"docker", "compose", "--file", generatedPath("docker-compose.yaml"), "down"}
-			fmt.Println("running:", strings.Join(cmd, " "))
-			if err := replaceCurrentProcess(cmd...); err != nil {
-				return fmt.Errorf("could not run command: %v", err)
-			}
-			return nil
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return containerRuntime.ComposeDown(cmd.Context())
 		},
 	}
 }
@@ -1,139 +1,179 @@
 package testnet
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	exportHeightFlag        int64
+	exportForZeroHeightFlag bool
+	exportOutDirFlag        string
+)
+
 func ExportCmd() *cobra.Command {
 	exportCmd := &cobra.Command{
-		Use:     "export",
-		Short:   "Pauses the current kava testnet, exports the current kava testnet state to a JSON file, then restarts the testnet.",
-		Example: "export",
-		Args:    cobra.NoArgs,
-		RunE: func(_ *cobra.Command, _ []string) error {
-			cmd := exec.Command("docker", "compose", "--file", generatedPath("docker-compose.yaml"), "stop")
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-
-			err := cmd.Run()
-			if err != nil {
-				return err
-			}
-			// docker ps -aqf "name=containername"
-			kavaContainerIDCmd := exec.Command("docker", "ps", "-aqf", "name=generated_kavanode_1")
-			kavaContainer, err := kavaContainerIDCmd.Output()
-			if err != nil {
-				return err
-			}
-
-			ibcChainContainerIDCmd := exec.Command("docker", "ps", "-aqf", "name=generated_ibcnode_1")
-			ibcContainer, err := ibcChainContainerIDCmd.Output()
-			if err != nil {
-				return err
-			}
-
-			makeNewKavaImageCmd := exec.Command("docker", "commit", strings.TrimSpace(string(kavaContainer)), "kava-export-temp")
-
-			kavaImageOutput, err := makeNewKavaImageCmd.Output()
-			if err != nil {
-				return err
-			}
-
-			makeNewIbcImageCmd := exec.Command("docker", "commit", strings.TrimSpace(string(ibcContainer)), "ibc-export-temp")
-			ibcImageOutput, err := makeNewIbcImageCmd.Output()
-			if err != nil {
-				return err
-			}
+		Use:   "export [services...]",
+		Short: "Pauses the current testnet, exports the state of its chain services to JSON files, then restarts the testnet.",
+		Long: `Pauses the current testnet, exports the state of chain services to JSON files, then restarts the testnet.
 
-			localKavaMountPath := generatedPath("kava", "initstate", ".kava", "config")
-			localIbcMountPath := generatedPath("ibcchain", "initstate", ".kava", "config")
+Chain services are the services of the generated docker-compose.yaml that publish the tendermint rpc port 26657,
+eg. kavanode, ibcnode & kava-pruning. All chain services are exported if none are given.
+Each export is written to <out-dir>/<service>-export-<unix timestamp>.json.
 
-			kavaExportCmd := exec.Command(
-				"docker", "run",
-				"-v", strings.TrimSpace(fmt.Sprintf("%s:/root/.kava/config", localKavaMountPath)),
-				"kava-export-temp",
-				"kava", "export")
-			kavaExportJSON, err := kavaExportCmd.Output()
-			if err != nil {
-				return err
-			}
-
-			ibcExportCmd := exec.Command(
-				"docker", "run",
-				"-v", strings.TrimSpace(fmt.Sprintf("%s:/root/.kava/config", localIbcMountPath)),
-				"ibc-export-temp",
-				"kava", "export")
-			ibcExportJSON, err := ibcExportCmd.Output()
-			if err != nil {
-				return err
-			}
-			ts := time.Now().Unix()
-			kavaFilename := fmt.Sprintf("kava-export-%d.json", ts)
-			ibcFilename := fmt.Sprintf("ibc-export-%d.json", ts)
+The temporary images & containers used for the export are removed & the testnet is restarted, even if an export fails.`,
+		Example: `Export every chain in the testnet to the current directory:
+$ kvtool testnet export
 
-			fmt.Printf("Created exports %s and %s\nCleaning up...", kavaFilename, ibcFilename)
+Export the kava chain at height 100 into ./exports:
+$ kvtool testnet export kavanode --height 100 --out-dir exports
 
-			err = os.WriteFile(kavaFilename, kavaExportJSON, 0644)
-			if err != nil {
-				return err
-			}
-			err = os.WriteFile(ibcFilename, ibcExportJSON, 0644)
-			if err != nil {
-				return err
-			}
+Export the kava chain for restarting at height zero:
+$ kvtool testnet export kavanode --for-zero-height`,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx := cmd.Context()
 
-			// docker ps -aqf "name=containername"
-			tempKavaContainerIDCmd := exec.Command("docker", "ps", "-aqf", "ancestor=kava-export-temp")
-			tempKavaContainer, err := tempKavaContainerIDCmd.Output()
-			if err != nil {
-				return err
-			}
-			tempIbcContainerIDCmd := exec.Command("docker", "ps", "-aqf", "ancestor=ibc-export-temp")
-			tempIbcContainer, err := tempIbcContainerIDCmd.Output()
+			compose, err := loadComposeFile(generatedPath("docker-compose.yaml"))
 			if err != nil {
 				return err
 			}
-
-			deleteKavaContainerCmd := exec.Command("docker", "rm", strings.TrimSpace(string(tempKavaContainer)))
-			err = deleteKavaContainerCmd.Run()
+			services, err := exportServices(compose, args)
 			if err != nil {
 				return err
 			}
-			deleteIbcContainerCmd := exec.Command("docker", "rm", strings.TrimSpace(string(tempIbcContainer)))
-			err = deleteIbcContainerCmd.Run()
-			if err != nil {
-				return err
+			if err := os.MkdirAll(exportOutDirFlag, 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
 			}
 
-			deleteKavaImageCmd := exec.Command("docker", "rmi", strings.TrimSpace(string(kavaImageOutput)))
-			err = deleteKavaImageCmd.Run()
-			if err != nil {
-				return err
-			}
-			deleteIbcImageCmd := exec.Command("docker", "rmi", strings.TrimSpace(string(ibcImageOutput)))
-			err = deleteIbcImageCmd.Run()
-			if err != nil {
+			if err := containerRuntime.ComposeStop(ctx); err != nil {
 				return err
 			}
+			defer func() {
+				fmt.Println("Restarting testnet...")
+				// restart even if the export was interrupted
+				if startErr := containerRuntime.ComposeStart(context.Background()); startErr != nil && err == nil {
+					err = startErr
+				}
+			}()
 
-			fmt.Printf("Restarting testnet...")
-			restartCmd := exec.Command("docker", "compose", "--file", generatedPath("docker-compose.yaml"), "start")
-			restartCmd.Stdout = os.Stdout
-			restartCmd.Stderr = os.Stderr
-
-			err = restartCmd.Run()
-			if err != nil {
-				return err
+			ts := time.Now().Unix()
+			for _, service := range services {
+				exportJSON, err := exportService(ctx, service, compose.Services[service])
+				if err != nil {
+					return err
+				}
+				filename := filepath.Join(exportOutDirFlag, fmt.Sprintf("%s-export-%d.json", service, ts))
+				if err := os.WriteFile(filename, exportJSON, 0644); err != nil {
+					return err
+				}
+				fmt.Printf("Created export %s\n", filename)
 			}
 			return nil
 		},
 	}
 
+	exportCmd.Flags().Int64Var(&exportHeightFlag, "height", 0, "height to export state at. defaults to the latest height.")
+	exportCmd.Flags().BoolVar(&exportForZeroHeightFlag, "for-zero-height", false, "export state to start a new chain at height zero")
+	exportCmd.Flags().StringVar(&exportOutDirFlag, "out-dir", ".", "directory the exports are written to")
+
 	return exportCmd
 }
+
+// exportServices returns the requested services after checking they are chain services, or all chain services if none are requested
+func exportServices(compose composeFile, requested []string) ([]string, error) {
+	chainNodes := compose.chainNodeNames()
+	if len(requested) == 0 {
+		if len(chainNodes) == 0 {
+			return nil, fmt.Errorf("no chain services found in the docker compose file")
+		}
+		return chainNodes, nil
+	}
+	for _, service := range requested {
+		if _, found := compose.Services[service]; !found {
+			return nil, fmt.Errorf("service %s is not in the testnet, chain services are: %s", service, strings.Join(chainNodes, ", "))
+		}
+		if !compose.Services[service].isChainNode() {
+			return nil, fmt.Errorf("service %s is not a chain service, chain services are: %s", service, strings.Join(chainNodes, ", "))
+		}
+	}
+	return requested, nil
+}
+
+// exportService commits the stopped container of a chain service to a temporary image & runs `kava export` in it,
+// with the service's volumes mounted. The temporary container & image are removed afterwards, even if the export fails.
+// The image is named after the network's compose project, so networks exported at the same time don't share it.
+func exportService(ctx context.Context, service string, definition composeService) (_ []byte, err error) {
+	containerID, err := containerRuntime.ServiceContainerID(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	tempImage := exportTempImage(service)
+	imageID, err := containerRuntime.Commit(ctx, containerID, tempImage)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// clean up even if the export was interrupted
+		if cleanupErr := removeImageAndContainers(context.Background(), tempImage, imageID); cleanupErr != nil && err == nil {
+			err = cleanupErr
+		}
+	}()
+
+	exportCmd := []string{"kava", "export"}
+	if exportHeightFlag > 0 {
+		exportCmd = append(exportCmd, "--height", strconv.FormatInt(exportHeightFlag, 10))
+	}
+	if exportForZeroHeightFlag {
+		exportCmd = append(exportCmd, "--for-zero-height")
+	}
+
+	composeDir, err := filepath.Abs(filepath.Dir(generatedPath("docker-compose.yaml")))
+	if err != nil {
+		return nil, err
+	}
+	var volumes []string
+	for _, mount := range definition.bindMounts(composeDir) {
+		volumes = append(volumes, mount.String())
+	}
+	var exportJSON, stderr bytes.Buffer
+	err = containerRuntime.Run(ctx, RunOptions{
+		Image:   tempImage,
+		Volumes: volumes,
+		Cmd:     exportCmd,
+		Stdout:  &exportJSON,
+		Stderr:  &stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export %s: %w\n%s", service, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return exportJSON.Bytes(), nil
+}
+
+// exportTempImage returns the temporary image of a service's export, eg. generated-kavanode-export-temp
+func exportTempImage(service string) string {
+	return fmt.Sprintf("%s-%s-export-temp", composeProjectName(generatedConfigDir), service)
+}
+
+// removeImageAndContainers removes a temporary image & all containers created from it
+func removeImageAndContainers(ctx context.Context, image, imageID string) error {
+	containers, err := containerRuntime.ContainersFromImage(ctx, image)
+	if err != nil {
+		return err
+	}
+	for _, id := range containers {
+		if err := containerRuntime.RemoveContainer(ctx, id); err != nil {
+			return err
+		}
+	}
+	return containerRuntime.RemoveImage(ctx, imageID)
+}
@@ -0,0 +1,110 @@
+package testnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testExportCompose = `services:
+  kavanode:
+    image: kava/kava
+    ports:
+      - "26657:26657"
+    volumes:
+      - "./kava/initstate/.kava:/root/.kava"
+  geth:
+    image: ethereum/client-go
+`
+
+// useExportNetwork sets up a generated dir & a running network of kavanode & geth
+func useExportNetwork(t *testing.T) (*fakeRuntime, string) {
+	t.Helper()
+	dir := useGeneratedDir(t)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte(testExportCompose), 0644))
+
+	fake := useFakeRuntime(t, DockerServiceKavaNode, "geth")
+	require.NoError(t, fake.ComposeUp(context.Background(), ComposeUpOptions{}))
+	fake.Calls = nil
+	return fake, dir
+}
+
+func TestExport(t *testing.T) {
+	fake, dir := useExportNetwork(t)
+	outDir := t.TempDir()
+	fake.RunFunc = func(opts RunOptions) error {
+		assert.Equal(t, []string{filepath.Join(dir, "kava", "initstate", ".kava") + ":/root/.kava"}, opts.Volumes)
+		_, err := fmt.Fprint(opts.Stdout, `{"chain_id":"kavalocalnet_8888-1"}`)
+		return err
+	}
+	containerID := fake.Containers[DockerServiceKavaNode].ID
+
+	require.NoError(t, executeTestnetCmd(t, "export", "--generated-dir", dir, "--out-dir", outDir, "--height", "100"))
+
+	require.Len(t, fake.Calls, 6)
+	assert.Equal(t, []string{
+		"stop",
+		fmt.Sprintf("commit %s generated-kavanode-export-temp", containerID),
+		"run generated-kavanode-export-temp kava export --height 100",
+	}, fake.Calls[:3])
+	// the temporary container & image are removed before the network is restarted
+	assert.Regexp(t, `^rm \w+$`, fake.Calls[3])
+	assert.Regexp(t, `^rmi sha256:\w+$`, fake.Calls[4])
+	assert.Equal(t, "start", fake.Calls[5])
+	assert.Empty(t, fake.Images)
+	assert.Empty(t, fake.RunContainers["generated-kavanode-export-temp"])
+
+	exports, err := filepath.Glob(filepath.Join(outDir, "kavanode-export-*.json"))
+	require.NoError(t, err)
+	require.Len(t, exports, 1)
+	bz, err := os.ReadFile(exports[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"chain_id":"kavalocalnet_8888-1"}`, string(bz))
+}
+
+func TestExportFailureCleansUp(t *testing.T) {
+	fake, dir := useExportNetwork(t)
+	fake.RunFunc = func(opts RunOptions) error {
+		fmt.Fprint(opts.Stderr, "panic: store not found")
+		return errors.New("exit status 2")
+	}
+
+	err := executeTestnetCmd(t, "export", "--generated-dir", dir, "--out-dir", t.TempDir())
+	require.ErrorContains(t, err, "failed to export kavanode")
+	require.ErrorContains(t, err, "panic: store not found")
+
+	require.Len(t, fake.Calls, 6)
+	assert.Regexp(t, `^rm \w+$`, fake.Calls[3])
+	assert.Regexp(t, `^rmi sha256:\w+$`, fake.Calls[4])
+	assert.Equal(t, "start", fake.Calls[5])
+	assert.Empty(t, fake.Images)
+}
+
+func TestExportRejectsNonChainServices(t *testing.T) {
+	fake, dir := useExportNetwork(t)
+
+	err := executeTestnetCmd(t, "export", "geth", "--generated-dir", dir, "--out-dir", t.TempDir())
+	require.ErrorContains(t, err, "service geth is not a chain service, chain services are: kavanode")
+	err = executeTestnetCmd(t, "export", "ibcnode", "--generated-dir", dir, "--out-dir", t.TempDir())
+	require.ErrorContains(t, err, "service ibcnode is not in the testnet")
+
+	// the network isn't stopped
+	assert.Empty(t, fake.Calls)
+}
+
+func TestExportTempImageIsNamespaced(t *testing.T) {
+	fake, dir := useExportNetwork(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte("name: devnet\n"+testExportCompose), 0644))
+	containerID := fake.Containers[DockerServiceKavaNode].ID
+
+	require.NoError(t, executeTestnetCmd(t, "export", "--name", "devnet", "--generated-dir", dir, "--out-dir", t.TempDir()))
+	assert.Equal(t, fmt.Sprintf("commit %s devnet-kavanode-export-temp", containerID), fake.Calls[1])
+	assert.Equal(t, "run devnet-kavanode-export-temp kava export", fake.Calls[2])
+}
@@ -20,7 +20,30 @@ available services: %s
 		Example:   "gen-config kava binance deputy --kava.configTemplate v0.10",
 		ValidArgs: supportedServices,
 		Args:      cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			overrides, err := kavaNodeConfigOverrides()
+			if err != nil {
+				return err
+			}
+			// the pruning node uses the genesis generated for the kava validators
+			if includePruningFlag && !stringSlice(args).contains(kavaServiceName) {
+				return fmt.Errorf("--pruning requires the %s service, the pruning node syncs from its validators", kavaServiceName)
+			}
+			relayer, err := newIbcRelayer(relayerFlag)
+			if err != nil {
+				return err
+			}
+			if stringSlice(args).contains(kavaServiceName) {
+				template, err := generate.LoadKavaTemplate(kavaConfigTemplate)
+				if err != nil {
+					return err
+				}
+				usage := generate.TemplateUsage{DbBackend: kavaDbBackend, Validators: numValidators, Pruning: includePruningFlag}
+				if err := template.CheckUsage(usage); err != nil {
+					return err
+				}
+			}
 
 			// 1) clear out generated config folder
 			if err := os.RemoveAll(generatedConfigDir); err != nil {
@@ -29,44 +52,64 @@ available services: %s
 
 			// 2) generate a complete docker-compose config
 			if stringSlice(args).contains(kavaServiceName) {
-				if err := generate.GenerateKavaConfig(kavaConfigTemplate, generatedConfigDir, kavaDbBackend); err != nil {
+				if err := generate.GenerateKavaConfig(kavaConfigTemplate, generatedConfigDir, kavaDbBackend, remapPortsFlag); err != nil {
+					return err
+				}
+				if err := generate.GenerateKavaValidatorsConfig(kavaConfigTemplate, generatedConfigDir, numValidators); err != nil {
 					return err
 				}
 			}
 			if stringSlice(args).contains(binanceServiceName) {
-				if err := generate.GenerateBnbConfig(generatedConfigDir); err != nil {
+				if err := generate.GenerateBnbConfig(generatedConfigDir, remapPortsFlag); err != nil {
 					return err
 				}
 			}
 			if stringSlice(args).contains(deputyServiceName) {
-				if err := generate.GenerateDeputyConfig(generatedConfigDir); err != nil {
+				if err := generate.GenerateDeputyConfig(generatedConfigDir, remapPortsFlag); err != nil {
 					return err
 				}
 			}
 			if includePruningFlag {
-				if err := generate.GenerateKavaPruningConfig(kavaConfigTemplate, generatedConfigDir, kavaDbBackend); err != nil {
+				if err := generate.GenerateKavaPruningConfig(kavaConfigTemplate, generatedConfigDir, kavaDbBackend, remapPortsFlag); err != nil {
 					return err
 				}
 			}
 			if ibcFlag {
-				if err := generate.GenerateIbcChainConfig(generatedConfigDir); err != nil {
+				if err := generate.GenerateIbcChainsConfig(generatedConfigDir, ibcTopology, remapPortsFlag); err != nil {
+					return err
+				}
+				if err := relayer.Generate(generatedConfigDir, ibcTopology); err != nil {
 					return err
 				}
 			}
 			if gethFlag {
-				if err := generate.GenerateGethConfig(generatedConfigDir); err != nil {
+				if err := generate.GenerateGethConfig(generatedConfigDir, remapPortsFlag); err != nil {
 					return err
 				}
 			}
-
-			return nil
+			// 3) apply the node settings to all generated kava nodes
+			if err := generate.ApplyNodeConfigOverrides(generatedConfigDir, overrides); err != nil {
+				return err
+			}
+			if includePruningFlag {
+				if err := generate.CopyKavaGenesisToPruningNode(kavaConfigTemplate, generatedConfigDir); err != nil {
+					return err
+				}
+			}
+			// 4) isolate the network from the other kvtool networks
+			_, err = finalizeNetwork(cmd)
+			return err
 		},
 	}
 
 	genConfigCmd.Flags().StringVar(&kavaConfigTemplate, "kava.configTemplate", "master", "the directory name of the template used to generating the kava config")
 	genConfigCmd.Flags().BoolVar(&includePruningFlag, "pruning", false, "flag for running pruning node alongside kava validator")
+	genConfigCmd.Flags().IntVar(&numValidators, "validators", 1, "number of kava validators to run. each additional validator gets fresh keys & a gentx in genesis.")
 	genConfigCmd.Flags().BoolVar(&ibcFlag, "ibc", false, "flag for if ibc is enabled")
+	genConfigCmd.Flags().StringVar(&relayerFlag, "relayer", relayerRly, fmt.Sprintf("the relayer whose config is generated for the --ibc paths, one of %v.", supportedRelayers))
 	genConfigCmd.Flags().BoolVar(&gethFlag, "geth", false, "flag for if geth node is enabled")
+	addNodeConfigFlags(genConfigCmd)
+	addPortOffsetFlag(genConfigCmd)
 
 	return genConfigCmd
 }
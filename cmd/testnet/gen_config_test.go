@@ -0,0 +1,62 @@
+package testnet
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+func TestGenConfigPruningRequiresKava(t *testing.T) {
+	dir := useGeneratedDir(t)
+
+	err := executeTestnetCmd(t, "gen-config", "deputy", "--generated-dir", dir, "--pruning")
+	require.ErrorContains(t, err, "--pruning requires the kava service")
+	assert.NoDirExists(t, dir)
+}
+
+func TestGenConfigPruning(t *testing.T) {
+	dir := useGeneratedDir(t)
+
+	require.NoError(t, executeTestnetCmd(t, "gen-config", "kava", "--generated-dir", dir, "--pruning", "--validators", "2"))
+	assert.FileExists(t, generatedPath("kava-pruning", "shared", "genesis.json"))
+	assert.FileExists(t, generatedPath("kava2", "initstate", ".kava", "config", "genesis.json"))
+}
+
+func TestGenConfigIbcRelayer(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          []string
+		expectedFiles []string
+	}{
+		{
+			name:          "rly",
+			expectedFiles: []string{"relayer/config/config.yaml"},
+		},
+		{
+			name:          "hermes",
+			args:          []string{"--relayer", relayerHermes},
+			expectedFiles: []string{"hermes/config.toml", "hermes/" + generate.HermesMnemonicFile},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := useGeneratedDir(t)
+
+			args := append([]string{"gen-config", "kava", "--generated-dir", dir, "--ibc"}, tc.args...)
+			require.NoError(t, executeTestnetCmd(t, args...))
+			for _, file := range tc.expectedFiles {
+				assert.FileExists(t, generatedPath(filepath.FromSlash(file)))
+			}
+		})
+	}
+
+	dir := useGeneratedDir(t)
+	err := executeTestnetCmd(t, "gen-config", "kava", "--generated-dir", dir, "--ibc", "--relayer", "hermez")
+	require.ErrorContains(t, err, `--relayer must be one of [rly hermes], found "hermez"`)
+	assert.NoDirExists(t, dir)
+}
@@ -0,0 +1,93 @@
+package testnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// networkListing is a kvtool network listed by ls
+type networkListing struct {
+	Name    string `json:"name"`
+	Project string `json:"project"`
+	Status  string `json:"status"`
+	Dir     string `json:"dir"`
+	// Info is set for networks started by bootstrap
+	Info *networkInfo `json:"info,omitempty"`
+}
+
+// LsCmd lists the default & named networks generated by kvtool
+func LsCmd() *cobra.Command {
+	var output string
+
+	lsCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List the kvtool networks & whether they are running",
+		Long: fmt.Sprintf(`List the default network & the networks started with --name, which are generated in %s.
+The status is the status of the network's compose project, eg. running(3), or not running.`, networksDir()),
+		Example: `$ kvtool testnet ls
+$ kvtool testnet ls --output json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if output != "text" && output != "json" {
+				return fmt.Errorf("--output must be text or json, found %q", output)
+			}
+			networks, err := listNetworks()
+			if err != nil {
+				return err
+			}
+			projects, projectsErr := containerRuntime.ComposeProjects(cmd.Context())
+			if projectsErr != nil {
+				fmt.Fprintf(os.Stderr, "failed to find running networks: %s\n", projectsErr)
+			}
+
+			listings := make([]networkListing, 0, len(networks))
+			for _, network := range networks {
+				listing := networkListing{Name: network.Name, Project: composeProjectName(network.Dir), Dir: network.Dir}
+				if listing.Name == "" {
+					listing.Name = "(default)"
+				}
+				switch status, ok := projects[listing.Project]; {
+				case projectsErr != nil:
+					listing.Status = "unknown"
+				case ok:
+					listing.Status = status
+				default:
+					listing.Status = "not running"
+				}
+				if listing.Info, err = readNetworkInfo(network.Dir); err != nil {
+					return err
+				}
+				listings = append(listings, listing)
+			}
+
+			if output == "json" {
+				bz, err := json.MarshalIndent(listings, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(bz))
+				return nil
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tSTATUS\tTEMPLATE\tVALIDATORS\tPORT OFFSET\tDIR\t")
+			for _, l := range listings {
+				template, validators, offset := "-", "-", "-"
+				if l.Info != nil {
+					template, validators, offset = l.Info.Template, strconv.Itoa(l.Info.Validators), strconv.Itoa(l.Info.PortOffset)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t\n", l.Name, l.Status, template, validators, offset, l.Dir)
+			}
+			return tw.Flush()
+		},
+	}
+
+	lsCmd.Flags().StringVar(&output, "output", "text", "output format, text or json")
+
+	return lsCmd
+}
@@ -0,0 +1,33 @@
+package testnet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kava-labs/kava/app"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+// TestMain runs the tests with the templates of the repo & a kvtool home that isn't the user's
+func TestMain(m *testing.M) {
+	// addresses use kava's prefixes, like the kvtool binary
+	app.SetSDKConfig()
+
+	templates, err := filepath.Abs(filepath.Join("..", "..", "config", "templates"))
+	if err != nil {
+		panic(err)
+	}
+	generate.ConfigTemplatesDir = templates
+
+	home, err := os.MkdirTemp("", "kvtool-home")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv(generate.KvtoolHomeEnv, home)
+
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
@@ -0,0 +1,239 @@
+package testnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+// networkInfoFile is written to the generated dir by bootstrap & records how the network was configured
+const networkInfoFile = "network.json"
+
+// networkInfo describes how a network was bootstrapped. It is informational, the generated configs are the source of truth.
+type networkInfo struct {
+	// Name is the --name of the network, empty for the default network
+	Name       string `json:"name,omitempty"`
+	PortOffset int    `json:"port_offset,omitempty"`
+	Template   string `json:"template"`
+	DbBackend  string `json:"db_backend"`
+	Validators int    `json:"validators"`
+	Pruning    bool   `json:"pruning"`
+	Ibc        bool   `json:"ibc"`
+	Geth       bool   `json:"geth"`
+	// Relayer is the --relayer of ibc networks
+	Relayer string `json:"relayer,omitempty"`
+	// ConfigOverrides & AppOverrides are the --kava.config & --kava.app settings
+	ConfigOverrides []string `json:"config_overrides,omitempty"`
+	AppOverrides    []string `json:"app_overrides,omitempty"`
+	// KavaTag is the KAVA_TAG the network was started with, if any
+	KavaTag   string    `json:"kava_tag,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// bootstrapNetworkInfo returns the networkInfo of the bootstrap flags
+func bootstrapNetworkInfo(kavaTag string, portOffset int) networkInfo {
+	info := networkInfo{
+		Name:       testnetName,
+		PortOffset: portOffset,
+		Template:   kavaConfigTemplate,
+		DbBackend:  kavaDbBackend,
+		Validators: numValidators,
+		Pruning:    includePruningFlag,
+		Ibc:        ibcFlag,
+		Geth:       gethFlag,
+		KavaTag:    kavaTag,
+		CreatedAt:  time.Now().UTC(),
+
+		ConfigOverrides: kavaConfigOverrides,
+		AppOverrides:    kavaAppOverrides,
+	}
+	if ibcFlag {
+		info.Relayer = relayerFlag
+	}
+	return info
+}
+
+func writeNetworkInfo(info networkInfo) error {
+	bz, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(generatedPath(networkInfoFile), bz, 0644)
+}
+
+// readNetworkInfo reads the networkInfo of the network generated in dir. Networks generated by gen-config don't have one.
+func readNetworkInfo(dir string) (*networkInfo, error) {
+	bz, err := os.ReadFile(filepath.Join(dir, networkInfoFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var info networkInfo
+	if err := json.Unmarshal(bz, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", networkInfoFile, err)
+	}
+	return &info, nil
+}
+
+const (
+	// networksDirName is the directory of the named networks, next to the default generated dir
+	networksDirName = "networks"
+	// portOffsetStep is the step of the host port offsets chosen for named networks
+	portOffsetStep = 1000
+)
+
+// testnetNameRegexp matches valid --name values, which are used as compose project names
+var testnetNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// kvtoolNetwork is a network generated by kvtool
+type kvtoolNetwork struct {
+	// Name is the --name of the network, empty for the default network
+	Name string
+	Dir  string
+}
+
+// networksDir returns the directory of the named networks, eg. full_configs/networks
+func networksDir() string {
+	return filepath.Join(filepath.Dir(filepath.Clean(defaultGeneratedConfigDir)), networksDirName)
+}
+
+// namedGeneratedDir returns the generated dir of a named network. Its snapshots are kept next to it.
+func namedGeneratedDir(name string) string {
+	return filepath.Join(networksDir(), name, "generated")
+}
+
+// selectNetwork points the testnet commands at the generated dir of --name, unless --generated-dir is set
+func selectNetwork(cmd *cobra.Command) error {
+	if testnetName == "" {
+		return nil
+	}
+	if !testnetNameRegexp.MatchString(testnetName) || testnetName == "generated" {
+		return fmt.Errorf("invalid --name %q, must be lowercase letters, digits, - & _ & can't be generated", testnetName)
+	}
+	if !cmd.Flags().Changed("generated-dir") {
+		generatedConfigDir = namedGeneratedDir(testnetName)
+	}
+	return nil
+}
+
+// listNetworks returns the default network & the named networks that have been generated
+func listNetworks() ([]kvtoolNetwork, error) {
+	var networks []kvtoolNetwork
+	if _, err := os.Stat(filepath.Join(defaultGeneratedConfigDir, "docker-compose.yaml")); err == nil {
+		networks = append(networks, kvtoolNetwork{Dir: defaultGeneratedConfigDir})
+	}
+	entries, err := os.ReadDir(networksDir())
+	if os.IsNotExist(err) {
+		return networks, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	for _, entry := range entries {
+		dir := namedGeneratedDir(entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, "docker-compose.yaml")); entry.IsDir() && err == nil {
+			networks = append(networks, kvtoolNetwork{Name: entry.Name(), Dir: dir})
+		}
+	}
+	return networks, nil
+}
+
+// composeProjectName returns the compose project name of the network generated in dir
+func composeProjectName(dir string) string {
+	if compose, err := loadComposeFile(filepath.Join(dir, "docker-compose.yaml")); err == nil && compose.Name != "" {
+		return compose.Name
+	}
+	// compose defaults to the name of the compose file's directory
+	return strings.ToLower(filepath.Base(filepath.Clean(dir)))
+}
+
+// composeNetwork returns the docker network of the generated network, joined by containers run outside of compose
+func composeNetwork() string {
+	return composeProjectName(generatedConfigDir) + "_default"
+}
+
+// containerName returns the name of a container run outside of compose, prefixed by --name so networks don't share it
+func containerName(name string) string {
+	if testnetName == "" {
+		return name
+	}
+	return testnetName + "-" + name
+}
+
+// finalizeNetwork names the generated compose project after --name & offsets its host ports.
+// It returns the host port offset.
+func finalizeNetwork(cmd *cobra.Command) (int, error) {
+	offset, err := hostPortOffset(cmd)
+	if err != nil {
+		return 0, err
+	}
+	if err := generate.OffsetComposeHostPorts(generatedConfigDir, offset); err != nil {
+		return 0, err
+	}
+	if offset != 0 {
+		fmt.Printf("host ports are offset by %d\n", offset)
+	}
+	return offset, generate.SetComposeProjectName(generatedConfigDir, testnetName)
+}
+
+// hostPortOffset returns --port-offset. Named networks default to the smallest multiple of portOffsetStep that
+// publishes none of the host ports of the other kvtool networks.
+func hostPortOffset(cmd *cobra.Command) (int, error) {
+	if flag := cmd.Flags().Lookup("port-offset"); flag != nil && flag.Changed {
+		if portOffsetFlag < 0 {
+			return 0, fmt.Errorf("--port-offset must be >= 0, found %d", portOffsetFlag)
+		}
+		return portOffsetFlag, nil
+	}
+	if testnetName == "" {
+		return 0, nil
+	}
+
+	ports, err := generate.ComposeHostPorts(generatedPath("docker-compose.yaml"))
+	if err != nil {
+		return 0, err
+	}
+	networks, err := listNetworks()
+	if err != nil {
+		return 0, err
+	}
+	used := map[int]bool{}
+	for _, network := range networks {
+		if filepath.Clean(network.Dir) == filepath.Clean(generatedConfigDir) {
+			continue
+		}
+		otherPorts, err := generate.ComposeHostPorts(filepath.Join(network.Dir, "docker-compose.yaml"))
+		if err != nil {
+			continue
+		}
+		for _, port := range otherPorts {
+			used[port] = true
+		}
+	}
+
+	for offset := portOffsetStep; ; offset += portOffsetStep {
+		free := true
+		for _, port := range ports {
+			if port+offset >= 65536 {
+				return 0, fmt.Errorf("no free host ports found for network %s, set --port-offset", testnetName)
+			}
+			if used[port+offset] {
+				free = false
+				break
+			}
+		}
+		if free {
+			return offset, nil
+		}
+	}
+}
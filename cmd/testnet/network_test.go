@@ -0,0 +1,238 @@
+package testnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useNetworksDir points the default network & the named networks at a temporary dir for the duration of the test.
+// It returns the generated dir of the default network.
+func useNetworksDir(t *testing.T) string {
+	t.Helper()
+	useGeneratedDir(t)
+	original := defaultGeneratedConfigDir
+	defaultGeneratedConfigDir = filepath.Join(t.TempDir(), "generated")
+	t.Cleanup(func() { defaultGeneratedConfigDir = original })
+	return defaultGeneratedConfigDir
+}
+
+// writeTestNetwork writes the docker-compose.yaml & network.json, if any, of a network generated in dir
+func writeTestNetwork(t *testing.T, dir, compose string, info *networkInfo) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte(compose), 0644))
+	if info != nil {
+		bz, err := json.Marshal(info)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, networkInfoFile), bz, 0644))
+	}
+}
+
+// executeTestnetCmdOutput runs `kvtool testnet args...` & returns what it printed to the command's output
+func executeTestnetCmdOutput(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := Cmd()
+	cmd.SetArgs(args)
+	cmd.SetOut(&out)
+	err := cmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func TestSelectNetwork(t *testing.T) {
+	testCases := []struct {
+		name         string
+		testnetName  string
+		generatedDir string
+		expectedDir  func(defaultDir string) string
+		expectedErr  string
+	}{
+		{
+			name:        "default network",
+			expectedDir: func(defaultDir string) string { return defaultDir },
+		},
+		{
+			name:        "named network",
+			testnetName: "devnet",
+			expectedDir: func(string) string { return namedGeneratedDir("devnet") },
+		},
+		{
+			name:         "--generated-dir takes precedence",
+			testnetName:  "devnet",
+			generatedDir: "custom",
+			expectedDir:  func(string) string { return "custom" },
+		},
+		{name: "uppercase", testnetName: "Devnet", expectedErr: `invalid --name "Devnet"`},
+		{name: "leading dash", testnetName: "-devnet", expectedErr: `invalid --name "-devnet"`},
+		{name: "dot", testnetName: "dev.net", expectedErr: `invalid --name "dev.net"`},
+		{name: "generated", testnetName: "generated", expectedErr: `invalid --name "generated"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defaultDir := useNetworksDir(t)
+			cmd := &cobra.Command{}
+			cmd.Flags().StringVar(&generatedConfigDir, "generated-dir", defaultDir, "")
+			if tc.generatedDir != "" {
+				require.NoError(t, cmd.Flags().Set("generated-dir", tc.generatedDir))
+			}
+			testnetName = tc.testnetName
+
+			err := selectNetwork(cmd)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedDir(defaultDir), generatedConfigDir)
+		})
+	}
+}
+
+func TestHostPortOffset(t *testing.T) {
+	const kavaCompose = "services:\n  kavanode:\n    ports:\n      - \"26657:26657\"\n      - \"1317:1317\"\n"
+
+	testCases := []struct {
+		name        string
+		testnetName string
+		args        []string
+		// networks are the composes of the other networks, by name. the default network has no name.
+		networks       map[string]string
+		compose        string
+		expectedOffset int
+		expectedErr    string
+	}{
+		{
+			name:           "--port-offset",
+			testnetName:    "devnet",
+			args:           []string{"--port-offset", "500"},
+			networks:       map[string]string{"": kavaCompose},
+			compose:        kavaCompose,
+			expectedOffset: 500,
+		},
+		{
+			name:        "negative --port-offset",
+			args:        []string{"--port-offset", "-1"},
+			compose:     kavaCompose,
+			expectedErr: "--port-offset must be >= 0, found -1",
+		},
+		{
+			name:           "default network",
+			networks:       map[string]string{"": kavaCompose},
+			compose:        kavaCompose,
+			expectedOffset: 0,
+		},
+		{
+			name:           "first named network",
+			testnetName:    "devnet",
+			networks:       map[string]string{"": kavaCompose},
+			compose:        kavaCompose,
+			expectedOffset: 1000,
+		},
+		{
+			name:        "ports of other named networks are skipped",
+			testnetName: "devnet",
+			networks: map[string]string{
+				"":      kavaCompose,
+				"other": "services:\n  kavanode:\n    ports:\n      - \"27657:26657\"\n",
+			},
+			compose:        kavaCompose,
+			expectedOffset: 2000,
+		},
+		{
+			name:        "the network's own ports don't conflict",
+			testnetName: "devnet",
+			compose:     "services:\n  kavanode:\n    ports:\n      - \"26657:26657\"\n      - \"27657:27657\"\n",
+			// the network is listed as a named network once its config is generated
+			expectedOffset: 1000,
+		},
+		{
+			name:        "out of range",
+			testnetName: "devnet",
+			compose:     "services:\n  kavanode:\n    ports:\n      - \"65000:26657\"\n",
+			expectedErr: "no free host ports found for network devnet, set --port-offset",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defaultDir := useNetworksDir(t)
+			for name, compose := range tc.networks {
+				dir := defaultDir
+				if name != "" {
+					dir = namedGeneratedDir(name)
+				}
+				writeTestNetwork(t, dir, compose, nil)
+			}
+			testnetName = tc.testnetName
+			generatedConfigDir = defaultDir
+			if tc.testnetName != "" {
+				generatedConfigDir = namedGeneratedDir(tc.testnetName)
+			}
+			writeTestNetwork(t, generatedConfigDir, tc.compose, nil)
+
+			cmd := &cobra.Command{}
+			addPortOffsetFlag(cmd)
+			require.NoError(t, cmd.ParseFlags(tc.args))
+
+			offset, err := hostPortOffset(cmd)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedOffset, offset)
+		})
+	}
+}
+
+func TestLs(t *testing.T) {
+	defaultDir := useNetworksDir(t)
+	writeTestNetwork(t, defaultDir, "services: {}\n", nil)
+	devnetDir := namedGeneratedDir("devnet")
+	writeTestNetwork(t, devnetDir, "name: devnet\nservices: {}\n", &networkInfo{Name: "devnet", PortOffset: 1000, Template: "master", Validators: 2})
+	// directories without a generated config aren't networks
+	require.NoError(t, os.MkdirAll(filepath.Join(networksDir(), "empty"), 0755))
+
+	fake := useFakeRuntime(t)
+	fake.Projects = map[string]string{"devnet": "running(3)", "unrelated": "running(1)"}
+
+	t.Run("text", func(t *testing.T) {
+		out, err := executeTestnetCmdOutput(t, "ls")
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, []string{"NAME", "STATUS", "TEMPLATE", "VALIDATORS", "PORT", "OFFSET", "DIR"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"(default)", "not", "running", "-", "-", "-", defaultDir}, strings.Fields(lines[1]))
+		assert.Equal(t, []string{"devnet", "running(3)", "master", "2", "1000", devnetDir}, strings.Fields(lines[2]))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, err := executeTestnetCmdOutput(t, "ls", "--output", "json")
+		require.NoError(t, err)
+
+		var listings []networkListing
+		require.NoError(t, json.Unmarshal([]byte(out), &listings))
+		require.Len(t, listings, 2)
+		assert.Equal(t, networkListing{Name: "(default)", Project: "generated", Status: "not running", Dir: defaultDir}, listings[0])
+		assert.Equal(t, "devnet", listings[1].Project)
+		assert.Equal(t, "running(3)", listings[1].Status)
+		require.NotNil(t, listings[1].Info)
+		assert.Equal(t, 1000, listings[1].Info.PortOffset)
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		_, err := executeTestnetCmdOutput(t, "ls", "--output", "yaml")
+		require.ErrorContains(t, err, `--output must be text or json, found "yaml"`)
+	})
+}
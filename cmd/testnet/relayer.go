@@ -0,0 +1,134 @@
+package testnet
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+const (
+	relayerRly    = "rly"
+	relayerHermes = "hermes"
+)
+
+var supportedRelayers = []string{relayerRly, relayerHermes}
+
+// ibcRelayer is an IBC relayer implementation. Each generates its config from the ibc topology, opens its paths &
+// runs the relayer service.
+type ibcRelayer interface {
+	// Generate writes the relayer's config & keys to the generated config dir
+	Generate(generatedConfigDir string, topology generate.IbcTopology) error
+	// Link opens the connection & channel of every path of the topology
+	Link(ctx context.Context, topology generate.IbcTopology) error
+	// Template is the template of the relayer's compose service, which relays once the paths are open
+	Template() string
+	// ServiceName is the compose service of the relayer
+	ServiceName() string
+}
+
+// newIbcRelayer returns the relayer of --relayer
+func newIbcRelayer(name string) (ibcRelayer, error) {
+	switch name {
+	case relayerRly:
+		return rlyRelayer{}, nil
+	case relayerHermes:
+		return hermesRelayer{}, nil
+	default:
+		return nil, fmt.Errorf("--relayer must be one of %v, found %q", supportedRelayers, name)
+	}
+}
+
+// runRelayerCmd runs a command of a relayer image on the network, with the relayer's generated dir mounted at home
+func runRelayerCmd(ctx context.Context, image, dir, home string, cmd []string) error {
+	return containerRuntime.Run(ctx, RunOptions{
+		Image:   image,
+		Name:    containerName("ibc-relayer"),
+		Network: composeNetwork(),
+		Volumes: []string{fmt.Sprintf("%s:%s", generatedPath(dir), home)},
+		Remove:  true,
+		Cmd:     cmd,
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+	})
+}
+
+// rlyRelayer is the go relayer, https://github.com/cosmos/relayer
+type rlyRelayer struct{}
+
+func (rlyRelayer) Generate(generatedConfigDir string, topology generate.IbcTopology) error {
+	return generate.GenerateRelayerConfig(generatedConfigDir, topology)
+}
+
+func (rlyRelayer) Link(ctx context.Context, topology generate.IbcTopology) error {
+	for _, path := range topology.Paths {
+		fmt.Printf("Attempting to establish IBC path %s between %s and %s...\n", path.Name, path.Src, path.Dst)
+		// ica paths only get a connection, their channel is opened by registering an interchain account
+		cmd := []string{"rly", "transact", "connection", path.Name, "-r", "10", "-t", "30s"}
+		if path.Type != generate.IbcPathIca {
+			cmd = []string{
+				"rly", "transact", "link", path.Name,
+				"--src-port", path.SrcPort, "--dst-port", path.DstPort,
+				"--version", path.Version, "--order", path.Order,
+				"-r", "10", "-t", "30s",
+			}
+		}
+		if err := runRelayerCmd(ctx, relayerImageTag, "relayer", "/home/relayer/.relayer", cmd); err != nil {
+			fmt.Println(err.Error())
+			return fmt.Errorf("[relayer] failed to open ibc path %s", path.Name)
+		}
+	}
+	return nil
+}
+
+func (rlyRelayer) Template() string    { return generate.RelayerTemplate }
+func (rlyRelayer) ServiceName() string { return "relayer" }
+
+// hermesRelayer is the hermes relayer, https://github.com/informalsystems/hermes
+type hermesRelayer struct{}
+
+// hermesHome is the home dir of the hermes image
+const hermesHome = "/home/hermes/.hermes"
+
+func (hermesRelayer) Generate(generatedConfigDir string, topology generate.IbcTopology) error {
+	return generate.GenerateHermesConfig(generatedConfigDir, topology)
+}
+
+func (hermesRelayer) Link(ctx context.Context, topology generate.IbcTopology) error {
+	chainIDs := []string{topology.ChainID(generate.KavaIbcChainName)}
+	for _, chain := range topology.Chains {
+		chainIDs = append(chainIDs, chain.ChainID)
+	}
+	for _, chainID := range chainIDs {
+		cmd := []string{
+			"keys", "add", "--chain", chainID, "--key-name", "testkey", "--overwrite",
+			"--mnemonic-file", hermesHome + "/" + generate.HermesMnemonicFile, "--hd-path", generate.HermesHdPath,
+		}
+		if err := runRelayerCmd(ctx, hermesImageTag, "hermes", hermesHome, cmd); err != nil {
+			return fmt.Errorf("[hermes] failed to add the relayer key of %s: %w", chainID, err)
+		}
+	}
+
+	for _, path := range topology.Paths {
+		fmt.Printf("Attempting to establish IBC path %s between %s and %s...\n", path.Name, path.Src, path.Dst)
+		src, dst := topology.ChainID(path.Src), topology.ChainID(path.Dst)
+		// ica paths only get a connection, their channel is opened by registering an interchain account
+		cmd := []string{"create", "connection", "--a-chain", src, "--b-chain", dst}
+		if path.Type != generate.IbcPathIca {
+			cmd = []string{
+				"create", "channel", "--a-chain", src, "--b-chain", dst, "--new-client-connection", "--yes",
+				"--a-port", path.SrcPort, "--b-port", path.DstPort,
+				"--channel-version", path.Version, "--order", path.Order,
+			}
+		}
+		if err := runRelayerCmd(ctx, hermesImageTag, "hermes", hermesHome, cmd); err != nil {
+			fmt.Println(err.Error())
+			return fmt.Errorf("[hermes] failed to open ibc path %s", path.Name)
+		}
+	}
+	return nil
+}
+
+func (hermesRelayer) Template() string    { return generate.HermesTemplate }
+func (hermesRelayer) ServiceName() string { return "hermes" }
@@ -0,0 +1,137 @@
+package testnet
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+// testRelayerTopology is kava with the default ibc chain & osmosis, with a transfer path from kava & an ica path
+// between the chains
+func testRelayerTopology(t *testing.T) generate.IbcTopology {
+	t.Helper()
+	topology, err := generate.IbcTopology{
+		Chains: []generate.IbcChain{
+			{Name: generate.DefaultIbcChainName},
+			{Name: "osmosis", ChainID: "osmosis_9000-1", Denom: "uosmo"},
+		},
+		Paths: []generate.IbcPath{
+			{Name: "kava-ibcchain", Src: generate.KavaIbcChainName, Dst: generate.DefaultIbcChainName},
+			{Name: "ibcchain-osmosis", Src: generate.DefaultIbcChainName, Dst: "osmosis", Type: generate.IbcPathIca},
+		},
+	}.Resolve()
+	require.NoError(t, err)
+	return topology
+}
+
+func TestNewIbcRelayer(t *testing.T) {
+	testCases := []struct {
+		name            string
+		expectedFiles   []string
+		expectedService string
+	}{
+		{name: relayerRly, expectedFiles: []string{"relayer/config/config.yaml"}, expectedService: "relayer"},
+		{name: relayerHermes, expectedFiles: []string{"hermes/config.toml", "hermes/" + generate.HermesMnemonicFile}, expectedService: "hermes"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			relayer, err := newIbcRelayer(tc.name)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedService, relayer.ServiceName())
+			assert.Equal(t, tc.expectedService, relayer.Template())
+
+			dir := t.TempDir()
+			require.NoError(t, relayer.Generate(dir, testRelayerTopology(t)))
+			for _, file := range tc.expectedFiles {
+				assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(file)))
+			}
+		})
+	}
+
+	_, err := newIbcRelayer("hermez")
+	require.ErrorContains(t, err, `--relayer must be one of [rly hermes], found "hermez"`)
+}
+
+func TestIbcRelayerLink(t *testing.T) {
+	testCases := []struct {
+		name          string
+		expectedImage string
+		expectedHome  string
+		expectedCmds  []string
+	}{
+		{
+			name:          relayerRly,
+			expectedImage: relayerImageTag,
+			expectedHome:  "relayer:/home/relayer/.relayer",
+			expectedCmds: []string{
+				"rly transact link kava-ibcchain --src-port transfer --dst-port transfer --version ics20-1 --order unordered -r 10 -t 30s",
+				"rly transact connection ibcchain-osmosis -r 10 -t 30s",
+			},
+		},
+		{
+			name:          relayerHermes,
+			expectedImage: hermesImageTag,
+			expectedHome:  "hermes:" + hermesHome,
+			expectedCmds: []string{
+				"keys add --chain kavalocalnet_8888-1 --key-name testkey --overwrite --mnemonic-file " + hermesHome + "/mnemonic.txt --hd-path " + generate.HermesHdPath,
+				"keys add --chain kavalocalnet_8889-2 --key-name testkey --overwrite --mnemonic-file " + hermesHome + "/mnemonic.txt --hd-path " + generate.HermesHdPath,
+				"keys add --chain osmosis_9000-1 --key-name testkey --overwrite --mnemonic-file " + hermesHome + "/mnemonic.txt --hd-path " + generate.HermesHdPath,
+				"create channel --a-chain kavalocalnet_8888-1 --b-chain kavalocalnet_8889-2 --new-client-connection --yes --a-port transfer --b-port transfer --channel-version ics20-1 --order unordered",
+				"create connection --a-chain kavalocalnet_8889-2 --b-chain osmosis_9000-1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := useGeneratedDir(t)
+			testnetName = "devnet"
+			fake := useFakeRuntime(t)
+			var cmds []string
+			fake.RunFunc = func(opts RunOptions) error {
+				assert.Equal(t, tc.expectedImage, opts.Image)
+				assert.Equal(t, "devnet-ibc-relayer", opts.Name)
+				assert.Equal(t, "generated_default", opts.Network)
+				assert.Equal(t, []string{filepath.Join(dir, tc.expectedHome)}, opts.Volumes)
+				assert.True(t, opts.Remove)
+				cmds = append(cmds, strings.Join(opts.Cmd, " "))
+				return nil
+			}
+
+			relayer, err := newIbcRelayer(tc.name)
+			require.NoError(t, err)
+			require.NoError(t, relayer.Link(context.Background(), testRelayerTopology(t)))
+			assert.Equal(t, tc.expectedCmds, cmds)
+		})
+	}
+}
+
+func TestIbcRelayerLinkFailure(t *testing.T) {
+	for _, name := range supportedRelayers {
+		t.Run(name, func(t *testing.T) {
+			useGeneratedDir(t)
+			fake := useFakeRuntime(t)
+			// opening the path to osmosis fails, rly names the path & hermes the chain
+			fake.RunFunc = func(opts RunOptions) error {
+				cmd := strings.Join(opts.Cmd, " ")
+				if strings.Contains(cmd, "ibcchain-osmosis") || strings.Contains(cmd, "--b-chain osmosis_9000-1") {
+					return errors.New("exit status 1")
+				}
+				return nil
+			}
+
+			relayer, err := newIbcRelayer(name)
+			require.NoError(t, err)
+			err = relayer.Link(context.Background(), testRelayerTopology(t))
+			require.ErrorContains(t, err, "failed to open ibc path ibcchain-osmosis")
+		})
+	}
+}
@@ -17,22 +17,50 @@ const (
 	deputyServiceName  = "deputy"
 
 	relayerImageTag = "kava/relayer:v2.4.2"
+	hermesImageTag  = "informalsystems/hermes:1.8.2"
 )
 
 var (
 	ibcFlag            bool
 	gethFlag           bool
 	includePruningFlag bool
+	numValidators      int
 	kavaConfigTemplate string
+	topologyFile       string
 
 	kavaDbBackend string
+	// remapPortsFlag publishes conflicting host ports of the merged templates on the next free port
+	remapPortsFlag bool
+
+	// ibcTopology are the chains & paths run by --ibc, set by a topology file
+	ibcTopology = generate.DefaultIbcTopology()
+	relayerFlag string
+
+	// kavaConfigOverrides & kavaAppOverrides are key=value settings of every kava node's config.toml & app.toml
+	kavaConfigOverrides []string
+	kavaAppOverrides    []string
+
+	kavaGenesisFile            string
+	kavaGenesisGodCommittee    bool
+	kavaGenesisMinPowerPercent float64
+	kavaGenesisPersistentPower bool
+	validateGenesis            bool
+	validateGenesisSkip        []string
 
 	chainUpgradeName         string
 	chainUpgradeHeight       int64
 	chainUpgradeBaseImageTag string
-
-	generatedConfigDir        string
-	defaultGeneratedConfigDir string = filepath.Join(generate.ConfigTemplatesDir, "../..", "full_configs", "generated")
+	chainUpgradePlanFile     string
+	chainUpgradeVia          string
+	chainUpgradeAssertions   string
+	// chainUpgradePlan is the upgrade plan of a topology file, it takes precedence over the upgrade flags
+	chainUpgradePlan *UpgradePlan
+
+	generatedConfigDir string
+	// testnetName is the --name of the network, empty for the default network
+	testnetName               string
+	portOffsetFlag            int
+	defaultGeneratedConfigDir string = defaultGeneratedDir()
 
 	supportedServices = []string{kavaServiceName, binanceServiceName, deputyServiceName}
 )
@@ -52,7 +80,13 @@ func Cmd() *cobra.Command {
 
 	Docker compose files are (by default) written to %s`, defaultGeneratedConfigDir),
 		Args: cobra.NoArgs,
-		RunE: func(_ *cobra.Command, args []string) error {
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			return selectNetwork(cmd)
+		},
+		PersistentPostRunE: func(_ *cobra.Command, _ []string) error {
+			return closeChainClients()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// 1) clear out generated config folder
 			if err := os.RemoveAll(generatedConfigDir); err != nil {
@@ -60,14 +94,17 @@ func Cmd() *cobra.Command {
 			}
 
 			// 2) generate a complete docker-compose config
-			if err := generate.GenerateDefaultConfig(generatedConfigDir, kavaDbBackend); err != nil {
+			if err := generate.GenerateDefaultConfig(generatedConfigDir, kavaDbBackend, remapPortsFlag); err != nil {
 				return fmt.Errorf("could not generate config: %v", err)
 			}
+			if _, err := finalizeNetwork(cmd); err != nil {
+				return err
+			}
 
 			// 3) run docker-compose up
-			cmd := []string{"docker", "compose", "--file", generatedPath("docker-compose.yaml"), "up"}
-			fmt.Println("running:", strings.Join(cmd, " "))
-			if err := replaceCurrentProcess(cmd...); err != nil {
+			upCmd := []string{"docker", "compose", "--file", generatedPath("docker-compose.yaml"), "up"}
+			fmt.Println("running:", strings.Join(upCmd, " "))
+			if err := replaceCurrentProcess(upCmd...); err != nil {
 				return fmt.Errorf("could not run command: %v", err)
 			}
 			return nil
@@ -75,12 +112,19 @@ func Cmd() *cobra.Command {
 	}
 
 	testnetCmd.PersistentFlags().StringVar(&generatedConfigDir, "generated-dir", defaultGeneratedConfigDir, "output directory for the generated config")
+	testnetCmd.PersistentFlags().StringVar(&testnetName, "name", "", "name of an isolated network, run alongside the default & other named networks. its config is generated in "+filepath.Join(networksDir(), "<name>", "generated")+".")
+	testnetCmd.PersistentFlags().StringSliceVar(&generate.TemplatesPath, "templates-path", nil, fmt.Sprintf("user template directories searched before %s, the kvtool config file & the built-in templates", generate.TemplatesPathEnv))
+	testnetCmd.PersistentFlags().BoolVar(&remapPortsFlag, "remap-ports", false, "publish host ports that are already used by another service on the next free port, instead of failing.")
 	testnetCmd.PersistentFlags().StringVar(&kavaDbBackend, "kava.db", "goleveldb", "update the db_backend of kava. KAVA_TAG must be compatible with db choice.")
 
 	testnetCmd.AddCommand(GenConfigCmd())
 	testnetCmd.AddCommand(BootstrapCmd())
 	testnetCmd.AddCommand(ExportCmd())
+	testnetCmd.AddCommand(StatusCmd())
+	testnetCmd.AddCommand(SnapshotCmd())
 	testnetCmd.AddCommand(DcCmd())
+	testnetCmd.AddCommand(TemplatesCmd())
+	testnetCmd.AddCommand(LsCmd())
 
 	// kept for convenience/legacy reasons.
 	testnetCmd.AddCommand(UpCmd())
@@ -89,6 +133,43 @@ func Cmd() *cobra.Command {
 	return testnetCmd
 }
 
+// defaultGeneratedDir is full_configs/generated in the kvtool repo, or generated in the kvtool home when kvtool is
+// built without a checkout of its repo
+func defaultGeneratedDir() string {
+	if generate.ConfigTemplatesDir != "" {
+		return filepath.Join(generate.ConfigTemplatesDir, "../..", "full_configs", "generated")
+	}
+	home, err := generate.KvtoolHome()
+	if err != nil {
+		return filepath.Join("full_configs", "generated")
+	}
+	return filepath.Join(home, "generated")
+}
+
+// kavaNodeConfigOverrides parses --kava.config & --kava.app
+func kavaNodeConfigOverrides() (generate.NodeConfigOverrides, error) {
+	var overrides generate.NodeConfigOverrides
+	var err error
+	if overrides.Config, err = generate.ParseConfigOverrides(kavaConfigOverrides); err != nil {
+		return overrides, fmt.Errorf("--kava.config: %w", err)
+	}
+	if overrides.App, err = generate.ParseConfigOverrides(kavaAppOverrides); err != nil {
+		return overrides, fmt.Errorf("--kava.app: %w", err)
+	}
+	return overrides, nil
+}
+
+// addPortOffsetFlag adds --port-offset to cmd
+func addPortOffsetFlag(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&portOffsetFlag, "port-offset", 0, fmt.Sprintf("shift every host port of the network by this amount. networks with --name default to the first multiple of %d that doesn't conflict with other networks.", portOffsetStep))
+}
+
+// addNodeConfigFlags adds --kava.config & --kava.app to cmd
+func addNodeConfigFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&kavaConfigOverrides, "kava.config", nil, "key=value setting of every kava node's config.toml, eg. consensus.timeout_commit=500ms. can be repeated.")
+	cmd.Flags().StringArrayVar(&kavaAppOverrides, "kava.app", nil, "key=value setting of every kava node's app.toml, eg. json-rpc.api=eth,net,web3. can be repeated.")
+}
+
 func KavaCmd() *cobra.Command {
 	kavaCmd := &cobra.Command{
 		Use:     "kava -- [kava commands & args]",
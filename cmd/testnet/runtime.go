@@ -0,0 +1,358 @@
+package testnet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ContainerRuntime runs & inspects the containers of the generated network.
+// All orchestration in the testnet package goes through it so that flows can be run against a fake.
+type ContainerRuntime interface {
+	// ComposeUp creates & starts the services of the generated docker-compose.yaml. All services are started if none are given.
+	ComposeUp(ctx context.Context, opts ComposeUpOptions) error
+	// ComposeDown stops & removes the containers & networks of the generated docker-compose.yaml
+	ComposeDown(ctx context.Context) error
+	// ComposePull pulls the images of all services
+	ComposePull(ctx context.Context) error
+	// ComposeStop stops the running services without removing them
+	ComposeStop(ctx context.Context) error
+	// ComposeStart starts previously stopped services
+	ComposeStart(ctx context.Context) error
+	// ComposeProjects returns the status of every compose project on the host, eg. "running(3)", keyed by project name
+	ComposeProjects(ctx context.Context) (map[string]string, error)
+
+	// ServiceContainerID returns the id of the container of a compose service, including exited containers
+	ServiceContainerID(ctx context.Context, service string) (string, error)
+	// ServiceState returns the state of the container of a compose service, eg "running" or "exited"
+	ServiceState(ctx context.Context, service string) (string, error)
+	// Exec runs a command in the running container of a compose service
+	Exec(ctx context.Context, opts ExecOptions) error
+	// ContainerImage returns the image a container was created from, eg. kava/kava:v0.26.0
+	ContainerImage(ctx context.Context, containerID string) (string, error)
+	// CopyFromContainer copies the contents of a directory in a container, including mounted volumes, into a host directory
+	CopyFromContainer(ctx context.Context, containerID, srcPath, destPath string) error
+	// CopyToContainer copies the contents of a host directory into a directory of a created or running container
+	CopyToContainer(ctx context.Context, containerID, srcPath, destPath string) error
+
+	// Logs returns all logs of a container
+	Logs(ctx context.Context, containerID string) (string, error)
+	// FollowLogs streams the log lines of a container until the context is done
+	FollowLogs(ctx context.Context, containerID string) (<-chan string, error)
+
+	// Commit creates an image from a container & returns the image id
+	Commit(ctx context.Context, containerID, image string) (string, error)
+	// Run creates & runs a container from an image, waiting for it to exit
+	Run(ctx context.Context, opts RunOptions) error
+	// ContainersFromImage returns the ids of all containers created from an image
+	ContainersFromImage(ctx context.Context, image string) ([]string, error)
+	RemoveContainer(ctx context.Context, containerID string) error
+	RemoveImage(ctx context.Context, image string) error
+	// PruneContainers removes all stopped containers
+	PruneContainers(ctx context.Context) error
+}
+
+// ComposeUpOptions configures ContainerRuntime.ComposeUp
+type ComposeUpOptions struct {
+	Services      []string
+	Detach        bool
+	ForceRecreate bool
+	RemoveOrphans bool
+	// NoStart creates the containers without starting them
+	NoStart bool
+	// Env are additional environment variables for the compose file, eg. KAVA_TAG=v0.25.0
+	Env []string
+}
+
+// ExecOptions configures ContainerRuntime.Exec
+type ExecOptions struct {
+	Service string
+	Cmd     []string
+	// Stdout & Stderr receive the command's output. Output is discarded when nil.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// RunOptions configures ContainerRuntime.Run
+type RunOptions struct {
+	Image   string
+	Name    string
+	Network string
+	// Volumes are bind mounts in the form <host path>:<container path>
+	Volumes []string
+	// Remove deletes the container once it exits
+	Remove bool
+	Cmd    []string
+	// Stdout & Stderr receive the container's output. Output is discarded when nil.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// containerRuntime is the runtime used by all testnet commands
+var containerRuntime ContainerRuntime = NewCLIRuntime(func() string { return generatedPath("docker-compose.yaml") })
+
+// cliRuntime is a ContainerRuntime that runs the docker CLI.
+// It only relies on stable output: container ids & fields of `docker inspect`.
+type cliRuntime struct {
+	composeFile func() string
+}
+
+var _ ContainerRuntime = cliRuntime{}
+
+// NewCLIRuntime returns a ContainerRuntime backed by the docker CLI.
+// composeFile returns the path of the docker-compose.yaml that compose commands operate on.
+func NewCLIRuntime(composeFile func() string) ContainerRuntime {
+	return cliRuntime{composeFile: composeFile}
+}
+
+func (r cliRuntime) ComposeUp(ctx context.Context, opts ComposeUpOptions) error {
+	args := []string{"up"}
+	if opts.Detach {
+		args = append(args, "-d")
+	}
+	if opts.ForceRecreate {
+		args = append(args, "--force-recreate")
+	}
+	if opts.RemoveOrphans {
+		args = append(args, "--remove-orphans")
+	}
+	if opts.NoStart {
+		args = append(args, "--no-start")
+	}
+	cmd := r.compose(ctx, append(args, opts.Services...)...)
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	return cmd.Run()
+}
+
+func (r cliRuntime) ComposeDown(ctx context.Context) error {
+	return r.compose(ctx, "down").Run()
+}
+
+func (r cliRuntime) ComposePull(ctx context.Context) error {
+	return r.compose(ctx, "pull").Run()
+}
+
+func (r cliRuntime) ComposeStop(ctx context.Context) error {
+	return r.compose(ctx, "stop").Run()
+}
+
+func (r cliRuntime) ComposeStart(ctx context.Context) error {
+	return r.compose(ctx, "start").Run()
+}
+
+func (r cliRuntime) ComposeProjects(ctx context.Context) (map[string]string, error) {
+	out, err := output(exec.CommandContext(ctx, "docker", "compose", "ls", "--all", "--format", "json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list compose projects: %w", err)
+	}
+	var projects []struct {
+		Name   string
+		Status string
+	}
+	if err := json.Unmarshal([]byte(out), &projects); err != nil {
+		return nil, fmt.Errorf("failed to parse compose projects: %w", err)
+	}
+	statuses := make(map[string]string, len(projects))
+	for _, project := range projects {
+		statuses[project.Name] = project.Status
+	}
+	return statuses, nil
+}
+
+func (r cliRuntime) ServiceContainerID(ctx context.Context, service string) (string, error) {
+	out, err := output(exec.CommandContext(ctx, "docker", "compose", "-f", r.composeFile(), "ps", "-a", "-q", service))
+	if err != nil {
+		return "", fmt.Errorf("failed to find container of %s: %w", service, err)
+	}
+	// the first line is the most recently created container
+	containerID := strings.TrimSpace(strings.SplitN(out, "\n", 2)[0])
+	if containerID == "" {
+		return "", fmt.Errorf("no container found for %s", service)
+	}
+	return containerID, nil
+}
+
+func (r cliRuntime) ServiceState(ctx context.Context, service string) (string, error) {
+	containerID, err := r.ServiceContainerID(ctx, service)
+	if err != nil {
+		return "", err
+	}
+	out, err := output(exec.CommandContext(ctx, "docker", "inspect", "--format", "{{.State.Status}}", containerID))
+	if err != nil {
+		return "", fmt.Errorf("error checking container state: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (r cliRuntime) Exec(ctx context.Context, opts ExecOptions) error {
+	args := append([]string{"compose", "-f", r.composeFile(), "exec", "-T", opts.Service}, opts.Cmd...)
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	return cmd.Run()
+}
+
+func (r cliRuntime) ContainerImage(ctx context.Context, containerID string) (string, error) {
+	out, err := output(exec.CommandContext(ctx, "docker", "inspect", "--format", "{{.Config.Image}}", containerID))
+	if err != nil {
+		return "", fmt.Errorf("error checking container image: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (r cliRuntime) CopyFromContainer(ctx context.Context, containerID, srcPath, destPath string) error {
+	if err := os.MkdirAll(destPath, 0755); err != nil {
+		return err
+	}
+	// the trailing /. copies the contents of the directory rather than the directory itself
+	_, err := output(exec.CommandContext(ctx, "docker", "cp", fmt.Sprintf("%s:%s/.", containerID, srcPath), destPath))
+	if err != nil {
+		return fmt.Errorf("failed to copy %s from container %s: %w", srcPath, containerID, err)
+	}
+	return nil
+}
+
+func (r cliRuntime) CopyToContainer(ctx context.Context, containerID, srcPath, destPath string) error {
+	_, err := output(exec.CommandContext(ctx, "docker", "cp", srcPath+"/.", fmt.Sprintf("%s:%s", containerID, destPath)))
+	if err != nil {
+		return fmt.Errorf("failed to copy %s to container %s: %w", srcPath, containerID, err)
+	}
+	return nil
+}
+
+func (r cliRuntime) Logs(ctx context.Context, containerID string) (string, error) {
+	out, err := exec.CommandContext(ctx, "docker", "logs", containerID).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to get container logs: %w", err)
+	}
+	return string(out), nil
+}
+
+func (r cliRuntime) FollowLogs(ctx context.Context, containerID string) (<-chan string, error) {
+	// Run with CommandContext so it automatically cancels when the context is
+	// done.
+	cmd := exec.CommandContext(ctx, "docker", "logs", "-f", containerID)
+	// pipe all stdout to a ReadCloser we can scan
+	cmdReader, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get command stdout pipe: %w", err)
+	}
+	// redirect all stderr output to stdout
+	cmd.Stderr = cmd.Stdout
+
+	scanner := bufio.NewScanner(cmdReader)
+	out := make(chan string)
+
+	go func() {
+		defer close(out)
+		for scanner.Scan() {
+			// Check if the context is done, if so close the channel and return.
+			// Don't need to manually stop the cmd since we use CommandContext()
+			select {
+			case <-ctx.Done():
+				return
+			case out <- scanner.Text():
+			}
+		}
+	}()
+
+	// Start process, but don't wait for it to finish since it follows logs.
+	// Don't use .Run() as it will block until it completes, causing a hang.
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run docker logs -f %s: %w", containerID, err)
+	}
+
+	return out, nil
+}
+
+func (r cliRuntime) Commit(ctx context.Context, containerID, image string) (string, error) {
+	out, err := output(exec.CommandContext(ctx, "docker", "commit", containerID, image))
+	if err != nil {
+		return "", fmt.Errorf("failed to commit container %s: %w", containerID, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (r cliRuntime) Run(ctx context.Context, opts RunOptions) error {
+	args := []string{"run"}
+	for _, volume := range opts.Volumes {
+		args = append(args, "-v", volume)
+	}
+	if opts.Name != "" {
+		args = append(args, "--name", opts.Name)
+	}
+	if opts.Remove {
+		args = append(args, "--rm")
+	}
+	if opts.Network != "" {
+		args = append(args, "--net", opts.Network)
+	}
+	args = append(args, opts.Image)
+	args = append(args, opts.Cmd...)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	return cmd.Run()
+}
+
+func (r cliRuntime) ContainersFromImage(ctx context.Context, image string) ([]string, error) {
+	out, err := output(exec.CommandContext(ctx, "docker", "ps", "-aq", "--filter", "ancestor="+image))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers of %s: %w", image, err)
+	}
+	return strings.Fields(out), nil
+}
+
+func (r cliRuntime) RemoveContainer(ctx context.Context, containerID string) error {
+	_, err := output(exec.CommandContext(ctx, "docker", "rm", containerID))
+	return err
+}
+
+func (r cliRuntime) RemoveImage(ctx context.Context, image string) error {
+	_, err := output(exec.CommandContext(ctx, "docker", "rmi", image))
+	return err
+}
+
+func (r cliRuntime) PruneContainers(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "docker", "container", "prune", "-f")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// compose returns a docker compose command for the generated config that prints its output
+func (r cliRuntime) compose(ctx context.Context, args ...string) *exec.Cmd {
+	// exec.Command requires all items to be in single []string variadic
+	// combine the args with the file flag & value
+	pieces := append([]string{"compose", "-f", r.composeFile()}, args...)
+	fmt.Printf("run: docker %s\n", strings.Join(pieces, " "))
+	cmd := exec.CommandContext(ctx, "docker", pieces...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// output runs cmd & returns its stdout. stderr is included in the error if the command fails.
+func output(cmd *exec.Cmd) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%s: %w", strings.TrimSpace(stderr.String()), err)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,363 @@
+package testnet
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRuntime is an in-memory ContainerRuntime for running testnet flows without docker.
+// It tracks the state of containers & images and records every call made to it.
+// Exec & Run delegate to ExecFunc & RunFunc so tests can script command output, eg. block heights.
+// OnCall is called with each recorded call, eg. to check the generated files at the time of the call.
+type fakeRuntime struct {
+	mu sync.Mutex
+
+	// Services are the services of the compose file. They are all started by ComposeUp without services.
+	Services []string
+	// Containers are the containers of compose services, keyed by service name
+	Containers map[string]*fakeContainer
+	// Images are the images created by Commit, keyed by name
+	Images map[string]string
+	// RunContainers are the ids of containers created by Run, keyed by image
+	RunContainers map[string][]string
+	// Calls is a log of the calls made, eg. "up -d kavanode"
+	Calls []string
+	// Projects are the statuses of the compose projects returned by ComposeProjects
+	Projects map[string]string
+
+	ExecFunc func(opts ExecOptions) error
+	RunFunc  func(opts RunOptions) error
+	OnCall   func(call string)
+
+	nextID int
+}
+
+// fakeContainer is a container in a fakeRuntime
+type fakeContainer struct {
+	ID    string
+	Image string
+	State string
+	Logs  []string
+	// Files are the contents of the container's files, keyed by absolute path
+	Files map[string]string
+}
+
+var _ ContainerRuntime = &fakeRuntime{}
+
+// newFakeRuntime returns a fakeRuntime for a compose file with the given services
+func newFakeRuntime(services ...string) *fakeRuntime {
+	return &fakeRuntime{
+		Services:      services,
+		Containers:    map[string]*fakeContainer{},
+		Images:        map[string]string{},
+		RunContainers: map[string][]string{},
+	}
+}
+
+// useFakeRuntime replaces the container runtime with a fakeRuntime for the duration of the test
+func useFakeRuntime(t *testing.T, services ...string) *fakeRuntime {
+	t.Helper()
+	fake := newFakeRuntime(services...)
+	original := containerRuntime
+	containerRuntime = fake
+	t.Cleanup(func() { containerRuntime = original })
+	return fake
+}
+
+func (f *fakeRuntime) ComposeUp(_ context.Context, opts ComposeUpOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("up", opts.Services...)
+
+	services := opts.Services
+	if len(services) == 0 {
+		services = f.Services
+	}
+	for _, service := range services {
+		container, found := f.Containers[service]
+		if !found || opts.ForceRecreate {
+			container = &fakeContainer{ID: f.newID(), Files: map[string]string{}}
+			f.Containers[service] = container
+		}
+		if opts.NoStart {
+			container.State = "created"
+		} else {
+			container.State = "running"
+		}
+	}
+	return nil
+}
+
+func (f *fakeRuntime) ComposeDown(_ context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("down")
+	f.Containers = map[string]*fakeContainer{}
+	return nil
+}
+
+func (f *fakeRuntime) ComposePull(_ context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("pull")
+	return nil
+}
+
+func (f *fakeRuntime) ComposeStop(_ context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("stop")
+	for _, container := range f.Containers {
+		container.State = "exited"
+	}
+	return nil
+}
+
+func (f *fakeRuntime) ComposeStart(_ context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("start")
+	for _, container := range f.Containers {
+		container.State = "running"
+	}
+	return nil
+}
+
+func (f *fakeRuntime) ComposeProjects(_ context.Context) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ls")
+	return f.Projects, nil
+}
+
+func (f *fakeRuntime) ServiceContainerID(_ context.Context, service string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	container, err := f.container(service)
+	if err != nil {
+		return "", err
+	}
+	return container.ID, nil
+}
+
+func (f *fakeRuntime) ServiceState(_ context.Context, service string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	container, err := f.container(service)
+	if err != nil {
+		return "", err
+	}
+	return container.State, nil
+}
+
+func (f *fakeRuntime) Exec(_ context.Context, opts ExecOptions) error {
+	f.mu.Lock()
+	f.record("exec", append([]string{opts.Service}, opts.Cmd...)...)
+	container, err := f.container(opts.Service)
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if container.State != "running" {
+		return fmt.Errorf("service %s is not running", opts.Service)
+	}
+	if f.ExecFunc == nil {
+		return nil
+	}
+	return f.ExecFunc(opts)
+}
+
+func (f *fakeRuntime) ContainerImage(_ context.Context, containerID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	container, err := f.containerByID(containerID)
+	if err != nil {
+		return "", err
+	}
+	return container.Image, nil
+}
+
+// CopyFromContainer writes the container files under srcPath to the host
+func (f *fakeRuntime) CopyFromContainer(_ context.Context, containerID, srcPath, destPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("cp", containerID+":"+srcPath, destPath)
+	container, err := f.containerByID(containerID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(destPath, 0755); err != nil {
+		return err
+	}
+	for path, contents := range container.Files {
+		rel, err := filepath.Rel(srcPath, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		dest := filepath.Join(destPath, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, []byte(contents), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyToContainer reads the host files under srcPath into the container's files
+func (f *fakeRuntime) CopyToContainer(_ context.Context, containerID, srcPath, destPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("cp", srcPath, containerID+":"+destPath)
+	container, err := f.containerByID(containerID)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(srcPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			return err
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		container.Files[filepath.Join(destPath, rel)] = string(contents)
+		return nil
+	})
+}
+
+func (f *fakeRuntime) Logs(_ context.Context, containerID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, container := range f.Containers {
+		if container.ID == containerID {
+			return strings.Join(container.Logs, "\n"), nil
+		}
+	}
+	return "", fmt.Errorf("no such container: %s", containerID)
+}
+
+// FollowLogs streams the logs the container has at the time of the call, then waits for the context to be done
+func (f *fakeRuntime) FollowLogs(ctx context.Context, containerID string) (<-chan string, error) {
+	logs, err := f.Logs(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		for _, line := range strings.Split(logs, "\n") {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- line:
+			}
+		}
+		<-ctx.Done()
+	}()
+	return out, nil
+}
+
+func (f *fakeRuntime) Commit(_ context.Context, containerID, image string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("commit", containerID, image)
+	imageID := "sha256:" + f.newID()
+	f.Images[image] = imageID
+	return imageID, nil
+}
+
+func (f *fakeRuntime) Run(_ context.Context, opts RunOptions) error {
+	f.mu.Lock()
+	f.record("run", append([]string{opts.Image}, opts.Cmd...)...)
+	if !opts.Remove {
+		f.RunContainers[opts.Image] = append(f.RunContainers[opts.Image], f.newID())
+	}
+	f.mu.Unlock()
+	if f.RunFunc == nil {
+		return nil
+	}
+	return f.RunFunc(opts)
+}
+
+func (f *fakeRuntime) ContainersFromImage(_ context.Context, image string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.RunContainers[image]...), nil
+}
+
+func (f *fakeRuntime) RemoveContainer(_ context.Context, containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("rm", containerID)
+	for image, ids := range f.RunContainers {
+		for i, id := range ids {
+			if id == containerID {
+				f.RunContainers[image] = append(ids[:i], ids[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no such container: %s", containerID)
+}
+
+func (f *fakeRuntime) RemoveImage(_ context.Context, image string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("rmi", image)
+	for name, id := range f.Images {
+		if name == image || id == image {
+			delete(f.Images, name)
+			return nil
+		}
+	}
+	return fmt.Errorf("no such image: %s", image)
+}
+
+func (f *fakeRuntime) PruneContainers(_ context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("container prune")
+	return nil
+}
+
+func (f *fakeRuntime) container(service string) (*fakeContainer, error) {
+	container, found := f.Containers[service]
+	if !found {
+		return nil, fmt.Errorf("no container found for %s", service)
+	}
+	return container, nil
+}
+
+func (f *fakeRuntime) containerByID(containerID string) (*fakeContainer, error) {
+	for _, container := range f.Containers {
+		if container.ID == containerID {
+			return container, nil
+		}
+	}
+	return nil, fmt.Errorf("no such container: %s", containerID)
+}
+
+func (f *fakeRuntime) newID() string {
+	f.nextID++
+	return fmt.Sprintf("%012x", f.nextID)
+}
+
+func (f *fakeRuntime) record(call string, args ...string) {
+	call = strings.TrimSpace(call + " " + strings.Join(args, " "))
+	f.Calls = append(f.Calls, call)
+	if f.OnCall != nil {
+		f.OnCall(call)
+	}
+}
@@ -0,0 +1,463 @@
+package testnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+// snapshotManifestFile describes a snapshot & is written to the root of its directory
+const snapshotManifestFile = "manifest.json"
+
+var (
+	snapshotsDirFlag   string
+	snapshotForceFlag  bool
+	snapshotNoWaitFlag bool
+)
+
+// snapshotManifest describes the network a snapshot was taken of
+type snapshotManifest struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	// Network is how the network was bootstrapped. It is nil for networks generated by gen-config.
+	Network  *networkInfo      `json:"network,omitempty"`
+	Services []snapshotService `json:"services"`
+}
+
+// snapshotService is a chain service whose node home is saved in the snapshot
+type snapshotService struct {
+	Name string `json:"name"`
+	// Image is the image the service was running, which may differ from the compose file after an upgrade
+	Image string `json:"image"`
+	// Home is the node's home directory in the container
+	Home string `json:"home"`
+	// Height is the latest height when the snapshot was taken, 0 if the node didn't respond
+	Height int64 `json:"height"`
+}
+
+func SnapshotCmd() *cobra.Command {
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save the chain data of the running testnet & restore it later.",
+		Long: `Save the chain data of the running testnet & restore it later.
+
+A snapshot contains the generated config dir & the home directory of every chain service (services that publish
+the tendermint rpc port 26657), copied from their containers. A manifest records the images the services were
+running, the template the network was bootstrapped with & the latest heights.
+
+Snapshots are written to <snapshots-dir>/<name>. The default snapshots dir is next to the generated config dir.`,
+	}
+
+	snapshotCmd.PersistentFlags().StringVar(&snapshotsDirFlag, "snapshots-dir", "", "directory snapshots are stored in. defaults to a snapshots dir next to the generated config dir.")
+
+	snapshotCmd.AddCommand(snapshotSaveCmd())
+	snapshotCmd.AddCommand(snapshotRestoreCmd())
+
+	return snapshotCmd
+}
+
+func snapshotSaveCmd() *cobra.Command {
+	saveCmd := &cobra.Command{
+		Use:   "save <name>",
+		Short: "Pauses the testnet, saves its chain data to a named snapshot, then restarts it.",
+		Example: `Save the current state of the testnet:
+$ kvtool testnet snapshot save after-setup`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx := cmd.Context()
+			name := args[0]
+			if err := validateSnapshotName(name); err != nil {
+				return err
+			}
+
+			dir := snapshotDir(name)
+			if _, err := os.Stat(dir); err == nil && !snapshotForceFlag {
+				return fmt.Errorf("snapshot %s already exists, use --force to replace it", name)
+			}
+			compose, err := loadComposeFile(generatedPath("docker-compose.yaml"))
+			if err != nil {
+				return err
+			}
+			network, err := readNetworkInfo(generatedConfigDir)
+			if err != nil {
+				return err
+			}
+			manifest := snapshotManifest{Name: name, CreatedAt: time.Now().UTC(), Network: network}
+
+			// heights are queried before the network is stopped
+			for _, service := range compose.chainNodeNames() {
+				containerID, err := containerRuntime.ServiceContainerID(ctx, service)
+				if err != nil {
+					return err
+				}
+				image, err := containerRuntime.ContainerImage(ctx, containerID)
+				if err != nil {
+					return err
+				}
+				queryCtx, cancel := context.WithTimeout(ctx, chainQueryTimeout)
+				height, err := latestHeight(queryCtx, service)
+				cancel()
+				if err != nil {
+					fmt.Printf("could not query the height of %s: %s\n", service, err)
+				}
+				manifest.Services = append(manifest.Services, snapshotService{
+					Name:   service,
+					Image:  image,
+					Home:   compose.Services[service].chainHome(),
+					Height: height,
+				})
+			}
+
+			if err := containerRuntime.ComposeStop(ctx); err != nil {
+				return err
+			}
+			defer func() {
+				fmt.Println("Restarting testnet...")
+				// restart even if the snapshot was interrupted
+				if startErr := containerRuntime.ComposeStart(context.Background()); startErr != nil && err == nil {
+					err = startErr
+				}
+			}()
+
+			// the snapshot is written to a temporary dir so a failed save doesn't leave a partial snapshot behind
+			tempDir := dir + ".tmp"
+			if err := os.RemoveAll(tempDir); err != nil {
+				return err
+			}
+			defer os.RemoveAll(tempDir)
+			if err := saveSnapshot(ctx, tempDir, compose, manifest); err != nil {
+				return fmt.Errorf("failed to save snapshot %s: %w", name, err)
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+			if err := os.Rename(tempDir, dir); err != nil {
+				return err
+			}
+
+			fmt.Printf("Saved snapshot %s to %s\n", name, dir)
+			return nil
+		},
+	}
+
+	saveCmd.Flags().BoolVar(&snapshotForceFlag, "force", false, "replace an existing snapshot with the same name")
+
+	return saveCmd
+}
+
+func snapshotRestoreCmd() *cobra.Command {
+	restoreCmd := &cobra.Command{
+		Use:   "restore <name>",
+		Short: "Replaces the testnet with a fresh one started from a named snapshot.",
+		Long: `Replaces the testnet with a fresh one started from a named snapshot.
+
+The current testnet is shut down & the generated config dir is replaced with the snapshot's. The services are
+pinned to the images they were running when the snapshot was taken, so networks saved after an upgrade restart
+with the upgraded binary. Chain data is copied into the new containers before they are started.
+
+The restored network is the current network, not the one the snapshot was taken of: its compose project is named
+after --name & its host ports are offset like a newly generated network's, see --port-offset.`,
+		Example: `Restore a snapshot:
+$ kvtool testnet snapshot restore after-setup
+
+Restore a snapshot of the default network as a named network alongside it:
+$ kvtool testnet snapshot restore after-setup --name devnet`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			name := args[0]
+			if err := validateSnapshotName(name); err != nil {
+				return err
+			}
+
+			dir := snapshotDir(name)
+			manifest, err := readSnapshotManifest(dir)
+			if err != nil {
+				return err
+			}
+
+			// shutdown existing networks if a docker-compose.yaml already exists.
+			if _, err := os.Stat(generatedPath("docker-compose.yaml")); err == nil {
+				if err := containerRuntime.ComposeDown(ctx); err != nil {
+					return err
+				}
+			}
+			if err := os.RemoveAll(generatedConfigDir); err != nil {
+				return fmt.Errorf("could not clear old generated config: %v", err)
+			}
+
+			if err := restoreSnapshot(cmd, dir, manifest); err != nil {
+				return fmt.Errorf("failed to restore snapshot %s: %w", name, err)
+			}
+
+			if err := containerRuntime.ComposeUp(ctx, ComposeUpOptions{Detach: true}); err != nil {
+				return err
+			}
+			fmt.Printf("Restored snapshot %s\n", name)
+
+			if snapshotNoWaitFlag {
+				return nil
+			}
+			for _, service := range manifest.Services {
+				if service.Name == DockerServiceKavaNode && service.Height > 0 {
+					return mustReachHeightOrLog(service.Height+1, 30*time.Second, DockerServiceKavaNode)
+				}
+			}
+			return nil
+		},
+	}
+
+	restoreCmd.Flags().BoolVar(&snapshotNoWaitFlag, "no-wait", false, "don't wait for the kava node to produce a block after restoring")
+	addPortOffsetFlag(restoreCmd)
+
+	return restoreCmd
+}
+
+// snapshotDir returns the directory of a named snapshot
+func snapshotDir(name string) string {
+	dir := snapshotsDirFlag
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(filepath.Clean(generatedConfigDir)), "snapshots")
+	}
+	return filepath.Join(dir, name)
+}
+
+func validateSnapshotName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.HasSuffix(name, ".tmp") {
+		return fmt.Errorf("invalid snapshot name %q", name)
+	}
+	return nil
+}
+
+// saveSnapshot writes the generated config dir, the home of every chain service & the manifest to dir.
+// The network must be stopped.
+func saveSnapshot(ctx context.Context, dir string, compose composeFile, manifest snapshotManifest) error {
+	composeDir, err := filepath.Abs(generatedConfigDir)
+	if err != nil {
+		return err
+	}
+
+	// host paths mounted into a chain home are saved with the home, they may contain files owned by the container's user
+	skip := map[string]bool{}
+	for _, service := range manifest.Services {
+		containerID, err := containerRuntime.ServiceContainerID(ctx, service.Name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("saving %s %s\n", service.Name, service.Home)
+		if err := containerRuntime.CopyFromContainer(ctx, containerID, service.Home, snapshotHomePath(dir, service.Name)); err != nil {
+			return err
+		}
+		for _, mount := range compose.Services[service.Name].bindMounts(composeDir) {
+			if isWithin(service.Home, mount.ContainerPath) {
+				skip[filepath.Clean(mount.HostPath)] = true
+			}
+		}
+	}
+
+	if err := copyDir(composeDir, filepath.Join(dir, "generated"), skip); err != nil {
+		return err
+	}
+
+	bz, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, snapshotManifestFile), bz, 0644)
+}
+
+// restoreSnapshot writes the snapshot's generated config dir & creates the network's containers with the saved chain homes.
+// The containers are not started.
+func restoreSnapshot(cmd *cobra.Command, dir string, manifest snapshotManifest) error {
+	ctx := cmd.Context()
+	if err := copyDir(filepath.Join(dir, "generated"), generatedConfigDir, nil); err != nil {
+		return err
+	}
+	if err := pinServiceImages(generatedPath("docker-compose.yaml"), manifest.Services); err != nil {
+		return err
+	}
+	if err := retargetSnapshot(cmd); err != nil {
+		return err
+	}
+
+	compose, err := loadComposeFile(generatedPath("docker-compose.yaml"))
+	if err != nil {
+		return err
+	}
+	composeDir, err := filepath.Abs(generatedConfigDir)
+	if err != nil {
+		return err
+	}
+
+	// homes that are bind mounted are restored on the host, the others are copied into the created containers
+	var copyIntoContainers []snapshotService
+	for _, service := range manifest.Services {
+		homeMounted := false
+		for _, mount := range compose.Services[service.Name].bindMounts(composeDir) {
+			if !isWithin(service.Home, mount.ContainerPath) {
+				continue
+			}
+			rel := strings.TrimPrefix(strings.TrimPrefix(path.Clean(mount.ContainerPath), path.Clean(service.Home)), "/")
+			if err := copyDir(filepath.Join(snapshotHomePath(dir, service.Name), filepath.FromSlash(rel)), mount.HostPath, nil); err != nil {
+				return err
+			}
+			if rel == "" {
+				homeMounted = true
+			}
+		}
+		if !homeMounted {
+			copyIntoContainers = append(copyIntoContainers, service)
+		}
+	}
+
+	if err := containerRuntime.ComposeUp(ctx, ComposeUpOptions{NoStart: true, RemoveOrphans: true}); err != nil {
+		return err
+	}
+	for _, service := range copyIntoContainers {
+		containerID, err := containerRuntime.ServiceContainerID(ctx, service.Name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("restoring %s %s\n", service.Name, service.Home)
+		if err := containerRuntime.CopyToContainer(ctx, containerID, snapshotHomePath(dir, service.Name), service.Home); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retargetSnapshot moves the restored config from the network the snapshot was taken of to the current network. The
+// snapshot's host port offset is undone before the ports are offset like a newly generated network's.
+func retargetSnapshot(cmd *cobra.Command) error {
+	snapshotOffset, err := generate.ComposePortOffset(generatedConfigDir)
+	if err != nil {
+		return err
+	}
+	if err := generate.OffsetComposeHostPorts(generatedConfigDir, -snapshotOffset); err != nil {
+		return err
+	}
+	portOffset, err := finalizeNetwork(cmd)
+	if err != nil {
+		return err
+	}
+
+	info, err := readNetworkInfo(generatedConfigDir)
+	if err != nil || info == nil {
+		return err
+	}
+	info.Name, info.PortOffset = testnetName, portOffset
+	return writeNetworkInfo(*info)
+}
+
+func snapshotHomePath(dir, service string) string {
+	return filepath.Join(dir, "services", service, "home")
+}
+
+func readSnapshotManifest(dir string) (snapshotManifest, error) {
+	var manifest snapshotManifest
+	bz, err := os.ReadFile(filepath.Join(dir, snapshotManifestFile))
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read snapshot manifest: %w", err)
+	}
+	if err := json.Unmarshal(bz, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse snapshot manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// pinServiceImages sets the image of the snapshot's services in the compose file to the images they were running
+func pinServiceImages(composePath string, services []snapshotService) error {
+	bz, err := os.ReadFile(composePath)
+	if err != nil {
+		return err
+	}
+	var compose map[string]interface{}
+	if err := yaml.Unmarshal(bz, &compose); err != nil {
+		return fmt.Errorf("failed to parse docker compose file %s: %w", composePath, err)
+	}
+	definitions, _ := compose["services"].(map[string]interface{})
+	for _, service := range services {
+		definition, ok := definitions[service.Name].(map[string]interface{})
+		if !ok || service.Image == "" {
+			continue
+		}
+		definition["image"] = service.Image
+	}
+	bz, err = yaml.Marshal(compose)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(composePath, bz, 0644)
+}
+
+// isWithin returns true if the container path p is dir or is inside it
+func isWithin(dir, p string) bool {
+	dir, p = path.Clean(dir), path.Clean(p)
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// copyDir copies the files of src to dest, preserving their modes. Paths in skip are not copied.
+func copyDir(src, dest string, skip map[string]bool) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if skip[filepath.Clean(p)] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(p, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dest string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
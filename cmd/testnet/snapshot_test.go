@@ -0,0 +1,300 @@
+package testnet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// testIbcService is the service of the ibc chain in testSnapshotCompose
+const testIbcService = "ibcnode"
+
+// testSnapshotCompose is a network with a kava node whose config is bind mounted & an ibc node whose home is
+// mounted
+const testSnapshotCompose = `services:
+  kavanode:
+    image: kava/kava:v0.24.0
+    ports:
+      - "46657:26657"
+    volumes:
+      - "./kava/initstate/.kava/config:/root/.kava/config"
+  ibcnode:
+    image: kava/kava:v0.24.0
+    ports:
+      - "46658:26657"
+    volumes:
+      - "./ibcchain/initstate/.kava:/root/.kava"
+  geth:
+    image: ethereum/client-go
+`
+
+// writeTestFiles writes files, keyed by path relative to dir
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+}
+
+// assertTestFiles asserts the files of dir, keyed by path relative to dir, have the expected contents
+func assertTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, expected := range files {
+		contents, err := os.ReadFile(filepath.Join(dir, name))
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, string(contents), name)
+		}
+	}
+}
+
+// useSnapshotNetwork writes the generated config of testSnapshotCompose & starts it in a fake runtime, with the kava
+// node at height 42 running an upgraded image
+func useSnapshotNetwork(t *testing.T, dir, compose string) *fakeRuntime {
+	t.Helper()
+	writeTestFiles(t, dir, map[string]string{
+		"docker-compose.yaml":                        compose,
+		"kava/initstate/.kava/config/genesis.json":   "kava genesis",
+		"ibcchain/initstate/.kava/config/app.toml":   "ibc app",
+		"ibcchain/initstate/.kava/data/ibc.db":       "ibc state",
+		"kava/initstate/.kava/keyring-test/key.info": "key",
+	})
+
+	fake := useFakeRuntime(t, DockerServiceKavaNode, testIbcService, "geth")
+	require.NoError(t, fake.ComposeUp(context.Background(), ComposeUpOptions{}))
+	fake.Containers[DockerServiceKavaNode].Image = "kava/kava:v0.25.0"
+	fake.Containers[DockerServiceKavaNode].Files = map[string]string{
+		"/root/.kava/config/genesis.json": "kava genesis",
+		"/root/.kava/data/application.db": "kava state",
+	}
+	fake.Containers[testIbcService].Image = "kava/kava:v0.24.0"
+	fake.Containers[testIbcService].Files = map[string]string{
+		"/root/.kava/config/app.toml": "ibc app",
+		"/root/.kava/data/ibc.db":     "ibc state",
+	}
+	height := scriptHeight(fake, nil)
+	height.Store(42)
+	fake.Calls = nil
+	return fake
+}
+
+func TestSnapshotSaveRestore(t *testing.T) {
+	dir := useGeneratedDir(t)
+	fake := useSnapshotNetwork(t, dir, testSnapshotCompose)
+	writeTestFiles(t, dir, map[string]string{networkInfoFile: `{"template":"master","validators":1}`})
+	snapshotsDir := t.TempDir()
+
+	require.NoError(t, executeTestnetCmd(t, "snapshot", "save", "after-setup", "--generated-dir", dir, "--snapshots-dir", snapshotsDir))
+
+	snapshot := filepath.Join(snapshotsDir, "after-setup")
+	manifest, err := readSnapshotManifest(snapshot)
+	require.NoError(t, err)
+	assert.Equal(t, "after-setup", manifest.Name)
+	require.NotNil(t, manifest.Network)
+	assert.Equal(t, "master", manifest.Network.Template)
+	assert.Equal(t, []snapshotService{
+		{Name: testIbcService, Image: "kava/kava:v0.24.0", Home: "/root/.kava", Height: 42},
+		{Name: DockerServiceKavaNode, Image: "kava/kava:v0.25.0", Home: "/root/.kava", Height: 42},
+	}, manifest.Services)
+
+	// the homes are saved from the containers & the host paths mounted into them aren't saved twice
+	assertTestFiles(t, snapshot, map[string]string{
+		"services/kavanode/home/data/application.db":           "kava state",
+		"services/kavanode/home/config/genesis.json":           "kava genesis",
+		"services/ibcnode/home/data/ibc.db":                    "ibc state",
+		"generated/kava/initstate/.kava/keyring-test/key.info": "key",
+	})
+	assert.NoDirExists(t, filepath.Join(snapshot, "generated", "kava", "initstate", ".kava", "config"))
+	assert.NoDirExists(t, filepath.Join(snapshot, "generated", "ibcchain", "initstate", ".kava"))
+	assert.NoDirExists(t, snapshot+".tmp")
+	// the heights of every chain service are queried before the network is stopped
+	var calls []string
+	for _, call := range fake.Calls {
+		if !strings.HasPrefix(call, "exec ") {
+			calls = append(calls, call)
+		}
+	}
+	assert.Equal(t, "stop", calls[0])
+	assert.Equal(t, "start", calls[len(calls)-1])
+
+	// saving again needs --force
+	err = executeTestnetCmd(t, "snapshot", "save", "after-setup", "--generated-dir", dir, "--snapshots-dir", snapshotsDir)
+	require.ErrorContains(t, err, "snapshot after-setup already exists, use --force to replace it")
+
+	// the network moves on & is restored
+	writeTestFiles(t, dir, map[string]string{
+		"kava/initstate/.kava/config/genesis.json": "changed genesis",
+		"ibcchain/initstate/.kava/data/ibc.db":     "changed ibc state",
+	})
+	fake.Calls = nil
+
+	require.NoError(t, executeTestnetCmd(t, "snapshot", "restore", "after-setup", "--generated-dir", dir, "--snapshots-dir", snapshotsDir, "--no-wait"))
+
+	assertTestFiles(t, dir, map[string]string{
+		"kava/initstate/.kava/config/genesis.json":   "kava genesis",
+		"ibcchain/initstate/.kava/data/ibc.db":       "ibc state",
+		"kava/initstate/.kava/keyring-test/key.info": "key",
+	})
+	kavaNode := fake.Containers[DockerServiceKavaNode]
+	assert.Equal(t, "kava state", kavaNode.Files["/root/.kava/data/application.db"])
+	// the ibc node's home is mounted, so it's only restored on the host
+	assert.Empty(t, fake.Containers[testIbcService].Files)
+	assert.Equal(t, []string{
+		"down",
+		"up",
+		"cp " + filepath.Join(snapshot, "services", "kavanode", "home") + " " + kavaNode.ID + ":/root/.kava",
+		"up",
+	}, fake.Calls)
+
+	// the services are pinned to the images they were running
+	compose, err := loadComposeFile(generatedPath("docker-compose.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "kava/kava:v0.25.0", compose.Services[DockerServiceKavaNode].Image)
+	assert.Equal(t, "kava/kava:v0.24.0", compose.Services[testIbcService].Image)
+	assert.Equal(t, "ethereum/client-go", compose.Services["geth"].Image)
+}
+
+func TestSnapshotRestoreRetargetsNetwork(t *testing.T) {
+	testCases := []struct {
+		name string
+		// args select the network restored into
+		args                  []string
+		expectedProject       string
+		expectedKavaPort      string
+		expectedNetworkName   string
+		expectedNetworkOffset int
+	}{
+		{
+			name:                  "default network",
+			expectedProject:       "",
+			expectedKavaPort:      "46657:26657",
+			expectedNetworkOffset: 0,
+		},
+		{
+			name: "named network",
+			args: []string{"--name", "other"},
+			// offset 1000 is taken by devnet, the network the snapshot is of
+			expectedProject:       "other",
+			expectedKavaPort:      "48657:26657",
+			expectedNetworkName:   "other",
+			expectedNetworkOffset: 2000,
+		},
+		{
+			name:                  "--port-offset",
+			args:                  []string{"--name", "other", "--port-offset", "500"},
+			expectedProject:       "other",
+			expectedKavaPort:      "47157:26657",
+			expectedNetworkName:   "other",
+			expectedNetworkOffset: 500,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			useNetworksDir(t)
+			snapshotsDir := t.TempDir()
+			devnetDir := namedGeneratedDir("devnet")
+			devnetCompose := "name: devnet\nx-kvtool-port-offset: 1000\n" + strings.NewReplacer(`"46657:`, `"47657:`, `"46658:`, `"47658:`).Replace(testSnapshotCompose)
+			useSnapshotNetwork(t, devnetDir, devnetCompose)
+			writeTestFiles(t, devnetDir, map[string]string{networkInfoFile: `{"name":"devnet","port_offset":1000,"template":"master"}`})
+			require.NoError(t, executeTestnetCmd(t, "snapshot", "save", "devnet-setup", "--name", "devnet", "--snapshots-dir", snapshotsDir))
+
+			args := append([]string{"snapshot", "restore", "devnet-setup", "--snapshots-dir", snapshotsDir, "--no-wait"}, tc.args...)
+			require.NoError(t, executeTestnetCmd(t, args...))
+
+			compose, err := loadComposeFile(generatedPath("docker-compose.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedProject, compose.Name)
+			assert.Equal(t, []interface{}{tc.expectedKavaPort}, compose.Services[DockerServiceKavaNode].Ports)
+			info, err := readNetworkInfo(generatedConfigDir)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedNetworkName, info.Name)
+			assert.Equal(t, tc.expectedNetworkOffset, info.PortOffset)
+			assert.Equal(t, "master", info.Template)
+
+			// the snapshot's network is left as is
+			devnet, err := loadComposeFile(filepath.Join(devnetDir, "docker-compose.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, "devnet", devnet.Name)
+		})
+	}
+}
+
+func TestPinServiceImages(t *testing.T) {
+	composePath := filepath.Join(t.TempDir(), "docker-compose.yaml")
+	require.NoError(t, os.WriteFile(composePath, []byte(testSnapshotCompose), 0644))
+
+	require.NoError(t, pinServiceImages(composePath, []snapshotService{
+		{Name: DockerServiceKavaNode, Image: "kava/kava:v0.25.0"},
+		// services without an image or that aren't in the compose file are skipped
+		{Name: testIbcService},
+		{Name: "missing", Image: "kava/kava:v0.25.0"},
+	}))
+
+	bz, err := os.ReadFile(composePath)
+	require.NoError(t, err)
+	var compose struct {
+		Services map[string]map[string]interface{}
+	}
+	require.NoError(t, yaml.Unmarshal(bz, &compose))
+	assert.Equal(t, "kava/kava:v0.25.0", compose.Services[DockerServiceKavaNode]["image"])
+	assert.Equal(t, "kava/kava:v0.24.0", compose.Services[testIbcService]["image"])
+	assert.Equal(t, "ethereum/client-go", compose.Services["geth"]["image"])
+	assert.NotContains(t, compose.Services, "missing")
+	// other fields are kept
+	assert.Equal(t, []interface{}{"46657:26657"}, compose.Services[DockerServiceKavaNode]["ports"])
+}
+
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{
+		"a.txt":         "a",
+		"nested/b.txt":  "b",
+		"skipped/c.txt": "c",
+		"skipped.txt":   "skipped",
+	})
+	require.NoError(t, os.Chmod(filepath.Join(src, "a.txt"), 0600))
+	require.NoError(t, os.Symlink("nested/b.txt", filepath.Join(src, "link")))
+	dest := filepath.Join(t.TempDir(), "dest")
+
+	require.NoError(t, copyDir(src, dest, map[string]bool{
+		filepath.Join(src, "skipped"):     true,
+		filepath.Join(src, "skipped.txt"): true,
+	}))
+
+	assertTestFiles(t, dest, map[string]string{"a.txt": "a", "nested/b.txt": "b", "link": "b"})
+	info, err := os.Stat(filepath.Join(dest, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	link, err := os.Readlink(filepath.Join(dest, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "nested/b.txt", link)
+	assert.NoDirExists(t, filepath.Join(dest, "skipped"))
+	assert.NoFileExists(t, filepath.Join(dest, "skipped.txt"))
+}
+
+func TestValidateSnapshotName(t *testing.T) {
+	for _, name := range []string{"after-setup", "v0.25", "snapshot_1"} {
+		assert.NoError(t, validateSnapshotName(name), name)
+	}
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`, "partial.tmp"} {
+		assert.ErrorContains(t, validateSnapshotName(name), "invalid snapshot name", name)
+	}
+}
+
+func TestReadSnapshotManifest(t *testing.T) {
+	dir := t.TempDir()
+	_, err := readSnapshotManifest(dir)
+	require.ErrorContains(t, err, "failed to read snapshot manifest")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, snapshotManifestFile), []byte("{"), 0644))
+	_, err = readSnapshotManifest(dir)
+	require.ErrorContains(t, err, "failed to parse snapshot manifest")
+}
@@ -0,0 +1,184 @@
+package testnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// chainQueryTimeout is how long status waits for a chain node's rpc before reporting it as unreachable
+const chainQueryTimeout = 3 * time.Second
+
+var (
+	statusWatchFlag    bool
+	statusIntervalFlag time.Duration
+	statusOutputFlag   string
+)
+
+// networkStatus is the status of every service of the generated network
+type networkStatus struct {
+	Time     time.Time       `json:"time"`
+	Services []serviceStatus `json:"services"`
+}
+
+// serviceStatus is the status of a docker compose service & its chain, if it runs a chain node
+type serviceStatus struct {
+	Service     string `json:"service"`
+	State       string `json:"state"`
+	ContainerID string `json:"container_id,omitempty"`
+	// Chain is set for chain nodes that responded to the status query
+	Chain *chainStatus `json:"chain,omitempty"`
+	// SecondsSinceLastBlock is the time between the latest block & the status query
+	SecondsSinceLastBlock float64 `json:"seconds_since_last_block,omitempty"`
+	Error                 string  `json:"error,omitempty"`
+}
+
+func StatusCmd() *cobra.Command {
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of every service in the generated network, and the sync status of chain nodes.",
+		Long: `Show the container state of every service in the generated docker-compose.yaml.
+
+For chain nodes (services that publish the tendermint rpc port 26657, eg. kavanode, ibcnode & kava-pruning)
+the latest height, whether the node is catching up, its peer count & the time since the latest block are
+queried from the rpc published on the host.`,
+		Example: `Show the status of the network:
+$ kvtool testnet status
+
+Refresh the status every 2 seconds:
+$ kvtool testnet status --watch --interval 2s
+
+Print the status as json, eg. for use in scripts:
+$ kvtool testnet status --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if statusOutputFlag != "text" && statusOutputFlag != "json" {
+				return fmt.Errorf("--output must be text or json, found %q", statusOutputFlag)
+			}
+			if statusIntervalFlag <= 0 {
+				return fmt.Errorf("--interval must be > 0, found %s", statusIntervalFlag)
+			}
+			ctx := cmd.Context()
+
+			if !statusWatchFlag {
+				status, err := collectNetworkStatus(ctx)
+				if err != nil {
+					return err
+				}
+				return printNetworkStatus(os.Stdout, status, statusOutputFlag, false)
+			}
+
+			ticker := time.NewTicker(statusIntervalFlag)
+			defer ticker.Stop()
+			for {
+				status, err := collectNetworkStatus(ctx)
+				if err != nil {
+					return err
+				}
+				if statusOutputFlag == "text" {
+					// clear the terminal so the table is redrawn in place
+					fmt.Print("\033[H\033[2J")
+				}
+				if err := printNetworkStatus(os.Stdout, status, statusOutputFlag, true); err != nil {
+					return err
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+
+	statusCmd.Flags().BoolVarP(&statusWatchFlag, "watch", "w", false, "refresh the status until interrupted")
+	statusCmd.Flags().DurationVar(&statusIntervalFlag, "interval", 5*time.Second, "refresh interval of --watch")
+	statusCmd.Flags().StringVarP(&statusOutputFlag, "output", "o", "text", "output format, text or json. with --watch, json is printed as one object per line")
+
+	return statusCmd
+}
+
+// collectNetworkStatus queries the state of every service in the generated docker-compose.yaml
+func collectNetworkStatus(ctx context.Context) (networkStatus, error) {
+	compose, err := loadComposeFile(generatedPath("docker-compose.yaml"))
+	if err != nil {
+		return networkStatus{}, err
+	}
+
+	status := networkStatus{Time: time.Now().UTC()}
+	for _, name := range compose.serviceNames() {
+		status.Services = append(status.Services, collectServiceStatus(ctx, name, compose.Services[name], status.Time))
+	}
+	return status, nil
+}
+
+func collectServiceStatus(ctx context.Context, name string, service composeService, now time.Time) serviceStatus {
+	status := serviceStatus{Service: name}
+
+	containerID, err := containerRuntime.ServiceContainerID(ctx, name)
+	if err != nil {
+		status.State = "not created"
+		return status
+	}
+	status.ContainerID = containerID
+
+	status.State, err = containerRuntime.ServiceState(ctx, name)
+	if err != nil {
+		status.State = "unknown"
+		status.Error = err.Error()
+		return status
+	}
+	if status.State != "running" || !service.isChainNode() {
+		return status
+	}
+
+	rpcURL, err := chainRpcURL(service, name)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, chainQueryTimeout)
+	defer cancel()
+	chain, err := queryChainStatus(queryCtx, rpcURL)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Chain = &chain
+	status.SecondsSinceLastBlock = now.Sub(chain.LatestBlockTime).Seconds()
+	return status
+}
+
+// printNetworkStatus writes the status as a table or json. compact json is used for streaming output.
+func printNetworkStatus(w io.Writer, status networkStatus, output string, compact bool) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		if !compact {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(status)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tSTATE\tHEIGHT\tCATCHING UP\tPEERS\tLAST BLOCK\t")
+	for _, service := range status.Services {
+		height, catchingUp, peers, lastBlock := "-", "-", "-", "-"
+		if service.Chain != nil {
+			height = fmt.Sprint(service.Chain.LatestHeight)
+			catchingUp = fmt.Sprint(service.Chain.CatchingUp)
+			peers = fmt.Sprint(service.Chain.Peers)
+			lastBlock = fmt.Sprintf("%s ago", time.Duration(service.SecondsSinceLastBlock*float64(time.Second)).Round(time.Second))
+		} else if service.Error != "" {
+			lastBlock = service.Error
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t\n", service.Service, service.State, height, catchingUp, peers, lastBlock)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,93 @@
+package testnet
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+// TemplatesCmd lists & describes the kava templates
+func TemplatesCmd() *cobra.Command {
+	templatesCmd := &cobra.Command{
+		Use:   "templates",
+		Short: "List & describe the kava templates that can be used with --kava.configTemplate",
+		Long: fmt.Sprintf(`Each kava template has a %s manifest describing the kava versions it supports, its default image, the db backends of its images, whether KAVA_TAG overrides the image tag, the chain id
+of its genesis & the optional kvtool features it supports:
+
+  validators - running more than one validator with --validators
+  pruning    - running a pruning node alongside the validator with --pruning
+  genesis    - replacing or checking the genesis with --genesis or --validate-genesis
+
+bootstrap & gen-config reject combinations a template doesn't support before anything is generated.
+
+Templates are searched for in kava/<name> of each template directory, in order:
+
+  1. --templates-path
+  2. %s, separated like PATH
+  3. templatesPath of %s in the kvtool home (%s, or ~/.kvtool)
+  4. the built-in templates
+
+A user template hides the built-in template of the same name, so built-in templates can be customized by copying
+them to a user template directory.`, generate.TemplateManifestFile, generate.TemplatesPathEnv, generate.KvtoolConfigFile, generate.KvtoolHomeEnv),
+		Args: cobra.NoArgs,
+	}
+
+	templatesCmd.AddCommand(&cobra.Command{
+		Use:          "list",
+		Short:        "List the kava templates",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			templates, err := generate.KavaTemplates()
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tKIND\tVERSIONS\tDEFAULT IMAGE\tDB BACKENDS\tFEATURES\tDIR\t")
+			for _, t := range templates {
+				image := "-"
+				if t.Kind == generate.TemplateKindNode {
+					image = fmt.Sprintf("%s:%s", t.Image, t.DefaultTag)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+					t.Name, t.Kind, listOrDash(t.Versions), image, listOrDash(t.DbBackends), listOrDash(t.Features), t.Dir)
+			}
+			return tw.Flush()
+		},
+	})
+
+	templatesCmd.AddCommand(&cobra.Command{
+		Use:          "show template",
+		Short:        "Show the manifest of a kava template",
+		Example:      "kvtool testnet templates show master",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			template, err := generate.LoadKavaTemplate(args[0])
+			if err != nil {
+				return err
+			}
+			bz, err := yaml.Marshal(template)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("name: %s\ndir: %s\n%s", template.Name, template.Dir, bz)
+			return nil
+		},
+	})
+
+	return templatesCmd
+}
+
+func listOrDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
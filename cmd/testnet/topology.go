@@ -0,0 +1,246 @@
+package testnet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+// topologyVersion is the version of the topology file format understood by this build of kvtool.
+// It must be bumped whenever a breaking change is made to the Topology struct.
+const topologyVersion = 1
+
+// supportedDbBackends are the db_backend values accepted by tendermint's config.toml
+var supportedDbBackends = []string{"goleveldb", "cleveldb", "rocksdb", "boltdb", "badgerdb", "pebbledb", "memdb"}
+
+// bootstrapTopologyFlags are the bootstrap flags that are also described by a topology file.
+// They can't be combined with --topology because it would be ambiguous which value wins.
+var bootstrapTopologyFlags = []string{
+	"kava.configTemplate", "kava.db", "kava.config", "kava.app", "validators", "pruning", "ibc", "relayer", "geth",
+	"upgrade-name", "upgrade-height", "upgrade-base-image-tag", "upgrade-plan", "upgrade-via", "upgrade-assertions",
+}
+
+// Topology is a declarative description of a network run by `testnet bootstrap`.
+// It is intended to be checked in to version control so networks can be reproduced exactly.
+type Topology struct {
+	// Version of the topology file format. Must match topologyVersion.
+	Version int              `yaml:"version"`
+	Kava    KavaTopology     `yaml:"kava"`
+	Pruning bool             `yaml:"pruning"`
+	Geth    bool             `yaml:"geth"`
+	Ibc     IbcTopology      `yaml:"ibc"`
+	Upgrade *UpgradeTopology `yaml:"upgrade,omitempty"`
+}
+
+// KavaTopology configures the primary kava validator
+type KavaTopology struct {
+	// Template is the directory name of the kava template. Defaults to "master".
+	Template string `yaml:"template"`
+	// ImageTag overrides the kava image tag for templates that honour KAVA_TAG.
+	ImageTag string `yaml:"imageTag"`
+	// Db is the db_backend of the node. Defaults to "goleveldb".
+	Db string `yaml:"db"`
+	// Validators is the number of validators to run. Defaults to 1.
+	Validators int `yaml:"validators"`
+	// Config & App are settings of every kava node's config.toml & app.toml, eg. consensus.timeout_commit: 500ms
+	Config map[string]string `yaml:"config"`
+	App    map[string]string `yaml:"app"`
+}
+
+// IbcTopology configures the counterparty chains & relayer. Without chains, enabled runs the ibcchain template &
+// a transfer path to kava.
+type IbcTopology struct {
+	Enabled bool `yaml:"enabled"`
+	// Relayer is rly or hermes. Defaults to rly.
+	Relayer              string `yaml:"relayer"`
+	generate.IbcTopology `yaml:",inline"`
+}
+
+// UpgradeTopology configures automated chain upgrades.
+// Either a single upgrade is described by Name & Height, or a sequence of upgrades by Steps.
+type UpgradeTopology struct {
+	Name         string        `yaml:"name"`
+	Height       int64         `yaml:"height"`
+	BaseImageTag string        `yaml:"baseImageTag"`
+	Via          string        `yaml:"via"`
+	Steps        []UpgradeStep `yaml:"steps"`
+	// Assertions is the path of an upgrade assertions file, relative to the topology file
+	Assertions string `yaml:"assertions"`
+}
+
+// plan returns the upgrade plan described by the topology
+func (u UpgradeTopology) plan() UpgradePlan {
+	if len(u.Steps) > 0 {
+		return UpgradePlan{BaseImageTag: u.BaseImageTag, Via: u.Via, Steps: u.Steps}
+	}
+	return UpgradePlan{
+		BaseImageTag: u.BaseImageTag,
+		Via:          u.Via,
+		Steps:        []UpgradeStep{{Name: u.Name, Height: u.Height}},
+	}
+}
+
+// LoadTopology reads, decodes & validates a topology file.
+// Unknown keys are rejected so that typos don't silently fall back to defaults.
+func LoadTopology(path string) (Topology, error) {
+	var topology Topology
+
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return topology, fmt.Errorf("failed to read topology file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(bz))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&topology); err != nil && !errors.Is(err, io.EOF) {
+		return topology, fmt.Errorf("failed to parse topology file %s: %w", path, err)
+	}
+
+	// genesis files are relative to the topology file
+	for i, chain := range topology.Ibc.Chains {
+		if chain.Genesis != "" && !filepath.IsAbs(chain.Genesis) {
+			topology.Ibc.Chains[i].Genesis = filepath.Join(filepath.Dir(path), chain.Genesis)
+		}
+	}
+
+	topology.setDefaults()
+	if err := topology.Validate(); err != nil {
+		return topology, fmt.Errorf("invalid topology file %s: %w", path, err)
+	}
+
+	return topology, nil
+}
+
+func (t *Topology) setDefaults() {
+	if t.Kava.Template == "" {
+		t.Kava.Template = "master"
+	}
+	if t.Kava.Db == "" {
+		t.Kava.Db = "goleveldb"
+	}
+	if t.Kava.Validators == 0 {
+		t.Kava.Validators = 1
+	}
+	if t.Ibc.Relayer == "" {
+		t.Ibc.Relayer = relayerRly
+	}
+}
+
+// Validate checks the topology against the schema & the templates available to kvtool.
+// It collects all problems found so they can be fixed in one go.
+func (t Topology) Validate() error {
+	var errs []error
+
+	if t.Version != topologyVersion {
+		errs = append(errs, fmt.Errorf("version: expected %d, found %d", topologyVersion, t.Version))
+	}
+
+	if template, err := generate.LoadKavaTemplate(t.Kava.Template); err != nil {
+		errs = append(errs, fmt.Errorf("kava.template: %w", err))
+	} else if err := template.CheckUsage(generate.TemplateUsage{
+		DbBackend:  t.Kava.Db,
+		Validators: t.Kava.Validators,
+		Pruning:    t.Pruning,
+		KavaTag:    t.Kava.ImageTag != "" || t.Upgrade != nil,
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("kava.template: %w", err))
+	}
+
+	if !stringSlice(supportedDbBackends).contains(t.Kava.Db) {
+		errs = append(errs, fmt.Errorf("kava.db: must be one of %v, found %q", supportedDbBackends, t.Kava.Db))
+	}
+
+	if t.Kava.Validators < 1 {
+		errs = append(errs, fmt.Errorf("kava.validators: at least one validator is required, found %d", t.Kava.Validators))
+	}
+
+	if !stringSlice(supportedRelayers).contains(t.Ibc.Relayer) {
+		errs = append(errs, fmt.Errorf("ibc.relayer: must be one of %v, found %q", supportedRelayers, t.Ibc.Relayer))
+	}
+	if t.Ibc.Enabled {
+		if _, err := t.Ibc.Resolve(); err != nil {
+			errs = append(errs, err)
+		}
+	} else if len(t.Ibc.Chains) > 0 || len(t.Ibc.Paths) > 0 {
+		errs = append(errs, fmt.Errorf("ibc: chains & paths require enabled: true"))
+	}
+
+	if t.Upgrade != nil {
+		if len(t.Upgrade.Steps) > 0 && (t.Upgrade.Name != "" || t.Upgrade.Height != 0) {
+			errs = append(errs, fmt.Errorf("upgrade: name & height cannot be combined with steps, add the upgrade to steps instead"))
+		} else {
+			for _, err := range t.Upgrade.plan().problems() {
+				errs = append(errs, fmt.Errorf("upgrade: %w", err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// applyTopology loads a topology file into the package level bootstrap flag values.
+// It errors if any flag described by the topology was also set on the command line.
+func applyTopology(cmd *cobra.Command, path string) error {
+	for _, name := range bootstrapTopologyFlags {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s cannot be used with --topology, set it in the topology file instead", name)
+		}
+	}
+
+	topology, err := LoadTopology(path)
+	if err != nil {
+		return err
+	}
+
+	kavaConfigTemplate = topology.Kava.Template
+	kavaDbBackend = topology.Kava.Db
+	numValidators = topology.Kava.Validators
+	kavaConfigOverrides = overrideFlagValues(topology.Kava.Config)
+	kavaAppOverrides = overrideFlagValues(topology.Kava.App)
+	includePruningFlag = topology.Pruning
+	gethFlag = topology.Geth
+	ibcFlag = topology.Ibc.Enabled
+	relayerFlag = topology.Ibc.Relayer
+	if ibcFlag {
+		if ibcTopology, err = topology.Ibc.Resolve(); err != nil {
+			return err
+		}
+	}
+
+	if topology.Upgrade != nil {
+		plan := topology.Upgrade.plan()
+		chainUpgradePlan = &plan
+		if topology.Upgrade.Assertions != "" {
+			chainUpgradeAssertions = topology.Upgrade.Assertions
+			if !filepath.IsAbs(chainUpgradeAssertions) {
+				chainUpgradeAssertions = filepath.Join(filepath.Dir(path), chainUpgradeAssertions)
+			}
+		}
+	}
+
+	// the image tag is consumed by the templates' docker-compose.yaml files
+	if topology.Kava.ImageTag != "" {
+		if err := os.Setenv(kavaTagEnv, topology.Kava.ImageTag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// overrideFlagValues returns the key=value flag values of the settings of a topology file
+func overrideFlagValues(settings map[string]string) []string {
+	var values []string
+	for _, override := range generate.ConfigOverridesFromMap(settings) {
+		values = append(values, fmt.Sprintf("%s=%s", override.Key, override.Value))
+	}
+	return values
+}
@@ -0,0 +1,161 @@
+package testnet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeTopology writes a topology file to a temporary dir & returns its path
+func writeTopology(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "topology.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func TestLoadTopology(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		expected Topology
+		errs     []string
+	}{
+		{
+			name:     "defaults",
+			contents: "version: 1\n",
+			expected: Topology{
+				Version: 1,
+				Kava:    KavaTopology{Template: "master", Db: "goleveldb", Validators: 1},
+				Ibc:     IbcTopology{Relayer: relayerRly},
+			},
+		},
+		{
+			name: "explicit values",
+			contents: `version: 1
+kava:
+  template: master
+  db: rocksdb
+  validators: 3
+  config:
+    consensus.timeout_commit: 500ms
+geth: true
+ibc:
+  enabled: true
+  relayer: hermes
+upgrade:
+  name: v0.26.0
+  height: 10
+  baseImageTag: v0.25.0
+`,
+			expected: Topology{
+				Version: 1,
+				Kava: KavaTopology{
+					Template:   "master",
+					Db:         "rocksdb",
+					Validators: 3,
+					Config:     map[string]string{"consensus.timeout_commit": "500ms"},
+				},
+				Geth:    true,
+				Ibc:     IbcTopology{Enabled: true, Relayer: relayerHermes},
+				Upgrade: &UpgradeTopology{Name: "v0.26.0", Height: 10, BaseImageTag: "v0.25.0"},
+			},
+		},
+		{
+			name:     "unknown key",
+			contents: "version: 1\nkava:\n  tempalte: master\n",
+			errs:     []string{"field tempalte not found"},
+		},
+		{
+			name:     "version mismatch",
+			contents: "version: 2\n",
+			errs:     []string{"version: expected 1, found 2"},
+		},
+		{
+			name:     "missing version",
+			contents: "kava:\n  template: master\n",
+			errs:     []string{"version: expected 1, found 0"},
+		},
+		{
+			name:     "unknown template",
+			contents: "version: 1\nkava:\n  template: v0.1\n",
+			errs:     []string{"kava.template:"},
+		},
+		{
+			name:     "upgrade height below minimum",
+			contents: "version: 1\nupgrade:\n  name: v0.26.0\n  height: 9\n  baseImageTag: v0.25.0\n",
+			errs:     []string{"upgrade: steps[0].height: must be >= 10, found 9"},
+		},
+		{
+			name:     "upgrade steps below minimum",
+			contents: "version: 1\nupgrade:\n  baseImageTag: v0.25.0\n  steps:\n    - name: v0.26.0\n      height: 9\n",
+			errs:     []string{"upgrade: steps[0].height: must be >= 10, found 9"},
+		},
+		{
+			name:     "upgrade name & steps",
+			contents: "version: 1\nupgrade:\n  name: v0.26.0\n  baseImageTag: v0.25.0\n  steps:\n    - name: v0.26.0\n      height: 20\n",
+			errs:     []string{"upgrade: name & height cannot be combined with steps"},
+		},
+		{
+			name:     "ibc chains without enabled",
+			contents: "version: 1\nibc:\n  chains:\n    - name: osmosis\n",
+			errs:     []string{"ibc: chains & paths require enabled: true"},
+		},
+		{
+			name: "combined errors",
+			contents: `version: 2
+kava:
+  db: sqlite
+ibc:
+  relayer: hermez
+upgrade:
+  height: 5
+  via: multisig
+`,
+			errs: []string{
+				"version: expected 1, found 2",
+				`kava.db: must be one of`,
+				`ibc.relayer: must be one of [rly hermes], found "hermez"`,
+				"upgrade: baseImageTag: required",
+				"upgrade: steps[0].name: required",
+				"upgrade: steps[0].height: must be >= 10, found 5",
+				`upgrade: via: must be committee or gov, found "multisig"`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			topology, err := LoadTopology(writeTopology(t, tc.contents))
+			if len(tc.errs) > 0 {
+				require.Error(t, err)
+				for _, msg := range tc.errs {
+					require.ErrorContains(t, err, msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, topology)
+		})
+	}
+}
+
+func TestLoadTopologyMissingFile(t *testing.T) {
+	_, err := LoadTopology(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorContains(t, err, "failed to read topology file")
+}
+
+func TestUpgradeTopologyPlan(t *testing.T) {
+	single := UpgradeTopology{Name: "v0.26.0", Height: 20, BaseImageTag: "v0.25.0", Via: upgradeViaGov}
+	require.Equal(t, UpgradePlan{
+		BaseImageTag: "v0.25.0",
+		Via:          upgradeViaGov,
+		Steps:        []UpgradeStep{{Name: "v0.26.0", Height: 20}},
+	}, single.plan())
+
+	steps := []UpgradeStep{{Name: "v0.26.0", Height: 20}, {Name: "v0.27.0", Height: 40}}
+	multi := UpgradeTopology{BaseImageTag: "v0.25.0", Steps: steps}
+	require.Equal(t, UpgradePlan{BaseImageTag: "v0.25.0", Steps: steps}, multi.plan())
+}
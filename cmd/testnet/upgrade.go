@@ -0,0 +1,524 @@
+package testnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kava-labs/kvtool/config/generate"
+)
+
+// godCommitteeID is the id of the committee that can pass software upgrades. it is 3 in the kava templates
+// & is set to the injected committee when bootstrapping from --genesis with --genesis-god-committee.
+var godCommitteeID uint64 = 3
+
+const (
+	// upgradeViaCommittee proposes upgrades to the God Committee, which passes as soon as the committee member votes
+	upgradeViaCommittee = "committee"
+	// upgradeViaGov proposes upgrades with x/gov, the way mainnet upgrades are run. All validators vote on the proposal.
+	upgradeViaGov = "gov"
+)
+
+// minUpgradeHeight is the lowest upgrade height. It isn't enough for gov upgrades, whose proposals take the voting
+// period to pass, which isn't known until the chain is running.
+const minUpgradeHeight = 10
+
+// upgradeProposalTimeout is the maximum time to wait for a gov proposal to pass after its voting period ends
+const upgradeProposalTimeout = 30 * time.Second
+
+// UpgradePlan is a sequence of chain upgrades run by `testnet bootstrap`.
+// The chain starts with BaseImageTag & is upgraded by each step in order.
+type UpgradePlan struct {
+	BaseImageTag string `yaml:"baseImageTag"`
+	// Via is how upgrades are proposed, "committee" or "gov". Defaults to --upgrade-via.
+	Via   string        `yaml:"via"`
+	Steps []UpgradeStep `yaml:"steps"`
+}
+
+// UpgradeStep is a single software upgrade
+type UpgradeStep struct {
+	// Name of the upgrade handler registered in the upgraded binary
+	Name   string `yaml:"name"`
+	Height int64  `yaml:"height"`
+	// ImageTag is the kava image tag the chain is restarted with once halted. It's required by plans of several
+	// steps, as each upgraded binary only registers its own upgrade handler. A single step may leave it empty to
+	// use the image tag of the compose file, which defaults to KAVA_TAG for supported templates.
+	ImageTag string `yaml:"imageTag"`
+}
+
+// LoadUpgradePlan reads & validates an upgrade plan yaml file
+func LoadUpgradePlan(path string) (UpgradePlan, error) {
+	var plan UpgradePlan
+
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return plan, fmt.Errorf("failed to read upgrade plan: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(bz))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&plan); err != nil && !errors.Is(err, io.EOF) {
+		return plan, fmt.Errorf("failed to parse upgrade plan %s: %w", path, err)
+	}
+	if err := plan.Validate(); err != nil {
+		return plan, fmt.Errorf("invalid upgrade plan %s: %w", path, err)
+	}
+	return plan, nil
+}
+
+// Validate checks the plan has a base image & at least one step with strictly increasing heights.
+// Every step of a plan with several steps must have an image tag.
+func (p UpgradePlan) Validate() error {
+	return errors.Join(p.problems()...)
+}
+
+// problems returns every problem of the plan, so they can be reported together with other problems, eg. of a topology
+func (p UpgradePlan) problems() []error {
+	var errs []error
+
+	if p.BaseImageTag == "" {
+		errs = append(errs, fmt.Errorf("baseImageTag: required"))
+	}
+	if len(p.Steps) == 0 {
+		errs = append(errs, fmt.Errorf("steps: at least one upgrade is required"))
+	}
+	if p.Via != "" && p.Via != upgradeViaCommittee && p.Via != upgradeViaGov {
+		errs = append(errs, fmt.Errorf("via: must be %s or %s, found %q", upgradeViaCommittee, upgradeViaGov, p.Via))
+	}
+
+	var previousHeight int64
+	for i, step := range p.Steps {
+		if step.Name == "" {
+			errs = append(errs, fmt.Errorf("steps[%d].name: required", i))
+		}
+		if step.Height < minUpgradeHeight {
+			errs = append(errs, fmt.Errorf(
+				"steps[%d].height: must be >= %d, found %d. upgrades via %s also need room for the voting period",
+				i, minUpgradeHeight, step.Height, upgradeViaGov,
+			))
+		}
+		if len(p.Steps) > 1 && step.ImageTag == "" {
+			errs = append(errs, fmt.Errorf("steps[%d].imageTag: required by plans of several upgrades, so the chain restarts with the binary of each upgrade", i))
+		}
+		if i > 0 && step.Height <= previousHeight {
+			errs = append(errs, fmt.Errorf("steps[%d].height: must be greater than the previous upgrade height %d, found %d", i, previousHeight, step.Height))
+		}
+		previousHeight = step.Height
+	}
+
+	return errs
+}
+
+// bootstrapUpgradePlan returns the upgrade plan configured by the bootstrap flags, or nil if no upgrade is configured
+// Plans that don't specify how upgrades are proposed use --upgrade-via.
+func bootstrapUpgradePlan() (*UpgradePlan, error) {
+	var plan *UpgradePlan
+	switch {
+	case chainUpgradePlan != nil:
+		plan = chainUpgradePlan
+	case chainUpgradePlanFile != "":
+		p, err := LoadUpgradePlan(chainUpgradePlanFile)
+		if err != nil {
+			return nil, err
+		}
+		plan = &p
+	case chainUpgradeName != "":
+		plan = &UpgradePlan{
+			BaseImageTag: chainUpgradeBaseImageTag,
+			Steps:        []UpgradeStep{{Name: chainUpgradeName, Height: chainUpgradeHeight}},
+		}
+	default:
+		return nil, nil
+	}
+
+	if plan.Via == "" {
+		plan.Via = chainUpgradeVia
+	}
+	return plan, plan.Validate()
+}
+
+// runUpgradePlan walks the chain through each upgrade of the plan, verifying the chain halts & resumes at every step
+func runUpgradePlan(ctx context.Context, plan UpgradePlan, assertions *assertionRunner) error {
+	fmt.Printf("configured for %d automated chain upgrade(s) via %s, starting tag: %s\n", len(plan.Steps), plan.Via, plan.BaseImageTag)
+	for i, step := range plan.Steps {
+		fmt.Printf(
+			"running upgrade %d/%d\n\tupgrade name: %s\n\tupgrade height: %d\n\timage tag: %s\n",
+			i+1, len(plan.Steps), step.Name, step.Height, step.ImageTag,
+		)
+		if err := runChainUpgrade(ctx, plan.Via, step, assertions); err != nil {
+			// report the assertions checked so far, the upgrade failure is the error returned
+			_ = assertions.report(os.Stdout)
+			return fmt.Errorf("upgrade %s failed: %w", step.Name, err)
+		}
+	}
+	return assertions.report(os.Stdout)
+}
+
+// runChainUpgrade proposes a software upgrade & gets it passed, then waits for the chain to halt
+// at the upgrade height & restarts the validators with the upgraded image.
+// Upgrade assertions are checked once the proposal passes & once the upgraded chain produces a block.
+func runChainUpgrade(ctx context.Context, via string, step UpgradeStep, assertions *assertionRunner) error {
+	var err error
+	switch via {
+	case upgradeViaGov:
+		err = proposeUpgradeViaGov(ctx, step)
+	default:
+		err = proposeUpgradeViaCommittee(ctx, step)
+	}
+	if err != nil {
+		return err
+	}
+	assertions.check(ctx, step.Name, assertBefore)
+	if err := haltAndRestart(ctx, step); err != nil {
+		return err
+	}
+	assertions.check(ctx, step.Name, assertAfter)
+	return nil
+}
+
+// proposeUpgradeViaCommittee submits a software upgrade proposal to the God Committee & votes on it with the committee member
+func proposeUpgradeViaCommittee(ctx context.Context, step UpgradeStep) error {
+	// write upgrade proposal to json file
+	upgradeJson, err := writeUpgradeProposal(step)
+	if err != nil {
+		return err
+	}
+
+	// submit upgrade proposal via God Committee
+	fmt.Println("submitting upgrade proposal")
+	cmd := fmt.Sprintf("tx committee submit-proposal %d %s --gas auto --gas-adjustment 1.2 --gas-prices 0.05ukava --from committee -y --output json",
+		godCommitteeID, upgradeJson,
+	)
+	proposalID, err := submitProposal(ctx, strings.Split(cmd, " "), "proposal_submit")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("submitted upgrade proposal %d\n", proposalID)
+
+	// Cosmos SDK no longer has broadcast mode block and the use of "sync" mode
+	// only waits for a CheckTx response. The proposal id is only known once the
+	// submit tx is committed, so the vote can't race the proposal. Retry anyway
+	// in case the committee account's sequence is not yet updated in CheckTx state.
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = 2 * time.Second
+	b.MaxElapsedTime = 20 * time.Second
+	err = backoff.Retry(func() error {
+		// vote on the committee proposal
+		cmd = fmt.Sprintf("tx committee vote %d yes --from committee --gas auto --gas-adjustment 1.8 --gas-prices 0.05ukava -y", proposalID)
+		return runKavaCli(ctx, strings.Split(cmd, " ")...)
+	}, b)
+	if err != nil {
+		return fmt.Errorf("error voting on committee proposal: %w", err)
+	}
+	return nil
+}
+
+// proposeUpgradeViaGov submits an x/gov software upgrade proposal with the minimum deposit from the validator,
+// has every validator vote yes & waits for the proposal to pass.
+func proposeUpgradeViaGov(ctx context.Context, step UpgradeStep) error {
+	params, err := queryGovParams(ctx)
+	if err != nil {
+		return err
+	}
+
+	// sdk v0.46 moved the legacy proposal types to submit-legacy-proposal
+	submitCmd := "submit-proposal"
+	if help, err := kavaCliOutput(ctx, "tx", "gov", "--help"); err == nil && bytes.Contains(help, []byte("submit-legacy-proposal")) {
+		submitCmd = "submit-legacy-proposal"
+	}
+
+	fmt.Println("submitting gov upgrade proposal")
+	proposalID, err := submitProposal(ctx, []string{
+		"tx", "gov", submitCmd, "software-upgrade", step.Name,
+		"--title", "Automated Chain Upgrade",
+		"--description", "An auto-magical chain upgrade performed by kvtool.",
+		"--upgrade-height", strconv.FormatInt(step.Height, 10),
+		"--deposit", params.minDeposit,
+		"--from", "validator",
+		"--gas", "auto", "--gas-adjustment", "1.5", "--gas-prices", "0.05ukava", "-y", "--output", "json",
+	}, "submit_proposal")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("submitted gov upgrade proposal %d\n", proposalID)
+
+	// every validator votes so the proposal passes regardless of how voting power is distributed
+	for i, voter := range generate.KavaValidatorKeyNames(numValidators) {
+		b := backoff.NewExponentialBackOff()
+		b.MaxInterval = 2 * time.Second
+		b.MaxElapsedTime = 20 * time.Second
+		err := backoff.Retry(func() error {
+			cmd := fmt.Sprintf("tx gov vote %d yes --from %s --gas auto --gas-adjustment 1.5 --gas-prices 0.05ukava -y", proposalID, voter)
+			return runKavaCli(ctx, strings.Split(cmd, " ")...)
+		}, b)
+		if err != nil {
+			return fmt.Errorf("error voting on gov proposal with validator %d: %w", i+1, err)
+		}
+	}
+
+	return waitForProposalPass(ctx, proposalID, params.votingPeriod+upgradeProposalTimeout)
+}
+
+// govParams are the gov params needed to run a proposal
+type govParams struct {
+	// minDeposit is formatted as coins, eg. 10000000ukava
+	minDeposit   string
+	votingPeriod time.Duration
+}
+
+// queryGovParams queries the gov params. Both the per-type params of sdk v0.46 & the combined params of later versions are supported.
+func queryGovParams(ctx context.Context) (govParams, error) {
+	out, err := kavaCliOutput(ctx, "q", "gov", "params", "--output", "json")
+	if err != nil {
+		return govParams{}, fmt.Errorf("failed to query gov params: %w", err)
+	}
+
+	type coin struct {
+		Denom  string `json:"denom"`
+		Amount string `json:"amount"`
+	}
+	type params struct {
+		MinDeposit   []coin `json:"min_deposit"`
+		VotingPeriod string `json:"voting_period"`
+	}
+	var res struct {
+		Params        *params `json:"params"`
+		DepositParams *params `json:"deposit_params"`
+		VotingParams  *params `json:"voting_params"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return govParams{}, fmt.Errorf("failed to parse gov params: %w", err)
+	}
+
+	var minDeposit []coin
+	var votingPeriod string
+	for _, p := range []*params{res.DepositParams, res.VotingParams, res.Params} {
+		if p == nil {
+			continue
+		}
+		if len(p.MinDeposit) > 0 {
+			minDeposit = p.MinDeposit
+		}
+		if p.VotingPeriod != "" {
+			votingPeriod = p.VotingPeriod
+		}
+	}
+
+	var deposit []string
+	for _, c := range minDeposit {
+		deposit = append(deposit, c.Amount+c.Denom)
+	}
+	// the cli prints durations as go durations (30s) or nanoseconds (30000000000) depending on the sdk version
+	period, err := time.ParseDuration(votingPeriod)
+	if err != nil {
+		ns, err2 := strconv.ParseInt(votingPeriod, 10, 64)
+		if err2 != nil {
+			return govParams{}, fmt.Errorf("invalid voting period %q: %w", votingPeriod, err)
+		}
+		period = time.Duration(ns)
+	}
+
+	return govParams{minDeposit: strings.Join(deposit, ","), votingPeriod: period}, nil
+}
+
+// waitForProposalPass polls a gov proposal until it passes. It fails as soon as the proposal is rejected or fails.
+func waitForProposalPass(ctx context.Context, proposalID uint64, timeout time.Duration) error {
+	fmt.Printf("waiting up to %s for gov proposal %d to pass\n", timeout, proposalID)
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = 5 * time.Second
+	b.MaxElapsedTime = timeout
+	return backoff.Retry(func() error {
+		out, err := kavaCliOutput(ctx, "q", "gov", "proposal", strconv.FormatUint(proposalID, 10), "--output", "json")
+		if err != nil {
+			return err
+		}
+		var proposal struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(out, &proposal); err != nil {
+			return backoff.Permanent(fmt.Errorf("failed to parse proposal: %w", err))
+		}
+		switch proposal.Status {
+		case "PROPOSAL_STATUS_PASSED":
+			fmt.Printf("gov proposal %d passed\n", proposalID)
+			return nil
+		case "PROPOSAL_STATUS_REJECTED", "PROPOSAL_STATUS_FAILED":
+			return backoff.Permanent(fmt.Errorf("gov proposal %d did not pass: %s", proposalID, proposal.Status))
+		default:
+			return fmt.Errorf("gov proposal %d is %s", proposalID, proposal.Status)
+		}
+	}, b)
+}
+
+// haltAndRestart waits for the chain to halt at the upgrade height, then restarts all validators with the step's image
+func haltAndRestart(ctx context.Context, step UpgradeStep) error {
+	// wait for chain halt at upgrade height
+	if err := waitForBlock(step.Height, time.Duration(step.Height)*4*time.Second, DockerServiceKavaNode); err != nil {
+		return err
+	}
+
+	fmt.Printf("chain has reached upgrade height @ %d, checking if halted\n", step.Height)
+
+	// Check if chain actually halted, if proposal or vote failed then it will
+	// continue to produce blocks and produce an invalid state after continuing
+	// with the new binary.
+	haltCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	if err := waitForUpgradeHalt(haltCtx, step.Name, step.Height, DockerServiceKavaNode); err != nil {
+		return fmt.Errorf("chain halt failed: %w", err)
+	}
+
+	fmt.Printf("chain has halted! restarting chain with upgraded image\n")
+
+	// only a single step plan may have no image tag, which runs with the desired image because KAVA_TAG will be
+	// correctly set, or if that is unset, the docker-compose files supporting upgrades default to the desired template version.
+	// all validators are restarted so they all run the upgraded binary.
+	restartOpts := ComposeUpOptions{
+		Services:      generate.KavaValidatorServiceNames(numValidators),
+		Detach:        true,
+		ForceRecreate: true,
+	}
+	if step.ImageTag != "" {
+		restartOpts.Env = []string{fmt.Sprintf("%s=%s", kavaTagEnv, step.ImageTag)}
+	}
+	if err := containerRuntime.ComposeUp(ctx, restartOpts); err != nil {
+		return err
+	}
+
+	// Ensure upgraded chain produces new blocks, at least 1.
+	// Retry since it may return an error while the container is being re-created
+	return mustReachHeightOrLog(step.Height+1, 10*time.Second, DockerServiceKavaNode)
+}
+
+// writeUpgradeProposal writes a proposal json to a file in the kavanode container and returns the path
+func writeUpgradeProposal(step UpgradeStep) (string, error) {
+	content := fmt.Sprintf(`{
+		"@type": "/cosmos.upgrade.v1beta1.SoftwareUpgradeProposal",
+		"title": "Automated Chain Upgrade",
+		"description": "An auto-magical chain upgrade performed by kvtool.",
+		"plan": { "name": "%s", "height": "%d" }
+	}`, step.Name, step.Height)
+	// write the file to a location inside the container
+	return "/root/.kava/config/upgrade-proposal.json", os.WriteFile(
+		generatedPath("kava", "initstate", ".kava", "config", "upgrade-proposal.json"),
+		[]byte(content),
+		0644,
+	)
+}
+
+// submitProposal runs a `kava tx` command that submits a proposal & returns the proposal id
+// found in the eventType event of the committed tx. The command must include `--output json`.
+func submitProposal(ctx context.Context, args []string, eventType string) (uint64, error) {
+	out, err := kavaCliOutput(ctx, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to submit proposal: %w", err)
+	}
+	var res struct {
+		TxHash string `json:"txhash"`
+		Code   uint32 `json:"code"`
+		RawLog string `json:"raw_log"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return 0, fmt.Errorf("failed to parse submit proposal response: %w", err)
+	}
+	if res.Code != 0 {
+		return 0, fmt.Errorf("submit proposal tx failed with code %d: %s", res.Code, res.RawLog)
+	}
+
+	var proposalID uint64
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = 2 * time.Second
+	b.MaxElapsedTime = 30 * time.Second
+	err = backoff.Retry(func() error {
+		// the tx is only queryable once it's committed
+		out, err := kavaCliOutput(ctx, "q", "tx", res.TxHash, "--output", "json")
+		if err != nil {
+			return fmt.Errorf("tx %s not found: %w", res.TxHash, err)
+		}
+		id, err := txEventAttribute(out, eventType, "proposal_id")
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		proposalID, err = strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return backoff.Permanent(fmt.Errorf("invalid proposal id %q: %w", id, err))
+		}
+		return nil
+	}, b)
+	return proposalID, err
+}
+
+// txEventAttribute returns the value of an event attribute from a json encoded tx response.
+// Events of the tx's logs are searched first, then the tx's events, whose attributes may be base64 encoded.
+func txEventAttribute(txJSON []byte, eventType, key string) (string, error) {
+	type event struct {
+		Type       string `json:"type"`
+		Attributes []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"attributes"`
+	}
+	var tx struct {
+		Code   uint32 `json:"code"`
+		RawLog string `json:"raw_log"`
+		Logs   []struct {
+			Events []event `json:"events"`
+		} `json:"logs"`
+		Events []event `json:"events"`
+	}
+	if err := json.Unmarshal(txJSON, &tx); err != nil {
+		return "", fmt.Errorf("failed to parse tx: %w", err)
+	}
+	if tx.Code != 0 {
+		return "", fmt.Errorf("tx failed with code %d: %s", tx.Code, tx.RawLog)
+	}
+
+	var events []event
+	for _, log := range tx.Logs {
+		events = append(events, log.Events...)
+	}
+	events = append(events, tx.Events...)
+	for _, e := range events {
+		if e.Type != eventType {
+			continue
+		}
+		for _, attr := range e.Attributes {
+			if attr.Key == key {
+				return attr.Value, nil
+			}
+			// tendermint v0.34 encodes event attributes of the tx result as base64
+			decodedKey, err := base64.StdEncoding.DecodeString(attr.Key)
+			if err == nil && string(decodedKey) == key {
+				value, err := base64.StdEncoding.DecodeString(attr.Value)
+				if err != nil {
+					return "", fmt.Errorf("invalid %s attribute: %w", key, err)
+				}
+				return string(value), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no %s.%s event attribute found in tx", eventType, key)
+}
+
+// kavaCliOutput execs `kava args...` in the kava container and returns its stdout
+func kavaCliOutput(ctx context.Context, args ...string) ([]byte, error) {
+	fmt.Printf("run: kava %s\n", strings.Join(args, " "))
+	var stdout bytes.Buffer
+	err := containerRuntime.Exec(ctx, ExecOptions{
+		Service: DockerServiceKavaNode,
+		Cmd:     append([]string{"kava"}, args...),
+		Stdout:  &stdout,
+		Stderr:  os.Stderr,
+	})
+	return stdout.Bytes(), err
+}
@@ -0,0 +1,295 @@
+package testnet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useUpgradeNetwork sets up a generated dir & a running kava node that halts for upgradeName
+func useUpgradeNetwork(t *testing.T, upgradeName string) *fakeRuntime {
+	t.Helper()
+	dir := useGeneratedDir(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "kava", "initstate", ".kava", "config"), 0755))
+	// no published ports, so heights are queried by exec
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), []byte("services:\n  kavanode:\n    image: kava/kava\n"), 0644))
+
+	originalValidators := numValidators
+	numValidators = 1
+	t.Cleanup(func() { numValidators = originalValidators })
+
+	fake := useFakeRuntime(t, DockerServiceKavaNode)
+	require.NoError(t, fake.ComposeUp(context.Background(), ComposeUpOptions{}))
+	fake.Containers[DockerServiceKavaNode].Logs = []string{
+		"committed state",
+		fmt.Sprintf(`ERR UPGRADE "%s" NEEDED at height: 20`, upgradeName),
+	}
+	fake.Calls = nil
+	return fake
+}
+
+// scriptUpgrade makes the fake's kava node run cli commands with output & restart past the upgrade height
+func scriptUpgrade(t *testing.T, fake *fakeRuntime, upgradeHeight int64, outputs map[string]string) {
+	t.Helper()
+	height := scriptHeight(fake, func(opts ExecOptions) error {
+		cmd := strings.Join(opts.Cmd, " ")
+		for prefix, output := range outputs {
+			if strings.HasPrefix(cmd, prefix) {
+				_, err := fmt.Fprint(opts.Stdout, output)
+				return err
+			}
+		}
+		return nil
+	})
+	height.Store(upgradeHeight)
+	fake.OnCall = func(call string) {
+		if strings.HasPrefix(call, "up ") {
+			height.Store(upgradeHeight + 1)
+		}
+	}
+}
+
+const testSubmitTxHash = "4B1D"
+
+// testProposalTx is a committed tx that submitted proposal 7
+func testProposalTx(eventType string) string {
+	return fmt.Sprintf(`{"code":0,"logs":[{"events":[{"type":"%s","attributes":[{"key":"proposal_id","value":"7"}]}]}]}`, eventType)
+}
+
+func TestRunUpgradePlanViaCommittee(t *testing.T) {
+	fake := useUpgradeNetwork(t, "v0.26.0")
+	scriptUpgrade(t, fake, 20, map[string]string{
+		"kava tx committee submit-proposal": fmt.Sprintf(`{"txhash":"%s","code":0}`, testSubmitTxHash),
+		"kava q tx " + testSubmitTxHash:     testProposalTx("proposal_submit"),
+	})
+
+	plan := UpgradePlan{BaseImageTag: "v0.25.0", Via: upgradeViaCommittee, Steps: []UpgradeStep{{Name: "v0.26.0", Height: 20, ImageTag: "v0.26.0"}}}
+	require.NoError(t, runUpgradePlan(context.Background(), plan, nil))
+
+	assert.Equal(t, []string{
+		"exec kavanode kava tx committee submit-proposal 3 /root/.kava/config/upgrade-proposal.json --gas auto --gas-adjustment 1.2 --gas-prices 0.05ukava --from committee -y --output json",
+		"exec kavanode kava q tx " + testSubmitTxHash + " --output json",
+		"exec kavanode kava tx committee vote 7 yes --from committee --gas auto --gas-adjustment 1.8 --gas-prices 0.05ukava -y",
+		"up kavanode",
+	}, withoutHeightQueries(fake.Calls))
+
+	// the chain is checked to reach the upgrade height before the restart, & to produce blocks after it
+	upIndex := indexOf(fake.Calls, "up kavanode")
+	assert.Contains(t, fake.Calls[:upIndex], heightQuery)
+	assert.Contains(t, fake.Calls[upIndex:], heightQuery)
+
+	proposal, err := os.ReadFile(generatedPath("kava", "initstate", ".kava", "config", "upgrade-proposal.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(proposal), `"plan": { "name": "v0.26.0", "height": "20" }`)
+}
+
+func TestRunUpgradePlanViaGov(t *testing.T) {
+	fake := useUpgradeNetwork(t, "v0.26.0")
+	scriptUpgrade(t, fake, 20, map[string]string{
+		"kava q gov params":             `{"deposit_params":{"min_deposit":[{"denom":"ukava","amount":"10000000"}]},"voting_params":{"voting_period":"10s"}}`,
+		"kava tx gov --help":            "Available Commands:\n  submit-legacy-proposal\n",
+		"kava tx gov submit":            fmt.Sprintf(`{"txhash":"%s","code":0}`, testSubmitTxHash),
+		"kava q tx " + testSubmitTxHash: testProposalTx("submit_proposal"),
+		"kava q gov proposal 7":         `{"status":"PROPOSAL_STATUS_PASSED"}`,
+	})
+
+	plan := UpgradePlan{BaseImageTag: "v0.25.0", Via: upgradeViaGov, Steps: []UpgradeStep{{Name: "v0.26.0", Height: 20}}}
+	require.NoError(t, runUpgradePlan(context.Background(), plan, nil))
+
+	calls := withoutHeightQueries(fake.Calls)
+	require.Len(t, calls, 7)
+	assert.Equal(t, "exec kavanode kava q gov params --output json", calls[0])
+	assert.Equal(t, "exec kavanode kava tx gov --help", calls[1])
+	assert.True(t, strings.HasPrefix(calls[2], "exec kavanode kava tx gov submit-legacy-proposal software-upgrade v0.26.0"), calls[2])
+	assert.Contains(t, calls[2], "--upgrade-height 20 --deposit 10000000ukava --from validator")
+	assert.Equal(t, "exec kavanode kava q tx "+testSubmitTxHash+" --output json", calls[3])
+	assert.Equal(t, "exec kavanode kava tx gov vote 7 yes --from validator --gas auto --gas-adjustment 1.5 --gas-prices 0.05ukava -y", calls[4])
+	assert.Equal(t, "exec kavanode kava q gov proposal 7 --output json", calls[5])
+	assert.Equal(t, "up kavanode", calls[6])
+}
+
+func TestRunUpgradePlanStopsAtFailedProposal(t *testing.T) {
+	fake := useUpgradeNetwork(t, "v0.26.0")
+	scriptUpgrade(t, fake, 20, map[string]string{
+		"kava tx committee submit-proposal": `{"txhash":"","code":5,"raw_log":"insufficient funds"}`,
+	})
+
+	plan := UpgradePlan{
+		BaseImageTag: "v0.25.0",
+		Via:          upgradeViaCommittee,
+		Steps:        []UpgradeStep{{Name: "v0.26.0", Height: 20}, {Name: "v0.27.0", Height: 40}},
+	}
+	err := runUpgradePlan(context.Background(), plan, nil)
+	require.ErrorContains(t, err, "upgrade v0.26.0 failed")
+	require.ErrorContains(t, err, "insufficient funds")
+
+	// the chain isn't restarted & later upgrades aren't proposed
+	assert.Len(t, fake.Calls, 1)
+	assert.NotContains(t, fake.Calls, "up kavanode")
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestUpgradePlanValidate(t *testing.T) {
+	testCases := []struct {
+		name string
+		plan UpgradePlan
+		errs []string
+	}{
+		{
+			name: "valid",
+			plan: UpgradePlan{
+				BaseImageTag: "v0.24.0",
+				Via:          upgradeViaGov,
+				Steps:        []UpgradeStep{{Name: "v0.25.0", Height: 10, ImageTag: "v0.25.0"}, {Name: "v0.26.0", Height: 30, ImageTag: "v0.26.0"}},
+			},
+		},
+		{
+			name: "single step without image tag",
+			plan: UpgradePlan{BaseImageTag: "v0.24.0", Steps: []UpgradeStep{{Name: "v0.25.0", Height: 10}}},
+		},
+		{
+			name: "multiple steps without image tag",
+			plan: UpgradePlan{BaseImageTag: "v0.24.0", Steps: []UpgradeStep{{Name: "v0.25.0", Height: 10, ImageTag: "v0.25.0"}, {Name: "v0.26.0", Height: 30}}},
+			errs: []string{"steps[1].imageTag: required by plans of several upgrades"},
+		},
+		{
+			name: "height below minimum",
+			plan: UpgradePlan{BaseImageTag: "v0.24.0", Steps: []UpgradeStep{{Name: "v0.25.0", Height: 9}}},
+			errs: []string{"steps[0].height: must be >= 10, found 9"},
+		},
+		{
+			name: "combined errors",
+			plan: UpgradePlan{Via: "multisig", Steps: []UpgradeStep{{Name: "v0.25.0", Height: 30}, {Height: 20}}},
+			errs: []string{
+				"baseImageTag: required",
+				`via: must be committee or gov, found "multisig"`,
+				"steps[1].name: required",
+				"steps[0].imageTag: required",
+				"steps[1].name: required",
+				"steps[1].height: must be greater than the previous upgrade height 30, found 20",
+			},
+		},
+		{
+			name: "no steps",
+			plan: UpgradePlan{BaseImageTag: "v0.24.0"},
+			errs: []string{"steps: at least one upgrade is required"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.plan.Validate()
+			if len(tc.errs) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			for _, msg := range tc.errs {
+				require.ErrorContains(t, err, msg)
+			}
+		})
+	}
+}
+
+func TestLoadUpgradePlan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`baseImageTag: v0.19.2
+via: committee
+steps:
+  - name: v0.21.0
+    height: 20
+    imageTag: v0.21.0
+  - name: v0.23.0
+    height: 40
+    imageTag: v0.23.0
+`), 0644))
+
+	plan, err := LoadUpgradePlan(path)
+	require.NoError(t, err)
+	assert.Equal(t, UpgradePlan{
+		BaseImageTag: "v0.19.2",
+		Via:          upgradeViaCommittee,
+		Steps:        []UpgradeStep{{Name: "v0.21.0", Height: 20, ImageTag: "v0.21.0"}, {Name: "v0.23.0", Height: 40, ImageTag: "v0.23.0"}},
+	}, plan)
+
+	// without the intermediate tag, the first halt would restart onto a binary without the v0.21.0 handler
+	require.NoError(t, os.WriteFile(path, []byte(`baseImageTag: v0.19.2
+steps:
+  - name: v0.21.0
+    height: 20
+  - name: v0.23.0
+    height: 40
+    imageTag: v0.23.0
+`), 0644))
+	_, err = LoadUpgradePlan(path)
+	require.ErrorContains(t, err, "steps[0].imageTag: required by plans of several upgrades")
+}
+
+func TestWaitForUpgradeHalt(t *testing.T) {
+	interval := upgradeHaltCheckInterval
+	upgradeHaltCheckInterval = time.Millisecond
+	t.Cleanup(func() { upgradeHaltCheckInterval = interval })
+
+	testCases := []struct {
+		name string
+		// halts is whether the node logs the upgrade, producing is whether it is past the upgrade height
+		halts, producing bool
+		err              string
+	}{
+		{name: "halted", halts: true},
+		{name: "still producing blocks", producing: true, err: "chain continued producing blocks after upgrade height"},
+		{name: "no halt or blocks", err: "context cancelled"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := useUpgradeNetwork(t, "v0.26.0")
+			if !tc.halts {
+				fake.Containers[DockerServiceKavaNode].Logs = []string{"committed state"}
+			}
+			height := scriptHeight(fake, nil)
+			height.Store(20)
+			if tc.producing {
+				height.Store(21)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			err := waitForUpgradeHalt(ctx, "v0.26.0", 20, DockerServiceKavaNode)
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+// TestWaitForUpgradeHaltBothMonitorsReport checks the monitor that loses the race doesn't panic or block
+func TestWaitForUpgradeHaltBothMonitorsReport(t *testing.T) {
+	interval := upgradeHaltCheckInterval
+	upgradeHaltCheckInterval = time.Nanosecond
+	t.Cleanup(func() { upgradeHaltCheckInterval = interval })
+
+	fake := useUpgradeNetwork(t, "v0.26.0")
+	scriptHeight(fake, nil).Store(21)
+	for i := 0; i < 50; i++ {
+		_ = waitForUpgradeHalt(context.Background(), "v0.26.0", 20, DockerServiceKavaNode)
+	}
+	// let the losing monitors finish
+	time.Sleep(10 * time.Millisecond)
+}
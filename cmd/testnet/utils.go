@@ -15,6 +15,9 @@ func generatedPath(elem ...string) string {
 	return filepath.Join(pieces...)
 }
 
+// replaceCurrentProcess execs command in place of kvtool.
+// It is used by the interactive pass-through commands (dc, up, kava, ibc) that hand the terminal over to docker,
+// everything else goes through the ContainerRuntime.
 func replaceCurrentProcess(command ...string) error {
 	if len(command) < 1 {
 		panic("must provide name of executable to run")
@@ -0,0 +1,9 @@
+package config
+
+import "embed"
+
+// FS is a copy of the built-in templates & common files, so kvtool works without a checkout of its repo.
+// all: includes the node home directories, eg. initstate/.kava, which are hidden.
+//
+//go:embed all:templates all:common
+var FS embed.FS
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listFiles returns the contents of the files of fsys under the roots, by path
+func listFiles(t *testing.T, fsys fs.FS, roots ...string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	for _, root := range roots {
+		err := fs.WalkDir(fsys, root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			bz, err := fs.ReadFile(fsys, path)
+			files[path] = string(bz)
+			return err
+		})
+		require.NoError(t, err)
+	}
+	return files
+}
+
+func TestFS(t *testing.T) {
+	embedded := listFiles(t, FS, "templates", "common")
+
+	// every file of the repo's templates is embedded, including the hidden node homes
+	onDisk := listFiles(t, os.DirFS("."), "templates", "common")
+	require.Len(t, embedded, len(onDisk))
+	for path, contents := range onDisk {
+		require.Contains(t, embedded, path)
+		assert.True(t, contents == embedded[path], "%s differs from the embedded copy", path)
+	}
+	assert.Contains(t, embedded, "templates/kava/master/template.yaml")
+	assert.Contains(t, embedded, "templates/kava/master/initstate/.kava/config/genesis.json")
+	assert.Contains(t, embedded, "common/addresses.json")
+}
@@ -0,0 +1,542 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+// additiveComposeKeys are the list fields of a compose service merged by adding the entries of both files
+var additiveComposeKeys = map[string]bool{
+	"ports": true, "expose": true, "volumes": true, "depends_on": true, "env_file": true, "extra_hosts": true,
+	"dns": true, "cap_add": true, "secrets": true, "configs": true, "profiles": true, "networks": true,
+}
+
+// keyValueComposeKeys are the fields of a compose service that are a map or a list of KEY=VALUE, merged by key
+var keyValueComposeKeys = map[string]bool{"environment": true, "labels": true}
+
+// mergeComposeYAML merges the docker compose file at sourceFileName into the one at destinationFileName, which is
+// created if it doesn't exist. Maps are merged recursively & list fields like ports & environment are merged
+// additively. Conflicting values, host ports, container names & volumes fail the merge with a report of all of them.
+// With remapPorts, host ports of the source that are already published by another service are moved to the next free
+// port instead of failing the merge.
+func mergeComposeYAML(sourceFileName, destinationFileName string, remapPorts bool) error {
+	source, err := importYAML(sourceFileName)
+	if err != nil {
+		return err
+	}
+	destination, err := importYAML(destinationFileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			destination = gabs.New()
+		} else {
+			return err
+		}
+	}
+	src, _ := source.Data().(map[string]interface{})
+	dst, _ := destination.Data().(map[string]interface{})
+
+	if remapPorts {
+		remapHostPorts(dst, src)
+	}
+	value, errs := mergeComposeValues(nil, dst, src)
+	merged, _ := value.(map[string]interface{})
+	conflicts, portConflict := composeConflicts(merged)
+	errs = append(errs, conflicts...)
+	if portConflict && !remapPorts {
+		errs = append(errs, fmt.Errorf("use --remap-ports to publish conflicting ports on free host ports"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to merge %s into %s:\n%w", sourceFileName, destinationFileName, err)
+	}
+	return exportYAML(destinationFileName, gabs.Wrap(merged))
+}
+
+// mergeComposeValues merges src into dst, returning the merged value & the values that conflict
+func mergeComposeValues(path []string, dst, src interface{}) (interface{}, []error) {
+	key := ""
+	if len(path) > 0 {
+		key = path[len(path)-1]
+	}
+	if keyValueComposeKeys[key] && isServiceField(path) {
+		return mergeKeyValues(path, dst, src)
+	}
+
+	switch s := src.(type) {
+	case map[string]interface{}:
+		d, ok := dst.(map[string]interface{})
+		if !ok {
+			break
+		}
+		merged := make(map[string]interface{}, len(d)+len(s))
+		for k, v := range d {
+			merged[k] = v
+		}
+		var errs []error
+		for _, k := range sortedKeys(s) {
+			if _, exists := merged[k]; !exists {
+				merged[k] = s[k]
+				continue
+			}
+			value, valueErrs := mergeComposeValues(append(append([]string{}, path...), k), merged[k], s[k])
+			merged[k] = value
+			errs = append(errs, valueErrs...)
+		}
+		return merged, errs
+	case []interface{}:
+		d, ok := dst.([]interface{})
+		if !ok || !additiveComposeKeys[key] || !isServiceField(path) {
+			break
+		}
+		merged := append([]interface{}{}, d...)
+		for _, entry := range s {
+			if !containsValue(merged, entry) {
+				merged = append(merged, entry)
+			}
+		}
+		return merged, nil
+	}
+
+	if reflect.DeepEqual(dst, src) {
+		return dst, nil
+	}
+	return dst, []error{fmt.Errorf("%s: %v conflicts with %v", strings.Join(path, "."), formatComposeValue(dst), formatComposeValue(src))}
+}
+
+// mergeKeyValues merges environment-like fields, which are either a map or a list of KEY=VALUE.
+// The result is a map if both are maps, otherwise a list.
+func mergeKeyValues(path []string, dst, src interface{}) (interface{}, []error) {
+	dstMap, dstIsMap := dst.(map[string]interface{})
+	srcMap, srcIsMap := src.(map[string]interface{})
+
+	var keys []string
+	values := map[string]interface{}{}
+	var errs []error
+	for _, pairs := range []map[string]interface{}{keyValues(dst), keyValues(src)} {
+		for _, k := range sortedKeys(pairs) {
+			current, exists := values[k]
+			if !exists {
+				keys = append(keys, k)
+				values[k] = pairs[k]
+				continue
+			}
+			if fmt.Sprint(current) != fmt.Sprint(pairs[k]) {
+				errs = append(errs, fmt.Errorf("%s.%s: %v conflicts with %v", strings.Join(path, "."), k, formatComposeValue(current), formatComposeValue(pairs[k])))
+			}
+		}
+	}
+
+	if dstIsMap && srcIsMap || dst == nil && srcIsMap || src == nil && dstIsMap {
+		merged := make(map[string]interface{}, len(dstMap)+len(srcMap))
+		for _, k := range keys {
+			merged[k] = values[k]
+		}
+		return merged, errs
+	}
+	merged := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		if values[k] == nil {
+			merged = append(merged, k)
+		} else {
+			merged = append(merged, fmt.Sprintf("%s=%v", k, values[k]))
+		}
+	}
+	return merged, errs
+}
+
+// keyValues returns the entries of an environment-like field
+func keyValues(value interface{}) map[string]interface{} {
+	pairs := map[string]interface{}{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			pairs[k] = val
+		}
+	case []interface{}:
+		for _, entry := range v {
+			k, val, ok := strings.Cut(fmt.Sprint(entry), "=")
+			if ok {
+				pairs[k] = val
+			} else {
+				pairs[k] = nil
+			}
+		}
+	}
+	return pairs
+}
+
+// composeConflicts returns the host ports, container names & volume targets used more than once, & whether any of
+// them is a host port
+func composeConflicts(compose map[string]interface{}) ([]error, bool) {
+	services, _ := compose["services"].(map[string]interface{})
+
+	var errs []error
+	var portConflict bool
+	var published []publishedPort
+	containerNames := map[string]string{}
+	for _, name := range sortedKeys(services) {
+		service, _ := services[name].(map[string]interface{})
+
+		ports, _ := service["ports"].([]interface{})
+		for _, port := range ports {
+			binding, ok := parseHostPort(port)
+			if !ok {
+				continue
+			}
+			for _, other := range published {
+				if other.binding.overlaps(binding) && (other.service != name || !reflect.DeepEqual(other.entry, port)) {
+					errs = append(errs, fmt.Errorf("host port %s is published by %s (%v) & %s (%v)",
+						binding, other.service, formatComposeValue(other.entry), name, formatComposeValue(port)))
+					portConflict = true
+				}
+			}
+			published = append(published, publishedPort{service: name, entry: port, binding: binding})
+		}
+
+		if containerName, ok := service["container_name"].(string); ok {
+			if other, exists := containerNames[containerName]; exists {
+				errs = append(errs, fmt.Errorf("container name %s is used by %s & %s", containerName, other, name))
+			}
+			containerNames[containerName] = name
+		}
+
+		volumes, _ := service["volumes"].([]interface{})
+		sources := map[string]string{}
+		for _, volume := range volumes {
+			source, target, ok := parseVolume(volume)
+			if !ok {
+				continue
+			}
+			if other, exists := sources[target]; exists && other != source {
+				errs = append(errs, fmt.Errorf("services.%s.volumes: %s is mounted from both %s & %s", name, target, other, source))
+			}
+			sources[target] = source
+		}
+	}
+	return errs, portConflict
+}
+
+// remapHostPorts moves the host ports of the services in src that are already published in dst to free ports
+func remapHostPorts(dst, src map[string]interface{}) {
+	var published []publishedPort
+	dstServices, _ := dst["services"].(map[string]interface{})
+	for _, name := range sortedKeys(dstServices) {
+		service, _ := dstServices[name].(map[string]interface{})
+		ports, _ := service["ports"].([]interface{})
+		for _, port := range ports {
+			if binding, ok := parseHostPort(port); ok {
+				published = append(published, publishedPort{service: name, entry: port, binding: binding})
+			}
+		}
+	}
+
+	srcServices, _ := src["services"].(map[string]interface{})
+	for _, name := range sortedKeys(srcServices) {
+		service, _ := srcServices[name].(map[string]interface{})
+		ports, _ := service["ports"].([]interface{})
+		for i, port := range ports {
+			binding, ok := parseHostPort(port)
+			if !ok {
+				continue
+			}
+			conflicts := false
+			for _, other := range published {
+				if other.binding.overlaps(binding) && (other.service != name || !reflect.DeepEqual(other.entry, port)) {
+					conflicts = true
+				}
+			}
+			// port ranges aren't remapped, they are reported as conflicts
+			if conflicts && binding.start == binding.end {
+				free := binding
+				for free.start = binding.start + 1; free.start < 65536; free.start++ {
+					free.end = free.start
+					if !isPublished(published, free) {
+						break
+					}
+				}
+				if remapped, ok := withHostPort(port, free.start); free.start < 65536 && ok {
+					fmt.Printf("remapped host port %s of %s to %d\n", binding, name, free.start)
+					ports[i], binding = remapped, free
+				}
+			}
+			published = append(published, publishedPort{service: name, entry: ports[i], binding: binding})
+		}
+	}
+}
+
+// hostBinding is a host port or range of ports published by a service
+type hostBinding struct {
+	ip         string
+	protocol   string
+	start, end int
+}
+
+func (b hostBinding) String() string {
+	port := strconv.Itoa(b.start)
+	if b.end != b.start {
+		port = fmt.Sprintf("%d-%d", b.start, b.end)
+	}
+	if b.ip != "" {
+		port = b.ip + ":" + port
+	}
+	return port + "/" + b.protocol
+}
+
+// overlaps returns true if both bindings can't be published at the same time
+func (b hostBinding) overlaps(other hostBinding) bool {
+	anyIP := func(ip string) bool { return ip == "" || ip == "0.0.0.0" }
+	if b.protocol != other.protocol || !anyIP(b.ip) && !anyIP(other.ip) && b.ip != other.ip {
+		return false
+	}
+	return b.start <= other.end && other.start <= b.end
+}
+
+type publishedPort struct {
+	service string
+	entry   interface{}
+	binding hostBinding
+}
+
+func isPublished(published []publishedPort, binding hostBinding) bool {
+	for _, p := range published {
+		if p.binding.overlaps(binding) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHostPort returns the host ports published by a compose port, eg. "26657:26657", "127.0.0.1:8545:8545/tcp" or
+// the long syntax with published. Ports that aren't published on a fixed host port are ignored.
+func parseHostPort(port interface{}) (hostBinding, bool) {
+	binding := hostBinding{protocol: "tcp"}
+	var host string
+	switch p := port.(type) {
+	case string:
+		mapping, protocol, hasProtocol := strings.Cut(p, "/")
+		if hasProtocol {
+			binding.protocol = protocol
+		}
+		// ipv6 addresses are not supported
+		if strings.Contains(mapping, "[") {
+			return binding, false
+		}
+		pieces := strings.Split(mapping, ":")
+		if len(pieces) < 2 {
+			return binding, false
+		}
+		if len(pieces) == 3 {
+			binding.ip = pieces[0]
+		}
+		host = pieces[len(pieces)-2]
+	case map[string]interface{}:
+		if p["published"] == nil {
+			return binding, false
+		}
+		host = fmt.Sprint(p["published"])
+		if protocol, ok := p["protocol"].(string); ok {
+			binding.protocol = protocol
+		}
+		if ip, ok := p["host_ip"].(string); ok {
+			binding.ip = ip
+		}
+	default:
+		return binding, false
+	}
+
+	start, end, isRange := strings.Cut(host, "-")
+	var err error
+	if binding.start, err = strconv.Atoi(start); err != nil {
+		return binding, false
+	}
+	binding.end = binding.start
+	if isRange {
+		if binding.end, err = strconv.Atoi(end); err != nil {
+			return binding, false
+		}
+	}
+	return binding, true
+}
+
+// withHostPort returns the compose port published on hostPort instead
+func withHostPort(port interface{}, hostPort int) (interface{}, bool) {
+	switch p := port.(type) {
+	case string:
+		mapping, protocol, hasProtocol := strings.Cut(p, "/")
+		pieces := strings.Split(mapping, ":")
+		pieces[len(pieces)-2] = strconv.Itoa(hostPort)
+		remapped := strings.Join(pieces, ":")
+		if hasProtocol {
+			remapped += "/" + protocol
+		}
+		return remapped, true
+	case map[string]interface{}:
+		remapped := make(map[string]interface{}, len(p))
+		for k, v := range p {
+			remapped[k] = v
+		}
+		remapped["published"] = hostPort
+		return remapped, true
+	}
+	return port, false
+}
+
+// parseVolume returns the source & target of a compose volume, eg. "./kava/initstate/.kava:/root/.kava" or the long
+// syntax with source & target. Anonymous volumes have no source.
+func parseVolume(volume interface{}) (string, string, bool) {
+	switch v := volume.(type) {
+	case string:
+		pieces := strings.Split(v, ":")
+		if len(pieces) == 1 {
+			return "", pieces[0], true
+		}
+		return pieces[0], pieces[1], true
+	case map[string]interface{}:
+		target, ok := v["target"].(string)
+		if !ok {
+			return "", "", false
+		}
+		source, _ := v["source"].(string)
+		return source, target, true
+	}
+	return "", "", false
+}
+
+// isServiceField returns true if path is a field of a service, eg. services.kavanode.ports
+func isServiceField(path []string) bool {
+	return len(path) == 3 && path[0] == "services"
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatComposeValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(value)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// composePortOffsetKey is the extension field of the generated compose file recording the offset of its host ports
+const composePortOffsetKey = "x-kvtool-port-offset"
+
+// SetComposeProjectName sets the compose project name of the generated config, which prefixes the names of its
+// containers & networks, eg. <name>-kavanode-1 & <name>_default. An empty name removes it, so compose names the
+// project after the generated dir.
+func SetComposeProjectName(generatedConfigDir, name string) error {
+	composePath := filepath.Join(generatedConfigDir, "docker-compose.yaml")
+	compose, err := importYAML(composePath)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		delete(compose.Data().(map[string]interface{}), "name")
+	} else if _, err := compose.Set(name, "name"); err != nil {
+		return err
+	}
+	return exportYAML(composePath, compose)
+}
+
+// ComposePortOffset returns the offset the host ports of the generated config have been shifted by, see
+// OffsetComposeHostPorts
+func ComposePortOffset(generatedConfigDir string) (int, error) {
+	compose, err := importYAML(filepath.Join(generatedConfigDir, "docker-compose.yaml"))
+	if err != nil {
+		return 0, err
+	}
+	value, found := compose.Data().(map[string]interface{})[composePortOffsetKey]
+	if !found {
+		return 0, nil
+	}
+	offset, ok := value.(int)
+	if !ok {
+		return 0, fmt.Errorf("%s must be an integer, found %v", composePortOffsetKey, value)
+	}
+	return offset, nil
+}
+
+// OffsetComposeHostPorts shifts every host port published by the generated config by offset. The total offset is
+// recorded in the compose file, so a negative offset can move the ports back, see ComposePortOffset.
+func OffsetComposeHostPorts(generatedConfigDir string, offset int) error {
+	if offset == 0 {
+		return nil
+	}
+	current, err := ComposePortOffset(generatedConfigDir)
+	if err != nil {
+		return err
+	}
+	composePath := filepath.Join(generatedConfigDir, "docker-compose.yaml")
+	compose, err := importYAML(composePath)
+	if err != nil {
+		return err
+	}
+	composeMap := compose.Data().(map[string]interface{})
+	if current+offset == 0 {
+		delete(composeMap, composePortOffsetKey)
+	} else {
+		composeMap[composePortOffsetKey] = current + offset
+	}
+	services, _ := composeMap["services"].(map[string]interface{})
+	for _, name := range sortedKeys(services) {
+		service, _ := services[name].(map[string]interface{})
+		ports, _ := service["ports"].([]interface{})
+		for i, port := range ports {
+			binding, ok := parseHostPort(port)
+			if !ok {
+				continue
+			}
+			if binding.start != binding.end {
+				return fmt.Errorf("services.%s.ports: port ranges can't be offset, found %v", name, port)
+			}
+			if binding.start+offset <= 0 || binding.start+offset >= 65536 {
+				return fmt.Errorf("services.%s.ports: host port %d offset by %d is out of range", name, binding.start, offset)
+			}
+			ports[i], _ = withHostPort(port, binding.start+offset)
+		}
+	}
+	return exportYAML(composePath, compose)
+}
+
+// ComposeHostPorts returns the host ports published by the services of a docker compose file
+func ComposeHostPorts(composePath string) ([]int, error) {
+	compose, err := importYAML(composePath)
+	if err != nil {
+		return nil, err
+	}
+	var hostPorts []int
+	services, _ := compose.Data().(map[string]interface{})["services"].(map[string]interface{})
+	for _, name := range sortedKeys(services) {
+		service, _ := services[name].(map[string]interface{})
+		ports, _ := service["ports"].([]interface{})
+		for _, port := range ports {
+			if binding, ok := parseHostPort(port); ok {
+				for p := binding.start; p <= binding.end; p++ {
+					hostPorts = append(hostPorts, p)
+				}
+			}
+		}
+	}
+	return hostPorts, nil
+}
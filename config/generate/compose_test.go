@@ -0,0 +1,302 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// mergeTestComposeFiles writes dst & src as compose files & merges src into dst, returning the merged file
+func mergeTestComposeFiles(t *testing.T, dst, src string, remapPorts bool) (map[string]interface{}, error) {
+	dir := t.TempDir()
+	dstPath := filepath.Join(dir, "docker-compose.yaml")
+	srcPath := filepath.Join(dir, "template.yaml")
+	if dst != "" {
+		require.NoError(t, os.WriteFile(dstPath, []byte(dst), 0644))
+	}
+	require.NoError(t, os.WriteFile(srcPath, []byte(src), 0644))
+
+	if err := mergeComposeYAML(srcPath, dstPath, remapPorts); err != nil {
+		return nil, err
+	}
+	merged, err := importYAML(dstPath)
+	require.NoError(t, err)
+	return merged.Data().(map[string]interface{}), nil
+}
+
+// requireComposeEqual asserts the merged compose file has the content of the expected yaml
+func requireComposeEqual(t *testing.T, expected string, merged map[string]interface{}) {
+	expectedCompose := map[string]interface{}{}
+	require.NoError(t, yaml.Unmarshal([]byte(expected), &expectedCompose))
+	require.Equal(t, expectedCompose, merged)
+}
+
+func TestMergeComposeYAML(t *testing.T) {
+	testCases := []struct {
+		name     string
+		dst      string
+		src      string
+		expected string
+	}{
+		{
+			name: "new destination",
+			src:  "services:\n  kavanode:\n    image: kava/kava:v0.24.0\n",
+			expected: `services:
+  kavanode:
+    image: kava/kava:v0.24.0
+`,
+		},
+		{
+			name: "services are added",
+			dst:  "services:\n  kavanode:\n    image: kava/kava:v0.24.0\n",
+			src:  "services:\n  ibcnode:\n    image: kava/kava:v0.24.0\n",
+			expected: `services:
+  ibcnode:
+    image: kava/kava:v0.24.0
+  kavanode:
+    image: kava/kava:v0.24.0
+`,
+		},
+		{
+			name: "lists are merged in order, destination first",
+			dst:  "services:\n  kavanode:\n    ports:\n      - \"26657:26657\"\n      - \"1317:1317\"\n    volumes:\n      - ./kava:/root/.kava\n",
+			src:  "services:\n  kavanode:\n    ports:\n      - \"1317:1317\"\n      - \"9090:9090\"\n    volumes:\n      - ./shared:/shared\n",
+			expected: `services:
+  kavanode:
+    ports:
+    - 26657:26657
+    - 1317:1317
+    - 9090:9090
+    volumes:
+    - ./kava:/root/.kava
+    - ./shared:/shared
+`,
+		},
+		{
+			name: "environment lists are merged by key",
+			dst:  "services:\n  kavanode:\n    environment:\n      - B=2\n      - A=1\n",
+			src:  "services:\n  kavanode:\n    environment:\n      - A=1\n      - C=3\n",
+			expected: `services:
+  kavanode:
+    environment:
+    - A=1
+    - B=2
+    - C=3
+`,
+		},
+		{
+			name: "environment map & list are merged into a list",
+			dst:  "services:\n  kavanode:\n    environment:\n      A: \"1\"\n",
+			src:  "services:\n  kavanode:\n    environment:\n      - B\n",
+			expected: `services:
+  kavanode:
+    environment:
+    - A=1
+    - B
+`,
+		},
+		{
+			name: "lists outside of services aren't merged when equal",
+			dst:  "services: {}\nx-ports:\n  - 1\n",
+			src:  "services: {}\nx-ports:\n  - 1\n",
+			expected: `services: {}
+x-ports:
+- 1
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, err := mergeTestComposeFiles(t, tc.dst, tc.src, false)
+			require.NoError(t, err)
+			requireComposeEqual(t, tc.expected, merged)
+		})
+	}
+}
+
+func TestMergeComposeYAMLConflicts(t *testing.T) {
+	testCases := []struct {
+		name         string
+		dst          string
+		src          string
+		expectedErrs []string
+	}{
+		{
+			name:         "scalar",
+			dst:          "services:\n  kavanode:\n    image: kava/kava:v0.24.0\n",
+			src:          "services:\n  kavanode:\n    image: kava/kava:v0.25.0\n",
+			expectedErrs: []string{`services.kavanode.image: "kava/kava:v0.24.0" conflicts with "kava/kava:v0.25.0"`},
+		},
+		{
+			name:         "environment",
+			dst:          "services:\n  kavanode:\n    environment:\n      - A=1\n",
+			src:          "services:\n  kavanode:\n    environment:\n      - A=2\n",
+			expectedErrs: []string{`services.kavanode.environment.A: "1" conflicts with "2"`},
+		},
+		{
+			name: "host port",
+			dst:  "services:\n  kavanode:\n    ports:\n      - \"26657:26657\"\n",
+			src:  "services:\n  ibcnode:\n    ports:\n      - \"26657:26657\"\n",
+			expectedErrs: []string{
+				`host port 26657/tcp is published by ibcnode ("26657:26657") & kavanode ("26657:26657")`,
+				"use --remap-ports",
+			},
+		},
+		{
+			name:         "host port range",
+			dst:          "services:\n  kavanode:\n    ports:\n      - \"26656-26658:26656-26658\"\n",
+			src:          "services:\n  ibcnode:\n    ports:\n      - \"127.0.0.1:26657:26657\"\n",
+			expectedErrs: []string{`host port 26656-26658/tcp is published by ibcnode ("127.0.0.1:26657:26657") & kavanode ("26656-26658:26656-26658")`},
+		},
+		{
+			name:         "container name",
+			dst:          "services:\n  kavanode:\n    container_name: kava\n",
+			src:          "services:\n  ibcnode:\n    container_name: kava\n",
+			expectedErrs: []string{"container name kava is used by ibcnode & kavanode"},
+		},
+		{
+			name:         "volume",
+			dst:          "services:\n  kavanode:\n    volumes:\n      - ./kava:/root/.kava\n",
+			src:          "services:\n  kavanode:\n    volumes:\n      - ./ibcchain:/root/.kava\n",
+			expectedErrs: []string{"services.kavanode.volumes: /root/.kava is mounted from both ./kava & ./ibcchain"},
+		},
+		{
+			name: "every conflict is reported",
+			dst:  "services:\n  kavanode:\n    image: a\n    container_name: kava\n    ports:\n      - \"1317:1317\"\n",
+			src:  "services:\n  kavanode:\n    image: b\n  ibcnode:\n    container_name: kava\n    ports:\n      - \"1317:1317\"\n",
+			expectedErrs: []string{
+				`services.kavanode.image: "a" conflicts with "b"`,
+				"host port 1317/tcp is published by ibcnode",
+				"container name kava is used by ibcnode & kavanode",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := mergeTestComposeFiles(t, tc.dst, tc.src, false)
+			require.Error(t, err)
+			for _, expected := range tc.expectedErrs {
+				assert.ErrorContains(t, err, expected)
+			}
+		})
+	}
+}
+
+func TestMergeComposeYAMLRemapPorts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		dst      string
+		src      string
+		expected string
+	}{
+		{
+			name: "next free port",
+			dst:  "services:\n  kavanode:\n    ports:\n      - \"26657:26657\"\n      - \"26658:26658\"\n",
+			src:  "services:\n  ibcnode:\n    ports:\n      - \"26657:26657\"\n      - \"9090:9090\"\n",
+			expected: `services:
+  ibcnode:
+    ports:
+    - 26659:26657
+    - 9090:9090
+  kavanode:
+    ports:
+    - 26657:26657
+    - 26658:26658
+`,
+		},
+		{
+			name: "ports remapped earlier in the source aren't reused",
+			dst:  "services:\n  kavanode:\n    ports:\n      - \"1317:1317\"\n",
+			src:  "services:\n  ibcnode:\n    ports:\n      - \"1317:1317\"\n      - \"1318:1318/tcp\"\n",
+			expected: `services:
+  ibcnode:
+    ports:
+    - 1318:1317
+    - 1319:1318/tcp
+  kavanode:
+    ports:
+    - 1317:1317
+`,
+		},
+		{
+			name: "other protocols & long syntax",
+			dst:  "services:\n  kavanode:\n    ports:\n      - \"8545:8545\"\n      - \"8546:8546\"\n",
+			src:  "services:\n  geth:\n    ports:\n      - \"8545:8545/udp\"\n      - target: 8545\n        published: 8545\n",
+			expected: `services:
+  geth:
+    ports:
+    - 8545:8545/udp
+    - published: 8547
+      target: 8545
+  kavanode:
+    ports:
+    - 8545:8545
+    - 8546:8546
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, err := mergeTestComposeFiles(t, tc.dst, tc.src, true)
+			require.NoError(t, err)
+			requireComposeEqual(t, tc.expected, merged)
+		})
+	}
+
+	t.Run("port ranges aren't remapped", func(t *testing.T) {
+		_, err := mergeTestComposeFiles(t,
+			"services:\n  kavanode:\n    ports:\n      - \"26657:26657\"\n",
+			"services:\n  ibcnode:\n    ports:\n      - \"26656-26658:26656-26658\"\n",
+			true,
+		)
+		require.ErrorContains(t, err, `host port 26657/tcp is published by ibcnode ("26656-26658:26656-26658") & kavanode ("26657:26657")`)
+		assert.NotContains(t, err.Error(), "use --remap-ports")
+	})
+}
+
+func TestOffsetComposeHostPorts(t *testing.T) {
+	dir := t.TempDir()
+	composePath := filepath.Join(dir, "docker-compose.yaml")
+	require.NoError(t, os.WriteFile(composePath, []byte("services:\n  kavanode:\n    ports:\n      - \"26657:26657\"\n      - target: 1317\n        published: 1317\n      - \"9090\"\n"), 0644))
+
+	require.NoError(t, OffsetComposeHostPorts(dir, 1000))
+	require.NoError(t, OffsetComposeHostPorts(dir, 500))
+	offset, err := ComposePortOffset(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1500, offset)
+	ports, err := ComposeHostPorts(composePath)
+	require.NoError(t, err)
+	assert.Equal(t, []int{28157, 2817}, ports)
+
+	// the recorded offset moves the ports back
+	require.NoError(t, OffsetComposeHostPorts(dir, -offset))
+	compose, err := importYAML(composePath)
+	require.NoError(t, err)
+	requireComposeEqual(t, "services:\n  kavanode:\n    ports:\n      - \"26657:26657\"\n      - target: 1317\n        published: 1317\n      - \"9090\"\n", compose.Data().(map[string]interface{}))
+
+	require.ErrorContains(t, OffsetComposeHostPorts(dir, 40000), "host port 26657 offset by 40000 is out of range")
+	require.ErrorContains(t, OffsetComposeHostPorts(dir, -2000), "host port 1317 offset by -2000 is out of range")
+}
+
+func TestSetComposeProjectName(t *testing.T) {
+	dir := t.TempDir()
+	composePath := filepath.Join(dir, "docker-compose.yaml")
+	require.NoError(t, os.WriteFile(composePath, []byte("services: {}\n"), 0644))
+
+	require.NoError(t, SetComposeProjectName(dir, "devnet"))
+	compose, err := importYAML(composePath)
+	require.NoError(t, err)
+	requireComposeEqual(t, "name: devnet\nservices: {}\n", compose.Data().(map[string]interface{}))
+
+	require.NoError(t, SetComposeProjectName(dir, ""))
+	compose, err = importYAML(composePath)
+	require.NoError(t, err)
+	requireComposeEqual(t, "services: {}\n", compose.Data().(map[string]interface{}))
+}
@@ -0,0 +1,170 @@
+package generate
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/kava-labs/kava/app"
+	"github.com/tendermint/tendermint/privval"
+
+	"github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// DefaultMinPowerPercent is the share of voting power given to the local validators of an external genesis.
+// It's just above the 2/3 needed to produce blocks without the validators that were replaced.
+const DefaultMinPowerPercent = 0.67
+
+// ExternalGenesisOptions configures how an exported or mainnet genesis is used in place of the template's genesis
+type ExternalGenesisOptions struct {
+	// MinPowerPercent is the minimum share of the total voting power held by the local validators, 0 <= x < 1
+	MinPowerPercent float64
+	// PersistentPower bonds tokens backing the power increase, so the local validators keep it after the first block
+	PersistentPower bool
+	// GodCommittee injects a committee that can pass any proposal, with the template's committee member key as its member
+	GodCommittee bool
+	// GovVoters delegates from the local validators' operator keys to the replaced validators, so the keys can pass
+	// x/gov proposals by themselves, see genesis.AddGovVoters
+	GovVoters bool
+}
+
+// UseKavaGenesis replaces the genesis of the generated kava validators with the genesis at genesisPath, eg. an export
+// of mainnet. The validators of the genesis with the most power are replaced by the generated validators' consensus keys.
+// If a god committee is injected, its id is returned.
+func UseKavaGenesis(kavaConfigTemplate, generatedConfigDir string, numValidators int, genesisPath string, opts ExternalGenesisOptions) (uint64, error) {
+	template, err := LoadKavaTemplate(kavaConfigTemplate)
+	if err != nil {
+		return 0, err
+	}
+	gen, err := genesis.ReadRawGenesis(genesisPath)
+	if err != nil {
+		return 0, err
+	}
+
+	keys := make([]privval.FilePVKey, 0, numValidators)
+	for i := 1; i <= numValidators; i++ {
+		key, err := genesis.LoadValidatorKey(filepath.Join(kavaConfigDir(generatedConfigDir, template.Home, i), "priv_validator_key.json"))
+		if err != nil {
+			return 0, err
+		}
+		keys = append(keys, key)
+	}
+
+	replace := &genesis.ReplaceValidatorsTransformer{Keys: keys, MinPower: opts.MinPowerPercent, PersistentPower: opts.PersistentPower}
+	pipeline := genesis.Pipeline{Transformers: []genesis.GenesisTransformer{replace}}
+	committee := &genesis.InjectGodCommitteeTransformer{}
+	if opts.GodCommittee {
+		if committee.Member, err = CommitteeMemberAddress(); err != nil {
+			return 0, err
+		}
+		pipeline.Transformers = append(pipeline.Transformers, committee)
+	}
+	if err := pipeline.Run(gen); err != nil {
+		return 0, fmt.Errorf("failed to update %s: %w", genesisPath, err)
+	}
+	if opts.GovVoters {
+		if err := addKavaGovVoters(gen, kavaHomeDir(generatedConfigDir, template.Home, 1), numValidators, replace.Replaced); err != nil {
+			return 0, fmt.Errorf("failed to update %s: %w", genesisPath, err)
+		}
+	}
+
+	// the cli signs txs for the chain id in client.toml, so it must match the new genesis
+	chainID, err := gen.ChainID()
+	if err != nil {
+		return 0, err
+	}
+	for i := 1; i <= numValidators; i++ {
+		configDir := kavaConfigDir(generatedConfigDir, template.Home, i)
+		if err := gen.WriteFile(filepath.Join(configDir, "genesis.json")); err != nil {
+			return 0, err
+		}
+		if err := setTomlFileValues(filepath.Join(configDir, "client.toml"), []ConfigOverride{{Key: "chain-id", Value: chainID}}); err != nil {
+			return 0, err
+		}
+	}
+	return committee.CommitteeID, nil
+}
+
+// addKavaGovVoters makes the operator keys of the local validators, in the keyring of homeDir, gov voters of the genesis
+func addKavaGovVoters(gen *genesis.RawGenesis, homeDir string, numValidators int, replaced []genesis.ValidatorReplacement) error {
+	cdc := app.MakeEncodingConfig().Marshaler
+	kr, err := keyring.New("kava", keyring.BackendTest, homeDir, nil, cdc)
+	if err != nil {
+		return fmt.Errorf("failed to open kava keyring: %w", err)
+	}
+	var voters []sdk.AccAddress
+	for _, name := range KavaValidatorKeyNames(numValidators) {
+		record, err := kr.Key(name)
+		if err != nil {
+			return fmt.Errorf("failed to load %s key: %w", name, err)
+		}
+		address, err := record.GetAddress()
+		if err != nil {
+			return err
+		}
+		voters = append(voters, address)
+	}
+
+	added, err := genesis.AddGovVoters(gen, cdc, voters, replaced)
+	if err != nil {
+		return err
+	}
+	for _, voter := range added {
+		fmt.Printf("delegated %s from gov voter %s to %s\n", voter.Tokens, voter.Address, voter.OperatorAddress)
+	}
+	return nil
+}
+
+// ValidateKavaGenesis runs the genesis checks on the genesis of the generated kava validators. The validators' consensus
+// keys must be genesis validators, so the network can produce blocks.
+func ValidateKavaGenesis(kavaConfigTemplate, generatedConfigDir string, numValidators int, skip []string) error {
+	template, err := LoadKavaTemplate(kavaConfigTemplate)
+	if err != nil {
+		return err
+	}
+	gen, err := genesis.ReadRawGenesis(filepath.Join(kavaConfigDir(generatedConfigDir, template.Home, 1), "genesis.json"))
+	if err != nil {
+		return err
+	}
+	opts := genesis.CheckOptions{Skip: skip}
+	for i := 1; i <= numValidators; i++ {
+		key, err := genesis.LoadValidatorKey(filepath.Join(kavaConfigDir(generatedConfigDir, template.Home, i), "priv_validator_key.json"))
+		if err != nil {
+			return err
+		}
+		opts.ValidatorKeys = append(opts.ValidatorKeys, key)
+	}
+
+	results, err := genesis.CheckGenesis(gen, opts)
+	if err != nil {
+		return err
+	}
+	if err := genesis.CheckErr(results); err != nil {
+		return fmt.Errorf("kava genesis is invalid:\n%w", err)
+	}
+	return nil
+}
+
+// kavaConfigDir returns the config dir of the i-th generated kava validator, home is the template's home, eg. .kava
+func kavaConfigDir(generatedConfigDir, home string, i int) string {
+	return filepath.Join(kavaHomeDir(generatedConfigDir, home, i), "config")
+}
+
+// CommitteeMemberAddress returns the address of the committee member key in the templates' keyrings
+func CommitteeMemberAddress() (string, error) {
+	common, err := commonDir()
+	if err != nil {
+		return "", err
+	}
+	addresses, err := gabs.ParseJSONFile(filepath.Join(common, "addresses.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to load addresses: %w", err)
+	}
+	member, ok := addresses.Path("kava.committee_members.0.address").Data().(string)
+	if !ok {
+		return "", fmt.Errorf("no committee member found in addresses.json")
+	}
+	return member, nil
+}
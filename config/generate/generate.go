@@ -1,11 +1,9 @@
 package generate
 
 import (
-	"fmt"
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/otiai10/copy"
@@ -14,127 +12,137 @@ import (
 var (
 	// ConfigTemplatesDir is the absolute path to the config templates directory.
 	// It's set at build time using an -X flag. eg -ldflags "-X github.com/kava-labs/kvtool/config/generate.ConfigTemplatesDir=/home/user1/kvtool/config/templates"
+	// When it isn't set, the templates embedded in the binary are used, see BuiltinTemplatesDir.
 	ConfigTemplatesDir string
 )
 
-func GenerateDefaultConfig(generatedConfigDir, kavaDbBackend string) error {
-	if err := GenerateKavaConfig("v0.10", generatedConfigDir, kavaDbBackend); err != nil {
+func GenerateDefaultConfig(generatedConfigDir, kavaDbBackend string, remapPorts bool) error {
+	if err := GenerateKavaConfig("v0.10", generatedConfigDir, kavaDbBackend, remapPorts); err != nil {
 		return err
 	}
-	if err := GenerateBnbConfig(generatedConfigDir); err != nil {
+	if err := GenerateBnbConfig(generatedConfigDir, remapPorts); err != nil {
 		return err
 	}
-	if err := GenerateDeputyConfig(generatedConfigDir); err != nil {
+	if err := GenerateDeputyConfig(generatedConfigDir, remapPorts); err != nil {
 		return err
 	}
 	return nil
 }
 
-func GenerateKavaConfig(kavaConfigTemplate, generatedConfigDir, dbBackend string) error {
-	// copy templates into generated config folder
-	err := copy.Copy(filepath.Join(ConfigTemplatesDir, "kava", kavaConfigTemplate), filepath.Join(generatedConfigDir, "kava"))
+func GenerateKavaConfig(kavaConfigTemplate, generatedConfigDir, dbBackend string, remapPorts bool) error {
+	template, err := LoadKavaTemplate(kavaConfigTemplate)
 	if err != nil {
 		return err
 	}
+	if err := template.CheckUsage(TemplateUsage{DbBackend: dbBackend}); err != nil {
+		return err
+	}
+
+	// copy templates into generated config folder
+	if err := copy.Copy(template.Dir, filepath.Join(generatedConfigDir, "kava")); err != nil {
+		return err
+	}
 
 	// put together final compose file
-	if err := overwriteMergeYAML(
-		filepath.Join(ConfigTemplatesDir, "kava", kavaConfigTemplate, "docker-compose.yaml"),
+	if err := mergeComposeYAML(
+		filepath.Join(template.Dir, "docker-compose.yaml"),
 		filepath.Join(generatedConfigDir, "docker-compose.yaml"),
+		remapPorts,
 	); err != nil {
 		return err
 	}
 
-	configTomlPath := path.Join(generatedConfigDir, "kava", "initstate", ".kava", "config", "config.toml")
+	configTomlPath := path.Join(generatedConfigDir, "kava", "initstate", template.Home, "config", "config.toml")
 	return changeConfigTomlDbBackend(configTomlPath, dbBackend)
 }
 
-func GenerateBnbConfig(generatedConfigDir string) error {
-	// copy templates into generated config folder
-	err := copy.Copy(filepath.Join(ConfigTemplatesDir, "binance/v0.8"), filepath.Join(generatedConfigDir, "binance"))
+func GenerateBnbConfig(generatedConfigDir string, remapPorts bool) error {
+	templateDir, err := findTemplate("binance/v0.8")
 	if err != nil {
 		return err
 	}
-
-	// put together final compose file
-	err = overwriteMergeYAML(
-		filepath.Join(ConfigTemplatesDir, "binance/v0.8/docker-compose.yaml"),
-		filepath.Join(generatedConfigDir, "docker-compose.yaml"),
-	)
-	return err
-}
-
-func GenerateDeputyConfig(generatedConfigDir string) error {
 	// copy templates into generated config folder
-	err := copy.Copy(filepath.Join(ConfigTemplatesDir, "deputy"), filepath.Join(generatedConfigDir, "deputy"))
+	err = copy.Copy(templateDir, filepath.Join(generatedConfigDir, "binance"))
 	if err != nil {
 		return err
 	}
 
 	// put together final compose file
-	err = overwriteMergeYAML(
-		filepath.Join(ConfigTemplatesDir, "deputy/docker-compose.yaml"),
+	err = mergeComposeYAML(
+		filepath.Join(templateDir, "docker-compose.yaml"),
 		filepath.Join(generatedConfigDir, "docker-compose.yaml"),
+		remapPorts,
 	)
 	return err
 }
 
-func GenerateGethConfig(generatedConfigDir string) error {
+func GenerateDeputyConfig(generatedConfigDir string, remapPorts bool) error {
+	templateDir, err := findTemplate("deputy")
+	if err != nil {
+		return err
+	}
 	// copy templates into generated config folder
-	err := copy.Copy(filepath.Join(ConfigTemplatesDir, "geth"), filepath.Join(generatedConfigDir, "geth"))
+	err = copy.Copy(templateDir, filepath.Join(generatedConfigDir, "deputy"))
 	if err != nil {
 		return err
 	}
 
 	// put together final compose file
-	err = overwriteMergeYAML(
-		filepath.Join(ConfigTemplatesDir, "geth", "docker-compose.yaml"),
+	err = mergeComposeYAML(
+		filepath.Join(templateDir, "docker-compose.yaml"),
 		filepath.Join(generatedConfigDir, "docker-compose.yaml"),
+		remapPorts,
 	)
 	return err
 }
 
-// GenerateIbcConfigs calls all necessary generation funcs for setting up the ibcchain & relayer
-func GenerateIbcConfigs(generatedConfigDir string) error {
-	if err := GenerateIbcChainConfig(generatedConfigDir); err != nil {
+func GenerateGethConfig(generatedConfigDir string, remapPorts bool) error {
+	templateDir, err := findTemplate("geth")
+	if err != nil {
 		return err
 	}
-	return GenerateRelayerConfig(generatedConfigDir)
-}
-
-func GenerateIbcChainConfig(generatedConfigDir string) error {
 	// copy templates into generated config folder
-	err := copy.Copy(filepath.Join(ConfigTemplatesDir, "ibcchain", "master"), filepath.Join(generatedConfigDir, "ibcchain"))
+	err = copy.Copy(templateDir, filepath.Join(generatedConfigDir, "geth"))
 	if err != nil {
 		return err
 	}
 
 	// put together final compose file
-	err = overwriteMergeYAML(
-		filepath.Join(ConfigTemplatesDir, "ibcchain", "master", "docker-compose.yaml"),
+	err = mergeComposeYAML(
+		filepath.Join(templateDir, "docker-compose.yaml"),
 		filepath.Join(generatedConfigDir, "docker-compose.yaml"),
+		remapPorts,
 	)
 	return err
 }
 
-func AddRelayerToNetwork(generatedConfigDir string) error {
-	return overwriteMergeYAML(
-		filepath.Join(ConfigTemplatesDir, "relayer", "docker-compose.yaml"),
+// AddRelayerToNetwork adds the service of a relayer template, eg. relayer or hermes, to the generated config
+func AddRelayerToNetwork(generatedConfigDir, relayerTemplate string, remapPorts bool) error {
+	templateDir, err := findTemplate(relayerTemplate)
+	if err != nil {
+		return err
+	}
+	return mergeComposeYAML(
+		filepath.Join(templateDir, "docker-compose.yaml"),
 		filepath.Join(generatedConfigDir, "docker-compose.yaml"),
+		remapPorts,
 	)
 }
 
-func GenerateRelayerConfig(generatedConfigDir string) error {
-	err := copy.Copy(
-		filepath.Join(ConfigTemplatesDir, "relayer"),
-		filepath.Join(generatedConfigDir, "relayer"),
-		copy.Options{AddPermission: 0666},
-	)
-	return err
-}
-
-func GenerateKavaPruningConfig(kavaConfigTemplate, generatedConfigDir, dbBackend string) error {
-	pruningTemplateDir := filepath.Join(ConfigTemplatesDir, "kava", "pruning-node")
+// GenerateKavaPruningConfig adds a pruning node syncing from the kava validators. Its genesis is copied from the
+// validators by CopyKavaGenesisToPruningNode once their genesis is final.
+func GenerateKavaPruningConfig(kavaConfigTemplate, generatedConfigDir, dbBackend string, remapPorts bool) error {
+	template, err := LoadKavaTemplate(kavaConfigTemplate)
+	if err != nil {
+		return err
+	}
+	if err := template.CheckUsage(TemplateUsage{DbBackend: dbBackend, Pruning: true}); err != nil {
+		return err
+	}
+	pruningTemplateDir, err := findTemplate(filepath.Join("kava", "pruning-node"))
+	if err != nil {
+		return err
+	}
 	serviceDir := filepath.Join(generatedConfigDir, "kava-pruning")
 	// copy configuration files
 	if err := copy.Copy(
@@ -144,22 +152,6 @@ func GenerateKavaPruningConfig(kavaConfigTemplate, generatedConfigDir, dbBackend
 		return err
 	}
 
-	// copy genesis file from kava template
-	if err := copy.Copy(
-		filepath.Join(ConfigTemplatesDir, "kava", kavaConfigTemplate, "initstate", ".kava", "config", "genesis.json"),
-		filepath.Join(serviceDir, "shared", "genesis.json"),
-	); err != nil {
-		return err
-	}
-
-	// get kava template's image tag
-	image, err := extractDockerComposeImage(
-		filepath.Join(ConfigTemplatesDir, "kava", kavaConfigTemplate, "docker-compose.yaml"),
-	)
-	if err != nil {
-		return err
-	}
-
 	// read template's docker-compose file
 	content, err := os.ReadFile(filepath.Join(pruningTemplateDir, "docker-compose.yaml"))
 	if err != nil {
@@ -167,7 +159,7 @@ func GenerateKavaPruningConfig(kavaConfigTemplate, generatedConfigDir, dbBackend
 	}
 
 	// replace image tag in template's docker-compose
-	updatedDockerCompose := strings.ReplaceAll(string(content), "KAVA_IMAGE_TAG_REPLACED_BY_KVTOOL_HERE", image)
+	updatedDockerCompose := strings.ReplaceAll(string(content), "KAVA_IMAGE_TAG_REPLACED_BY_KVTOOL_HERE", template.ImageRef())
 
 	// save docker-compose
 	if err := os.WriteFile(filepath.Join(serviceDir, "docker-compose.yaml"),
@@ -176,9 +168,10 @@ func GenerateKavaPruningConfig(kavaConfigTemplate, generatedConfigDir, dbBackend
 	}
 
 	// put together final compose file
-	if err := overwriteMergeYAML(
+	if err := mergeComposeYAML(
 		filepath.Join(serviceDir, "docker-compose.yaml"),
 		filepath.Join(generatedConfigDir, "docker-compose.yaml"),
+		remapPorts,
 	); err != nil {
 		return err
 	}
@@ -187,42 +180,20 @@ func GenerateKavaPruningConfig(kavaConfigTemplate, generatedConfigDir, dbBackend
 	return changeConfigTomlDbBackend(configTomlPath, dbBackend)
 }
 
-func extractDockerComposeImage(dockerComposeFilePath string) (string, error) {
-	// read docker-compose contents
-	content, err := os.ReadFile(dockerComposeFilePath)
+// CopyKavaGenesisToPruningNode copies the genesis of the kava validators to the pruning node, so it syncs from them.
+// The validators' genesis is the only source of the pruning node's genesis, so this must run once it's final, ie. after
+// the additional validators & --genesis are generated.
+func CopyKavaGenesisToPruningNode(kavaConfigTemplate, generatedConfigDir string) error {
+	template, err := LoadKavaTemplate(kavaConfigTemplate)
 	if err != nil {
-		return "", fmt.Errorf("error opening docker-compose file (%s): %s", dockerComposeFilePath, err)
-	}
-
-	// extract image & tag
-	matches := regexp.MustCompile(`image:\s*"(.*)"`).FindStringSubmatch(string(content))
-	if len(matches) != 2 {
-		return "", fmt.Errorf("expected exactly one line with 'image:' in %s, found %+v", dockerComposeFilePath, matches)
-	}
-
-	dockerImage := strings.TrimSpace(matches[1])
-	if dockerImage == "" {
-		return "", fmt.Errorf("empty image found in %s", dockerComposeFilePath)
+		return err
 	}
-
-	return dockerImage, nil
+	return copy.Copy(
+		filepath.Join(kavaConfigDir(generatedConfigDir, template.Home, 1), "genesis.json"),
+		filepath.Join(generatedConfigDir, "kava-pruning", "shared", "genesis.json"),
+	)
 }
 
 func changeConfigTomlDbBackend(configTomlPath, db string) error {
-	// load the chain's config.toml
-	content, err := os.ReadFile(configTomlPath)
-	if err != nil {
-		return err
-	}
-
-	// replace the db_backend line with new db
-	lines := strings.Split(string(content), "\n")
-	for i, line := range lines {
-		if strings.HasPrefix(line, "db_backend =") {
-			lines[i] = fmt.Sprintf("db_backend = \"%s\"", db)
-			break
-		}
-	}
-
-	return os.WriteFile(configTomlPath, []byte(strings.Join(lines, "\n")), 0644)
+	return setTomlFileValues(configTomlPath, []ConfigOverride{{Key: "db_backend", Value: db}})
 }
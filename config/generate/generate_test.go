@@ -0,0 +1,42 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateKavaPruningConfigUsesValidatorsGenesis(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, GenerateKavaConfig("master", dir, "goleveldb", false))
+	require.NoError(t, GenerateKavaValidatorsConfig("master", dir, 3))
+	require.NoError(t, GenerateKavaPruningConfig("master", dir, "goleveldb", false))
+	require.NoError(t, CopyKavaGenesisToPruningNode("master", dir))
+
+	validatorGenesis, err := os.ReadFile(filepath.Join(dir, "kava", "initstate", ".kava", "config", "genesis.json"))
+	require.NoError(t, err)
+	pruningGenesis, err := os.ReadFile(filepath.Join(dir, "kava-pruning", "shared", "genesis.json"))
+	require.NoError(t, err)
+	require.Equal(t, string(validatorGenesis), string(pruningGenesis))
+
+	templateGenesis, err := os.ReadFile(filepath.Join(ConfigTemplatesDir, "kava", "master", "initstate", ".kava", "config", "genesis.json"))
+	require.NoError(t, err)
+	require.NotEqual(t, string(templateGenesis), string(pruningGenesis), "the validators' gentxs should be in the genesis")
+}
+
+func TestCopyKavaGenesisToPruningNodeUsesFinalGenesis(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, GenerateKavaConfig("master", dir, "goleveldb", false))
+	require.NoError(t, GenerateKavaPruningConfig("master", dir, "goleveldb", false))
+	// eg. the validators' genesis is replaced by --genesis after the pruning node is generated
+	require.NoError(t, GenerateKavaValidatorsConfig("master", dir, 2))
+	require.NoError(t, CopyKavaGenesisToPruningNode("master", dir))
+
+	validatorGenesis, err := os.ReadFile(filepath.Join(dir, "kava2", "initstate", ".kava", "config", "genesis.json"))
+	require.NoError(t, err)
+	pruningGenesis, err := os.ReadFile(filepath.Join(dir, "kava-pruning", "shared", "genesis.json"))
+	require.NoError(t, err)
+	require.Equal(t, string(validatorGenesis), string(pruningGenesis))
+}
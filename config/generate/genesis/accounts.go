@@ -0,0 +1,87 @@
+package genesis
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+// whaleFunds are the funds given to the whale accounts. $valoper is replaced with the validator's operator address.
+const whaleFunds = "1000000000000ukava,10000000000000000bkava-$valoper,10000000000000000bnb,10000000000000000btcb,10000000000000000busd,1000000000000000000hard,1000000000000000000swp,10000000000000000usdx,10000000000000000xrpb"
+
+// templateAccount is an account from addresses.json that is funded in the template genesis
+type templateAccount struct {
+	// variable is the name the address is exported as for substitution in the json fragments
+	variable string
+	// path of the account in addresses.json
+	path string
+	// keyName is the name of the account in the keyring. if empty, the account is not added to the keyring.
+	keyName string
+	// eth accounts use coin type 60 & ethermint's eth_secp256k1 signing algorithm
+	eth   bool
+	funds string
+}
+
+// templateAccounts are the accounts funded in genesis, in the order they are funded
+var templateAccounts = []templateAccount{
+	// bep3 deputies
+	{"bnb_cold", "kava.deputys.bnb.cold_wallet", "deputy-bnb-cold", false, "1000000000000ukava"},
+	{"bnb_deputy", "kava.deputys.bnb.hot_wallet", "deputy-bnb-hot", false, "1000000000000ukava"},
+	{"btcb_cold", "kava.deputys.btcb.cold_wallet", "deputy-btcb-cold", false, "1000000000000ukava"},
+	{"btcb_deputy", "kava.deputys.btcb.hot_wallet", "deputy-btcb-hot", false, "1000000000000ukava"},
+	{"xrpb_cold", "kava.deputys.xrpb.cold_wallet", "deputy-xrpb-cold", false, "1000000000000ukava"},
+	{"xrpb_deputy", "kava.deputys.xrpb.hot_wallet", "deputy-xrpb-hot", false, "1000000000000ukava"},
+	{"busd_cold", "kava.deputys.busd.cold_wallet", "deputy-busd-cold", false, "1000000000000ukava"},
+	{"busd_deputy", "kava.deputys.busd.hot_wallet", "deputy-busd-hot", false, "1000000000000ukava"},
+	// users
+	{"generic_0", "kava.users.generic_0", "generic-0", false, "1000000000000ukava"},
+	{"generic_1", "kava.users.generic_1", "generic-1", false, "1000000000000ukava"},
+	{"generic_2", "kava.users.generic_2", "generic-2", false, "1000000000000ukava"},
+	{"vesting_periodic", "kava.users.vesting_periodic", "vesting-periodic", false, "10000000000ukava"},
+	{"user", "kava.users.user", "user", true, "1000000000ukava"},
+	{"whale", "kava.users.whale", "whale", false, whaleFunds},
+	// another whale, but setup as EthAccount
+	{"whale2", "kava.users.whale2", "whale2", true, whaleFunds},
+	// dev-wallet! key is in 1pass.
+	{"devwallet", "kava.users.dev_wallet", "", false, whaleFunds},
+	// misc
+	{"oracle", "kava.oracles.0", "oracle", false, "1000000000000ukava"},
+	{"committee", "kava.committee_members.0", "committee", true, "1000000000000ukava"},
+	{"bridge_relayer", "kava.users.bridge_relayer", "bridge_relayer", true, "1000000000000ukava"},
+}
+
+// moduleBalances fund accounts that have no keys.
+var moduleBalances = []struct {
+	address string
+	funds   string
+}{
+	// issuance module
+	{"kava1cj7njkw2g9fqx4e768zc75dp9sks8u9znxrf0w", "1000000000000ukava,1000000000000swp,1000000000000hard"},
+	// swap module
+	{"kava1mfru9azs5nua2wxcd4sq64g5nt7nn4n8s2w8cu", "5000000000ukava,200000000btcb,1000000000hard,5000000000swp,103000000000usdx"},
+	// fractional balance test account used for x/precisebank development and
+	// must match total value of evmutil.accounts[].balance (2ukava)
+	{"kava1w9vxuke5dz6hyza2j932qgmxltnfxwl78u920k", "2ukava"},
+}
+
+// addressBook is the parsed contents of config/common/addresses.json
+type addressBook struct {
+	*gabs.Container
+}
+
+func loadAddressBook(path string) (addressBook, error) {
+	container, err := gabs.ParseJSONFile(path)
+	if err != nil {
+		return addressBook{}, fmt.Errorf("failed to load addresses: %w", err)
+	}
+	return addressBook{container}, nil
+}
+
+// lookup returns the value of field for the account at the dot separated path, eg "kava.users.whale"
+func (a addressBook) lookup(path, field string) (string, error) {
+	value, ok := a.Path(path + "." + field).Data().(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("no %s found for %s in addresses", field, path)
+	}
+	return value, nil
+}
@@ -0,0 +1,331 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	ethermint "github.com/evmos/ethermint/types"
+	"gopkg.in/yaml.v3"
+)
+
+// AccountGrant funds an account in a genesis, creating it if it doesn't exist
+type AccountGrant struct {
+	// Address is a kava bech32 address or a 0x evm address
+	Address string `yaml:"address"`
+	// Coins are added to the account's balance, eg. 1000000ukava,10hard
+	Coins string `yaml:"coins"`
+	// Eth creates new accounts as an EthAccount. Defaults to true for 0x addresses without vesting.
+	Eth *bool `yaml:"eth,omitempty"`
+	// Vesting creates new accounts as a periodic vesting account
+	Vesting *VestingSchedule `yaml:"vesting,omitempty"`
+}
+
+// VestingSchedule is the vesting of a periodic vesting account. The coins of the periods must be part of the grant's coins.
+type VestingSchedule struct {
+	// Start is when vesting starts. Defaults to the genesis time.
+	Start   *time.Time      `yaml:"start,omitempty"`
+	Periods []VestingPeriod `yaml:"periods"`
+}
+
+// VestingPeriod releases coins after length has passed since the previous period
+type VestingPeriod struct {
+	Length time.Duration `yaml:"length"`
+	Coins  string        `yaml:"coins"`
+}
+
+// AddAccountsResult counts how the grants of AddAccounts were applied
+type AddAccountsResult struct {
+	Created  int
+	ToppedUp int
+	Skipped  int
+}
+
+// LoadAccountGrants reads a yaml or json file with a list of grants under accounts, eg.
+//
+//	accounts:
+//	  - address: kava1...
+//	    coins: 1000000ukava
+//	  - address: 0x...
+//	    coins: 1000000ukava
+//	    vesting:
+//	      periods:
+//	        - { length: 720h, coins: 500000ukava }
+func LoadAccountGrants(path string) ([]AccountGrant, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accounts: %w", err)
+	}
+	var file struct {
+		Accounts []AccountGrant `yaml:"accounts"`
+	}
+	// json is valid yaml, so both are decoded as yaml
+	decoder := yaml.NewDecoder(bytes.NewReader(bz))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse accounts %s: %w", path, err)
+	}
+	if len(file.Accounts) == 0 {
+		return nil, fmt.Errorf("no accounts found in %s", path)
+	}
+	return file.Accounts, nil
+}
+
+// AddAccounts funds the accounts of grants. Accounts that don't exist are created with the next free account numbers.
+// Existing accounts have the coins added to their balance, or are left as is if skipExisting.
+// The bank supply of every denom granted is increased, unless the supply is empty & calculated by x/bank.
+func (g *RawGenesis) AddAccounts(cdc codec.JSONCodec, grants []AccountGrant, skipExisting bool) (AddAccountsResult, error) {
+	var result AddAccountsResult
+
+	var accounts []json.RawMessage
+	if err := g.unmarshalModuleField(authtypes.ModuleName, "accounts", &accounts); err != nil {
+		return result, err
+	}
+	existing, nextAccountNumber, err := indexAccounts(accounts)
+	if err != nil {
+		return result, err
+	}
+	var balances []json.RawMessage
+	if err := g.unmarshalModuleField(banktypes.ModuleName, "balances", &balances); err != nil {
+		return result, err
+	}
+	balanceIndexes, err := indexBalances(balances)
+	if err != nil {
+		return result, err
+	}
+	genesisTime, err := g.genesisTime()
+	if err != nil {
+		return result, err
+	}
+
+	added := sdk.NewCoins()
+	for _, grant := range grants {
+		address, isEvmAddress, err := parseAccountAddress(grant.Address)
+		if err != nil {
+			return result, err
+		}
+		coins, err := sdk.ParseCoinsNormalized(grant.Coins)
+		if err != nil {
+			return result, fmt.Errorf("invalid coins for %s: %w", grant.Address, err)
+		}
+
+		if existing[address.String()] {
+			if skipExisting {
+				result.Skipped++
+				continue
+			}
+			if grant.Vesting != nil {
+				return result, fmt.Errorf("%s already exists, vesting can only be added to new accounts", grant.Address)
+			}
+			result.ToppedUp++
+		} else {
+			eth := isEvmAddress && grant.Vesting == nil
+			if grant.Eth != nil {
+				eth = *grant.Eth
+			}
+			account, err := newGenesisAccount(address, nextAccountNumber, coins, eth, grant.Vesting, genesisTime)
+			if err != nil {
+				return result, fmt.Errorf("invalid account %s: %w", grant.Address, err)
+			}
+			accountJSON, err := cdc.MarshalInterfaceJSON(account)
+			if err != nil {
+				return result, fmt.Errorf("failed to marshal account %s: %w", address, err)
+			}
+			accounts = append(accounts, accountJSON)
+			existing[address.String()] = true
+			nextAccountNumber++
+			result.Created++
+		}
+
+		if coins.Empty() {
+			continue
+		}
+		balance := banktypes.Balance{Address: address.String(), Coins: coins}
+		i, found := balanceIndexes[address.String()]
+		if found {
+			var current banktypes.Balance
+			if err := cdc.UnmarshalJSON(balances[i], &current); err != nil {
+				return result, fmt.Errorf("failed to unmarshal balance of %s: %w", address, err)
+			}
+			balance.Coins = current.Coins.Add(coins...)
+		} else {
+			i = len(balances)
+			balances = append(balances, nil)
+			balanceIndexes[address.String()] = i
+		}
+		if balances[i], err = cdc.MarshalJSON(&balance); err != nil {
+			return result, fmt.Errorf("failed to marshal balance of %s: %w", address, err)
+		}
+		added = added.Add(coins...)
+	}
+
+	if err := g.setModuleField(authtypes.ModuleName, "accounts", accounts); err != nil {
+		return result, err
+	}
+	if err := g.setModuleField(banktypes.ModuleName, "balances", balances); err != nil {
+		return result, err
+	}
+	return result, g.addSupply(added)
+}
+
+// GrantBalance adds coins to the balance of address. If the address has no account, a base account is created.
+func (g *RawGenesis) GrantBalance(cdc codec.JSONCodec, address sdk.AccAddress, coins sdk.Coins) error {
+	eth := false
+	_, err := g.AddAccounts(cdc, []AccountGrant{{Address: address.String(), Coins: coins.String(), Eth: &eth}}, false)
+	return err
+}
+
+// newGenesisAccount creates a base, eth or periodic vesting account
+func newGenesisAccount(
+	address sdk.AccAddress,
+	accountNumber uint64,
+	coins sdk.Coins,
+	eth bool,
+	vesting *VestingSchedule,
+	genesisTime time.Time,
+) (authtypes.GenesisAccount, error) {
+	base := authtypes.NewBaseAccount(address, nil, accountNumber, 0)
+	if vesting == nil {
+		if eth {
+			return &ethermint.EthAccount{
+				BaseAccount: base,
+				CodeHash:    common.BytesToHash(crypto.Keccak256(nil)).String(),
+			}, nil
+		}
+		return base, nil
+	}
+
+	if eth {
+		return nil, fmt.Errorf("vesting accounts can't be eth accounts")
+	}
+	if len(vesting.Periods) == 0 {
+		return nil, fmt.Errorf("vesting requires at least one period")
+	}
+	start := genesisTime
+	if vesting.Start != nil {
+		start = *vesting.Start
+	}
+	periods := make(vestingtypes.Periods, len(vesting.Periods))
+	originalVesting := sdk.NewCoins()
+	for i, p := range vesting.Periods {
+		if p.Length <= 0 {
+			return nil, fmt.Errorf("vesting period %d must have a positive length", i+1)
+		}
+		periodCoins, err := sdk.ParseCoinsNormalized(p.Coins)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coins for vesting period %d: %w", i+1, err)
+		}
+		periods[i] = vestingtypes.Period{Length: int64(p.Length.Seconds()), Amount: periodCoins}
+		originalVesting = originalVesting.Add(periodCoins...)
+	}
+	if !coins.IsAllGTE(originalVesting) {
+		return nil, fmt.Errorf("vesting %s is more than the granted coins %s", originalVesting, coins)
+	}
+	account := vestingtypes.NewPeriodicVestingAccount(base, originalVesting, start.Unix(), periods)
+	if err := account.Validate(); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// parseAccountAddress parses a kava bech32 or 0x evm address & returns whether it was an evm address
+func parseAccountAddress(address string) (sdk.AccAddress, bool, error) {
+	if strings.HasPrefix(address, "0x") {
+		if !common.IsHexAddress(address) {
+			return nil, false, fmt.Errorf("invalid evm address %q", address)
+		}
+		return sdk.AccAddress(common.HexToAddress(address).Bytes()), true, nil
+	}
+	accAddress, err := sdk.AccAddressFromBech32(address)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	return accAddress, false, nil
+}
+
+// indexAccounts returns the addresses of the json encoded accounts & the next free account number
+func indexAccounts(accounts []json.RawMessage) (map[string]bool, uint64, error) {
+	addresses := make(map[string]bool, len(accounts))
+	var nextAccountNumber uint64
+	for _, raw := range accounts {
+		base, err := unmarshalBaseAccount(raw)
+		if err != nil {
+			return nil, 0, err
+		}
+		addresses[base.Address] = true
+		if base.AccountNumber == "" {
+			continue
+		}
+		number, err := strconv.ParseUint(base.AccountNumber, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid account number %q: %w", base.AccountNumber, err)
+		}
+		if number >= nextAccountNumber {
+			nextAccountNumber = number + 1
+		}
+	}
+	return addresses, nextAccountNumber, nil
+}
+
+// indexBalances returns the index of each address in the json encoded balances
+func indexBalances(balances []json.RawMessage) (map[string]int, error) {
+	indexes := make(map[string]int, len(balances))
+	for i, raw := range balances {
+		var balance struct {
+			Address string `json:"address"`
+		}
+		if err := json.Unmarshal(raw, &balance); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal balance: %w", err)
+		}
+		indexes[balance.Address] = i
+	}
+	return indexes, nil
+}
+
+// genesisTime returns the genesis_time of the genesis
+func (g *RawGenesis) genesisTime() (time.Time, error) {
+	var t time.Time
+	if err := json.Unmarshal(g.doc["genesis_time"], &t); err != nil {
+		return t, fmt.Errorf("failed to unmarshal genesis_time: %w", err)
+	}
+	return t, nil
+}
+
+// baseAccount is the part of a json encoded account that's common to all account types
+type baseAccount struct {
+	Address       string `json:"address"`
+	AccountNumber string `json:"account_number"`
+}
+
+// unmarshalBaseAccount decodes the base account of a json encoded account.
+// The base account of eth, module & vesting accounts is nested.
+func unmarshalBaseAccount(raw json.RawMessage) (baseAccount, error) {
+	var account struct {
+		baseAccount
+		BaseAccount        *baseAccount `json:"base_account"`
+		BaseVestingAccount *struct {
+			BaseAccount *baseAccount `json:"base_account"`
+		} `json:"base_vesting_account"`
+	}
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return baseAccount{}, fmt.Errorf("failed to unmarshal account: %w", err)
+	}
+	if account.BaseAccount != nil {
+		return *account.BaseAccount, nil
+	}
+	if account.BaseVestingAccount != nil && account.BaseVestingAccount.BaseAccount != nil {
+		return *account.BaseVestingAccount.BaseAccount, nil
+	}
+	return account.baseAccount, nil
+}
@@ -0,0 +1,373 @@
+package genesis
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/ethereum/go-ethereum/common"
+	ethermint "github.com/evmos/ethermint/types"
+	"github.com/kava-labs/kava/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// testNewAddress has no account in the test genesis
+	testNewAddress = "kava1ypjp0m04pyp73hwgtc0dgkx0e9rrydecm054da"
+	// testEvmAddress has no account in the test genesis
+	testEvmAddress = "0x6767114FFAA17C6439D7AEA480738B982CE63A02"
+	// testFundedAddress is a base account of the test genesis with a balance of 1000000000ukava
+	testFundedAddress = "kava14w4avgdvqrlpww6l5dhgj4egfn6ln7gmxhytjv"
+	// testUnfundedAddress is an eth account of the test genesis without a balance
+	testUnfundedAddress = "kava1seqjrgakfgzasu5g8gwsmgzwacg85e8nnxeqxl"
+	// testNextAccountNumber is the account number after the highest of the test genesis
+	testNextAccountNumber = 24
+)
+
+// testGenesisAccounts returns the accounts of the genesis by address
+func testGenesisAccounts(t *testing.T, gen *RawGenesis) map[string]authtypes.GenesisAccount {
+	t.Helper()
+	var authState authtypes.GenesisState
+	require.NoError(t, app.MakeEncodingConfig().Marshaler.UnmarshalJSON(gen.AppState[authtypes.ModuleName], &authState))
+	accounts, err := authtypes.UnpackAccounts(authState.Accounts)
+	require.NoError(t, err)
+	byAddress := make(map[string]authtypes.GenesisAccount, len(accounts))
+	for _, account := range accounts {
+		byAddress[account.GetAddress().String()] = account
+	}
+	return byAddress
+}
+
+// testBankState returns the balances of the genesis by address & its supply
+func testBankState(t *testing.T, gen *RawGenesis) (map[string]sdk.Coins, sdk.Coins) {
+	t.Helper()
+	var bankState banktypes.GenesisState
+	require.NoError(t, app.MakeEncodingConfig().Marshaler.UnmarshalJSON(gen.AppState[banktypes.ModuleName], &bankState))
+	balances := make(map[string]sdk.Coins, len(bankState.Balances))
+	for _, balance := range bankState.Balances {
+		balances[balance.Address] = balance.Coins
+	}
+	return balances, bankState.Supply
+}
+
+// setTestSupply sets a ukava supply of 1000 on the genesis. The test genesis leaves the supply to x/bank, so tests of
+// updates that keep the supply in sync with the balances need one to be set.
+func setTestSupply(t *testing.T, gen *RawGenesis) {
+	t.Helper()
+	require.NoError(t, gen.SetAppStateValue("bank.supply", json.RawMessage(`[{"denom":"ukava","amount":"1000"}]`)))
+}
+
+func TestAddAccounts(t *testing.T) {
+	evmAddress := sdk.AccAddress(common.HexToAddress(testEvmAddress).Bytes()).String()
+	noEth := false
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		grants       []AccountGrant
+		skipExisting bool
+		// expectedResult counts the created, topped up & skipped accounts
+		expectedResult AddAccountsResult
+		// expectedTypes are the types of the accounts after the grants, by address
+		expectedTypes map[string]interface{}
+		// expectedBalances are the balances after the grants, by address
+		expectedBalances map[string]string
+		// expectedSupply is the supply after the grants, which starts as 1000ukava
+		expectedSupply string
+	}{
+		{
+			name:             "new bech32 address",
+			grants:           []AccountGrant{{Address: testNewAddress, Coins: "10ukava,5hard"}},
+			expectedResult:   AddAccountsResult{Created: 1},
+			expectedTypes:    map[string]interface{}{testNewAddress: &authtypes.BaseAccount{}},
+			expectedBalances: map[string]string{testNewAddress: "5hard,10ukava"},
+			expectedSupply:   "5hard,1010ukava",
+		},
+		{
+			name:             "new evm address",
+			grants:           []AccountGrant{{Address: testEvmAddress, Coins: "10ukava"}},
+			expectedResult:   AddAccountsResult{Created: 1},
+			expectedTypes:    map[string]interface{}{evmAddress: &ethermint.EthAccount{}},
+			expectedBalances: map[string]string{evmAddress: "10ukava"},
+			expectedSupply:   "1010ukava",
+		},
+		{
+			name:             "new evm address as a base account",
+			grants:           []AccountGrant{{Address: testEvmAddress, Coins: "10ukava", Eth: &noEth}},
+			expectedResult:   AddAccountsResult{Created: 1},
+			expectedTypes:    map[string]interface{}{evmAddress: &authtypes.BaseAccount{}},
+			expectedBalances: map[string]string{evmAddress: "10ukava"},
+			expectedSupply:   "1010ukava",
+		},
+		{
+			name: "new vesting account",
+			grants: []AccountGrant{{
+				Address: testEvmAddress,
+				Coins:   "10ukava",
+				Vesting: &VestingSchedule{Start: &start, Periods: []VestingPeriod{{Length: time.Hour, Coins: "4ukava"}, {Length: time.Hour, Coins: "6ukava"}}},
+			}},
+			expectedResult:   AddAccountsResult{Created: 1},
+			expectedTypes:    map[string]interface{}{evmAddress: &vestingtypes.PeriodicVestingAccount{}},
+			expectedBalances: map[string]string{evmAddress: "10ukava"},
+			expectedSupply:   "1010ukava",
+		},
+		{
+			name:             "new account without coins",
+			grants:           []AccountGrant{{Address: testNewAddress}},
+			expectedResult:   AddAccountsResult{Created: 1},
+			expectedTypes:    map[string]interface{}{testNewAddress: &authtypes.BaseAccount{}},
+			expectedBalances: map[string]string{testNewAddress: ""},
+			expectedSupply:   "1000ukava",
+		},
+		{
+			name:             "existing account is topped up",
+			grants:           []AccountGrant{{Address: testFundedAddress, Coins: "10ukava,5hard"}},
+			expectedResult:   AddAccountsResult{ToppedUp: 1},
+			expectedTypes:    map[string]interface{}{testFundedAddress: &authtypes.BaseAccount{}},
+			expectedBalances: map[string]string{testFundedAddress: "5hard,1000000010ukava"},
+			expectedSupply:   "5hard,1010ukava",
+		},
+		{
+			name:             "existing account without a balance",
+			grants:           []AccountGrant{{Address: testUnfundedAddress, Coins: "10ukava"}},
+			expectedResult:   AddAccountsResult{ToppedUp: 1},
+			expectedTypes:    map[string]interface{}{testUnfundedAddress: &ethermint.EthAccount{}},
+			expectedBalances: map[string]string{testUnfundedAddress: "10ukava"},
+			expectedSupply:   "1010ukava",
+		},
+		{
+			name: "existing accounts are skipped",
+			grants: []AccountGrant{
+				{Address: testFundedAddress, Coins: "10ukava"},
+				{Address: testNewAddress, Coins: "10ukava"},
+			},
+			skipExisting:     true,
+			expectedResult:   AddAccountsResult{Created: 1, Skipped: 1},
+			expectedBalances: map[string]string{testFundedAddress: "1000000000ukava", testNewAddress: "10ukava"},
+			expectedSupply:   "1010ukava",
+		},
+		{
+			name: "an address granted twice is created then topped up",
+			grants: []AccountGrant{
+				{Address: testNewAddress, Coins: "10ukava"},
+				{Address: testNewAddress, Coins: "5ukava,5hard"},
+			},
+			expectedResult:   AddAccountsResult{Created: 1, ToppedUp: 1},
+			expectedTypes:    map[string]interface{}{testNewAddress: &authtypes.BaseAccount{}},
+			expectedBalances: map[string]string{testNewAddress: "5hard,15ukava"},
+			expectedSupply:   "5hard,1015ukava",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, err := ReadRawGenesis(testGenesisPath)
+			require.NoError(t, err)
+			setTestSupply(t, gen)
+			accountsBefore := testGenesisAccounts(t, gen)
+
+			result, err := gen.AddAccounts(app.MakeEncodingConfig().Marshaler, tc.grants, tc.skipExisting)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedResult, result)
+
+			accounts := testGenesisAccounts(t, gen)
+			assert.Len(t, accounts, len(accountsBefore)+tc.expectedResult.Created)
+			for address, expectedType := range tc.expectedTypes {
+				require.Contains(t, accounts, address)
+				assert.IsType(t, expectedType, accounts[address])
+				if _, existed := accountsBefore[address]; !existed {
+					assert.Equal(t, uint64(testNextAccountNumber), accounts[address].GetAccountNumber())
+				}
+			}
+
+			balances, supply := testBankState(t, gen)
+			for address, expected := range tc.expectedBalances {
+				coins, err := sdk.ParseCoinsNormalized(expected)
+				require.NoError(t, err)
+				assert.Equal(t, coins.String(), balances[address].String(), address)
+			}
+			assert.Equal(t, tc.expectedSupply, supply.String())
+		})
+	}
+}
+
+func TestAddAccountsAccountNumbers(t *testing.T) {
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	grants := []AccountGrant{{Address: testNewAddress, Coins: "1ukava"}, {Address: testEvmAddress, Coins: "1ukava"}}
+	_, err = gen.AddAccounts(app.MakeEncodingConfig().Marshaler, grants, false)
+	require.NoError(t, err)
+
+	accounts := testGenesisAccounts(t, gen)
+	evmAddress := sdk.AccAddress(common.HexToAddress(testEvmAddress).Bytes()).String()
+	assert.Equal(t, uint64(testNextAccountNumber), accounts[testNewAddress].GetAccountNumber())
+	assert.Equal(t, uint64(testNextAccountNumber+1), accounts[evmAddress].GetAccountNumber())
+
+	// vesting starts at the genesis time by default
+	_, err = gen.AddAccounts(app.MakeEncodingConfig().Marshaler, []AccountGrant{{
+		Address: "kava1fy5zeuutmxzwcx5hncu5q83ug3zcqmxcpwrjsn",
+		Coins:   "10ukava",
+		Vesting: &VestingSchedule{Periods: []VestingPeriod{{Length: time.Hour, Coins: "10ukava"}}},
+	}}, false)
+	require.NoError(t, err)
+	account, ok := testGenesisAccounts(t, gen)["kava1fy5zeuutmxzwcx5hncu5q83ug3zcqmxcpwrjsn"].(*vestingtypes.PeriodicVestingAccount)
+	require.True(t, ok, "expected a periodic vesting account")
+	assert.Equal(t, time.Date(2022, 5, 25, 17, 0, 0, 0, time.UTC).Unix(), account.StartTime)
+	assert.Equal(t, account.StartTime+3600, account.EndTime)
+}
+
+func TestAddAccountsEmptySupply(t *testing.T) {
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	_, err = gen.AddAccounts(app.MakeEncodingConfig().Marshaler, []AccountGrant{{Address: testNewAddress, Coins: "10ukava"}}, false)
+	require.NoError(t, err)
+
+	// an empty supply is calculated by x/bank
+	_, supply := testBankState(t, gen)
+	assert.Empty(t, supply)
+}
+
+func TestAddAccountsErrors(t *testing.T) {
+	eth := true
+	vesting := &VestingSchedule{Periods: []VestingPeriod{{Length: time.Hour, Coins: "10ukava"}}}
+
+	testCases := []struct {
+		name   string
+		grant  AccountGrant
+		errMsg string
+	}{
+		{
+			name:   "invalid address",
+			grant:  AccountGrant{Address: "cosmos1ypjp0m04pyp73hwgtc0dgkx0e9rrydecm054da", Coins: "10ukava"},
+			errMsg: "invalid address",
+		},
+		{
+			name:   "invalid evm address",
+			grant:  AccountGrant{Address: "0x6767", Coins: "10ukava"},
+			errMsg: `invalid evm address "0x6767"`,
+		},
+		{
+			name:   "invalid coins",
+			grant:  AccountGrant{Address: testNewAddress, Coins: "ten ukava"},
+			errMsg: "invalid coins for " + testNewAddress,
+		},
+		{
+			name:   "vesting an existing account",
+			grant:  AccountGrant{Address: testFundedAddress, Coins: "10ukava", Vesting: vesting},
+			errMsg: "already exists, vesting can only be added to new accounts",
+		},
+		{
+			name:   "vesting eth account",
+			grant:  AccountGrant{Address: testNewAddress, Coins: "10ukava", Eth: &eth, Vesting: vesting},
+			errMsg: "vesting accounts can't be eth accounts",
+		},
+		{
+			name:   "vesting without periods",
+			grant:  AccountGrant{Address: testNewAddress, Coins: "10ukava", Vesting: &VestingSchedule{}},
+			errMsg: "vesting requires at least one period",
+		},
+		{
+			name:   "vesting more than the coins",
+			grant:  AccountGrant{Address: testNewAddress, Coins: "5ukava", Vesting: vesting},
+			errMsg: "vesting 10ukava is more than the granted coins 5ukava",
+		},
+		{
+			name: "vesting period without a length",
+			grant: AccountGrant{Address: testNewAddress, Coins: "10ukava", Vesting: &VestingSchedule{
+				Periods: []VestingPeriod{{Coins: "10ukava"}},
+			}},
+			errMsg: "vesting period 1 must have a positive length",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, err := ReadRawGenesis(testGenesisPath)
+			require.NoError(t, err)
+
+			_, err = gen.AddAccounts(app.MakeEncodingConfig().Marshaler, []AccountGrant{tc.grant}, false)
+			require.ErrorContains(t, err, tc.errMsg)
+		})
+	}
+}
+
+func TestLoadAccountGrants(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		expected []AccountGrant
+		errMsg   string
+	}{
+		{
+			name: "yaml",
+			contents: `accounts:
+  - address: kava1ypjp0m04pyp73hwgtc0dgkx0e9rrydecm054da
+    coins: 10ukava
+  - address: 0x6767114FFAA17C6439D7AEA480738B982CE63A02
+    coins: 10ukava
+    vesting:
+      periods:
+        - { length: 720h, coins: 5ukava }
+`,
+			expected: []AccountGrant{
+				{Address: testNewAddress, Coins: "10ukava"},
+				{Address: testEvmAddress, Coins: "10ukava", Vesting: &VestingSchedule{Periods: []VestingPeriod{{Length: 720 * time.Hour, Coins: "5ukava"}}}},
+			},
+		},
+		{
+			name:     "json",
+			contents: `{"accounts": [{"address": "kava1ypjp0m04pyp73hwgtc0dgkx0e9rrydecm054da", "coins": "10ukava"}]}`,
+			expected: []AccountGrant{{Address: testNewAddress, Coins: "10ukava"}},
+		},
+		{
+			name:     "unknown field",
+			contents: "accounts:\n  - address: " + testNewAddress + "\n    amount: 10ukava\n",
+			errMsg:   "field amount not found",
+		},
+		{
+			name:     "no accounts",
+			contents: "accounts: []\n",
+			errMsg:   "no accounts found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "accounts.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0644))
+
+			grants, err := LoadAccountGrants(path)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, grants)
+		})
+	}
+}
+
+func TestAddAccountsTransformer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("accounts:\n  - address: "+testNewAddress+"\n    coins: 10ukava\n"), 0644))
+
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+	// the grants of the file are applied before the inline grants
+	transformer := &AddAccountsTransformer{File: path, Accounts: []AccountGrant{{Address: testNewAddress, Coins: "5ukava"}}}
+	require.NoError(t, Pipeline{Transformers: []GenesisTransformer{transformer}}.Run(gen))
+	balances, _ := testBankState(t, gen)
+	assert.Equal(t, "15ukava", balances[testNewAddress].String())
+
+	err = Pipeline{Transformers: []GenesisTransformer{&AddAccountsTransformer{}}}.Run(gen)
+	require.ErrorContains(t, err, "no accounts to add")
+}
@@ -0,0 +1,180 @@
+package genesis
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/kava-labs/kava/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRepoDir                = "../../.."
+	testValidatorAddress       = "kava1ypjp0m04pyp73hwgtc0dgkx0e9rrydecm054da"
+	testCommitteeMemberAddress = "kava1r3emvfrc242jcfr9f9ygc34ztpp58xxu6spvdq"
+)
+
+var (
+	testMasterHomeDir = filepath.Join(testRepoDir, "config/templates/kava/master/initstate/.kava")
+	testIbcHomeDir    = filepath.Join(testRepoDir, "config/templates/ibcchain/master/initstate/.kava")
+)
+
+// newTestBuilder returns a builder of the template at homeDir. The fragments are those of the kava version of the
+// templates, which can be newer than the kava version kvtool is built with, so the template genesis is the base.
+func newTestBuilder(homeDir string) Builder {
+	builder := NewBuilder(testRepoDir, homeDir)
+	builder.BaseGenesisPath = filepath.Join(homeDir, "config", "genesis.json")
+	return builder
+}
+
+// requireGentxSigner checks the genesis has a single gentx, created by the template validator with amount
+func requireGentxSigner(t *testing.T, gen *RawGenesis, amount string) {
+	t.Helper()
+	gentxs, err := gen.Gentxs()
+	require.NoError(t, err)
+	require.Len(t, gentxs, 1)
+	var gentx struct {
+		Body struct {
+			Messages []struct {
+				DelegatorAddress string          `json:"delegator_address"`
+				Value            json.RawMessage `json:"value"`
+			} `json:"messages"`
+		} `json:"body"`
+	}
+	require.NoError(t, json.Unmarshal(gentxs[0], &gentx))
+	require.Len(t, gentx.Body.Messages, 1)
+	assert.Equal(t, testValidatorAddress, gentx.Body.Messages[0].DelegatorAddress)
+	assert.Contains(t, string(gentx.Body.Messages[0].Value), amount)
+}
+
+func TestBuilderBuild(t *testing.T) {
+	result, err := newTestBuilder(testMasterHomeDir).Build()
+	require.NoError(t, err)
+
+	chainID, err := result.Genesis.ChainID()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultChainID, chainID)
+	bondDenom, err := result.Genesis.BondDenom()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDenom, bondDenom)
+	assert.Equal(t, `"2024-01-01T00:00:00Z"`, string(result.Genesis.doc["genesis_time"]))
+	requireGentxSigner(t, result.Genesis, DefaultDenom)
+
+	// the template accounts are funded
+	var balances []struct {
+		Address string `json:"address"`
+	}
+	require.NoError(t, json.Unmarshal(result.Genesis.AppState["bank"], &struct {
+		Balances *[]struct {
+			Address string `json:"address"`
+		} `json:"balances"`
+	}{&balances}))
+	var addresses []string
+	for _, balance := range balances {
+		addresses = append(addresses, balance.Address)
+	}
+	assert.Contains(t, addresses, testValidatorAddress)
+	assert.Contains(t, addresses, testCommitteeMemberAddress)
+
+	// the gentx written to the home dir is the gentx of the genesis
+	homeDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(homeDir, "config"), 0755))
+	require.NoError(t, result.WriteHome(homeDir))
+	gentx, err := os.ReadFile(filepath.Join(homeDir, "config", "gentx", "gentx-"+string(result.NodeID)+".json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(result.Gentx), string(gentx))
+	written, err := ReadRawGenesis(filepath.Join(homeDir, "config", "genesis.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(result.Genesis.AppState["genutil"]), string(written.AppState["genutil"]))
+}
+
+func TestBuilderBuildChainIDAndDenom(t *testing.T) {
+	builder := newTestBuilder(testIbcHomeDir)
+	builder.ChainID = "kavalocalnet_8889-2"
+	builder.Denom = "uatom"
+	builder.SkipIncentives = true
+	result, err := builder.Build()
+	require.NoError(t, err)
+
+	chainID, err := result.Genesis.ChainID()
+	require.NoError(t, err)
+	assert.Equal(t, "kavalocalnet_8889-2", chainID)
+	bondDenom, err := result.Genesis.BondDenom()
+	require.NoError(t, err)
+	assert.Equal(t, "uatom", bondDenom)
+	requireGentxSigner(t, result.Genesis, "uatom")
+}
+
+func TestBuilderBuildMissingFiles(t *testing.T) {
+	_, err := NewBuilder(t.TempDir(), testMasterHomeDir).Build()
+	require.Error(t, err)
+
+	_, err = NewBuilder(testRepoDir, t.TempDir()).Build()
+	require.ErrorContains(t, err, "failed to load node key")
+}
+
+func TestBuilderRechain(t *testing.T) {
+	gen, err := ReadRawGenesis(filepath.Join(testIbcHomeDir, "config", "genesis.json"))
+	require.NoError(t, err)
+	builder := NewBuilder(testRepoDir, testIbcHomeDir)
+	builder.ChainID = "osmosislocal_1-1"
+	builder.Denom = "uosmo"
+
+	result, err := builder.Rechain(gen, "uatom")
+	require.NoError(t, err)
+
+	chainID, err := result.Genesis.ChainID()
+	require.NoError(t, err)
+	assert.Equal(t, "osmosislocal_1-1", chainID)
+	bondDenom, err := result.Genesis.BondDenom()
+	require.NoError(t, err)
+	assert.Equal(t, "uosmo", bondDenom)
+	requireGentxSigner(t, result.Genesis, "uosmo")
+
+	bz, err := json.Marshal(result.Genesis.AppState)
+	require.NoError(t, err)
+	assert.NotContains(t, string(bz), `"uatom"`)
+}
+
+func TestBuilderRechainInvalidDenom(t *testing.T) {
+	gen, err := ReadRawGenesis(filepath.Join(testIbcHomeDir, "config", "genesis.json"))
+	require.NoError(t, err)
+	builder := NewBuilder(testRepoDir, testIbcHomeDir)
+	builder.Denom = "u"
+
+	_, err = builder.Rechain(gen, "uatom")
+	require.Error(t, err)
+}
+
+func TestBuilderWriteKeyring(t *testing.T) {
+	homeDir := t.TempDir()
+	builder := NewBuilder(testRepoDir, testMasterHomeDir)
+	require.NoError(t, builder.WriteKeyring(homeDir))
+
+	kr, err := keyring.New("kava", keyring.BackendTest, homeDir, nil, app.MakeEncodingConfig().Marshaler)
+	require.NoError(t, err)
+	records, err := kr.List()
+	require.NoError(t, err)
+	names := make(map[string]string, len(records))
+	for _, record := range records {
+		address, err := record.GetAddress()
+		require.NoError(t, err)
+		names[record.Name] = address.String()
+	}
+
+	assert.Equal(t, testValidatorAddress, names["validator"])
+	assert.Equal(t, testCommitteeMemberAddress, names["committee"])
+	for _, account := range templateAccounts {
+		if account.keyName == "" {
+			assert.NotContains(t, names, account.keyName)
+			continue
+		}
+		assert.Contains(t, names, account.keyName)
+		assert.True(t, strings.HasPrefix(names[account.keyName], "kava1"))
+	}
+}
@@ -0,0 +1,81 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	committeetypes "github.com/kava-labs/kava/x/committee/types"
+)
+
+const (
+	// GodCommitteeDescription is the description of committees injected by InjectGodCommittee
+	GodCommitteeDescription = "Kava God Committee (testing only)"
+	// godCommitteeMemberFunds are given to the committee member if it has no account, to pay for proposals & votes
+	godCommitteeMemberFunds = "100000000000000ukava"
+	// godCommitteeProposalDuration is how long proposals to the god committee are open for
+	godCommitteeProposalDuration = 7 * 24 * time.Hour
+)
+
+// InjectGodCommittee adds a committee with a single member & permission to pass any proposal, eg. software upgrades.
+// The member's account is created & funded if it doesn't exist. It returns the id of the new committee.
+func InjectGodCommittee(gen *RawGenesis, cdc codec.JSONCodec, member string) (uint64, error) {
+	memberAddress, err := sdk.AccAddressFromBech32(member)
+	if err != nil {
+		return 0, fmt.Errorf("invalid committee member address: %w", err)
+	}
+
+	// fund the member to pay for proposals & votes, unless it already has an account
+	eth := false
+	grant := AccountGrant{Address: member, Coins: godCommitteeMemberFunds, Eth: &eth}
+	if _, err := gen.AddAccounts(cdc, []AccountGrant{grant}, true); err != nil {
+		return 0, err
+	}
+
+	var committees []struct {
+		BaseCommittee struct {
+			ID string `json:"id"`
+		} `json:"base_committee"`
+	}
+	if err := gen.unmarshalModuleField(committeetypes.ModuleName, "committees", &committees); err != nil {
+		return 0, err
+	}
+	var rawCommittees []json.RawMessage
+	if err := gen.unmarshalModuleField(committeetypes.ModuleName, "committees", &rawCommittees); err != nil {
+		return 0, err
+	}
+	nextID := uint64(1)
+	for _, committee := range committees {
+		id, err := strconv.ParseUint(committee.BaseCommittee.ID, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid committee id %q: %w", committee.BaseCommittee.ID, err)
+		}
+		if id >= nextID {
+			nextID = id + 1
+		}
+	}
+
+	godCommittee, err := committeetypes.NewMemberCommittee(
+		nextID,
+		GodCommitteeDescription,
+		[]sdk.AccAddress{memberAddress},
+		[]committeetypes.Permission{&committeetypes.GodPermission{}},
+		sdk.MustNewDecFromStr("0.667000000000000000"),
+		godCommitteeProposalDuration,
+		committeetypes.TALLY_OPTION_FIRST_PAST_THE_POST,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create god committee: %w", err)
+	}
+	committeeJSON, err := cdc.MarshalInterfaceJSON(godCommittee)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal god committee: %w", err)
+	}
+	if err := gen.setModuleField(committeetypes.ModuleName, "committees", append(rawCommittees, committeeJSON)); err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
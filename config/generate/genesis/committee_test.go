@@ -0,0 +1,132 @@
+package genesis
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/kava-labs/kava/app"
+	committeetypes "github.com/kava-labs/kava/x/committee/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testGenesisPath               = "./testdata/test_genesis.json"
+	testGodCommitteeMemberAddress = "kava1fy5zeuutmxzwcx5hncu5q83ug3zcqmxcpwrjsn"
+)
+
+func init() {
+	// Configure cosmos-sdk with kava overrides
+	app.SetSDKConfig()
+}
+
+func TestInjectGodCommitteeAddsFundedMember(t *testing.T) {
+	cdc := app.MakeEncodingConfig().Marshaler
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	var committeeState committeetypes.GenesisState
+	require.NoError(t, cdc.UnmarshalJSON(gen.AppState[committeetypes.ModuleName], &committeeState))
+	existingNumberOfCommittees := len(committeeState.Committees)
+
+	var bankState banktypes.GenesisState
+	require.NoError(t, cdc.UnmarshalJSON(gen.AppState[banktypes.ModuleName], &bankState))
+	initialSupply := bankState.Supply.AmountOf("ukava")
+
+	id, err := InjectGodCommittee(gen, cdc, testGodCommitteeMemberAddress)
+	require.NoError(t, err)
+
+	// the committee state should still be decodable, with 1 more committee than before
+	committeeState = committeetypes.GenesisState{}
+	require.NoError(t, cdc.UnmarshalJSON(gen.AppState[committeetypes.ModuleName], &committeeState))
+	require.Len(t, committeeState.Committees, existingNumberOfCommittees+1)
+
+	// the new committee is a god committee with the member
+	godCommittee, ok := committeeState.Committees[existingNumberOfCommittees].GetCachedValue().(*committeetypes.MemberCommittee)
+	require.True(t, ok, "expected a member committee")
+	assert.Equal(t, id, godCommittee.ID)
+	assert.Equal(t, GodCommitteeDescription, godCommittee.Description)
+	assert.Contains(t, godCommittee.Members, sdk.MustAccAddressFromBech32(testGodCommitteeMemberAddress))
+	require.Len(t, godCommittee.Permissions, 1)
+	assert.IsType(t, &committeetypes.GodPermission{}, godCommittee.Permissions[0].GetCachedValue())
+	for _, committee := range committeeState.Committees[:existingNumberOfCommittees] {
+		assert.Less(t, committee.GetCachedValue().(committeetypes.Committee).GetID(), id, "expected the id to be unused")
+	}
+
+	// the member should have an account
+	var authState authtypes.GenesisState
+	require.NoError(t, cdc.UnmarshalJSON(gen.AppState[authtypes.ModuleName], &authState))
+	accounts, err := authtypes.UnpackAccounts(authState.Accounts)
+	require.NoError(t, err)
+	var memberAccountExists bool
+	for _, account := range accounts {
+		if account.GetAddress().String() == testGodCommitteeMemberAddress {
+			memberAccountExists = true
+		}
+	}
+	assert.True(t, memberAccountExists, "expected member's account to be created")
+
+	// with a balance that's included in the supply, if the genesis defines one. an empty supply is calculated by x/bank
+	funds, err := sdk.ParseCoinsNormalized(godCommitteeMemberFunds)
+	require.NoError(t, err)
+	bankState = banktypes.GenesisState{}
+	require.NoError(t, cdc.UnmarshalJSON(gen.AppState[banktypes.ModuleName], &bankState))
+	var memberBalance sdk.Coins
+	for _, balance := range bankState.Balances {
+		if balance.Address == testGodCommitteeMemberAddress {
+			memberBalance = balance.Coins
+		}
+	}
+	assert.Equal(t, funds, memberBalance)
+	if initialSupply.IsZero() {
+		assert.Empty(t, bankState.Supply)
+	} else {
+		assert.Equal(t, initialSupply.Add(funds.AmountOf("ukava")), bankState.Supply.AmountOf("ukava"))
+	}
+}
+
+func TestInjectGodCommitteeIncreasesSupply(t *testing.T) {
+	cdc := app.MakeEncodingConfig().Marshaler
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+	setTestSupply(t, gen)
+
+	_, err = InjectGodCommittee(gen, cdc, testGodCommitteeMemberAddress)
+	require.NoError(t, err)
+
+	funds, err := sdk.ParseCoinsNormalized(godCommitteeMemberFunds)
+	require.NoError(t, err)
+	var bankState banktypes.GenesisState
+	require.NoError(t, cdc.UnmarshalJSON(gen.AppState[banktypes.ModuleName], &bankState))
+	assert.Equal(t, sdk.NewInt(1000).Add(funds.AmountOf("ukava")), bankState.Supply.AmountOf("ukava"))
+}
+
+func TestInjectGodCommitteeExistingMember(t *testing.T) {
+	cdc := app.MakeEncodingConfig().Marshaler
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	first, err := InjectGodCommittee(gen, cdc, testGodCommitteeMemberAddress)
+	require.NoError(t, err)
+	var bankState banktypes.GenesisState
+	require.NoError(t, cdc.UnmarshalJSON(gen.AppState[banktypes.ModuleName], &bankState))
+	balances := bankState.Balances
+
+	// a second committee gets the next id & the member, who already has an account, isn't funded again
+	second, err := InjectGodCommittee(gen, cdc, testGodCommitteeMemberAddress)
+	require.NoError(t, err)
+	assert.Equal(t, first+1, second)
+	bankState = banktypes.GenesisState{}
+	require.NoError(t, cdc.UnmarshalJSON(gen.AppState[banktypes.ModuleName], &bankState))
+	assert.Equal(t, balances, bankState.Balances)
+}
+
+func TestInjectGodCommitteeInvalidMember(t *testing.T) {
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	_, err = InjectGodCommittee(gen, app.MakeEncodingConfig().Marshaler, "cosmos1fy5zeuutmxzwcx5hncu5q83ug3zcqmxc")
+	require.ErrorContains(t, err, "invalid committee member address")
+}
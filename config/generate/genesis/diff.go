@@ -0,0 +1,582 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	capabilitytypes "github.com/cosmos/cosmos-sdk/x/capability/types"
+	crisistypes "github.com/cosmos/cosmos-sdk/x/crisis/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	evidencetypes "github.com/cosmos/cosmos-sdk/x/evidence/types"
+	genutiltypes "github.com/cosmos/cosmos-sdk/x/genutil/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	govv1 "github.com/cosmos/cosmos-sdk/x/gov/types/v1"
+	minttypes "github.com/cosmos/cosmos-sdk/x/mint/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	ibctransfertypes "github.com/cosmos/ibc-go/v6/modules/apps/transfer/types"
+	ibchost "github.com/cosmos/ibc-go/v6/modules/core/24-host"
+	ibctypes "github.com/cosmos/ibc-go/v6/modules/core/types"
+	evmtypes "github.com/evmos/ethermint/x/evm/types"
+	feemarkettypes "github.com/evmos/ethermint/x/feemarket/types"
+	"github.com/kava-labs/kava/app"
+	auctiontypes "github.com/kava-labs/kava/x/auction/types"
+	bep3types "github.com/kava-labs/kava/x/bep3/types"
+	cdptypes "github.com/kava-labs/kava/x/cdp/types"
+	committeetypes "github.com/kava-labs/kava/x/committee/types"
+	earntypes "github.com/kava-labs/kava/x/earn/types"
+	evmutiltypes "github.com/kava-labs/kava/x/evmutil/types"
+	hardtypes "github.com/kava-labs/kava/x/hard/types"
+	incentivetypes "github.com/kava-labs/kava/x/incentive/types"
+	issuancetypes "github.com/kava-labs/kava/x/issuance/types"
+	kavadisttypes "github.com/kava-labs/kava/x/kavadist/types"
+	pricefeedtypes "github.com/kava-labs/kava/x/pricefeed/types"
+	savingstypes "github.com/kava-labs/kava/x/savings/types"
+	swaptypes "github.com/kava-labs/kava/x/swap/types"
+)
+
+// DefaultDiffIgnore are the volatile fields ignored by Diff by default
+var DefaultDiffIgnore = []string{"genesis_time"}
+
+// GenesisDiff is the semantic difference between two genesis files, a & b
+type GenesisDiff struct {
+	// Fields are the changed fields outside of app_state, eg. chain_id & consensus_params
+	Fields []FieldChange `json:"fields,omitempty"`
+	// Validators are changes to the tendermint validator set of the genesis, by address
+	Validators []EntryChange `json:"validators,omitempty"`
+	Modules    []ModuleDiff  `json:"modules,omitempty"`
+}
+
+// ModuleDiff is the difference of a module's app_state
+type ModuleDiff struct {
+	Module string `json:"module"`
+	// Status is added or removed if the module is only in one of the genesis files, otherwise changed
+	Status string `json:"status"`
+	// Params are the changed fields of the module's params
+	Params []FieldChange `json:"params,omitempty"`
+	// Supply are the changed bank supply amounts, by denom
+	Supply []FieldChange `json:"supply,omitempty"`
+	// Counts are the changed lengths of the module's lists, eg. accounts
+	Counts []CountChange `json:"counts,omitempty"`
+	// Entries are changes to notable list entries, eg. staking validators & committees
+	Entries []EntryChange `json:"entries,omitempty"`
+	// Other are the other top level fields of the module's state that changed
+	Other []string `json:"other,omitempty"`
+}
+
+// FieldChange is a changed value. A or B is nil if the field doesn't exist in that genesis.
+type FieldChange struct {
+	Path string      `json:"path"`
+	A    interface{} `json:"a,omitempty"`
+	B    interface{} `json:"b,omitempty"`
+}
+
+// CountChange is the changed length of a list
+type CountChange struct {
+	Name string `json:"name"`
+	A    int    `json:"a"`
+	B    int    `json:"b"`
+}
+
+// EntryChange is an added, removed or changed entry of a list, eg. a validator
+type EntryChange struct {
+	Kind   string        `json:"kind"`
+	Key    string        `json:"key"`
+	Label  string        `json:"label,omitempty"`
+	Status string        `json:"status"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+const (
+	diffAdded   = "added"
+	diffRemoved = "removed"
+	diffChanged = "changed"
+)
+
+// entryKind describes how the entries of a list are matched & compared
+type entryKind struct {
+	kind string
+	// key & label are paths in an entry
+	key   string
+	label string
+	// fields are the compared paths of an entry
+	fields []string
+}
+
+// moduleEntries are the lists of module state whose entries are compared, by module & field
+var moduleEntries = map[string]map[string]entryKind{
+	"staking": {
+		"validators": {
+			kind:   "validator",
+			key:    "operator_address",
+			label:  "description.moniker",
+			fields: []string{"status", "jailed", "tokens", "delegator_shares", "consensus_pubkey.key", "commission.commission_rates.rate", "description.moniker"},
+		},
+	},
+	"committee": {
+		"committees": {
+			kind:   "committee",
+			key:    "base_committee.id",
+			label:  "base_committee.description",
+			fields: []string{"@type", "base_committee.description", "base_committee.members", "base_committee.permissions", "base_committee.vote_threshold", "base_committee.proposal_duration", "base_committee.tally_option"},
+		},
+	},
+}
+
+// tendermintValidators compares the validators of the genesis document
+var tendermintValidators = entryKind{kind: "validator", key: "address", label: "name", fields: []string{"power", "name", "pub_key.value"}}
+
+// DiffFiles compares the genesis files at pathA & pathB, see Diff. The modules of both files are decoded with the kava
+// app codec, so equivalent state like an omitted default value isn't reported. Modules the codec rejects in either
+// file, eg. those of another kava version, are compared as json.
+func DiffFiles(pathA, pathB string, ignore []string) (GenesisDiff, error) {
+	a, err := readGenericJSON(pathA)
+	if err != nil {
+		return GenesisDiff{}, err
+	}
+	b, err := readGenericJSON(pathB)
+	if err != nil {
+		return GenesisDiff{}, err
+	}
+	decodeModules(a, b)
+	return Diff(a, b, ignore), nil
+}
+
+// Diff compares two json decoded genesis documents module by module.
+// ignore are dot separated paths that aren't compared, eg. genesis_time or app_state.mint.minter.
+func Diff(a, b map[string]interface{}, ignore []string) GenesisDiff {
+	for _, path := range ignore {
+		deletePath(a, strings.Split(path, "."))
+		deletePath(b, strings.Split(path, "."))
+	}
+
+	var diff GenesisDiff
+	for _, key := range unionKeys(a, b) {
+		switch key {
+		case "app_state":
+			continue
+		case "validators":
+			diff.Validators = diffEntries(tendermintValidators, asSlice(a[key]), asSlice(b[key]))
+		default:
+			diff.Fields = append(diff.Fields, diffValues(key, a[key], b[key])...)
+		}
+	}
+
+	appStateA, _ := a["app_state"].(map[string]interface{})
+	appStateB, _ := b["app_state"].(map[string]interface{})
+	for _, module := range unionKeys(appStateA, appStateB) {
+		stateA, inA := appStateA[module]
+		stateB, inB := appStateB[module]
+		switch {
+		case !inA:
+			diff.Modules = append(diff.Modules, ModuleDiff{Module: module, Status: diffAdded})
+		case !inB:
+			diff.Modules = append(diff.Modules, ModuleDiff{Module: module, Status: diffRemoved})
+		case !reflect.DeepEqual(stateA, stateB):
+			diff.Modules = append(diff.Modules, diffModule(module, asMap(stateA), asMap(stateB)))
+		}
+	}
+	return diff
+}
+
+// IsEmpty returns true if the genesis files are the same, apart from ignored fields
+func (d GenesisDiff) IsEmpty() bool {
+	return len(d.Fields) == 0 && len(d.Validators) == 0 && len(d.Modules) == 0
+}
+
+// diffModule compares the state of a module that is in both genesis files
+func diffModule(module string, a, b map[string]interface{}) ModuleDiff {
+	diff := ModuleDiff{Module: module, Status: diffChanged}
+	for _, field := range unionKeys(a, b) {
+		valueA, valueB := a[field], b[field]
+		if reflect.DeepEqual(valueA, valueB) {
+			continue
+		}
+
+		if field == "params" || strings.HasSuffix(field, "_params") {
+			diff.Params = append(diff.Params, diffValues(field, valueA, valueB)...)
+			continue
+		}
+		if module == "bank" && field == "supply" {
+			diff.Supply = diffCoins(field, asSlice(valueA), asSlice(valueB))
+		}
+
+		listA, isListA := valueA.([]interface{})
+		listB, isListB := valueB.([]interface{})
+		if isListA || isListB {
+			if len(listA) != len(listB) {
+				diff.Counts = append(diff.Counts, CountChange{Name: field, A: len(listA), B: len(listB)})
+			}
+			if module == "auth" && field == "accounts" {
+				diff.Counts = append(diff.Counts, countAccountTypes(listA, listB)...)
+			}
+			kind, hasEntries := moduleEntries[module][field]
+			if hasEntries {
+				diff.Entries = append(diff.Entries, diffEntries(kind, listA, listB)...)
+			}
+			// lists that changed length or whose entries are compared are already reported
+			if hasEntries || len(listA) != len(listB) {
+				continue
+			}
+		}
+		if module == "bank" && field == "supply" {
+			continue
+		}
+		diff.Other = append(diff.Other, field)
+	}
+	return diff
+}
+
+// diffValues returns the changed leaf values of a & b. Lists of different lengths are compared as a whole.
+func diffValues(path string, a, b interface{}) []FieldChange {
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+	mapA, isMapA := a.(map[string]interface{})
+	mapB, isMapB := b.(map[string]interface{})
+	if isMapA && isMapB {
+		var changes []FieldChange
+		for _, key := range unionKeys(mapA, mapB) {
+			changes = append(changes, diffValues(path+"."+key, mapA[key], mapB[key])...)
+		}
+		return changes
+	}
+	listA, isListA := a.([]interface{})
+	listB, isListB := b.([]interface{})
+	if isListA && isListB && len(listA) == len(listB) {
+		var changes []FieldChange
+		for i := range listA {
+			changes = append(changes, diffValues(fmt.Sprintf("%s[%d]", path, i), listA[i], listB[i])...)
+		}
+		return changes
+	}
+	return []FieldChange{{Path: path, A: a, B: b}}
+}
+
+// diffCoins compares lists of coins by denom
+func diffCoins(path string, a, b []interface{}) []FieldChange {
+	amountsA, amountsB := coinAmounts(a), coinAmounts(b)
+	var changes []FieldChange
+	for _, denom := range unionKeys(amountsA, amountsB) {
+		if amountsA[denom] != amountsB[denom] {
+			changes = append(changes, FieldChange{Path: path + "." + denom, A: amountsA[denom], B: amountsB[denom]})
+		}
+	}
+	return changes
+}
+
+// coinAmounts returns the amounts of json encoded coins by denom
+func coinAmounts(coins []interface{}) map[string]interface{} {
+	amounts := make(map[string]interface{}, len(coins))
+	for _, coin := range coins {
+		c := asMap(coin)
+		amounts[fmt.Sprint(c["denom"])] = c["amount"]
+	}
+	return amounts
+}
+
+// countAccountTypes returns the changed number of accounts of each type
+func countAccountTypes(a, b []interface{}) []CountChange {
+	count := func(accounts []interface{}) map[string]interface{} {
+		counts := map[string]interface{}{}
+		for _, account := range accounts {
+			accountType := fmt.Sprint(asMap(account)["@type"])
+			n, _ := counts[accountType].(int)
+			counts[accountType] = n + 1
+		}
+		return counts
+	}
+	countsA, countsB := count(a), count(b)
+	var changes []CountChange
+	for _, accountType := range unionKeys(countsA, countsB) {
+		nA, _ := countsA[accountType].(int)
+		nB, _ := countsB[accountType].(int)
+		if nA != nB {
+			changes = append(changes, CountChange{Name: "accounts " + accountType, A: nA, B: nB})
+		}
+	}
+	return changes
+}
+
+// diffEntries matches the entries of a & b by key & compares their fields
+func diffEntries(kind entryKind, a, b []interface{}) []EntryChange {
+	index := func(entries []interface{}) map[string]interface{} {
+		indexed := make(map[string]interface{}, len(entries))
+		for _, entry := range entries {
+			indexed[fmt.Sprint(lookupPath(entry, kind.key))] = entry
+		}
+		return indexed
+	}
+	entriesA, entriesB := index(a), index(b)
+
+	var changes []EntryChange
+	for _, key := range unionKeys(entriesA, entriesB) {
+		entryA, inA := entriesA[key]
+		entryB, inB := entriesB[key]
+		change := EntryChange{Kind: kind.kind, Key: key}
+		switch {
+		case !inA:
+			change.Status = diffAdded
+			change.Label = labelOf(entryB, kind.label)
+		case !inB:
+			change.Status = diffRemoved
+			change.Label = labelOf(entryA, kind.label)
+		default:
+			for _, field := range kind.fields {
+				change.Fields = append(change.Fields, diffValues(field, lookupPath(entryA, field), lookupPath(entryB, field))...)
+			}
+			if len(change.Fields) == 0 {
+				continue
+			}
+			change.Status = diffChanged
+			change.Label = labelOf(entryB, kind.label)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// WriteText writes a human readable report of the diff
+func (d GenesisDiff) WriteText(w io.Writer) error {
+	var buf bytes.Buffer
+	if d.IsEmpty() {
+		buf.WriteString("no differences\n")
+	}
+	for _, change := range d.Fields {
+		fmt.Fprintf(&buf, "%s\n", change)
+	}
+	if len(d.Validators) > 0 {
+		buf.WriteString("validators:\n")
+		for _, change := range d.Validators {
+			fmt.Fprintf(&buf, "  %s\n", change)
+		}
+	}
+	for _, module := range d.Modules {
+		if module.Status != diffChanged {
+			fmt.Fprintf(&buf, "== %s: %s ==\n", module.Module, module.Status)
+			continue
+		}
+		fmt.Fprintf(&buf, "== %s ==\n", module.Module)
+		for _, change := range module.Params {
+			fmt.Fprintf(&buf, "  %s\n", change)
+		}
+		for _, change := range module.Supply {
+			fmt.Fprintf(&buf, "  %s\n", change)
+		}
+		for _, count := range module.Counts {
+			fmt.Fprintf(&buf, "  %s: %d -> %d (%+d)\n", count.Name, count.A, count.B, count.B-count.A)
+		}
+		for _, change := range module.Entries {
+			fmt.Fprintf(&buf, "  %s\n", change)
+		}
+		if len(module.Other) > 0 {
+			fmt.Fprintf(&buf, "  other changes: %s\n", strings.Join(module.Other, ", "))
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, formatDiffValue(c.A), formatDiffValue(c.B))
+}
+
+func (c EntryChange) String() string {
+	name := c.Kind + " " + c.Key
+	if c.Label != "" {
+		name += fmt.Sprintf(" (%s)", c.Label)
+	}
+	if c.Status != diffChanged {
+		return name + ": " + c.Status
+	}
+	fields := make([]string, len(c.Fields))
+	for i, field := range c.Fields {
+		fields[i] = field.String()
+	}
+	return name + ": " + strings.Join(fields, ", ")
+}
+
+// maxTextValueLength is the length above which lists are summarized in the text report
+const maxTextValueLength = 120
+
+// formatDiffValue formats a json value for the text report. Long lists are summarized by their length.
+func formatDiffValue(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	bz, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if list, isList := v.([]interface{}); isList && len(bz) > maxTextValueLength {
+		return fmt.Sprintf("[%d entries]", len(list))
+	}
+	return string(bz)
+}
+
+// readGenericJSON decodes a json file without the app codec, keeping numbers as json.Number
+func readGenericJSON(path string) (map[string]interface{}, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read genesis file: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := decodeGenericJSON(bz, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal genesis %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+func decodeGenericJSON(bz []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(bz))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// moduleGenesisTypes are the genesis types of the kava app's modules, by module. Modules without state aren't decoded.
+var moduleGenesisTypes = map[string]func() codec.ProtoMarshaler{
+	authtypes.ModuleName:        func() codec.ProtoMarshaler { return &authtypes.GenesisState{} },
+	authz.ModuleName:            func() codec.ProtoMarshaler { return &authz.GenesisState{} },
+	banktypes.ModuleName:        func() codec.ProtoMarshaler { return &banktypes.GenesisState{} },
+	capabilitytypes.ModuleName:  func() codec.ProtoMarshaler { return &capabilitytypes.GenesisState{} },
+	crisistypes.ModuleName:      func() codec.ProtoMarshaler { return &crisistypes.GenesisState{} },
+	distrtypes.ModuleName:       func() codec.ProtoMarshaler { return &distrtypes.GenesisState{} },
+	evidencetypes.ModuleName:    func() codec.ProtoMarshaler { return &evidencetypes.GenesisState{} },
+	genutiltypes.ModuleName:     func() codec.ProtoMarshaler { return &genutiltypes.GenesisState{} },
+	govtypes.ModuleName:         func() codec.ProtoMarshaler { return &govv1.GenesisState{} },
+	minttypes.ModuleName:        func() codec.ProtoMarshaler { return &minttypes.GenesisState{} },
+	slashingtypes.ModuleName:    func() codec.ProtoMarshaler { return &slashingtypes.GenesisState{} },
+	stakingtypes.ModuleName:     func() codec.ProtoMarshaler { return &stakingtypes.GenesisState{} },
+	ibchost.ModuleName:          func() codec.ProtoMarshaler { return &ibctypes.GenesisState{} },
+	ibctransfertypes.ModuleName: func() codec.ProtoMarshaler { return &ibctransfertypes.GenesisState{} },
+	evmtypes.ModuleName:         func() codec.ProtoMarshaler { return &evmtypes.GenesisState{} },
+	feemarkettypes.ModuleName:   func() codec.ProtoMarshaler { return &feemarkettypes.GenesisState{} },
+	auctiontypes.ModuleName:     func() codec.ProtoMarshaler { return &auctiontypes.GenesisState{} },
+	bep3types.ModuleName:        func() codec.ProtoMarshaler { return &bep3types.GenesisState{} },
+	cdptypes.ModuleName:         func() codec.ProtoMarshaler { return &cdptypes.GenesisState{} },
+	committeetypes.ModuleName:   func() codec.ProtoMarshaler { return &committeetypes.GenesisState{} },
+	earntypes.ModuleName:        func() codec.ProtoMarshaler { return &earntypes.GenesisState{} },
+	evmutiltypes.ModuleName:     func() codec.ProtoMarshaler { return &evmutiltypes.GenesisState{} },
+	hardtypes.ModuleName:        func() codec.ProtoMarshaler { return &hardtypes.GenesisState{} },
+	incentivetypes.ModuleName:   func() codec.ProtoMarshaler { return &incentivetypes.GenesisState{} },
+	issuancetypes.ModuleName:    func() codec.ProtoMarshaler { return &issuancetypes.GenesisState{} },
+	kavadisttypes.ModuleName:    func() codec.ProtoMarshaler { return &kavadisttypes.GenesisState{} },
+	pricefeedtypes.ModuleName:   func() codec.ProtoMarshaler { return &pricefeedtypes.GenesisState{} },
+	savingstypes.ModuleName:     func() codec.ProtoMarshaler { return &savingstypes.GenesisState{} },
+	swaptypes.ModuleName:        func() codec.ProtoMarshaler { return &swaptypes.GenesisState{} },
+}
+
+// decodeModules replaces the state of each module in both a & b with its state decoded & encoded by the app codec.
+// Modules the codec rejects in either document are left as json.
+func decodeModules(a, b map[string]interface{}) {
+	cdc := app.MakeEncodingConfig().Marshaler
+	appStateA, _ := a["app_state"].(map[string]interface{})
+	appStateB, _ := b["app_state"].(map[string]interface{})
+	for module, newGenesis := range moduleGenesisTypes {
+		stateA, inA := appStateA[module]
+		stateB, inB := appStateB[module]
+		if !inA || !inB {
+			continue
+		}
+		decodedA, errA := decodeModule(cdc, newGenesis(), stateA)
+		decodedB, errB := decodeModule(cdc, newGenesis(), stateB)
+		if errA == nil && errB == nil {
+			appStateA[module], appStateB[module] = decodedA, decodedB
+		}
+	}
+}
+
+// decodeModule decodes a module's state into genesis with the app codec & returns it encoded again as generic json
+func decodeModule(cdc codec.JSONCodec, genesis codec.ProtoMarshaler, state interface{}) (interface{}, error) {
+	bz, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := cdc.UnmarshalJSON(bz, genesis); err != nil {
+		return nil, err
+	}
+	if bz, err = cdc.MarshalJSON(genesis); err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	return decoded, decodeGenericJSON(bz, &decoded)
+}
+
+// lookupPath returns the value at a dot separated path, or nil if there is none
+func lookupPath(v interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+// deletePath removes the value at path, if it exists. Numeric keys index lists, so app_state.x.list.0.field works.
+func deletePath(v interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	switch container := v.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(container, path[0])
+			return
+		}
+		deletePath(container[path[0]], path[1:])
+	case []interface{}:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(container) || len(path) == 1 {
+			return
+		}
+		deletePath(container[i], path[1:])
+	}
+}
+
+func labelOf(entry interface{}, path string) string {
+	if path == "" {
+		return ""
+	}
+	if label := lookupPath(entry, path); label != nil {
+		return fmt.Sprint(label)
+	}
+	return ""
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
+
+// unionKeys returns the sorted keys of both maps
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, found := a[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,331 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/kava/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeTestDoc decodes a json genesis document like readGenericJSON
+func decodeTestDoc(t *testing.T, doc string) map[string]interface{} {
+	t.Helper()
+	decoder := json.NewDecoder(strings.NewReader(doc))
+	decoder.UseNumber()
+	var decoded map[string]interface{}
+	require.NoError(t, decoder.Decode(&decoded))
+	return decoded
+}
+
+func TestDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a        string
+		b        string
+		ignore   []string
+		expected GenesisDiff
+	}{
+		{
+			name: "same genesis",
+			a:    `{"chain_id": "kava_2222-10", "app_state": {"mint": {"params": {"inflation": "0.1"}}}}`,
+			b:    `{"chain_id": "kava_2222-10", "app_state": {"mint": {"params": {"inflation": "0.1"}}}}`,
+		},
+		{
+			name:   "ignored fields",
+			a:      `{"genesis_time": "2022-05-25T17:00:00Z", "app_state": {"mint": {"minter": {"inflation": "0.1"}, "params": {}}}}`,
+			b:      `{"genesis_time": "2024-01-01T00:00:00Z", "app_state": {"mint": {"minter": {"inflation": "0.2"}, "params": {}}}}`,
+			ignore: []string{"genesis_time", "app_state.mint.minter"},
+		},
+		{
+			name: "fields outside of app_state",
+			a:    `{"genesis_time": "2022-05-25T17:00:00Z", "chain_id": "kava_2222-10", "consensus_params": {"block": {"max_gas": "-1"}}}`,
+			b:    `{"genesis_time": "2024-01-01T00:00:00Z", "chain_id": "kava_2221-17000", "consensus_params": {"block": {"max_gas": "20000000"}}, "initial_height": "2"}`,
+			expected: GenesisDiff{Fields: []FieldChange{
+				{Path: "chain_id", A: "kava_2222-10", B: "kava_2221-17000"},
+				{Path: "consensus_params.block.max_gas", A: "-1", B: "20000000"},
+				{Path: "genesis_time", A: "2022-05-25T17:00:00Z", B: "2024-01-01T00:00:00Z"},
+				{Path: "initial_height", B: "2"},
+			}},
+		},
+		{
+			name: "tendermint validators",
+			a: `{"validators": [
+				{"address": "A1", "name": "one", "power": "10", "pub_key": {"value": "a1"}},
+				{"address": "A2", "name": "two", "power": "10", "pub_key": {"value": "a2"}}
+			]}`,
+			b: `{"validators": [
+				{"address": "A1", "name": "one", "power": "20", "pub_key": {"value": "a1"}},
+				{"address": "A3", "name": "three", "power": "10", "pub_key": {"value": "a3"}}
+			]}`,
+			expected: GenesisDiff{Validators: []EntryChange{
+				{Kind: "validator", Key: "A1", Label: "one", Status: diffChanged, Fields: []FieldChange{{Path: "power", A: "10", B: "20"}}},
+				{Kind: "validator", Key: "A2", Label: "two", Status: diffRemoved},
+				{Kind: "validator", Key: "A3", Label: "three", Status: diffAdded},
+			}},
+		},
+		{
+			name: "added & removed modules",
+			a:    `{"app_state": {"kavadist": {}, "mint": {}}}`,
+			b:    `{"app_state": {"mint": {}, "router": {}}}`,
+			expected: GenesisDiff{Modules: []ModuleDiff{
+				{Module: "kavadist", Status: diffRemoved},
+				{Module: "router", Status: diffAdded},
+			}},
+		},
+		{
+			name: "changed params & other fields",
+			a:    `{"app_state": {"gov": {"deposit_params": {"min_deposit": [{"denom": "ukava", "amount": "1"}]}, "params": {"quorum": "0.33"}, "starting_proposal_id": "1"}}}`,
+			b:    `{"app_state": {"gov": {"deposit_params": {"min_deposit": [{"denom": "ukava", "amount": "2"}]}, "params": {"quorum": "0.5", "burn_vote_veto": true}, "starting_proposal_id": "5"}}}`,
+			expected: GenesisDiff{Modules: []ModuleDiff{{
+				Module: "gov",
+				Status: diffChanged,
+				Params: []FieldChange{
+					{Path: "deposit_params.min_deposit[0].amount", A: "1", B: "2"},
+					{Path: "params.burn_vote_veto", B: true},
+					{Path: "params.quorum", A: "0.33", B: "0.5"},
+				},
+				Other: []string{"starting_proposal_id"},
+			}}},
+		},
+		{
+			name: "supply & balances",
+			a: `{"app_state": {"bank": {
+				"balances": [{"address": "kava1a", "coins": [{"denom": "ukava", "amount": "1"}]}],
+				"supply": [{"denom": "ukava", "amount": "1"}]
+			}}}`,
+			b: `{"app_state": {"bank": {
+				"balances": [{"address": "kava1a", "coins": [{"denom": "ukava", "amount": "2"}]}, {"address": "kava1b", "coins": [{"denom": "hard", "amount": "5"}]}],
+				"supply": [{"denom": "hard", "amount": "5"}, {"denom": "ukava", "amount": "2"}]
+			}}}`,
+			expected: GenesisDiff{Modules: []ModuleDiff{{
+				Module: "bank",
+				Status: diffChanged,
+				Supply: []FieldChange{
+					{Path: "supply.hard", B: "5"},
+					{Path: "supply.ukava", A: "1", B: "2"},
+				},
+				Counts: []CountChange{{Name: "balances", A: 1, B: 2}, {Name: "supply", A: 1, B: 2}},
+			}}},
+		},
+		{
+			name: "changed supply amount",
+			a:    `{"app_state": {"bank": {"supply": [{"denom": "ukava", "amount": "1"}]}}}`,
+			b:    `{"app_state": {"bank": {"supply": [{"denom": "ukava", "amount": "2"}]}}}`,
+			expected: GenesisDiff{Modules: []ModuleDiff{{
+				Module: "bank",
+				Status: diffChanged,
+				Supply: []FieldChange{{Path: "supply.ukava", A: "1", B: "2"}},
+			}}},
+		},
+		{
+			name: "accounts by type",
+			a:    `{"app_state": {"auth": {"accounts": [{"@type": "/cosmos.auth.v1beta1.BaseAccount", "address": "kava1a"}]}}}`,
+			b: `{"app_state": {"auth": {"accounts": [
+				{"@type": "/cosmos.auth.v1beta1.BaseAccount", "address": "kava1a"},
+				{"@type": "/ethermint.types.v1.EthAccount", "base_account": {"address": "kava1b"}}
+			]}}}`,
+			expected: GenesisDiff{Modules: []ModuleDiff{{
+				Module: "auth",
+				Status: diffChanged,
+				Counts: []CountChange{
+					{Name: "accounts", A: 1, B: 2},
+					{Name: "accounts /ethermint.types.v1.EthAccount", A: 0, B: 1},
+				},
+			}}},
+		},
+		{
+			name: "staking validators",
+			a: `{"app_state": {"staking": {"validators": [
+				{"operator_address": "kavavaloper1a", "description": {"moniker": "one"}, "status": "BOND_STATUS_BONDED", "tokens": "10"},
+				{"operator_address": "kavavaloper1b", "description": {"moniker": "two"}, "status": "BOND_STATUS_BONDED", "tokens": "10"}
+			]}}}`,
+			b: `{"app_state": {"staking": {"validators": [
+				{"operator_address": "kavavaloper1a", "description": {"moniker": "one"}, "status": "BOND_STATUS_UNBONDING", "tokens": "20"},
+				{"operator_address": "kavavaloper1c", "description": {"moniker": "three"}, "status": "BOND_STATUS_BONDED", "tokens": "10"}
+			]}}}`,
+			expected: GenesisDiff{Modules: []ModuleDiff{{
+				Module: "staking",
+				Status: diffChanged,
+				Entries: []EntryChange{
+					{Kind: "validator", Key: "kavavaloper1a", Label: "one", Status: diffChanged, Fields: []FieldChange{
+						{Path: "status", A: "BOND_STATUS_BONDED", B: "BOND_STATUS_UNBONDING"},
+						{Path: "tokens", A: "10", B: "20"},
+					}},
+					{Kind: "validator", Key: "kavavaloper1b", Label: "two", Status: diffRemoved},
+					{Kind: "validator", Key: "kavavaloper1c", Label: "three", Status: diffAdded},
+				},
+			}}},
+		},
+		{
+			name: "committees",
+			a: `{"app_state": {"committee": {"committees": [
+				{"@type": "/kava.committee.v1beta1.MemberCommittee", "base_committee": {"id": "1", "description": "god", "members": ["kava1a"]}}
+			]}}}`,
+			b: `{"app_state": {"committee": {"committees": [
+				{"@type": "/kava.committee.v1beta1.MemberCommittee", "base_committee": {"id": "1", "description": "god", "members": ["kava1b"]}},
+				{"@type": "/kava.committee.v1beta1.TokenCommittee", "base_committee": {"id": "2", "description": "stability"}}
+			]}}}`,
+			expected: GenesisDiff{Modules: []ModuleDiff{{
+				Module: "committee",
+				Status: diffChanged,
+				Counts: []CountChange{{Name: "committees", A: 1, B: 2}},
+				Entries: []EntryChange{
+					{Kind: "committee", Key: "1", Label: "god", Status: diffChanged, Fields: []FieldChange{{Path: "base_committee.members[0]", A: "kava1a", B: "kava1b"}}},
+					{Kind: "committee", Key: "2", Label: "stability", Status: diffAdded},
+				},
+			}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := Diff(decodeTestDoc(t, tc.a), decodeTestDoc(t, tc.b), tc.ignore)
+			assert.Equal(t, tc.expected, diff)
+			assert.Equal(t, len(tc.expected.Fields)+len(tc.expected.Validators)+len(tc.expected.Modules) == 0, diff.IsEmpty())
+		})
+	}
+}
+
+func TestGenesisDiffWriteText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		diff     GenesisDiff
+		expected string
+	}{
+		{
+			name:     "no differences",
+			expected: "no differences\n",
+		},
+		{
+			name: "fields & validators",
+			diff: GenesisDiff{
+				Fields: []FieldChange{{Path: "chain_id", A: "kava_2222-10", B: "kava_2221-17000"}, {Path: "initial_height", B: "2"}},
+				Validators: []EntryChange{
+					{Kind: "validator", Key: "A1", Label: "one", Status: diffChanged, Fields: []FieldChange{{Path: "power", A: "10", B: "20"}}},
+					{Kind: "validator", Key: "A2", Status: diffRemoved},
+				},
+			},
+			expected: `chain_id: "kava_2222-10" -> "kava_2221-17000"
+initial_height: <none> -> "2"
+validators:
+  validator A1 (one): power: "10" -> "20"
+  validator A2: removed
+`,
+		},
+		{
+			name: "modules",
+			diff: GenesisDiff{Modules: []ModuleDiff{
+				{Module: "kavadist", Status: diffRemoved},
+				{
+					Module: "bank",
+					Status: diffChanged,
+					Supply: []FieldChange{{Path: "supply.hard", B: "5"}},
+					Counts: []CountChange{{Name: "balances", A: 3, B: 1}},
+				},
+				{
+					Module: "gov",
+					Status: diffChanged,
+					Params: []FieldChange{{Path: "params.quorum", A: "0.33", B: "0.5"}},
+					Other:  []string{"proposals", "starting_proposal_id"},
+				},
+				{
+					Module:  "staking",
+					Status:  diffChanged,
+					Entries: []EntryChange{{Kind: "validator", Key: "kavavaloper1c", Label: "three", Status: diffAdded}},
+				},
+			}},
+			expected: `== kavadist: removed ==
+== bank ==
+  supply.hard: <none> -> "5"
+  balances: 3 -> 1 (-2)
+== gov ==
+  params.quorum: "0.33" -> "0.5"
+  other changes: proposals, starting_proposal_id
+== staking ==
+  validator kavavaloper1c (three): added
+`,
+		},
+		{
+			name: "long lists are summarized",
+			diff: GenesisDiff{Modules: []ModuleDiff{{
+				Module: "evm",
+				Status: diffChanged,
+				Params: []FieldChange{{
+					Path: "params.eip712_allowed_msgs",
+					A:    []interface{}{},
+					B:    []interface{}{strings.Repeat("a", maxTextValueLength), "b"},
+				}},
+			}}},
+			expected: `== evm ==
+  params.eip712_allowed_msgs: [] -> [2 entries]
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, tc.diff.WriteText(&buf))
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestDiffFiles(t *testing.T) {
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+	require.NoError(t, gen.SetGenesisTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+	require.NoError(t, gen.SetAppStateValue("swap.params.swap_fee", json.RawMessage(`"0.003000000000000000"`)))
+	updated := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, gen.WriteFile(updated))
+
+	// the genesis time is ignored by default
+	diff, err := DiffFiles(testGenesisPath, updated, DefaultDiffIgnore)
+	require.NoError(t, err)
+	assert.Equal(t, GenesisDiff{Modules: []ModuleDiff{{
+		Module: "swap",
+		Status: diffChanged,
+		Params: []FieldChange{{Path: "params.swap_fee", A: "0.001500000000000000", B: "0.003000000000000000"}},
+	}}}, diff)
+
+	diff, err = DiffFiles(testGenesisPath, updated, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []FieldChange{{Path: "genesis_time", A: "2022-05-25T17:00:00Z", B: "2024-01-01T00:00:00Z"}}, diff.Fields)
+
+	diff, err = DiffFiles(testGenesisPath, testGenesisPath, nil)
+	require.NoError(t, err)
+	assert.True(t, diff.IsEmpty())
+
+	// modules are decoded with the app codec, so equivalent values aren't reported. cdp is of another kava version, so
+	// the codec rejects it & it's compared as json.
+	require.NoError(t, gen.SetAppStateValue("swap.params.swap_fee", json.RawMessage(`"0.0015"`)))
+	require.NoError(t, gen.SetAppStateValue("cdp.params.circuit_breaker", json.RawMessage(`true`)))
+	require.NoError(t, gen.WriteFile(updated))
+	diff, err = DiffFiles(testGenesisPath, updated, DefaultDiffIgnore)
+	require.NoError(t, err)
+	assert.Equal(t, GenesisDiff{Modules: []ModuleDiff{{
+		Module: "cdp",
+		Status: diffChanged,
+		Params: []FieldChange{{Path: "params.circuit_breaker", A: false, B: true}},
+	}}}, diff)
+
+	_, err = DiffFiles(testGenesisPath, filepath.Join(t.TempDir(), "missing.json"), nil)
+	require.ErrorContains(t, err, "failed to read genesis file")
+}
+
+func TestModuleGenesisTypes(t *testing.T) {
+	cdc := app.MakeEncodingConfig().Marshaler
+	for module, newGenesis := range moduleGenesisTypes {
+		require.Contains(t, app.ModuleBasics, module)
+		// the default genesis of each module can be decoded into its type
+		state := decodeTestDoc(t, string(app.ModuleBasics[module].DefaultGenesis(cdc)))
+		_, err := decodeModule(cdc, newGenesis(), state)
+		assert.NoError(t, err, module)
+	}
+}
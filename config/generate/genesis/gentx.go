@@ -0,0 +1,90 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	clienttx "github.com/cosmos/cosmos-sdk/client/tx"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/kava-labs/kava/app/params"
+)
+
+// Validator describes a validator that is created at genesis by a gentx
+type Validator struct {
+	Moniker string
+	// OperatorKey is the private key of the validator's operator account. It signs the gentx.
+	OperatorKey cryptotypes.PrivKey
+	// ConsensusPubKey is the public key of the validator's priv_validator_key.json
+	ConsensusPubKey cryptotypes.PubKey
+	// PeerAddress is the node's p2p address in the form <node id>@<host>:<port>. It's stored in the memo.
+	PeerAddress    string
+	SelfDelegation sdk.Coin
+}
+
+// OperatorAddress returns the account address of the validator's operator
+func (v Validator) OperatorAddress() sdk.AccAddress {
+	return sdk.AccAddress(v.OperatorKey.PubKey().Address())
+}
+
+// NewGentx builds and signs a MsgCreateValidator transaction for inclusion in app_state.genutil.gen_txs.
+// The operator account must exist in genesis with a sequence of 0 and enough funds for the self delegation.
+func NewGentx(encodingConfig params.EncodingConfig, chainID string, val Validator) (json.RawMessage, error) {
+	commission := stakingtypes.NewCommissionRates(
+		sdk.MustNewDecFromStr("0.10"),
+		sdk.MustNewDecFromStr("0.20"),
+		sdk.MustNewDecFromStr("0.01"),
+	)
+	msg, err := stakingtypes.NewMsgCreateValidator(
+		sdk.ValAddress(val.OperatorAddress()),
+		val.ConsensusPubKey,
+		val.SelfDelegation,
+		stakingtypes.NewDescription(val.Moniker, "", "", "", ""),
+		commission,
+		sdk.OneInt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MsgCreateValidator for %s: %w", val.Moniker, err)
+	}
+
+	txConfig := encodingConfig.TxConfig
+	txBuilder := txConfig.NewTxBuilder()
+	if err := txBuilder.SetMsgs(msg); err != nil {
+		return nil, err
+	}
+	txBuilder.SetMemo(val.PeerAddress)
+	txBuilder.SetGasLimit(flags.DefaultGasLimit)
+
+	// gentxs are delivered at height 0 where the account number is always 0
+	signMode := txConfig.SignModeHandler().DefaultMode()
+	signerData := authsigning.SignerData{
+		Address:       val.OperatorAddress().String(),
+		ChainID:       chainID,
+		AccountNumber: 0,
+		Sequence:      0,
+		PubKey:        val.OperatorKey.PubKey(),
+	}
+
+	// the signer info must be set before signing because it is part of the sign bytes
+	emptySig := signing.SignatureV2{
+		PubKey:   val.OperatorKey.PubKey(),
+		Data:     &signing.SingleSignatureData{SignMode: signMode},
+		Sequence: 0,
+	}
+	if err := txBuilder.SetSignatures(emptySig); err != nil {
+		return nil, err
+	}
+	sig, err := clienttx.SignWithPrivKey(signMode, signerData, txBuilder, val.OperatorKey, txConfig, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign gentx for %s: %w", val.Moniker, err)
+	}
+	if err := txBuilder.SetSignatures(sig); err != nil {
+		return nil, err
+	}
+
+	return txConfig.TxJSONEncoder()(txBuilder.GetTx())
+}
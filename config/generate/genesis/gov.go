@@ -0,0 +1,290 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// govVoterFunds are given to each gov voter besides the min deposit, to pay for proposals & votes
+const govVoterFunds = 1_000_000_000
+
+// GovVoter is an account given a delegation by AddGovVoters
+type GovVoter struct {
+	Address sdk.AccAddress
+	// OperatorAddress is the valoper address of the validator delegated to
+	OperatorAddress string
+	Tokens          sdk.Int
+}
+
+// AddGovVoters makes the voters delegators of the replaced validators, with enough bonded tokens between them to reach
+// the x/gov quorum. The validators & delegators of the genesis don't vote, so the voters pass any proposal they all vote
+// yes on. Each voter is funded with the min deposit & tokens for fees.
+//
+// The delegated tokens add to the power of the replaced validators, which keeps the consensus power in line with their
+// tokens & the local validators' share of the total power above what it was.
+func AddGovVoters(gen *RawGenesis, cdc codec.JSONCodec, voters []sdk.AccAddress, replaced []ValidatorReplacement) ([]GovVoter, error) {
+	if len(voters) == 0 {
+		return nil, fmt.Errorf("at least one voter is required")
+	}
+	var validators []ValidatorReplacement
+	for _, r := range replaced {
+		if r.OperatorAddress != "" {
+			validators = append(validators, r)
+		}
+	}
+	if len(validators) == 0 {
+		return nil, fmt.Errorf("no replaced validator has a staking validator to delegate to")
+	}
+
+	bondDenom, err := gen.BondDenom()
+	if err != nil {
+		return nil, err
+	}
+	quorum, minDeposit, err := govQuorumAndMinDeposit(gen)
+	if err != nil {
+		return nil, err
+	}
+	initialHeight, err := gen.initialHeight()
+	if err != nil {
+		return nil, err
+	}
+	staking, err := parseModuleState(gen, stakingtypes.ModuleName)
+	if err != nil {
+		return nil, err
+	}
+	distribution, err := parseModuleState(gen, distributiontypes.ModuleName)
+	if err != nil {
+		return nil, err
+	}
+
+	power, err := govVoterPower(staking, quorum, len(voters))
+	if err != nil {
+		return nil, err
+	}
+	tokens := sdk.TokensFromConsensusPower(power, sdk.DefaultPowerReduction)
+
+	result := make([]GovVoter, len(voters))
+	powerIncrease := map[string]int64{}
+	for i, voter := range voters {
+		validator := validators[i%len(validators)]
+		if err := delegate(staking, distribution, voter, validator.OperatorAddress, tokens, initialHeight); err != nil {
+			return nil, fmt.Errorf("failed to delegate from %s to %s: %w", voter, validator.OperatorAddress, err)
+		}
+		powerIncrease[validator.OperatorAddress] += power
+		result[i] = GovVoter{Address: voter, OperatorAddress: validator.OperatorAddress, Tokens: tokens}
+	}
+
+	if err := increaseStakingPower(staking, powerIncrease); err != nil {
+		return nil, err
+	}
+	consensusIncrease := map[string]int64{}
+	for _, validator := range validators {
+		consensusIncrease[validator.NewConsAddress] = powerIncrease[validator.OperatorAddress]
+	}
+	if err := gen.increaseValidatorPower(consensusIncrease); err != nil {
+		return nil, err
+	}
+	gen.AppState[stakingtypes.ModuleName] = staking.Bytes()
+	gen.AppState[distributiontypes.ModuleName] = distribution.Bytes()
+
+	bondedPool := authtypes.NewModuleAddress(stakingtypes.BondedPoolName)
+	bonded := sdk.NewCoins(sdk.NewCoin(bondDenom, tokens.MulRaw(int64(len(voters)))))
+	if err := gen.GrantBalance(cdc, bondedPool, bonded); err != nil {
+		return nil, err
+	}
+	funds := minDeposit.Add(sdk.NewInt64Coin(bondDenom, govVoterFunds))
+	for _, voter := range voters {
+		if err := gen.GrantBalance(cdc, voter, funds); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// govQuorumAndMinDeposit returns the x/gov quorum & min deposit. Newer versions of the sdk moved tally_params &
+// deposit_params into params.
+func govQuorumAndMinDeposit(gen *RawGenesis) (sdk.Dec, sdk.Coins, error) {
+	type govParams struct {
+		Quorum     string    `json:"quorum"`
+		MinDeposit sdk.Coins `json:"min_deposit"`
+	}
+	var params, tallyParams, depositParams govParams
+	if err := gen.unmarshalModuleField(govtypes.ModuleName, "params", &params); err != nil {
+		return sdk.Dec{}, nil, err
+	}
+	if err := gen.unmarshalModuleField(govtypes.ModuleName, "tally_params", &tallyParams); err != nil {
+		return sdk.Dec{}, nil, err
+	}
+	if err := gen.unmarshalModuleField(govtypes.ModuleName, "deposit_params", &depositParams); err != nil {
+		return sdk.Dec{}, nil, err
+	}
+	if params.Quorum == "" {
+		params.Quorum = tallyParams.Quorum
+	}
+	if params.MinDeposit.Empty() {
+		params.MinDeposit = depositParams.MinDeposit
+	}
+
+	quorum, err := sdk.NewDecFromStr(params.Quorum)
+	if err != nil {
+		return sdk.Dec{}, nil, fmt.Errorf("invalid gov quorum %q: %w", params.Quorum, err)
+	}
+	if quorum.GTE(sdk.OneDec()) {
+		return sdk.Dec{}, nil, fmt.Errorf("gov quorum must be less than 1 to be reached with new delegations, found %s", quorum)
+	}
+	return quorum, params.MinDeposit, nil
+}
+
+// govVoterPower returns the power each of numVoters delegations needs for the delegations to reach the quorum of the
+// bonded tokens, including their own. One more power than necessary is delegated, so rounding can't miss the quorum.
+func govVoterPower(staking *gabs.Container, quorum sdk.Dec, numVoters int) (int64, error) {
+	bonded := sdk.ZeroInt()
+	for _, validator := range staking.Path("validators").Children() {
+		if jsonString(validator, "status") != stakingtypes.Bonded.String() {
+			continue
+		}
+		tokens, ok := sdk.NewIntFromString(jsonString(validator, "tokens"))
+		if !ok {
+			return 0, fmt.Errorf("invalid tokens %q", jsonString(validator, "tokens"))
+		}
+		bonded = bonded.Add(tokens)
+	}
+
+	// quorum = delegated / (bonded + delegated)
+	delegated := quorum.MulInt(bonded).Quo(sdk.OneDec().Sub(quorum))
+	perVoter := delegated.QuoInt64(int64(numVoters)).QuoInt(sdk.DefaultPowerReduction).Ceil().TruncateInt()
+	return perVoter.Int64() + 1, nil
+}
+
+// delegate adds a delegation of tokens from delegator to a bonded validator, with a starting info that starts earning
+// rewards from the validator's current period
+func delegate(staking, distribution *gabs.Container, delegator sdk.AccAddress, operator string, tokens sdk.Int, height int64) error {
+	for _, delegation := range staking.Path("delegations").Children() {
+		if jsonString(delegation, "delegator_address") == delegator.String() && jsonString(delegation, "validator_address") == operator {
+			return fmt.Errorf("delegation already exists")
+		}
+	}
+	validator, err := findBondedValidator(staking, operator)
+	if err != nil {
+		return err
+	}
+	shares, err := addValidatorTokens(validator, tokens)
+	if err != nil {
+		return err
+	}
+	if err := staking.ArrayAppend(map[string]interface{}{
+		"delegator_address": delegator.String(),
+		"validator_address": operator,
+		"shares":            shares.String(),
+	}, "delegations"); err != nil {
+		return err
+	}
+
+	// new delegations reference the last finished period of the validator, see x/distribution's initializeDelegation
+	period, err := referencePreviousPeriod(distribution, operator)
+	if err != nil {
+		return err
+	}
+	stake, err := sharesStake(validator, shares)
+	if err != nil {
+		return err
+	}
+	return distribution.ArrayAppend(map[string]interface{}{
+		"delegator_address": delegator.String(),
+		"validator_address": operator,
+		"starting_info": map[string]interface{}{
+			"previous_period": strconv.FormatUint(period, 10),
+			"stake":           stake.String(),
+			"height":          strconv.FormatInt(height, 10),
+		},
+	}, "delegator_starting_infos")
+}
+
+// referencePreviousPeriod increments the reference count of the historical rewards of the validator's last finished
+// period & returns the period
+func referencePreviousPeriod(distribution *gabs.Container, operator string) (uint64, error) {
+	var currentPeriod uint64
+	found := false
+	for _, current := range distribution.Path("validator_current_rewards").Children() {
+		if jsonString(current, "validator_address") != operator {
+			continue
+		}
+		period, err := strconv.ParseUint(jsonString(current, "rewards.period"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid current rewards period: %w", err)
+		}
+		currentPeriod, found = period, true
+	}
+	if !found || currentPeriod == 0 {
+		return 0, fmt.Errorf("no distribution current rewards found for the validator")
+	}
+
+	previousPeriod := currentPeriod - 1
+	for _, historical := range distribution.Path("validator_historical_rewards").Children() {
+		if jsonString(historical, "validator_address") != operator || jsonString(historical, "period") != strconv.FormatUint(previousPeriod, 10) {
+			continue
+		}
+		count, err := strconv.ParseUint(jsonString(historical, "rewards.reference_count"), 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid historical rewards reference count: %w", err)
+		}
+		if _, err := historical.Set(json.Number(strconv.FormatUint(count+1, 10)), "rewards", "reference_count"); err != nil {
+			return 0, err
+		}
+		return previousPeriod, nil
+	}
+	return 0, fmt.Errorf("no distribution historical rewards found for period %d of the validator", previousPeriod)
+}
+
+// increaseStakingPower adds power to the last validator powers & last total power of app_state.staking, by operator
+func increaseStakingPower(staking *gabs.Container, increase map[string]int64) error {
+	total := int64(0)
+	for _, validatorPower := range staking.Path("last_validator_powers").Children() {
+		delta, found := increase[jsonString(validatorPower, "address")]
+		if !found {
+			continue
+		}
+		power, err := strconv.ParseInt(jsonString(validatorPower, "power"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid last validator power: %w", err)
+		}
+		if _, err := validatorPower.Set(strconv.FormatInt(power+delta, 10), "power"); err != nil {
+			return err
+		}
+		total += delta
+	}
+	lastTotalPower, ok := sdk.NewIntFromString(jsonString(staking, "last_total_power"))
+	if !ok {
+		return fmt.Errorf("invalid app_state.staking.last_total_power")
+	}
+	_, err := staking.Set(lastTotalPower.AddRaw(total).String(), "last_total_power")
+	return err
+}
+
+// increaseValidatorPower adds power to the validators at the root of the genesis, by consensus address
+func (g *RawGenesis) increaseValidatorPower(increase map[string]int64) error {
+	var validators []tmtypes.GenesisValidator
+	if err := tmjson.Unmarshal(g.doc["validators"], &validators); err != nil {
+		return fmt.Errorf("failed to unmarshal validators: %w", err)
+	}
+	for i, validator := range validators {
+		validators[i].Power += increase[sdk.ConsAddress(validator.Address).String()]
+	}
+	validatorsJSON, err := tmjson.Marshal(validators)
+	if err != nil {
+		return fmt.Errorf("failed to marshal validators: %w", err)
+	}
+	g.doc["validators"] = validatorsJSON
+	return nil
+}
@@ -0,0 +1,259 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/kava-labs/kava/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+)
+
+// testReplacements returns the replacements of validators of a genesis made by newTestExportedGenesis, which keep
+// their consensus keys
+func testReplacements(validators []testValidator) []ValidatorReplacement {
+	var replaced []ValidatorReplacement
+	for i, validator := range validators {
+		replaced = append(replaced, ValidatorReplacement{
+			Name:            fmt.Sprintf("validator-%d", i),
+			OldConsAddress:  validator.ConsAddress,
+			NewConsAddress:  validator.ConsAddress,
+			OperatorAddress: validator.Operator,
+			Power:           validator.Power,
+		})
+	}
+	return replaced
+}
+
+// testVoters returns n new account addresses
+func testVoters(n int) []sdk.AccAddress {
+	var voters []sdk.AccAddress
+	for i := 0; i < n; i++ {
+		voters = append(voters, sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()))
+	}
+	return voters
+}
+
+func TestAddGovVoters(t *testing.T) {
+	testCases := []struct {
+		name      string
+		powers    []int64
+		replaced  int
+		numVoters int
+		// expectedPower is the power delegated by each voter, one more than needed for the 0.334 quorum of the test
+		// genesis. eg. 100 power needs 50.15 more to reach the quorum, 25.075 for each of 2 voters.
+		expectedPower int64
+	}{
+		{name: "one voter", powers: []int64{60, 40}, replaced: 1, numVoters: 1, expectedPower: 52},
+		{name: "voters split over validators", powers: []int64{60, 40}, replaced: 2, numVoters: 2, expectedPower: 27},
+		{name: "more voters than validators", powers: []int64{60, 40}, replaced: 1, numVoters: 3, expectedPower: 18},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cdc := app.MakeEncodingConfig().Marshaler
+			gen, validators := newTestExportedGenesis(t, tc.powers...)
+			setTestSupply(t, gen)
+			replaced := testReplacements(validators[:tc.replaced])
+			voters := testVoters(tc.numVoters)
+
+			result, err := AddGovVoters(gen, cdc, voters, replaced)
+			require.NoError(t, err)
+
+			tokens := sdk.TokensFromConsensusPower(tc.expectedPower, sdk.DefaultPowerReduction)
+			require.Len(t, result, tc.numVoters)
+			delegations := map[string]int{}
+			for i, voter := range result {
+				assert.Equal(t, voters[i], voter.Address)
+				assert.Equal(t, replaced[i%len(replaced)].OperatorAddress, voter.OperatorAddress)
+				assert.Equal(t, tokens, voter.Tokens)
+				delegations[voter.OperatorAddress]++
+			}
+
+			// the voters hold the quorum of the bonded tokens
+			staking, err := parseModuleState(gen, stakingtypes.ModuleName)
+			require.NoError(t, err)
+			bonded := sdk.ZeroInt()
+			for _, validator := range staking.Path("validators").Children() {
+				validatorTokens, ok := sdk.NewIntFromString(jsonString(validator, "tokens"))
+				require.True(t, ok)
+				bonded = bonded.Add(validatorTokens)
+			}
+			voted := tokens.MulRaw(int64(tc.numVoters))
+			assert.True(t, sdk.NewDecFromInt(voted).QuoInt(bonded).GTE(sdk.MustNewDecFromStr("0.334")), "%s of %s bonded", voted, bonded)
+
+			for _, voter := range result {
+				delegation := findByAddresses(t, staking, "delegations", voter.Address.String(), voter.OperatorAddress)
+				assert.Equal(t, sdk.NewDecFromInt(tokens).String(), jsonString(delegation, "shares"))
+			}
+
+			// the validators' power includes the delegations, everywhere it's recorded
+			expectedPowers := map[string]int64{}
+			totalPower := int64(0)
+			for i, validator := range validators {
+				expectedPowers[validator.Operator] = tc.powers[i] + int64(delegations[validator.Operator])*tc.expectedPower
+				totalPower += expectedPowers[validator.Operator]
+			}
+			for _, validatorPower := range staking.Path("last_validator_powers").Children() {
+				operator := jsonString(validatorPower, "address")
+				assert.Equal(t, strconv.FormatInt(expectedPowers[operator], 10), jsonString(validatorPower, "power"))
+			}
+			assert.Equal(t, strconv.FormatInt(totalPower, 10), jsonString(staking, "last_total_power"))
+			for _, validator := range staking.Path("validators").Children() {
+				operator := jsonString(validator, "operator_address")
+				assert.Equal(t, sdk.TokensFromConsensusPower(expectedPowers[operator], sdk.DefaultPowerReduction).String(), jsonString(validator, "tokens"))
+			}
+			consensusPowers := map[string]int64{}
+			for _, validator := range genesisValidators(t, gen) {
+				consensusPowers[sdk.ConsAddress(validator.Address).String()] = validator.Power
+			}
+			for _, validator := range validators {
+				assert.Equal(t, expectedPowers[validator.Operator], consensusPowers[validator.ConsAddress])
+			}
+
+			// the delegations earn rewards from the validators' last finished period
+			distribution, err := parseModuleState(gen, distributiontypes.ModuleName)
+			require.NoError(t, err)
+			for _, voter := range result {
+				startingInfo := findByAddresses(t, distribution, "delegator_starting_infos", voter.Address.String(), voter.OperatorAddress)
+				assert.Equal(t, "1", startingInfo.Path("starting_info.previous_period").Data())
+				assert.Equal(t, sdk.NewDecFromInt(tokens).String(), startingInfo.Path("starting_info.stake").Data())
+				assert.Equal(t, "1", startingInfo.Path("starting_info.height").Data())
+			}
+			for _, historical := range distribution.Path("validator_historical_rewards").Children() {
+				operator := jsonString(historical, "validator_address")
+				assert.Equal(t, strconv.Itoa(2+delegations[operator]), jsonString(historical, "rewards.reference_count"))
+			}
+
+			// the bonded pool holds the delegated tokens & the voters can pay the deposit & fees
+			var bankState banktypes.GenesisState
+			require.NoError(t, cdc.UnmarshalJSON(gen.AppState[banktypes.ModuleName], &bankState))
+			balances := map[string]sdk.Coins{}
+			for _, balance := range bankState.Balances {
+				balances[balance.Address] = balance.Coins
+			}
+			assert.Equal(t, voted, balances[authtypes.NewModuleAddress(stakingtypes.BondedPoolName).String()].AmountOf("ukava"))
+			voterFunds := sdk.NewInt(10_000_000 + govVoterFunds)
+			for _, voter := range voters {
+				assert.Equal(t, voterFunds, balances[voter.String()].AmountOf("ukava"))
+			}
+			expectedSupply := sdk.NewInt(1000).Add(voted).Add(voterFunds.MulRaw(int64(tc.numVoters)))
+			assert.Equal(t, expectedSupply, bankState.Supply.AmountOf("ukava"))
+		})
+	}
+}
+
+func TestAddGovVotersErrors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		setup       func(t *testing.T, gen *RawGenesis, replaced []ValidatorReplacement) []ValidatorReplacement
+		numVoters   int
+		expectedErr string
+	}{
+		{
+			name:        "no voters",
+			numVoters:   0,
+			expectedErr: "at least one voter is required",
+		},
+		{
+			name: "no operator address",
+			setup: func(t *testing.T, gen *RawGenesis, replaced []ValidatorReplacement) []ValidatorReplacement {
+				replaced[0].OperatorAddress = ""
+				return replaced
+			},
+			numVoters:   1,
+			expectedErr: "no replaced validator has a staking validator to delegate to",
+		},
+		{
+			name: "unreachable quorum",
+			setup: func(t *testing.T, gen *RawGenesis, replaced []ValidatorReplacement) []ValidatorReplacement {
+				require.NoError(t, gen.SetAppStateValue("gov.params.quorum", json.RawMessage(`"1.000000000000000000"`)))
+				return replaced
+			},
+			numVoters:   1,
+			expectedErr: "gov quorum must be less than 1",
+		},
+		{
+			name: "no current rewards",
+			setup: func(t *testing.T, gen *RawGenesis, replaced []ValidatorReplacement) []ValidatorReplacement {
+				require.NoError(t, gen.SetAppStateValue("distribution.validator_current_rewards", json.RawMessage(`[]`)))
+				return replaced
+			},
+			numVoters:   1,
+			expectedErr: "no distribution current rewards found",
+		},
+		{
+			name: "no historical rewards",
+			setup: func(t *testing.T, gen *RawGenesis, replaced []ValidatorReplacement) []ValidatorReplacement {
+				require.NoError(t, gen.SetAppStateValue("distribution.validator_historical_rewards", json.RawMessage(`[]`)))
+				return replaced
+			},
+			numVoters:   1,
+			expectedErr: "no distribution historical rewards found for period 1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, validators := newTestExportedGenesis(t, 10)
+			replaced := testReplacements(validators)
+			if tc.setup != nil {
+				replaced = tc.setup(t, gen, replaced)
+			}
+
+			_, err := AddGovVoters(gen, app.MakeEncodingConfig().Marshaler, testVoters(tc.numVoters), replaced)
+			require.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestGovQuorumAndMinDeposit(t *testing.T) {
+	testCases := []struct {
+		name               string
+		params             string
+		tallyParams        string
+		depositParams      string
+		expectedQuorum     string
+		expectedMinDeposit string
+	}{
+		{
+			name:               "params",
+			params:             `{"quorum":"0.4","min_deposit":[{"denom":"ukava","amount":"5"}]}`,
+			tallyParams:        `{"quorum":"0.2"}`,
+			depositParams:      `{"min_deposit":[{"denom":"ukava","amount":"10"}]}`,
+			expectedQuorum:     "0.400000000000000000",
+			expectedMinDeposit: "5ukava",
+		},
+		{
+			name:               "legacy params",
+			params:             `null`,
+			tallyParams:        `{"quorum":"0.2"}`,
+			depositParams:      `{"min_deposit":[{"denom":"ukava","amount":"10"}]}`,
+			expectedQuorum:     "0.200000000000000000",
+			expectedMinDeposit: "10ukava",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, err := ReadRawGenesis(testGenesisPath)
+			require.NoError(t, err)
+			require.NoError(t, gen.SetAppStateValue("gov.params", json.RawMessage(tc.params)))
+			require.NoError(t, gen.SetAppStateValue("gov.tally_params", json.RawMessage(tc.tallyParams)))
+			require.NoError(t, gen.SetAppStateValue("gov.deposit_params", json.RawMessage(tc.depositParams)))
+
+			quorum, minDeposit, err := govQuorumAndMinDeposit(gen)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuorum, quorum.String())
+			assert.Equal(t, tc.expectedMinDeposit, minDeposit.String())
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package genesis
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// PersistPowerIncrease backs the power increase of replaced validators with bonded tokens, so x/staking doesn't revert
+// it to the power of the validators' delegations after the first block.
+//
+// Each validator's tokens & delegator shares are increased & the new shares are added to its self delegation, or its
+// largest delegation if it has none. The delegation's x/distribution starting info is given the stake of the new
+// shares, so rewards & slashes are calculated against it. The bonded pool is funded with the new tokens & the bank
+// supply is kept consistent.
+func PersistPowerIncrease(gen *RawGenesis, cdc codec.JSONCodec, replaced []ValidatorReplacement) error {
+	bondDenom, err := gen.BondDenom()
+	if err != nil {
+		return err
+	}
+	staking, err := parseModuleState(gen, stakingtypes.ModuleName)
+	if err != nil {
+		return err
+	}
+	distribution, err := parseModuleState(gen, distributiontypes.ModuleName)
+	if err != nil {
+		return err
+	}
+
+	bondedTokens := sdk.ZeroInt()
+	for _, r := range replaced {
+		if r.PowerIncrease == 0 {
+			continue
+		}
+		if r.OperatorAddress == "" {
+			return fmt.Errorf("validator %q has no staking validator to add tokens to", r.Name)
+		}
+		tokens := sdk.TokensFromConsensusPower(r.PowerIncrease, sdk.DefaultPowerReduction)
+		if err := bondTokens(staking, distribution, r.OperatorAddress, tokens); err != nil {
+			return fmt.Errorf("failed to bond tokens to %s: %w", r.OperatorAddress, err)
+		}
+		bondedTokens = bondedTokens.Add(tokens)
+	}
+	if bondedTokens.IsZero() {
+		return nil
+	}
+	gen.AppState[stakingtypes.ModuleName] = staking.Bytes()
+	gen.AppState[distributiontypes.ModuleName] = distribution.Bytes()
+
+	bondedPool := authtypes.NewModuleAddress(stakingtypes.BondedPoolName)
+	return gen.GrantBalance(cdc, bondedPool, sdk.NewCoins(sdk.NewCoin(bondDenom, bondedTokens)))
+}
+
+// bondTokens adds tokens to a bonded validator & the shares they're worth to one of its delegations, whose starting
+// info is updated with the delegation's new stake
+func bondTokens(staking, distribution *gabs.Container, operator string, tokens sdk.Int) error {
+	validator, err := findBondedValidator(staking, operator)
+	if err != nil {
+		return err
+	}
+	shares, err := addValidatorTokens(validator, tokens)
+	if err != nil {
+		return err
+	}
+
+	delegation, err := findBondingDelegation(staking, operator)
+	if err != nil {
+		return err
+	}
+	delegationShares, err := sdk.NewDecFromStr(jsonString(delegation, "shares"))
+	if err != nil {
+		return fmt.Errorf("invalid delegation shares: %w", err)
+	}
+	delegationShares = delegationShares.Add(shares)
+	if _, err := delegation.Set(delegationShares.String(), "shares"); err != nil {
+		return err
+	}
+
+	stake, err := sharesStake(validator, delegationShares)
+	if err != nil {
+		return err
+	}
+	return setStartingInfoStake(distribution, jsonString(delegation, "delegator_address"), operator, stake)
+}
+
+// findBondedValidator returns the validator of app_state.staking with the operator address, which must be bonded
+func findBondedValidator(staking *gabs.Container, operator string) (*gabs.Container, error) {
+	for _, validator := range staking.Path("validators").Children() {
+		if jsonString(validator, "operator_address") != operator {
+			continue
+		}
+		if status := jsonString(validator, "status"); status != stakingtypes.Bonded.String() {
+			return nil, fmt.Errorf("only bonded validators can be given tokens, found status %q", status)
+		}
+		return validator, nil
+	}
+	return nil, fmt.Errorf("validator not found")
+}
+
+// addValidatorTokens adds tokens to a validator & returns the delegator shares they're worth. Shares are issued at the
+// validator's current exchange rate, as x/staking does for new delegations.
+func addValidatorTokens(validator *gabs.Container, tokens sdk.Int) (sdk.Dec, error) {
+	validatorTokens, ok := sdk.NewIntFromString(jsonString(validator, "tokens"))
+	if !ok {
+		return sdk.Dec{}, fmt.Errorf("invalid tokens %q", jsonString(validator, "tokens"))
+	}
+	delegatorShares, err := sdk.NewDecFromStr(jsonString(validator, "delegator_shares"))
+	if err != nil {
+		return sdk.Dec{}, fmt.Errorf("invalid delegator shares: %w", err)
+	}
+	shares := sdk.NewDecFromInt(tokens)
+	if !validatorTokens.IsZero() {
+		shares = delegatorShares.MulInt(tokens).QuoInt(validatorTokens)
+	}
+
+	if _, err := validator.Set(validatorTokens.Add(tokens).String(), "tokens"); err != nil {
+		return sdk.Dec{}, err
+	}
+	if _, err := validator.Set(delegatorShares.Add(shares).String(), "delegator_shares"); err != nil {
+		return sdk.Dec{}, err
+	}
+	return shares, nil
+}
+
+// sharesStake returns the tokens of a validator that shares are worth. x/distribution calculates the stake of a
+// delegation the same way, see Validator.TokensFromSharesTruncated.
+func sharesStake(validator *gabs.Container, shares sdk.Dec) (sdk.Dec, error) {
+	validatorTokens, ok := sdk.NewIntFromString(jsonString(validator, "tokens"))
+	if !ok {
+		return sdk.Dec{}, fmt.Errorf("invalid tokens %q", jsonString(validator, "tokens"))
+	}
+	delegatorShares, err := sdk.NewDecFromStr(jsonString(validator, "delegator_shares"))
+	if err != nil {
+		return sdk.Dec{}, fmt.Errorf("invalid delegator shares: %w", err)
+	}
+	return shares.MulInt(validatorTokens).QuoTruncate(delegatorShares), nil
+}
+
+// setStartingInfoStake sets the stake of a delegation's x/distribution starting info. Rewards are calculated for the
+// stake from the starting info's period & x/distribution panics if the stake is more than the delegation's tokens.
+func setStartingInfoStake(distribution *gabs.Container, delegator, operator string, stake sdk.Dec) error {
+	for _, info := range distribution.Path("delegator_starting_infos").Children() {
+		if jsonString(info, "delegator_address") != delegator || jsonString(info, "validator_address") != operator {
+			continue
+		}
+		_, err := info.Set(stake.String(), "starting_info", "stake")
+		return err
+	}
+	return fmt.Errorf("no distribution starting info found for the delegation of %s to %s", delegator, operator)
+}
+
+// findBondingDelegation returns the validator's self delegation, or its largest delegation if it has none
+func findBondingDelegation(staking *gabs.Container, operator string) (*gabs.Container, error) {
+	valAddress, err := sdk.ValAddressFromBech32(operator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operator address: %w", err)
+	}
+	self := sdk.AccAddress(valAddress).String()
+
+	var largest *gabs.Container
+	largestShares := sdk.ZeroDec()
+	for _, delegation := range staking.Path("delegations").Children() {
+		if jsonString(delegation, "validator_address") != operator {
+			continue
+		}
+		if jsonString(delegation, "delegator_address") == self {
+			return delegation, nil
+		}
+		shares, err := sdk.NewDecFromStr(jsonString(delegation, "shares"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid delegation shares: %w", err)
+		}
+		if largest == nil || shares.GT(largestShares) {
+			largest, largestShares = delegation, shares
+		}
+	}
+	if largest == nil {
+		return nil, fmt.Errorf("validator has no delegations")
+	}
+	return largest, nil
+}
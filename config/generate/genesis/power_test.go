@@ -0,0 +1,280 @@
+package genesis
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/kava-labs/kava/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+)
+
+// findByAddresses returns the entry of a staking or distribution array with the given delegator & validator
+func findByAddresses(t *testing.T, state *gabs.Container, array, delegator, validator string) *gabs.Container {
+	t.Helper()
+	for _, entry := range state.Path(array).Children() {
+		if jsonString(entry, "delegator_address") == delegator && jsonString(entry, "validator_address") == validator {
+			return entry
+		}
+	}
+	require.FailNowf(t, "entry not found", "no %s of %s to %s", array, delegator, validator)
+	return nil
+}
+
+// addTestDelegation adds a delegation & its starting info to a validator of a genesis made by newTestExportedGenesis,
+// along with the shares & tokens it's worth
+func addTestDelegation(t *testing.T, gen *RawGenesis, operator, delegator string, tokens sdk.Int) {
+	t.Helper()
+	staking, err := parseModuleState(gen, stakingtypes.ModuleName)
+	require.NoError(t, err)
+	distribution, err := parseModuleState(gen, distributiontypes.ModuleName)
+	require.NoError(t, err)
+
+	require.NoError(t, staking.ArrayAppend(map[string]interface{}{
+		"delegator_address": delegator,
+		"validator_address": operator,
+		"shares":            sdk.NewDecFromInt(tokens).String(),
+	}, "delegations"))
+	require.NoError(t, distribution.ArrayAppend(map[string]interface{}{
+		"delegator_address": delegator,
+		"validator_address": operator,
+		"starting_info": map[string]interface{}{
+			"previous_period": "1",
+			"stake":           sdk.NewDecFromInt(tokens).String(),
+			"height":          "0",
+		},
+	}, "delegator_starting_infos"))
+	for _, validator := range staking.Path("validators").Children() {
+		if jsonString(validator, "operator_address") != operator {
+			continue
+		}
+		validatorTokens, ok := sdk.NewIntFromString(jsonString(validator, "tokens"))
+		require.True(t, ok)
+		shares := sdk.MustNewDecFromStr(jsonString(validator, "delegator_shares"))
+		_, err = validator.Set(validatorTokens.Add(tokens).String(), "tokens")
+		require.NoError(t, err)
+		_, err = validator.Set(shares.Add(sdk.NewDecFromInt(tokens)).String(), "delegator_shares")
+		require.NoError(t, err)
+	}
+	gen.AppState[stakingtypes.ModuleName] = staking.Bytes()
+	gen.AppState[distributiontypes.ModuleName] = distribution.Bytes()
+}
+
+func TestPersistPowerIncrease(t *testing.T) {
+	otherDelegator := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()).String()
+
+	testCases := []struct {
+		name string
+		// setup modifies the genesis & returns the delegator expected to receive the new shares
+		setup func(t *testing.T, gen *RawGenesis, operator string) string
+		// increase is the power added to the validator
+		increase int64
+		// expectedShares & expectedStake are the delegation's shares & starting info stake after the increase
+		expectedShares string
+		expectedStake  string
+		// expectedDelegatorShares is the validator's delegator shares after the increase
+		expectedDelegatorShares string
+	}{
+		{
+			name: "self delegation",
+			setup: func(t *testing.T, gen *RawGenesis, operator string) string {
+				valAddress, err := sdk.ValAddressFromBech32(operator)
+				require.NoError(t, err)
+				return sdk.AccAddress(valAddress).String()
+			},
+			increase:                5,
+			expectedShares:          "15000000.000000000000000000",
+			expectedStake:           "15000000.000000000000000000",
+			expectedDelegatorShares: "15000000.000000000000000000",
+		},
+		{
+			name: "largest delegation without a self delegation",
+			setup: func(t *testing.T, gen *RawGenesis, operator string) string {
+				// the self delegation is moved to another delegator & a smaller delegation is added
+				valAddress, err := sdk.ValAddressFromBech32(operator)
+				require.NoError(t, err)
+				self := sdk.AccAddress(valAddress).String()
+				staking, err := parseModuleState(gen, stakingtypes.ModuleName)
+				require.NoError(t, err)
+				distribution, err := parseModuleState(gen, distributiontypes.ModuleName)
+				require.NoError(t, err)
+				_, err = findByAddresses(t, staking, "delegations", self, operator).Set(otherDelegator, "delegator_address")
+				require.NoError(t, err)
+				_, err = findByAddresses(t, distribution, "delegator_starting_infos", self, operator).Set(otherDelegator, "delegator_address")
+				require.NoError(t, err)
+				gen.AppState[stakingtypes.ModuleName] = staking.Bytes()
+				gen.AppState[distributiontypes.ModuleName] = distribution.Bytes()
+
+				addTestDelegation(t, gen, operator, sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address()).String(), sdk.NewInt(1_000_000))
+				return otherDelegator
+			},
+			increase:                5,
+			expectedShares:          "15000000.000000000000000000",
+			expectedStake:           "15000000.000000000000000000",
+			expectedDelegatorShares: "16000000.000000000000000000",
+		},
+		{
+			name: "slashed validator",
+			setup: func(t *testing.T, gen *RawGenesis, operator string) string {
+				// the validator lost half its tokens, so each token is worth 2 shares
+				staking, err := parseModuleState(gen, stakingtypes.ModuleName)
+				require.NoError(t, err)
+				for _, validator := range staking.Path("validators").Children() {
+					if jsonString(validator, "operator_address") == operator {
+						_, err = validator.Set("5000000", "tokens")
+						require.NoError(t, err)
+					}
+				}
+				gen.AppState[stakingtypes.ModuleName] = staking.Bytes()
+
+				valAddress, err := sdk.ValAddressFromBech32(operator)
+				require.NoError(t, err)
+				return sdk.AccAddress(valAddress).String()
+			},
+			increase:                5,
+			expectedShares:          "20000000.000000000000000000",
+			expectedStake:           "10000000.000000000000000000",
+			expectedDelegatorShares: "20000000.000000000000000000",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cdc := app.MakeEncodingConfig().Marshaler
+			gen, validators := newTestExportedGenesis(t, 10, 20)
+			operator := validators[0].Operator
+			delegator := tc.setup(t, gen, operator)
+			setTestSupply(t, gen)
+
+			staking, err := parseModuleState(gen, stakingtypes.ModuleName)
+			require.NoError(t, err)
+			var initialTokens sdk.Int
+			for _, validator := range staking.Path("validators").Children() {
+				if jsonString(validator, "operator_address") == operator {
+					initialTokens, _ = sdk.NewIntFromString(jsonString(validator, "tokens"))
+				}
+			}
+
+			err = PersistPowerIncrease(gen, cdc, []ValidatorReplacement{
+				{Name: "validator-0", OperatorAddress: operator, PowerIncrease: tc.increase},
+				// validators without an increase are left alone
+				{Name: "validator-1", OperatorAddress: validators[1].Operator},
+			})
+			require.NoError(t, err)
+			tokens := sdk.TokensFromConsensusPower(tc.increase, sdk.DefaultPowerReduction)
+
+			staking, err = parseModuleState(gen, stakingtypes.ModuleName)
+			require.NoError(t, err)
+			for _, validator := range staking.Path("validators").Children() {
+				switch jsonString(validator, "operator_address") {
+				case operator:
+					assert.Equal(t, initialTokens.Add(tokens).String(), jsonString(validator, "tokens"))
+					assert.Equal(t, tc.expectedDelegatorShares, jsonString(validator, "delegator_shares"))
+				case validators[1].Operator:
+					assert.Equal(t, "20000000", jsonString(validator, "tokens"))
+				}
+			}
+			delegation := findByAddresses(t, staking, "delegations", delegator, operator)
+			assert.Equal(t, tc.expectedShares, jsonString(delegation, "shares"))
+
+			distribution, err := parseModuleState(gen, distributiontypes.ModuleName)
+			require.NoError(t, err)
+			startingInfo := findByAddresses(t, distribution, "delegator_starting_infos", delegator, operator)
+			assert.Equal(t, tc.expectedStake, startingInfo.Path("starting_info.stake").Data())
+
+			var bankState banktypes.GenesisState
+			require.NoError(t, cdc.UnmarshalJSON(gen.AppState[banktypes.ModuleName], &bankState))
+			bondedPool := authtypes.NewModuleAddress(stakingtypes.BondedPoolName).String()
+			var bondedPoolBalance sdk.Coins
+			for _, balance := range bankState.Balances {
+				if balance.Address == bondedPool {
+					bondedPoolBalance = balance.Coins
+				}
+			}
+			assert.Equal(t, tokens, bondedPoolBalance.AmountOf("ukava"))
+			assert.Equal(t, sdk.NewInt(1000).Add(tokens), bankState.Supply.AmountOf("ukava"))
+		})
+	}
+}
+
+func TestPersistPowerIncreaseNoIncrease(t *testing.T) {
+	gen, validators := newTestExportedGenesis(t, 10)
+	staking := gen.AppState[stakingtypes.ModuleName]
+	bank := gen.AppState[banktypes.ModuleName]
+
+	err := PersistPowerIncrease(gen, app.MakeEncodingConfig().Marshaler, []ValidatorReplacement{
+		{Name: "validator-0", OperatorAddress: validators[0].Operator},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, staking, gen.AppState[stakingtypes.ModuleName])
+	assert.Equal(t, bank, gen.AppState[banktypes.ModuleName])
+}
+
+func TestPersistPowerIncreaseErrors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		setup       func(t *testing.T, gen *RawGenesis, operator string) ValidatorReplacement
+		expectedErr string
+	}{
+		{
+			name: "no operator address",
+			setup: func(t *testing.T, gen *RawGenesis, operator string) ValidatorReplacement {
+				return ValidatorReplacement{Name: "validator-0", PowerIncrease: 1}
+			},
+			expectedErr: `validator "validator-0" has no staking validator`,
+		},
+		{
+			name: "validator not found",
+			setup: func(t *testing.T, gen *RawGenesis, operator string) ValidatorReplacement {
+				unknown := sdk.ValAddress(secp256k1.GenPrivKey().PubKey().Address()).String()
+				return ValidatorReplacement{Name: "validator-0", OperatorAddress: unknown, PowerIncrease: 1}
+			},
+			expectedErr: "validator not found",
+		},
+		{
+			name: "unbonded validator",
+			setup: func(t *testing.T, gen *RawGenesis, operator string) ValidatorReplacement {
+				staking, err := parseModuleState(gen, stakingtypes.ModuleName)
+				require.NoError(t, err)
+				_, err = staking.Path("validators").Index(0).Set(stakingtypes.Unbonded.String(), "status")
+				require.NoError(t, err)
+				gen.AppState[stakingtypes.ModuleName] = staking.Bytes()
+				return ValidatorReplacement{Name: "validator-0", OperatorAddress: operator, PowerIncrease: 1}
+			},
+			expectedErr: "only bonded validators can be given tokens",
+		},
+		{
+			name: "no delegations",
+			setup: func(t *testing.T, gen *RawGenesis, operator string) ValidatorReplacement {
+				require.NoError(t, gen.SetAppStateValue("staking.delegations", json.RawMessage(`[]`)))
+				return ValidatorReplacement{Name: "validator-0", OperatorAddress: operator, PowerIncrease: 1}
+			},
+			expectedErr: "validator has no delegations",
+		},
+		{
+			name: "no starting info",
+			setup: func(t *testing.T, gen *RawGenesis, operator string) ValidatorReplacement {
+				require.NoError(t, gen.SetAppStateValue("distribution.delegator_starting_infos", json.RawMessage(`[]`)))
+				return ValidatorReplacement{Name: "validator-0", OperatorAddress: operator, PowerIncrease: 1}
+			},
+			expectedErr: "no distribution starting info found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, validators := newTestExportedGenesis(t, 10)
+			replacement := tc.setup(t, gen, validators[0].Operator)
+
+			err := PersistPowerIncrease(gen, app.MakeEncodingConfig().Marshaler, []ValidatorReplacement{replacement})
+			require.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
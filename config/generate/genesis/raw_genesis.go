@@ -0,0 +1,325 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	genutiltypes "github.com/cosmos/cosmos-sdk/x/genutil/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+// RawGenesis is a genesis file that is only decoded as far as is necessary to edit it.
+// Any fields that aren't edited are preserved byte for byte, which allows editing genesis files
+// produced by kava versions with module state that differs from the version kvtool is built with.
+type RawGenesis struct {
+	doc      map[string]json.RawMessage
+	AppState map[string]json.RawMessage
+}
+
+// ReadRawGenesis loads the genesis file at path
+func ReadRawGenesis(path string) (*RawGenesis, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read genesis file: %w", err)
+	}
+
+	genesis, err := NewRawGenesis(bz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load genesis file %s: %w", path, err)
+	}
+	return genesis, nil
+}
+
+// NewRawGenesis decodes a json genesis document
+func NewRawGenesis(bz []byte) (*RawGenesis, error) {
+	genesis := &RawGenesis{}
+	if err := json.Unmarshal(bz, &genesis.doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal genesis: %w", err)
+	}
+	if err := json.Unmarshal(genesis.doc["app_state"], &genesis.AppState); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal app_state: %w", err)
+	}
+	return genesis, nil
+}
+
+// WriteFile saves the genesis, including any updates to the app state, to path
+func (g *RawGenesis) WriteFile(path string) error {
+	bz, err := g.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, bz, 0644)
+}
+
+// MarshalJSON encodes the genesis, including any updates to the app state, as indented json
+func (g *RawGenesis) MarshalJSON() ([]byte, error) {
+	appState, err := json.Marshal(g.AppState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal app_state: %w", err)
+	}
+	g.doc["app_state"] = appState
+
+	bz, err := json.MarshalIndent(g.doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal genesis: %w", err)
+	}
+	return bz, nil
+}
+
+// ChainID returns the chain_id of the genesis
+func (g *RawGenesis) ChainID() (string, error) {
+	var chainID string
+	if err := json.Unmarshal(g.doc["chain_id"], &chainID); err != nil {
+		return "", fmt.Errorf("failed to unmarshal chain_id: %w", err)
+	}
+	return chainID, nil
+}
+
+// initialHeight returns the height of the first block of the genesis, which is encoded as a string or number.
+// It defaults to 1 if unset, like tendermint.
+func (g *RawGenesis) initialHeight() (int64, error) {
+	raw, found := g.doc["initial_height"]
+	if !found || string(raw) == "null" {
+		return 1, nil
+	}
+	var height json.Number
+	if err := json.Unmarshal(raw, &height); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal initial_height: %w", err)
+	}
+	value, err := height.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("invalid initial_height %q: %w", height, err)
+	}
+	if value == 0 {
+		return 1, nil
+	}
+	return value, nil
+}
+
+// BondDenom returns the staking module's bond denom
+func (g *RawGenesis) BondDenom() (string, error) {
+	var params struct {
+		BondDenom string `json:"bond_denom"`
+	}
+	if err := g.unmarshalModuleField(stakingtypes.ModuleName, "params", &params); err != nil {
+		return "", err
+	}
+	if params.BondDenom == "" {
+		return "", fmt.Errorf("app_state.staking.params.bond_denom is empty")
+	}
+	return params.BondDenom, nil
+}
+
+// Gentxs returns the genesis transactions that will be delivered during InitChain
+func (g *RawGenesis) Gentxs() ([]json.RawMessage, error) {
+	var gentxs []json.RawMessage
+	if err := g.unmarshalModuleField(genutiltypes.ModuleName, "gen_txs", &gentxs); err != nil {
+		return nil, err
+	}
+	return gentxs, nil
+}
+
+// AddGentx appends a signed genesis transaction to app_state.genutil.gen_txs
+func (g *RawGenesis) AddGentx(gentx json.RawMessage) error {
+	gentxs, err := g.Gentxs()
+	if err != nil {
+		return err
+	}
+	return g.setModuleField(genutiltypes.ModuleName, "gen_txs", append(gentxs, gentx))
+}
+
+// AddAccount adds an account to app_state.auth.accounts and funds it with coins, see AddBalance.
+func (g *RawGenesis) AddAccount(cdc codec.JSONCodec, account authtypes.GenesisAccount, coins sdk.Coins) error {
+	accountJSON, err := cdc.MarshalInterfaceJSON(account)
+	if err != nil {
+		return fmt.Errorf("failed to marshal account %s: %w", account.GetAddress(), err)
+	}
+
+	var accounts []json.RawMessage
+	if err := g.unmarshalModuleField(authtypes.ModuleName, "accounts", &accounts); err != nil {
+		return err
+	}
+	if err := g.setModuleField(authtypes.ModuleName, "accounts", append(accounts, accountJSON)); err != nil {
+		return err
+	}
+
+	if coins.Empty() {
+		return nil
+	}
+
+	return g.AddBalance(cdc, account.GetAddress().String(), coins)
+}
+
+// AddBalance adds a balance for address to app_state.bank.balances.
+// If the bank supply is defined, it is increased to account for the new balance.
+// An empty supply is left as is, it is calculated by the bank module during InitGenesis.
+func (g *RawGenesis) AddBalance(cdc codec.JSONCodec, address string, coins sdk.Coins) error {
+	var balances []json.RawMessage
+	if err := g.unmarshalModuleField(banktypes.ModuleName, "balances", &balances); err != nil {
+		return err
+	}
+	balanceJSON, err := cdc.MarshalJSON(&banktypes.Balance{
+		Address: address,
+		Coins:   coins,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal balance of %s: %w", address, err)
+	}
+	if err := g.setModuleField(banktypes.ModuleName, "balances", append(balances, balanceJSON)); err != nil {
+		return err
+	}
+
+	return g.addSupply(coins)
+}
+
+// addSupply increases the bank supply by coins. An empty supply is left as is, it is calculated by the bank module.
+func (g *RawGenesis) addSupply(coins sdk.Coins) error {
+	var supply sdk.Coins
+	if err := g.unmarshalModuleField(banktypes.ModuleName, "supply", &supply); err != nil {
+		return err
+	}
+	if supply.Empty() {
+		return nil
+	}
+	return g.setModuleField(banktypes.ModuleName, "supply", supply.Add(coins...))
+}
+
+// SetAppStateValue sets the value at a dot separated path in app_state, eg "gov.voting_params.voting_period".
+// Objects along the path that don't exist are created.
+func (g *RawGenesis) SetAppStateValue(path string, value json.RawMessage) error {
+	keys := strings.Split(path, ".")
+	updated, err := setJSONValue(g.AppState[keys[0]], keys[1:], value)
+	if err != nil {
+		return fmt.Errorf("failed to set app_state.%s: %w", path, err)
+	}
+	g.AppState[keys[0]] = updated
+	return nil
+}
+
+// HasAppStateValue returns true if a value exists at a dot separated path in app_state
+func (g *RawGenesis) HasAppStateValue(path string) bool {
+	keys := strings.Split(path, ".")
+	raw, found := g.AppState[keys[0]]
+	for _, key := range keys[1:] {
+		if !found {
+			return false
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return false
+		}
+		raw, found = obj[key]
+	}
+	return found
+}
+
+// setJSONValue replaces the value at path in the json object raw, without decoding sibling values
+func setJSONValue(raw json.RawMessage, path []string, value json.RawMessage) (json.RawMessage, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	obj := map[string]json.RawMessage{}
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("%s is not an object: %w", path[0], err)
+		}
+	}
+	updated, err := setJSONValue(obj[path[0]], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	obj[path[0]] = updated
+	return json.Marshal(obj)
+}
+
+// unmarshalModuleField decodes app_state.<module>.<field> into v
+func (g *RawGenesis) unmarshalModuleField(module, field string, v interface{}) error {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(g.AppState[module], &state); err != nil {
+		return fmt.Errorf("failed to unmarshal app_state.%s: %w", module, err)
+	}
+	raw, found := state[field]
+	if !found || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("failed to unmarshal app_state.%s.%s: %w", module, field, err)
+	}
+	return nil
+}
+
+// setModuleField replaces app_state.<module>.<field> with v, leaving other fields untouched
+func (g *RawGenesis) setModuleField(module, field string, v interface{}) error {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(g.AppState[module], &state); err != nil {
+		return fmt.Errorf("failed to unmarshal app_state.%s: %w", module, err)
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal app_state.%s.%s: %w", module, field, err)
+	}
+	state[field] = raw
+
+	g.AppState[module], err = json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal app_state.%s: %w", module, err)
+	}
+	return nil
+}
+
+// SetChainID sets the chain_id of the genesis
+func (g *RawGenesis) SetChainID(chainID string) error {
+	bz, err := json.Marshal(chainID)
+	if err != nil {
+		return err
+	}
+	return g.SetValue("chain_id", bz)
+}
+
+// SetGenesisTime sets the genesis_time of the genesis
+func (g *RawGenesis) SetGenesisTime(t time.Time) error {
+	bz, err := json.Marshal(t.UTC())
+	if err != nil {
+		return err
+	}
+	return g.SetValue("genesis_time", bz)
+}
+
+// SetValue sets the value at a dot separated path in the genesis document, eg "consensus_params.block.max_gas".
+// Paths within app_state are delegated to SetAppStateValue.
+func (g *RawGenesis) SetValue(path string, value json.RawMessage) error {
+	keys := strings.Split(path, ".")
+	if keys[0] == "app_state" {
+		if len(keys) == 1 {
+			return fmt.Errorf("app_state can't be replaced, set its modules instead")
+		}
+		return g.SetAppStateValue(strings.Join(keys[1:], "."), value)
+	}
+	updated, err := setJSONValue(g.doc[keys[0]], keys[1:], value)
+	if err != nil {
+		return fmt.Errorf("failed to set %s: %w", path, err)
+	}
+	g.doc[keys[0]] = updated
+	return nil
+}
+
+// SetVotingPeriod sets the x/gov voting period. Newer versions of the sdk moved voting_params.voting_period into params.
+func (g *RawGenesis) SetVotingPeriod(period time.Duration) error {
+	// durations are encoded as seconds in proto json, eg. 30s
+	bz, err := json.Marshal(strconv.FormatFloat(period.Seconds(), 'f', -1, 64) + "s")
+	if err != nil {
+		return err
+	}
+	if g.HasAppStateValue("gov.params.voting_period") {
+		return g.SetAppStateValue("gov.params.voting_period", bz)
+	}
+	return g.SetAppStateValue("gov.voting_params.voting_period", bz)
+}
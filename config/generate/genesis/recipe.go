@@ -0,0 +1,147 @@
+package genesis
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// transformerTypes are the transformers that can be used in recipe steps, by step name
+var transformerTypes = map[string]func() GenesisTransformer{
+	"replace-validators":   func() GenesisTransformer { return &ReplaceValidatorsTransformer{} },
+	"inject-god-committee": func() GenesisTransformer { return &InjectGodCommitteeTransformer{} },
+	"set-voting-period":    func() GenesisTransformer { return &SetVotingPeriodTransformer{} },
+	"set-chain-id":         func() GenesisTransformer { return &SetChainIDTransformer{} },
+	"grant-balance":        func() GenesisTransformer { return &GrantBalanceTransformer{} },
+	"add-accounts":         func() GenesisTransformer { return &AddAccountsTransformer{} },
+	"set":                  func() GenesisTransformer { return &SetValueTransformer{} },
+}
+
+// Recipe describes edits of a genesis file, eg.
+//
+//	genesis: export.json
+//	out: mirrornet-genesis.json
+//	validate: false
+//	steps:
+//	  - set-chain-id: { chainId: kavamirror_2221-1 }
+//	  - replace-validators: { keysDir: keys, minPower: 0.67 }
+//	  - set: { path: app_state.swap.params.swap_fee, value: "0.001" }
+//
+// Relative paths are relative to the recipe file.
+type Recipe struct {
+	// Genesis is the genesis file to edit
+	Genesis string `yaml:"genesis"`
+	// Out is where the result is written. Defaults to Genesis.
+	Out string `yaml:"out"`
+	// Validate checks the result with the kava app's ValidateGenesis. Defaults to true.
+	Validate *bool        `yaml:"validate"`
+	Steps    []RecipeStep `yaml:"steps"`
+}
+
+// RecipeStep is a step of a recipe. In yaml, it is a map of the step name to the transformer's fields.
+type RecipeStep struct {
+	Name        string
+	Transformer GenesisTransformer
+}
+
+// pathResolver is implemented by transformers with paths that are relative to the recipe
+type pathResolver interface {
+	resolvePaths(dir string)
+}
+
+// LoadRecipe reads & validates a recipe yaml file
+func LoadRecipe(path string) (Recipe, error) {
+	var recipe Recipe
+
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return recipe, fmt.Errorf("failed to read recipe: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(bz))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&recipe); err != nil {
+		return recipe, fmt.Errorf("failed to parse recipe %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	if recipe.Genesis != "" && !filepath.IsAbs(recipe.Genesis) {
+		recipe.Genesis = filepath.Join(dir, recipe.Genesis)
+	}
+	if recipe.Out != "" && !filepath.IsAbs(recipe.Out) {
+		recipe.Out = filepath.Join(dir, recipe.Out)
+	}
+	for _, step := range recipe.Steps {
+		if resolver, ok := step.Transformer.(pathResolver); ok {
+			resolver.resolvePaths(dir)
+		}
+	}
+
+	if len(recipe.Steps) == 0 {
+		return recipe, fmt.Errorf("recipe %s has no steps", path)
+	}
+	return recipe, nil
+}
+
+// Pipeline returns the pipeline that applies the recipe's steps
+func (r Recipe) Pipeline() Pipeline {
+	pipeline := Pipeline{Validate: r.Validate == nil || *r.Validate}
+	for _, step := range r.Steps {
+		pipeline.Transformers = append(pipeline.Transformers, step.Transformer)
+	}
+	return pipeline
+}
+
+// UnmarshalYAML decodes a single entry map of step name to transformer fields. Unknown fields are an error.
+func (s *RecipeStep) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode || len(node.Content) != 2 {
+		return fmt.Errorf("line %d: a step must be a map with a single step name, eg. set-chain-id: { chainId: kava_2222-10 }", node.Line)
+	}
+	name := node.Content[0].Value
+	newTransformer, found := transformerTypes[name]
+	if !found {
+		return fmt.Errorf("line %d: unknown step %q, must be one of %v", node.Line, name, TransformerNames())
+	}
+	s.Name = name
+	s.Transformer = newTransformer()
+
+	value := node.Content[1]
+	if value.Tag == "!!null" {
+		return nil
+	}
+	// re-encode the fields so they can be decoded strictly
+	bz, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(bz))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(s.Transformer); err != nil {
+		return fmt.Errorf("line %d: invalid %s step: %w", node.Line, name, err)
+	}
+	return nil
+}
+
+// TransformerNames returns the step names that can be used in recipes
+func TransformerNames() []string {
+	names := make([]string, 0, len(transformerTypes))
+	for name := range transformerTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// transformerName returns the recipe step name of a transformer, or its type if it isn't a recipe step
+func transformerName(t GenesisTransformer) string {
+	for name, newTransformer := range transformerTypes {
+		if reflect.TypeOf(newTransformer()) == reflect.TypeOf(t) {
+			return name
+		}
+	}
+	return reflect.TypeOf(t).String()
+}
@@ -0,0 +1,218 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/kava-labs/kava/app"
+	"github.com/tendermint/tendermint/privval"
+)
+
+// GenesisTransformer is a single edit of a genesis, eg. replacing its validators or overriding a param.
+// Transformers are applied in order by a Pipeline & are configured by the steps of a Recipe.
+type GenesisTransformer interface {
+	Transform(gen *RawGenesis, cdc codec.Codec) error
+}
+
+// Pipeline applies transformers to a genesis in order
+type Pipeline struct {
+	Transformers []GenesisTransformer
+	// Validate checks the result with the kava app's ValidateGenesis. Only genesis files of the kava version kvtool is
+	// built with can be validated.
+	Validate bool
+}
+
+// Run applies the transformers to gen & validates the result if enabled
+func (p Pipeline) Run(gen *RawGenesis) error {
+	cdc := app.MakeEncodingConfig().Marshaler
+	for i, t := range p.Transformers {
+		if err := t.Transform(gen, cdc); err != nil {
+			return fmt.Errorf("step %d (%s) failed: %w", i+1, transformerName(t), err)
+		}
+	}
+	if p.Validate {
+		return ValidateAppState(gen)
+	}
+	return nil
+}
+
+// RunFile loads the genesis at inPath, runs the pipeline & writes the result to outPath
+func (p Pipeline) RunFile(inPath, outPath string) error {
+	gen, err := ReadRawGenesis(inPath)
+	if err != nil {
+		return err
+	}
+	if err := p.Run(gen); err != nil {
+		return err
+	}
+	return gen.WriteFile(outPath)
+}
+
+// ReplaceValidatorsTransformer replaces the consensus keys of the validators with the most power, see ReplaceValidators
+type ReplaceValidatorsTransformer struct {
+	// KeysDir contains the keys named <KeyPrefix><index>.json, starting from index 0. Not used if Keys is set.
+	KeysDir   string `yaml:"keysDir"`
+	KeyPrefix string `yaml:"keyPrefix"`
+	// MinPower is the minimum share of the total power held by the replaced validators, 0 <= x < 1
+	MinPower float64 `yaml:"minPower"`
+	// PersistentPower backs the power increase with bonded tokens so it isn't reverted after the first block
+	PersistentPower bool `yaml:"persistentPower"`
+
+	Keys []privval.FilePVKey `yaml:"-"`
+	// Replaced is set to the replaced validators once transformed
+	Replaced []ValidatorReplacement `yaml:"-"`
+}
+
+func (t *ReplaceValidatorsTransformer) Transform(gen *RawGenesis, cdc codec.Codec) error {
+	keys := t.Keys
+	if keys == nil {
+		prefix := t.KeyPrefix
+		if prefix == "" {
+			prefix = DefaultValidatorKeyPrefix
+		}
+		var err error
+		if keys, err = LoadValidatorKeys(t.KeysDir, prefix); err != nil {
+			return err
+		}
+	}
+	replaced, err := ReplaceValidators(gen, keys, t.MinPower)
+	if err != nil {
+		return err
+	}
+	for _, r := range replaced {
+		fmt.Printf("replaced validator %q %s -> %s, power %d\n", r.Name, r.OldConsAddress, r.NewConsAddress, r.Power)
+	}
+	t.Replaced = replaced
+	if t.PersistentPower {
+		return PersistPowerIncrease(gen, cdc, replaced)
+	}
+	return nil
+}
+
+// InjectGodCommitteeTransformer adds a committee that can pass any proposal, see InjectGodCommittee
+type InjectGodCommitteeTransformer struct {
+	Member string `yaml:"member"`
+
+	// CommitteeID is set to the id of the new committee once transformed
+	CommitteeID uint64 `yaml:"-"`
+}
+
+func (t *InjectGodCommitteeTransformer) Transform(gen *RawGenesis, cdc codec.Codec) error {
+	if t.Member == "" {
+		return fmt.Errorf("a committee member is required")
+	}
+	id, err := InjectGodCommittee(gen, cdc, t.Member)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("added god committee %d with member %s\n", id, t.Member)
+	t.CommitteeID = id
+	return nil
+}
+
+// SetVotingPeriodTransformer sets the x/gov voting period
+type SetVotingPeriodTransformer struct {
+	Period time.Duration `yaml:"period"`
+}
+
+func (t *SetVotingPeriodTransformer) Transform(gen *RawGenesis, _ codec.Codec) error {
+	if t.Period <= 0 {
+		return fmt.Errorf("voting period must be positive, found %s", t.Period)
+	}
+	return gen.SetVotingPeriod(t.Period)
+}
+
+// SetChainIDTransformer sets the chain id
+type SetChainIDTransformer struct {
+	ChainID string `yaml:"chainId"`
+}
+
+func (t *SetChainIDTransformer) Transform(gen *RawGenesis, _ codec.Codec) error {
+	if t.ChainID == "" {
+		return fmt.Errorf("chain id must not be empty")
+	}
+	return gen.SetChainID(t.ChainID)
+}
+
+// GrantBalanceTransformer funds an address, creating its account if it doesn't exist
+type GrantBalanceTransformer struct {
+	Address string `yaml:"address"`
+	Coins   string `yaml:"coins"`
+}
+
+func (t *GrantBalanceTransformer) Transform(gen *RawGenesis, cdc codec.Codec) error {
+	address, err := sdk.AccAddressFromBech32(t.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	coins, err := sdk.ParseCoinsNormalized(t.Coins)
+	if err != nil {
+		return fmt.Errorf("invalid coins: %w", err)
+	}
+	return gen.GrantBalance(cdc, address, coins)
+}
+
+// AddAccountsTransformer funds the accounts of a file & a list of grants, see AddAccounts
+type AddAccountsTransformer struct {
+	// File is a yaml or json file of grants, see LoadAccountGrants
+	File     string         `yaml:"file"`
+	Accounts []AccountGrant `yaml:"accounts"`
+	// SkipExisting leaves existing accounts as is, instead of adding the coins to their balance
+	SkipExisting bool `yaml:"skipExisting"`
+}
+
+func (t *AddAccountsTransformer) Transform(gen *RawGenesis, cdc codec.Codec) error {
+	grants := t.Accounts
+	if t.File != "" {
+		loaded, err := LoadAccountGrants(t.File)
+		if err != nil {
+			return err
+		}
+		grants = append(loaded, grants...)
+	}
+	if len(grants) == 0 {
+		return fmt.Errorf("no accounts to add")
+	}
+	result, err := gen.AddAccounts(cdc, grants, t.SkipExisting)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created %d accounts, topped up %d & skipped %d existing accounts\n", result.Created, result.ToppedUp, result.Skipped)
+	return nil
+}
+
+// SetValueTransformer overrides the value at a dot separated path of the genesis, eg. app_state.swap.params.swap_fee
+type SetValueTransformer struct {
+	Path string `yaml:"path"`
+	// Value is set as json. yaml values are converted, eg. a yaml map becomes a json object.
+	Value interface{} `yaml:"value"`
+}
+
+func (t *SetValueTransformer) Transform(gen *RawGenesis, _ codec.Codec) error {
+	if t.Path == "" {
+		return fmt.Errorf("a path is required")
+	}
+	bz, err := json.Marshal(t.Value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value of %s: %w", t.Path, err)
+	}
+	return gen.SetValue(t.Path, bz)
+}
+
+// resolvePaths makes the accounts file relative to dir, eg. the dir of a recipe
+func (t *AddAccountsTransformer) resolvePaths(dir string) {
+	if t.File != "" && !filepath.IsAbs(t.File) {
+		t.File = filepath.Join(dir, t.File)
+	}
+}
+
+// resolvePaths makes the keys dir relative to dir, eg. the dir of a recipe
+func (t *ReplaceValidatorsTransformer) resolvePaths(dir string) {
+	if t.KeysDir != "" && !filepath.IsAbs(t.KeysDir) {
+		t.KeysDir = filepath.Join(dir, t.KeysDir)
+	}
+}
@@ -0,0 +1,589 @@
+package genesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/kava-labs/kava/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	"github.com/tendermint/tendermint/privval"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// testValidator is a bonded validator of a genesis made by newTestExportedGenesis
+type testValidator struct {
+	Operator    string
+	ConsAddress string
+	Power       int64
+}
+
+// newTestExportedGenesis returns the test genesis with bonded validators of the given powers, as if it was exported
+// from a running chain. Each validator has a self delegation with a starting info, distribution rewards & a signing
+// info, the first is the previous proposer.
+func newTestExportedGenesis(t *testing.T, powers ...int64) (*RawGenesis, []testValidator) {
+	t.Helper()
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+	staking, err := parseModuleState(gen, stakingtypes.ModuleName)
+	require.NoError(t, err)
+	slashing, err := parseModuleState(gen, slashingtypes.ModuleName)
+	require.NoError(t, err)
+	distribution, err := parseModuleState(gen, distributiontypes.ModuleName)
+	require.NoError(t, err)
+
+	var genesisValidators []tmtypes.GenesisValidator
+	var validators []testValidator
+	totalPower := int64(0)
+	for i, power := range powers {
+		pubKey := ed25519.GenPrivKey().PubKey()
+		operator := sdk.ValAddress(secp256k1.GenPrivKey().PubKey().Address())
+		consAddress := sdk.ConsAddress(pubKey.Address()).String()
+		tokens := sdk.TokensFromConsensusPower(power, sdk.DefaultPowerReduction)
+
+		genesisValidators = append(genesisValidators, tmtypes.GenesisValidator{
+			Address: pubKey.Address(),
+			PubKey:  pubKey,
+			Power:   power,
+			Name:    fmt.Sprintf("validator-%d", i),
+		})
+		require.NoError(t, staking.ArrayAppend(map[string]interface{}{
+			"operator_address": operator.String(),
+			"consensus_pubkey": map[string]interface{}{"@type": ed25519PubKeyType, "key": pubKey.Bytes()},
+			"status":           stakingtypes.Bonded.String(),
+			"tokens":           tokens.String(),
+			"delegator_shares": sdk.NewDecFromInt(tokens).String(),
+		}, "validators"))
+		require.NoError(t, staking.ArrayAppend(map[string]interface{}{
+			"delegator_address": sdk.AccAddress(operator).String(),
+			"validator_address": operator.String(),
+			"shares":            sdk.NewDecFromInt(tokens).String(),
+		}, "delegations"))
+		require.NoError(t, distribution.ArrayAppend(map[string]interface{}{
+			"delegator_address": sdk.AccAddress(operator).String(),
+			"validator_address": operator.String(),
+			"starting_info": map[string]interface{}{
+				"previous_period": "1",
+				"stake":           sdk.NewDecFromInt(tokens).String(),
+				"height":          "0",
+			},
+		}, "delegator_starting_infos"))
+		require.NoError(t, distribution.ArrayAppend(map[string]interface{}{
+			"validator_address": operator.String(),
+			"rewards":           map[string]interface{}{"rewards": []interface{}{}, "period": "2"},
+		}, "validator_current_rewards"))
+		// period 1 is referenced by the current rewards & the self delegation's starting info
+		require.NoError(t, distribution.ArrayAppend(map[string]interface{}{
+			"validator_address": operator.String(),
+			"period":            "1",
+			"rewards":           map[string]interface{}{"cumulative_reward_ratio": []interface{}{}, "reference_count": 2},
+		}, "validator_historical_rewards"))
+		require.NoError(t, staking.ArrayAppend(map[string]interface{}{
+			"address": operator.String(),
+			"power":   strconv.FormatInt(power, 10),
+		}, "last_validator_powers"))
+		require.NoError(t, slashing.ArrayAppend(map[string]interface{}{
+			"address":                consAddress,
+			"validator_signing_info": map[string]interface{}{"address": consAddress},
+		}, "signing_infos"))
+
+		validators = append(validators, testValidator{Operator: operator.String(), ConsAddress: consAddress, Power: power})
+		totalPower += power
+	}
+	_, err = staking.Set(strconv.FormatInt(totalPower, 10), "last_total_power")
+	require.NoError(t, err)
+	gen.AppState[stakingtypes.ModuleName] = staking.Bytes()
+	gen.AppState[slashingtypes.ModuleName] = slashing.Bytes()
+	gen.AppState[distributiontypes.ModuleName] = distribution.Bytes()
+
+	gen.doc["validators"], err = tmjson.Marshal(genesisValidators)
+	require.NoError(t, err)
+	if len(validators) > 0 {
+		require.NoError(t, gen.setModuleField("distribution", "previous_proposer", validators[0].ConsAddress))
+	}
+	return gen, validators
+}
+
+// writeTestValidatorKeys writes n validator keys named priv_validator_key_<index>.json to a new dir
+func writeTestValidatorKeys(t *testing.T, n int) (string, []privval.FilePVKey) {
+	t.Helper()
+	dir := t.TempDir()
+	var keys []privval.FilePVKey
+	for i := 0; i < n; i++ {
+		pv := privval.GenFilePV(
+			filepath.Join(dir, fmt.Sprintf("%s%d.json", DefaultValidatorKeyPrefix, i)),
+			filepath.Join(dir, fmt.Sprintf("state_%d.json", i)),
+		)
+		pv.Save()
+		keys = append(keys, pv.Key)
+	}
+	return dir, keys
+}
+
+// genesisValidators returns the validators at the root of the genesis
+func genesisValidators(t *testing.T, gen *RawGenesis) []tmtypes.GenesisValidator {
+	t.Helper()
+	var validators []tmtypes.GenesisValidator
+	require.NoError(t, tmjson.Unmarshal(gen.doc["validators"], &validators))
+	return validators
+}
+
+func TestPipelineRun(t *testing.T) {
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	pipeline := Pipeline{Transformers: []GenesisTransformer{
+		&SetChainIDTransformer{ChainID: "kavamirror_2221-1"},
+		&SetVotingPeriodTransformer{Period: 30 * time.Second},
+		&SetValueTransformer{Path: "app_state.swap.params.swap_fee", Value: "0.001"},
+		// later steps see the result of earlier ones
+		&SetValueTransformer{Path: "app_state.swap.params.swap_fee", Value: "0.002"},
+	}}
+	require.NoError(t, pipeline.Run(gen))
+
+	chainID, err := gen.ChainID()
+	require.NoError(t, err)
+	assert.Equal(t, "kavamirror_2221-1", chainID)
+	var govParams struct {
+		VotingPeriod string `json:"voting_period"`
+	}
+	require.NoError(t, gen.unmarshalModuleField("gov", "params", &govParams))
+	assert.Equal(t, "30s", govParams.VotingPeriod)
+	var swapParams struct {
+		SwapFee string `json:"swap_fee"`
+	}
+	require.NoError(t, gen.unmarshalModuleField("swap", "params", &swapParams))
+	assert.Equal(t, "0.002", swapParams.SwapFee)
+}
+
+func TestPipelineRunStepError(t *testing.T) {
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	pipeline := Pipeline{Transformers: []GenesisTransformer{
+		&SetChainIDTransformer{ChainID: "kavamirror_2221-1"},
+		&SetVotingPeriodTransformer{},
+		&SetChainIDTransformer{ChainID: "never_1-1"},
+	}}
+	err = pipeline.Run(gen)
+	require.EqualError(t, err, "step 2 (set-voting-period) failed: voting period must be positive, found 0s")
+
+	// the steps before the failure were applied, the ones after it weren't
+	chainID, err := gen.ChainID()
+	require.NoError(t, err)
+	assert.Equal(t, "kavamirror_2221-1", chainID)
+}
+
+func TestPipelineRunFile(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.json")
+	pipeline := Pipeline{Transformers: []GenesisTransformer{&SetChainIDTransformer{ChainID: "kavamirror_2221-1"}}}
+	require.NoError(t, pipeline.RunFile(testGenesisPath, out))
+
+	gen, err := ReadRawGenesis(out)
+	require.NoError(t, err)
+	chainID, err := gen.ChainID()
+	require.NoError(t, err)
+	assert.Equal(t, "kavamirror_2221-1", chainID)
+
+	// the input is left as is
+	original, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+	chainID, err = original.ChainID()
+	require.NoError(t, err)
+	assert.Equal(t, "proto_2221-17000", chainID)
+
+	err = pipeline.RunFile(filepath.Join(t.TempDir(), "missing.json"), out)
+	require.Error(t, err)
+}
+
+func TestReplaceValidatorsTransformer(t *testing.T) {
+	testCases := []struct {
+		name            string
+		powers          []int64
+		numKeys         int
+		minPower        float64
+		persistentPower bool
+		// replaced are the indexes of the validators expected to be replaced, by highest power
+		replaced      []int
+		powerIncrease int64
+	}{
+		{
+			name:     "highest power first",
+			powers:   []int64{10, 60, 30},
+			numKeys:  2,
+			replaced: []int{1, 2},
+		},
+		{
+			name:     "more keys than validators",
+			powers:   []int64{10},
+			numKeys:  2,
+			replaced: []int{0},
+		},
+		{
+			name:     "enough power",
+			powers:   []int64{60, 40},
+			numKeys:  1,
+			minPower: 0.6,
+			replaced: []int{0},
+		},
+		{
+			// (0.67*100 - 60) / (1 - 0.67) = 21.2, rounded up
+			name:          "power increase",
+			powers:        []int64{60, 40},
+			numKeys:       1,
+			minPower:      0.67,
+			replaced:      []int{0},
+			powerIncrease: 22,
+		},
+		{
+			name:            "persistent power increase",
+			powers:          []int64{60, 40},
+			numKeys:         1,
+			minPower:        0.67,
+			persistentPower: true,
+			replaced:        []int{0},
+			powerIncrease:   22,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, validators := newTestExportedGenesis(t, tc.powers...)
+			keysDir, keys := writeTestValidatorKeys(t, tc.numKeys)
+
+			transformer := &ReplaceValidatorsTransformer{KeysDir: keysDir, MinPower: tc.minPower, PersistentPower: tc.persistentPower}
+			require.NoError(t, Pipeline{Transformers: []GenesisTransformer{transformer}}.Run(gen))
+
+			require.Len(t, transformer.Replaced, len(tc.replaced))
+			replacedOperators := map[string]bool{}
+			for i, index := range tc.replaced {
+				replacement := transformer.Replaced[i]
+				assert.Equal(t, fmt.Sprintf("validator-%d", index), replacement.Name)
+				assert.Equal(t, validators[index].ConsAddress, replacement.OldConsAddress)
+				assert.Equal(t, sdk.ConsAddress(keys[i].Address).String(), replacement.NewConsAddress)
+				assert.Equal(t, validators[index].Operator, replacement.OperatorAddress)
+				assert.Equal(t, tc.powerIncrease, replacement.PowerIncrease)
+				assert.Equal(t, validators[index].Power+tc.powerIncrease, replacement.Power)
+				replacedOperators[replacement.OperatorAddress] = true
+			}
+
+			// the genesis validators have the new keys & power
+			byName := map[string]tmtypes.GenesisValidator{}
+			for _, validator := range genesisValidators(t, gen) {
+				byName[validator.Name] = validator
+			}
+			for i, index := range tc.replaced {
+				validator := byName[fmt.Sprintf("validator-%d", index)]
+				assert.Equal(t, keys[i].PubKey, validator.PubKey)
+				assert.Equal(t, keys[i].Address, validator.Address)
+				assert.Equal(t, tc.powers[index]+tc.powerIncrease, validator.Power)
+			}
+
+			// the staking & slashing state use the new keys
+			cdc := app.MakeEncodingConfig().Marshaler
+			var stakingState stakingtypes.GenesisState
+			require.NoError(t, cdc.UnmarshalJSON(gen.AppState[stakingtypes.ModuleName], &stakingState))
+			newConsAddresses := map[string]bool{}
+			for _, replacement := range transformer.Replaced {
+				newConsAddresses[replacement.NewConsAddress] = true
+			}
+			for _, validator := range stakingState.Validators {
+				require.NoError(t, validator.UnpackInterfaces(cdc))
+				consAddress, err := validator.GetConsAddr()
+				require.NoError(t, err)
+				assert.Equal(t, replacedOperators[validator.OperatorAddress], newConsAddresses[consAddress.String()], validator.OperatorAddress)
+			}
+			totalIncrease := tc.powerIncrease * int64(len(tc.replaced))
+			totalPower := int64(0)
+			for _, power := range tc.powers {
+				totalPower += power
+			}
+			assert.Equal(t, sdk.NewInt(totalPower+totalIncrease), stakingState.LastTotalPower)
+			for _, power := range stakingState.LastValidatorPowers {
+				for _, validator := range validators {
+					if validator.Operator != power.Address {
+						continue
+					}
+					expected := validator.Power
+					if replacedOperators[power.Address] {
+						expected += tc.powerIncrease
+					}
+					assert.Equal(t, expected, power.Power)
+				}
+			}
+
+			var slashingState slashingtypes.GenesisState
+			require.NoError(t, cdc.UnmarshalJSON(gen.AppState[slashingtypes.ModuleName], &slashingState))
+			for _, info := range slashingState.SigningInfos {
+				assert.Equal(t, info.Address, info.ValidatorSigningInfo.Address)
+			}
+			signingAddresses := map[string]bool{}
+			for _, info := range slashingState.SigningInfos {
+				signingAddresses[info.Address] = true
+			}
+			for address := range newConsAddresses {
+				assert.True(t, signingAddresses[address], "expected a signing info for %s", address)
+			}
+
+			// the bonded pool is only funded when the increase is persisted
+			bondedPool := authtypes.NewModuleAddress(stakingtypes.BondedPoolName).String()
+			var bankState banktypes.GenesisState
+			require.NoError(t, cdc.UnmarshalJSON(gen.AppState[banktypes.ModuleName], &bankState))
+			var bondedBalance sdk.Coins
+			for _, balance := range bankState.Balances {
+				if balance.Address == bondedPool {
+					bondedBalance = balance.Coins
+				}
+			}
+			expectedBonded := sdk.ZeroInt()
+			if tc.persistentPower {
+				expectedBonded = sdk.TokensFromConsensusPower(totalIncrease, sdk.DefaultPowerReduction)
+			}
+			assert.Equal(t, expectedBonded, bondedBalance.AmountOf("ukava"))
+		})
+	}
+}
+
+func TestReplaceValidatorsTransformerPreviousProposer(t *testing.T) {
+	gen, validators := newTestExportedGenesis(t, 10, 20)
+	_, keys := writeTestValidatorKeys(t, 1)
+
+	// the first validator is the previous proposer, but only the second is replaced
+	transformer := &ReplaceValidatorsTransformer{Keys: keys}
+	require.NoError(t, Pipeline{Transformers: []GenesisTransformer{transformer}}.Run(gen))
+	var previousProposer string
+	require.NoError(t, gen.unmarshalModuleField("distribution", "previous_proposer", &previousProposer))
+	assert.Equal(t, validators[0].ConsAddress, previousProposer)
+
+	gen, _ = newTestExportedGenesis(t, 20, 10)
+	transformer = &ReplaceValidatorsTransformer{Keys: keys}
+	require.NoError(t, Pipeline{Transformers: []GenesisTransformer{transformer}}.Run(gen))
+	require.NoError(t, gen.unmarshalModuleField("distribution", "previous_proposer", &previousProposer))
+	assert.Equal(t, sdk.ConsAddress(keys[0].Address).String(), previousProposer)
+}
+
+func TestReplaceValidatorsTransformerErrors(t *testing.T) {
+	emptyKeysDir := t.TempDir()
+	keysDir, _ := writeTestValidatorKeys(t, 1)
+
+	testCases := []struct {
+		name        string
+		powers      []int64
+		transformer *ReplaceValidatorsTransformer
+		errMsg      string
+	}{
+		{
+			name:        "not exported",
+			transformer: &ReplaceValidatorsTransformer{KeysDir: keysDir},
+			errMsg:      "genesis has no validators to replace",
+		},
+		{
+			name:        "no keys",
+			powers:      []int64{10},
+			transformer: &ReplaceValidatorsTransformer{KeysDir: emptyKeysDir},
+			errMsg:      "at least one validator key is required",
+		},
+		{
+			name:        "other key prefix",
+			powers:      []int64{10},
+			transformer: &ReplaceValidatorsTransformer{KeysDir: keysDir, KeyPrefix: "key_"},
+			errMsg:      "at least one validator key is required",
+		},
+		{
+			name:        "min power out of range",
+			powers:      []int64{10},
+			transformer: &ReplaceValidatorsTransformer{KeysDir: keysDir, MinPower: 1},
+			errMsg:      "minimum power is a percent",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, _ := newTestExportedGenesis(t, tc.powers...)
+			err := Pipeline{Transformers: []GenesisTransformer{tc.transformer}}.Run(gen)
+			require.ErrorContains(t, err, "step 1 (replace-validators) failed")
+			require.ErrorContains(t, err, tc.errMsg)
+		})
+	}
+}
+
+func TestInjectGodCommitteeTransformer(t *testing.T) {
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	transformer := &InjectGodCommitteeTransformer{Member: testGodCommitteeMemberAddress}
+	require.NoError(t, Pipeline{Transformers: []GenesisTransformer{transformer}}.Run(gen))
+	assert.NotZero(t, transformer.CommitteeID)
+
+	err = Pipeline{Transformers: []GenesisTransformer{&InjectGodCommitteeTransformer{}}}.Run(gen)
+	require.EqualError(t, err, "step 1 (inject-god-committee) failed: a committee member is required")
+}
+
+func TestGrantBalanceTransformer(t *testing.T) {
+	const address = "kava1ypjp0m04pyp73hwgtc0dgkx0e9rrydecm054da"
+
+	testCases := []struct {
+		name        string
+		transformer *GrantBalanceTransformer
+		errMsg      string
+	}{
+		{
+			name:        "valid",
+			transformer: &GrantBalanceTransformer{Address: address, Coins: "10ukava,5hard"},
+		},
+		{
+			name:        "invalid address",
+			transformer: &GrantBalanceTransformer{Address: "cosmos1ypjp0m04pyp73hwgtc0dgkx0e9rrydecm054da", Coins: "10ukava"},
+			errMsg:      "invalid address",
+		},
+		{
+			name:        "invalid coins",
+			transformer: &GrantBalanceTransformer{Address: address, Coins: "ten ukava"},
+			errMsg:      "invalid coins",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, err := ReadRawGenesis(testGenesisPath)
+			require.NoError(t, err)
+
+			err = tc.transformer.Transform(gen, app.MakeEncodingConfig().Marshaler)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			var bankState banktypes.GenesisState
+			require.NoError(t, app.MakeEncodingConfig().Marshaler.UnmarshalJSON(gen.AppState[banktypes.ModuleName], &bankState))
+			var balance sdk.Coins
+			for _, b := range bankState.Balances {
+				if b.Address == address {
+					balance = b.Coins
+				}
+			}
+			assert.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("ukava", 10), sdk.NewInt64Coin("hard", 5)), balance)
+		})
+	}
+}
+
+func TestSetValueTransformer(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		value    interface{}
+		expected string
+		errMsg   string
+	}{
+		{
+			name:     "app state scalar",
+			path:     "app_state.swap.params.swap_fee",
+			value:    "0.001",
+			expected: `"0.001"`,
+		},
+		{
+			name:     "app state object",
+			path:     "app_state.gov.params.min_deposit",
+			value:    []interface{}{map[string]interface{}{"denom": "ukava", "amount": "1"}},
+			expected: `[{"denom":"ukava","amount":"1"}]`,
+		},
+		{
+			name:     "new field",
+			path:     "app_state.swap.params.new_field",
+			value:    true,
+			expected: `true`,
+		},
+		{
+			name:     "root field",
+			path:     "initial_height",
+			value:    "100",
+			expected: `"100"`,
+		},
+		{
+			name:   "no path",
+			value:  "x",
+			errMsg: "a path is required",
+		},
+		{
+			name:   "app state",
+			path:   "app_state",
+			value:  map[string]interface{}{},
+			errMsg: "app_state can't be replaced",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, err := ReadRawGenesis(testGenesisPath)
+			require.NoError(t, err)
+
+			err = (&SetValueTransformer{Path: tc.path, Value: tc.value}).Transform(gen, nil)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			bz, err := json.Marshal(gen)
+			require.NoError(t, err)
+			var doc interface{}
+			require.NoError(t, json.Unmarshal(bz, &doc))
+			value := doc
+			for _, key := range strings.Split(tc.path, ".") {
+				value = value.(map[string]interface{})[key]
+			}
+			actual, err := json.Marshal(value)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, string(actual))
+		})
+	}
+}
+
+func TestSetChainIDTransformer(t *testing.T) {
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	require.EqualError(t, (&SetChainIDTransformer{}).Transform(gen, nil), "chain id must not be empty")
+	require.NoError(t, (&SetChainIDTransformer{ChainID: "kavamirror_2221-1"}).Transform(gen, nil))
+	chainID, err := gen.ChainID()
+	require.NoError(t, err)
+	assert.Equal(t, "kavamirror_2221-1", chainID)
+}
+
+func TestSetVotingPeriodTransformer(t *testing.T) {
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	require.EqualError(t, (&SetVotingPeriodTransformer{Period: -time.Second}).Transform(gen, nil), "voting period must be positive, found -1s")
+	require.NoError(t, (&SetVotingPeriodTransformer{Period: 90 * time.Second}).Transform(gen, nil))
+
+	var govParams struct {
+		VotingPeriod string `json:"voting_period"`
+	}
+	require.NoError(t, gen.unmarshalModuleField("gov", "params", &govParams))
+	assert.Equal(t, "90s", govParams.VotingPeriod)
+
+	// genesis files of older sdk versions only have voting_params
+	require.NoError(t, gen.setModuleField("gov", "params", nil))
+	require.NoError(t, (&SetVotingPeriodTransformer{Period: 1500 * time.Millisecond}).Transform(gen, nil))
+	var votingParams struct {
+		VotingPeriod string `json:"voting_period"`
+	}
+	require.NoError(t, gen.unmarshalModuleField("gov", "voting_params", &votingParams))
+	assert.Equal(t, "1.5s", votingParams.VotingPeriod)
+}
@@ -0,0 +1,399 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/Jeffail/gabs/v2"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	genutiltypes "github.com/cosmos/cosmos-sdk/x/genutil/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/kava-labs/kava/app"
+	"github.com/kava-labs/kava/app/params"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	"github.com/tendermint/tendermint/privval"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// GenesisCheck is a named sanity check of a genesis
+type GenesisCheck struct {
+	Name        string
+	Description string
+	check       func(gen *RawGenesis, opts CheckOptions) error
+}
+
+// CheckOptions configures the genesis checks
+type CheckOptions struct {
+	// Skip are the names of checks that aren't run, eg. modules for genesis files of other kava versions
+	Skip []string
+	// ValidatorKeys are the consensus keys of the nodes that will run the genesis. Each must be a genesis validator.
+	ValidatorKeys []privval.FilePVKey
+}
+
+// CheckResult is the result of a genesis check. Err is nil if it passed.
+type CheckResult struct {
+	Name    string
+	Skipped bool
+	Err     error
+}
+
+// genesisChecks are the checks of CheckGenesis, in the order they're run
+var genesisChecks = []GenesisCheck{
+	{
+		Name:        "modules",
+		Description: "the kava app's ValidateGenesis passes for every module",
+		check:       checkModules,
+	},
+	{
+		Name:        "supply",
+		Description: "the bank balances sum to the supply",
+		check:       checkSupply,
+	},
+	{
+		Name:        "validators",
+		Description: "the genesis validators match the staking validators, gentxs & validator keys",
+		check:       checkValidators,
+	},
+	{
+		Name:        "denoms",
+		Description: "the markets of the cdp collateral & hard money market params exist in the pricefeed params",
+		check:       checkDenoms,
+	},
+}
+
+// GenesisChecks returns the checks run by CheckGenesis
+func GenesisChecks() []GenesisCheck {
+	return genesisChecks
+}
+
+// CheckGenesis runs every genesis check that isn't skipped. Use CheckErr to combine the failures.
+func CheckGenesis(gen *RawGenesis, opts CheckOptions) ([]CheckResult, error) {
+	skip := make(map[string]bool, len(opts.Skip))
+	for _, name := range opts.Skip {
+		skip[name] = true
+	}
+	for name := range skip {
+		if !isGenesisCheck(name) {
+			return nil, fmt.Errorf("unknown genesis check %q", name)
+		}
+	}
+
+	results := make([]CheckResult, 0, len(genesisChecks))
+	for _, c := range genesisChecks {
+		if skip[c.Name] {
+			results = append(results, CheckResult{Name: c.Name, Skipped: true})
+			continue
+		}
+		results = append(results, CheckResult{Name: c.Name, Err: c.check(gen, opts)})
+	}
+	return results, nil
+}
+
+// CheckErr joins the errors of the failed checks, or returns nil if they all passed
+func CheckErr(results []CheckResult) error {
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isGenesisCheck(name string) bool {
+	for _, c := range genesisChecks {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkModules runs the ValidateGenesis of each module of the kava app. Unlike ValidateAppState, it reports every
+// invalid module instead of the first.
+func checkModules(gen *RawGenesis, _ CheckOptions) error {
+	encodingConfig := app.MakeEncodingConfig()
+	modules := make([]string, 0, len(app.ModuleBasics))
+	for name := range app.ModuleBasics {
+		modules = append(modules, name)
+	}
+	sort.Strings(modules)
+
+	var errs []error
+	for _, name := range modules {
+		state, found := gen.AppState[name]
+		if !found {
+			errs = append(errs, fmt.Errorf("app_state.%s is missing", name))
+			continue
+		}
+		if err := validateModule(name, state, encodingConfig); err != nil {
+			errs = append(errs, fmt.Errorf("app_state.%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateModule runs a module's ValidateGenesis. Some modules panic on malformed state, which is returned as an error.
+func validateModule(name string, state json.RawMessage, encodingConfig params.EncodingConfig) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("validation panicked: %v", r)
+		}
+	}()
+	return app.ModuleBasics[name].ValidateGenesis(encodingConfig.Marshaler, encodingConfig.TxConfig, state)
+}
+
+// checkSupply checks the bank balances sum to the supply. An empty supply is calculated by x/bank, so it's not checked.
+func checkSupply(gen *RawGenesis, _ CheckOptions) error {
+	var bank struct {
+		Balances []struct {
+			Address string    `json:"address"`
+			Coins   sdk.Coins `json:"coins"`
+		} `json:"balances"`
+		Supply sdk.Coins `json:"supply"`
+	}
+	if err := unmarshalModuleState(gen, banktypes.ModuleName, &bank); err != nil {
+		return err
+	}
+	if bank.Supply.Empty() {
+		return nil
+	}
+
+	total := sdk.NewCoins()
+	for _, balance := range bank.Balances {
+		total = total.Add(balance.Coins...)
+	}
+	var errs []error
+	for _, denom := range coinDenoms(total, bank.Supply) {
+		if balances, supply := total.AmountOf(denom), bank.Supply.AmountOf(denom); !balances.Equal(supply) {
+			errs = append(errs, fmt.Errorf("balances of %s sum to %s, but the supply is %s", denom, balances, supply))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkValidators checks the validators of the genesis document match the power of the bonded staking validators &
+// the gentxs, as tendermint requires when the chain starts. Every validator key must be one of the validators.
+func checkValidators(gen *RawGenesis, opts CheckOptions) error {
+	var errs []error
+
+	var validators []tmtypes.GenesisValidator
+	if raw, found := gen.doc["validators"]; found && string(raw) != "null" {
+		if err := tmjson.Unmarshal(raw, &validators); err != nil {
+			return fmt.Errorf("failed to unmarshal validators: %w", err)
+		}
+	}
+	// consensus pubkey -> power of the genesis document's validators
+	genesisPowers := make(map[string]int64, len(validators))
+	for _, v := range validators {
+		key := base64.StdEncoding.EncodeToString(v.PubKey.Bytes())
+		if !bytes.Equal(v.Address, v.PubKey.Address()) {
+			errs = append(errs, fmt.Errorf("validator %s (%s) has the address of a different pubkey", v.Address, v.Name))
+		}
+		if _, found := genesisPowers[key]; found {
+			errs = append(errs, fmt.Errorf("validator %s (%s) is a duplicate", v.Address, v.Name))
+		}
+		genesisPowers[key] = v.Power
+	}
+
+	stakingPowers, err := stakingValidatorPowers(gen)
+	if err != nil {
+		return err
+	}
+	gentxKeys, err := gentxPubKeys(gen)
+	if err != nil {
+		return err
+	}
+	for _, key := range unionKeys(gentxKeys, nil) {
+		if _, found := stakingPowers[key]; found {
+			errs = append(errs, fmt.Errorf("gentx validator %s is already a staking validator", key))
+		}
+	}
+
+	// an empty validator set is filled in from the app state when the chain starts
+	if len(validators) > 0 {
+		for _, key := range unionKeys(stakingPowers, nil) {
+			power := stakingPowers[key]
+			genesisPower, found := genesisPowers[key]
+			switch {
+			case !found:
+				errs = append(errs, fmt.Errorf("bonded staking validator %s is not in validators", key))
+			case genesisPower != power:
+				errs = append(errs, fmt.Errorf("validator %s has power %d, but its staking power is %d", key, genesisPower, power))
+			}
+		}
+		for _, key := range unionKeys(gentxKeys, nil) {
+			if _, found := genesisPowers[key]; !found {
+				errs = append(errs, fmt.Errorf("gentx validator %s is not in validators", key))
+			}
+		}
+		for _, key := range unionKeys(genesisPowers, nil) {
+			_, isStaking := stakingPowers[key]
+			_, isGentx := gentxKeys[key]
+			if !isStaking && !isGentx {
+				errs = append(errs, fmt.Errorf("validator %s is not a bonded staking validator or gentx", key))
+			}
+		}
+	}
+
+	for i, pvKey := range opts.ValidatorKeys {
+		key := base64.StdEncoding.EncodeToString(pvKey.PubKey.Bytes())
+		_, isGenesis := genesisPowers[key]
+		_, isStaking := stakingPowers[key]
+		_, isGentx := gentxKeys[key]
+		if !isGenesis && !isStaking && !isGentx {
+			errs = append(errs, fmt.Errorf("validator key %d (%s) is not a genesis validator, its node won't sign blocks", i+1, key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stakingValidatorPowers returns the last power of the bonded staking validators, by base64 consensus pubkey
+func stakingValidatorPowers(gen *RawGenesis) (map[string]int64, error) {
+	staking, err := parseOptionalModuleState(gen, stakingtypes.ModuleName)
+	if err != nil || staking == nil {
+		return nil, err
+	}
+	lastPowers := map[string]int64{}
+	for _, validatorPower := range staking.Path("last_validator_powers").Children() {
+		power, err := strconv.ParseInt(jsonString(validatorPower, "power"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid last validator power: %w", err)
+		}
+		lastPowers[jsonString(validatorPower, "address")] = power
+	}
+
+	powers := map[string]int64{}
+	for _, validator := range staking.Path("validators").Children() {
+		power, found := lastPowers[jsonString(validator, "operator_address")]
+		if !found || jsonString(validator, "status") != stakingtypes.Bonded.String() {
+			continue
+		}
+		powers[jsonString(validator, "consensus_pubkey.key")] = power
+	}
+	return powers, nil
+}
+
+// gentxPubKeys returns the consensus pubkeys of the validators created by gentxs, base64 encoded
+func gentxPubKeys(gen *RawGenesis) (map[string]bool, error) {
+	if _, found := gen.AppState[genutiltypes.ModuleName]; !found {
+		return nil, nil
+	}
+	gentxs, err := gen.Gentxs()
+	if err != nil {
+		return nil, err
+	}
+	decode := app.MakeEncodingConfig().TxConfig.TxJSONDecoder()
+	keys := make(map[string]bool, len(gentxs))
+	for i, raw := range gentxs {
+		tx, err := decode(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gentx %d: %w", i, err)
+		}
+		for _, msg := range tx.GetMsgs() {
+			createValidator, ok := msg.(*stakingtypes.MsgCreateValidator)
+			if !ok {
+				continue
+			}
+			pubKey, ok := createValidator.Pubkey.GetCachedValue().(cryptotypes.PubKey)
+			if !ok {
+				return nil, fmt.Errorf("gentx %d has an invalid pubkey", i)
+			}
+			key := base64.StdEncoding.EncodeToString(pubKey.Bytes())
+			if keys[key] {
+				return nil, fmt.Errorf("gentx %d has a duplicate pubkey %s", i, key)
+			}
+			keys[key] = true
+		}
+	}
+	return keys, nil
+}
+
+// checkDenoms checks the pricefeed markets referenced by the cdp collateral & hard money market params exist
+func checkDenoms(gen *RawGenesis, _ CheckOptions) error {
+	pricefeed, err := parseOptionalModuleState(gen, "pricefeed")
+	if err != nil || pricefeed == nil {
+		return err
+	}
+	cdp, err := parseOptionalModuleState(gen, "cdp")
+	if err != nil {
+		return err
+	}
+	hard, err := parseOptionalModuleState(gen, "hard")
+	if err != nil {
+		return err
+	}
+
+	markets := paramSet(pricefeed, "params.markets", "market_id")
+	var errs []error
+	errs = append(errs, checkReferences(cdp, "params.collateral_params", "spot_market_id", markets)...)
+	errs = append(errs, checkReferences(cdp, "params.collateral_params", "liquidation_market_id", markets)...)
+	errs = append(errs, checkReferences(hard, "params.money_markets", "spot_market_id", markets)...)
+	return errors.Join(errs...)
+}
+
+// checkReferences checks the field of each entry of the list at path is one of the pricefeed markets
+func checkReferences(state *gabs.Container, path, field string, markets map[string]bool) []error {
+	if state == nil {
+		return nil
+	}
+	var errs []error
+	for i, entry := range state.Path(path).Children() {
+		value := jsonString(entry, field)
+		if value != "" && !markets[value] {
+			errs = append(errs, fmt.Errorf("%s[%d].%s: %q is not a pricefeed market", path, i, field, value))
+		}
+	}
+	return errs
+}
+
+// paramSet returns the values of field of each entry of the list at path
+func paramSet(state *gabs.Container, path, field string) map[string]bool {
+	values := map[string]bool{}
+	if state == nil {
+		return values
+	}
+	for _, entry := range state.Path(path).Children() {
+		values[jsonString(entry, field)] = true
+	}
+	return values
+}
+
+// parseOptionalModuleState parses app_state.<module>, or returns nil if the module isn't in the genesis
+func parseOptionalModuleState(gen *RawGenesis, module string) (*gabs.Container, error) {
+	if _, found := gen.AppState[module]; !found {
+		return nil, nil
+	}
+	return parseModuleState(gen, module)
+}
+
+// unmarshalModuleState decodes app_state.<module> without the app codec, so unknown fields are ignored
+func unmarshalModuleState(gen *RawGenesis, module string, v interface{}) error {
+	state, found := gen.AppState[module]
+	if !found {
+		return fmt.Errorf("app_state.%s is missing", module)
+	}
+	if err := json.Unmarshal(state, v); err != nil {
+		return fmt.Errorf("failed to unmarshal app_state.%s: %w", module, err)
+	}
+	return nil
+}
+
+// coinDenoms returns the sorted denoms of both coins
+func coinDenoms(a, b sdk.Coins) []string {
+	denoms := map[string]bool{}
+	for _, coin := range a {
+		denoms[coin.Denom] = true
+	}
+	for _, coin := range b {
+		denoms[coin.Denom] = true
+	}
+	return unionKeys(denoms, nil)
+}
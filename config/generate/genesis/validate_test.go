@@ -0,0 +1,315 @@
+package genesis
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/kava-labs/kava/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	"github.com/tendermint/tendermint/privval"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// newTestDefaultGenesis returns a genesis with the default state of every module of the kava app, which passes
+// the modules check
+func newTestDefaultGenesis(t *testing.T) *RawGenesis {
+	t.Helper()
+	appState, err := json.Marshal(app.ModuleBasics.DefaultGenesis(app.MakeEncodingConfig().Marshaler))
+	require.NoError(t, err)
+	doc, err := json.Marshal(map[string]json.RawMessage{
+		"genesis_time": json.RawMessage(`"2022-05-25T17:00:00Z"`),
+		"chain_id":     json.RawMessage(`"kavalocalnet_8888-1"`),
+		"app_state":    appState,
+	})
+	require.NoError(t, err)
+	gen, err := NewRawGenesis(doc)
+	require.NoError(t, err)
+	return gen
+}
+
+// setGenesisValidators replaces the validators at the root of the genesis
+func setGenesisValidators(t *testing.T, gen *RawGenesis, validators []tmtypes.GenesisValidator) {
+	t.Helper()
+	var err error
+	gen.doc["validators"], err = tmjson.Marshal(validators)
+	require.NoError(t, err)
+}
+
+// consensusKey is the base64 consensus pubkey that identifies a validator in the check errors
+func consensusKey(validator tmtypes.GenesisValidator) string {
+	return base64.StdEncoding.EncodeToString(validator.PubKey.Bytes())
+}
+
+func TestCheckGenesisModules(t *testing.T) {
+	testCases := []struct {
+		name         string
+		update       func(t *testing.T, gen *RawGenesis)
+		expectedErrs []string
+	}{
+		{
+			name:   "valid",
+			update: func(*testing.T, *RawGenesis) {},
+		},
+		{
+			name: "invalid module state",
+			update: func(t *testing.T, gen *RawGenesis) {
+				require.NoError(t, gen.SetAppStateValue("mint.params.mint_denom", json.RawMessage(`""`)))
+			},
+			expectedErrs: []string{"app_state.mint: mint denom cannot be blank"},
+		},
+		{
+			name: "undecodable module state",
+			update: func(t *testing.T, gen *RawGenesis) {
+				require.NoError(t, gen.SetAppStateValue("swap.params.unknown", json.RawMessage(`"1"`)))
+			},
+			expectedErrs: []string{`app_state.swap: unknown field "unknown"`},
+		},
+		{
+			name: "missing modules",
+			update: func(t *testing.T, gen *RawGenesis) {
+				delete(gen.AppState, "router")
+				delete(gen.AppState, "swap")
+			},
+			expectedErrs: []string{"app_state.router is missing", "app_state.swap is missing"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen := newTestDefaultGenesis(t)
+			tc.update(t, gen)
+
+			results, err := CheckGenesis(gen, CheckOptions{})
+			require.NoError(t, err)
+			require.Equal(t, "modules", results[0].Name)
+			if len(tc.expectedErrs) == 0 {
+				require.NoError(t, CheckErr(results))
+				return
+			}
+			for _, expected := range tc.expectedErrs {
+				assert.ErrorContains(t, results[0].Err, expected)
+			}
+			for _, result := range results[1:] {
+				assert.NoError(t, result.Err, result.Name)
+			}
+		})
+	}
+}
+
+func TestCheckGenesis(t *testing.T) {
+	testCases := []struct {
+		name string
+		// update breaks the test genesis, whose validators have powers of 10 & 20
+		update func(t *testing.T, gen *RawGenesis, validators []tmtypes.GenesisValidator) CheckOptions
+		// expectedCheck is the check that fails, with errors containing expectedErrs
+		expectedCheck string
+		expectedErrs  func(validators []tmtypes.GenesisValidator) []string
+	}{
+		{
+			name: "valid",
+			update: func(*testing.T, *RawGenesis, []tmtypes.GenesisValidator) CheckOptions {
+				return CheckOptions{}
+			},
+		},
+		{
+			name: "supply matching the balances",
+			update: func(t *testing.T, gen *RawGenesis, _ []tmtypes.GenesisValidator) CheckOptions {
+				balances, _ := testBankState(t, gen)
+				total := sdk.NewCoins()
+				for _, coins := range balances {
+					total = total.Add(coins...)
+				}
+				supply, err := json.Marshal(total)
+				require.NoError(t, err)
+				require.NoError(t, gen.SetAppStateValue("bank.supply", supply))
+				return CheckOptions{}
+			},
+		},
+		{
+			name: "supply doesn't match the balances",
+			update: func(t *testing.T, gen *RawGenesis, _ []tmtypes.GenesisValidator) CheckOptions {
+				require.NoError(t, gen.SetAppStateValue("bank.supply", json.RawMessage(`[{"denom":"ukava","amount":"1000"}]`)))
+				return CheckOptions{}
+			},
+			expectedCheck: "supply",
+			expectedErrs: func([]tmtypes.GenesisValidator) []string {
+				return []string{
+					"balances of ukava sum to 10002000230009000000000, but the supply is 1000",
+					"balances of hard sum to 100005000000000, but the supply is 0",
+				}
+			},
+		},
+		{
+			name: "validator power doesn't match its staking power",
+			update: func(t *testing.T, gen *RawGenesis, validators []tmtypes.GenesisValidator) CheckOptions {
+				validators[1].Power = 30
+				setGenesisValidators(t, gen, validators)
+				return CheckOptions{}
+			},
+			expectedCheck: "validators",
+			expectedErrs: func(validators []tmtypes.GenesisValidator) []string {
+				return []string{fmt.Sprintf("validator %s has power 30, but its staking power is 20", consensusKey(validators[1]))}
+			},
+		},
+		{
+			name: "bonded staking validator isn't a validator",
+			update: func(t *testing.T, gen *RawGenesis, validators []tmtypes.GenesisValidator) CheckOptions {
+				setGenesisValidators(t, gen, validators[:1])
+				return CheckOptions{}
+			},
+			expectedCheck: "validators",
+			expectedErrs: func(validators []tmtypes.GenesisValidator) []string {
+				return []string{fmt.Sprintf("bonded staking validator %s is not in validators", consensusKey(validators[1]))}
+			},
+		},
+		{
+			name: "validator isn't a staking validator or gentx",
+			update: func(t *testing.T, gen *RawGenesis, validators []tmtypes.GenesisValidator) CheckOptions {
+				pubKey := ed25519.GenPrivKey().PubKey()
+				validators = append(validators, tmtypes.GenesisValidator{Address: pubKey.Address(), PubKey: pubKey, Power: 10, Name: "extra"})
+				setGenesisValidators(t, gen, validators)
+				return CheckOptions{}
+			},
+			expectedCheck: "validators",
+			expectedErrs: func(validators []tmtypes.GenesisValidator) []string {
+				return []string{"is not a bonded staking validator or gentx"}
+			},
+		},
+		{
+			name: "duplicate validator with the address of another pubkey",
+			update: func(t *testing.T, gen *RawGenesis, validators []tmtypes.GenesisValidator) CheckOptions {
+				duplicate := validators[0]
+				duplicate.Address = validators[1].Address
+				setGenesisValidators(t, gen, append(validators, duplicate))
+				return CheckOptions{}
+			},
+			expectedCheck: "validators",
+			expectedErrs: func(validators []tmtypes.GenesisValidator) []string {
+				return []string{
+					fmt.Sprintf("validator %s (validator-0) has the address of a different pubkey", validators[1].Address),
+					fmt.Sprintf("validator %s (validator-0) is a duplicate", validators[1].Address),
+				}
+			},
+		},
+		{
+			name: "gentx validator isn't a validator",
+			update: func(t *testing.T, gen *RawGenesis, _ []tmtypes.GenesisValidator) CheckOptions {
+				original, err := ReadRawGenesis(testGenesisPath)
+				require.NoError(t, err)
+				gen.AppState["genutil"] = original.AppState["genutil"]
+				return CheckOptions{}
+			},
+			expectedCheck: "validators",
+			expectedErrs: func([]tmtypes.GenesisValidator) []string {
+				return []string{"gentx validator SzSj9ej4GZAopruFI2pltVGj4fMq7m0JQePS9lELUiA= is not in validators"}
+			},
+		},
+		{
+			name: "validator key isn't a genesis validator",
+			update: func(t *testing.T, gen *RawGenesis, _ []tmtypes.GenesisValidator) CheckOptions {
+				pubKey := ed25519.GenPrivKey().PubKey()
+				return CheckOptions{ValidatorKeys: []privval.FilePVKey{{Address: pubKey.Address(), PubKey: pubKey}}}
+			},
+			expectedCheck: "validators",
+			expectedErrs: func([]tmtypes.GenesisValidator) []string {
+				return []string{"validator key 1 (", "is not a genesis validator, its node won't sign blocks"}
+			},
+		},
+		{
+			name: "market isn't a pricefeed market",
+			update: func(t *testing.T, gen *RawGenesis, _ []tmtypes.GenesisValidator) CheckOptions {
+				pricefeed, err := parseModuleState(gen, "pricefeed")
+				require.NoError(t, err)
+				var markets []interface{}
+				for _, market := range pricefeed.Path("params.markets").Children() {
+					if jsonString(market, "market_id") != "btc:usd:30" {
+						markets = append(markets, market.Data())
+					}
+				}
+				_, err = pricefeed.SetP(markets, "params.markets")
+				require.NoError(t, err)
+				gen.AppState["pricefeed"] = pricefeed.Bytes()
+				return CheckOptions{}
+			},
+			expectedCheck: "denoms",
+			expectedErrs: func([]tmtypes.GenesisValidator) []string {
+				return []string{
+					`params.money_markets[0].spot_market_id: "btc:usd:30" is not a pricefeed market`,
+					`params.money_markets[2].spot_market_id: "btc:usd:30" is not a pricefeed market`,
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, _ := newTestExportedGenesis(t, 10, 20)
+			// the validators replace the gentx of the test genesis
+			require.NoError(t, gen.SetAppStateValue("genutil.gen_txs", json.RawMessage(`[]`)))
+			validators := genesisValidators(t, gen)
+			opts := tc.update(t, gen, validators)
+			// the test genesis is from another kava version, so its modules aren't checked
+			opts.Skip = []string{"modules"}
+
+			results, err := CheckGenesis(gen, opts)
+			require.NoError(t, err)
+			for _, result := range results {
+				if result.Name == "modules" {
+					assert.True(t, result.Skipped)
+					continue
+				}
+				if result.Name != tc.expectedCheck {
+					assert.NoError(t, result.Err, result.Name)
+					continue
+				}
+				require.Error(t, result.Err)
+				for _, expected := range tc.expectedErrs(validators) {
+					assert.ErrorContains(t, result.Err, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckGenesisSkip(t *testing.T) {
+	gen, err := ReadRawGenesis(testGenesisPath)
+	require.NoError(t, err)
+
+	// the modules of the test genesis are from another kava version
+	results, err := CheckGenesis(gen, CheckOptions{})
+	require.NoError(t, err)
+	err = CheckErr(results)
+	require.ErrorContains(t, err, "modules: app_state.cdp")
+	assert.NotContains(t, err.Error(), "supply:")
+
+	results, err = CheckGenesis(gen, CheckOptions{Skip: []string{"modules", "denoms"}})
+	require.NoError(t, err)
+	assert.Equal(t, []CheckResult{
+		{Name: "modules", Skipped: true},
+		{Name: "supply"},
+		{Name: "validators"},
+		{Name: "denoms", Skipped: true},
+	}, results)
+	require.NoError(t, CheckErr(results))
+
+	_, err = CheckGenesis(gen, CheckOptions{Skip: []string{"module"}})
+	require.EqualError(t, err, `unknown genesis check "module"`)
+}
+
+func TestCheckErr(t *testing.T) {
+	assert.NoError(t, CheckErr(nil))
+	assert.NoError(t, CheckErr([]CheckResult{{Name: "modules", Skipped: true}, {Name: "supply"}}))
+
+	err := CheckErr([]CheckResult{
+		{Name: "modules", Err: fmt.Errorf("app_state.swap is missing")},
+		{Name: "supply"},
+		{Name: "denoms", Err: fmt.Errorf("unknown market")},
+	})
+	require.EqualError(t, err, "modules: app_state.swap is missing\ndenoms: unknown market")
+}
@@ -0,0 +1,324 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	"github.com/tendermint/tendermint/privval"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const (
+	// DefaultValidatorKeyPrefix is the default file prefix of the keys read by LoadValidatorKeys
+	DefaultValidatorKeyPrefix = "priv_validator_key_"
+	// ed25519PubKeyType is the type url of the consensus keys in app_state.staking.validators
+	ed25519PubKeyType = "/cosmos.crypto.ed25519.PubKey"
+)
+
+// ValidatorReplacement is a genesis validator whose consensus key was replaced
+type ValidatorReplacement struct {
+	Name           string
+	OldConsAddress string
+	NewConsAddress string
+	// OperatorAddress is the valoper address of the validator in app_state.staking, if it was found
+	OperatorAddress string
+	// Power is the validator's power after any increase
+	Power int64
+	// PowerIncrease is the power added to hold the minimum share of the total power
+	PowerIncrease int64
+}
+
+// LoadValidatorKey reads a priv_validator_key.json
+func LoadValidatorKey(path string) (privval.FilePVKey, error) {
+	var key privval.FilePVKey
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return key, fmt.Errorf("failed to read validator key: %w", err)
+	}
+	// tendermint's json is required to decode the amino encoded keys
+	if err := tmjson.Unmarshal(bz, &key); err != nil {
+		return key, fmt.Errorf("failed to parse validator key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// LoadValidatorKeys reads the validator keys named <prefix><index>.json in dir, starting from index 0
+func LoadValidatorKeys(dir, prefix string) ([]privval.FilePVKey, error) {
+	var keys []privval.FilePVKey
+	for i := 0; ; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%s%d.json", prefix, i))
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		key, err := LoadValidatorKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ReplaceValidators replaces the consensus keys of the genesis validators with keys, in order of highest power.
+// Validators without a replacement key are left as is. The consensus addresses are updated in the staking,
+// slashing & distribution state & the genesis time is set to now.
+//
+// If the replaced validators hold less than minPowerPercent (0 <= minPowerPercent < 1) of the total power, their power
+// is increased so they hold at least that much. Note the increase is reverted by x/staking after the first block.
+func ReplaceValidators(gen *RawGenesis, keys []privval.FilePVKey, minPowerPercent float64) ([]ValidatorReplacement, error) {
+	if minPowerPercent < 0 || minPowerPercent >= 1 {
+		return nil, fmt.Errorf("minimum power is a percent. out of range: 0 <= power < 1")
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one validator key is required")
+	}
+
+	var validators []tmtypes.GenesisValidator
+	if raw, found := gen.doc["validators"]; found && string(raw) != "null" {
+		if err := tmjson.Unmarshal(raw, &validators); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal validators: %w", err)
+		}
+	}
+	if len(validators) == 0 {
+		return nil, fmt.Errorf("genesis has no validators to replace, only exported genesis files can have their validators replaced")
+	}
+
+	numReplace := len(validators)
+	if len(keys) < numReplace {
+		numReplace = len(keys)
+	}
+
+	// replace validators from highest power to lowest
+	sort.SliceStable(validators, func(i, j int) bool {
+		return validators[i].Power > validators[j].Power
+	})
+	// original valcons address -> new key
+	replacements := make(map[string]privval.FilePVKey, numReplace)
+	replacedPower := sdk.ZeroInt()
+	result := make([]ValidatorReplacement, numReplace)
+	for i := 0; i < numReplace; i++ {
+		orig := sdk.ConsAddress(validators[i].Address).String()
+		replacements[orig] = keys[i]
+		result[i] = ValidatorReplacement{
+			Name:           validators[i].Name,
+			OldConsAddress: orig,
+			NewConsAddress: sdk.ConsAddress(keys[i].Address).String(),
+		}
+
+		validators[i].PubKey = keys[i].PubKey
+		validators[i].Address = keys[i].Address
+		replacedPower = replacedPower.AddRaw(validators[i].Power)
+	}
+
+	powerDelta, operators, err := replaceStakingValidators(gen, replacements, replacedPower, numReplace, minPowerPercent)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < numReplace; i++ {
+		validators[i].Power += powerDelta
+		result[i].Power = validators[i].Power
+		result[i].PowerIncrease = powerDelta
+		result[i].OperatorAddress = operators[result[i].OldConsAddress]
+	}
+	validatorsJSON, err := tmjson.Marshal(validators)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal validators: %w", err)
+	}
+	gen.doc["validators"] = validatorsJSON
+
+	if err := replaceSlashingAddresses(gen, replacements); err != nil {
+		return nil, err
+	}
+	if err := replaceDistributionAddresses(gen, replacements); err != nil {
+		return nil, err
+	}
+
+	// the genesis time must be recent to avoid a consensus error, see https://github.com/tendermint/tendermint/issues/8773
+	if err := gen.SetGenesisTime(time.Now()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// replaceStakingValidators replaces the consensus keys of app_state.staking.validators & increases the power of the
+// replaced validators to hold minPowerPercent of the total. It returns the power added to each replaced validator &
+// the operator addresses of the replaced validators by original consensus address.
+func replaceStakingValidators(
+	gen *RawGenesis,
+	replacements map[string]privval.FilePVKey,
+	replacedPower sdk.Int,
+	numReplace int,
+	minPowerPercent float64,
+) (int64, map[string]string, error) {
+	staking, err := parseModuleState(gen, stakingtypes.ModuleName)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	lastTotalPower, ok := sdk.NewIntFromString(jsonString(staking, "last_total_power"))
+	if !ok {
+		return 0, nil, fmt.Errorf("invalid app_state.staking.last_total_power")
+	}
+	totalPowerDelta := calcPowerDelta(lastTotalPower, replacedPower, minPowerPercent)
+	powerDelta := int64(0)
+	if !totalPowerDelta.IsZero() {
+		powerDelta = totalPowerDelta.QuoRaw(int64(numReplace)).Int64()
+	}
+
+	// operator addresses of the replaced validators, for updating their power
+	replacedOperators := map[string]bool{}
+	operators := map[string]string{}
+	for _, validator := range staking.Path("validators").Children() {
+		var pubKey struct {
+			Type string `json:"@type"`
+			Key  []byte `json:"key"`
+		}
+		if err := json.Unmarshal(validator.Path("consensus_pubkey").Bytes(), &pubKey); err != nil {
+			return 0, nil, fmt.Errorf("failed to unmarshal consensus pubkey: %w", err)
+		}
+		if pubKey.Type != ed25519PubKeyType {
+			continue
+		}
+		orig := sdk.ConsAddress((&ed25519.PubKey{Key: pubKey.Key}).Address()).String()
+		replacement, found := replacements[orig]
+		if !found {
+			continue
+		}
+		if _, err := validator.Set(map[string]interface{}{
+			"@type": ed25519PubKeyType,
+			"key":   replacement.PubKey.Bytes(),
+		}, "consensus_pubkey"); err != nil {
+			return 0, nil, err
+		}
+		replacedOperators[jsonString(validator, "operator_address")] = true
+		operators[orig] = jsonString(validator, "operator_address")
+	}
+
+	for _, validatorPower := range staking.Path("last_validator_powers").Children() {
+		if !replacedOperators[jsonString(validatorPower, "address")] {
+			continue
+		}
+		power, err := strconv.ParseInt(jsonString(validatorPower, "power"), 10, 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid last validator power: %w", err)
+		}
+		if _, err := validatorPower.Set(strconv.FormatInt(power+powerDelta, 10), "power"); err != nil {
+			return 0, nil, err
+		}
+	}
+	newTotalPower := lastTotalPower.Add(sdk.NewInt(powerDelta).MulRaw(int64(numReplace)))
+	if _, err := staking.Set(newTotalPower.String(), "last_total_power"); err != nil {
+		return 0, nil, err
+	}
+
+	gen.AppState[stakingtypes.ModuleName] = staking.Bytes()
+	return powerDelta, operators, nil
+}
+
+// replaceSlashingAddresses replaces the consensus addresses of the missed blocks & signing infos in app_state.slashing
+func replaceSlashingAddresses(gen *RawGenesis, replacements map[string]privval.FilePVKey) error {
+	slashing, err := parseModuleState(gen, slashingtypes.ModuleName)
+	if err != nil {
+		return err
+	}
+	for _, missedBlocks := range slashing.Path("missed_blocks").Children() {
+		if replacement, found := replacements[jsonString(missedBlocks, "address")]; found {
+			if _, err := missedBlocks.Set(sdk.ConsAddress(replacement.Address).String(), "address"); err != nil {
+				return err
+			}
+		}
+	}
+	for _, signingInfo := range slashing.Path("signing_infos").Children() {
+		if replacement, found := replacements[jsonString(signingInfo, "address")]; found {
+			address := sdk.ConsAddress(replacement.Address).String()
+			if _, err := signingInfo.Set(address, "address"); err != nil {
+				return err
+			}
+			if _, err := signingInfo.Set(address, "validator_signing_info", "address"); err != nil {
+				return err
+			}
+		}
+	}
+	gen.AppState[slashingtypes.ModuleName] = slashing.Bytes()
+	return nil
+}
+
+// replaceDistributionAddresses replaces the consensus address of app_state.distribution.previous_proposer
+func replaceDistributionAddresses(gen *RawGenesis, replacements map[string]privval.FilePVKey) error {
+	var previousProposer string
+	if err := gen.unmarshalModuleField(distributiontypes.ModuleName, "previous_proposer", &previousProposer); err != nil {
+		return err
+	}
+	replacement, found := replacements[previousProposer]
+	if !found {
+		return nil
+	}
+	return gen.setModuleField(distributiontypes.ModuleName, "previous_proposer", sdk.ConsAddress(replacement.Address).String())
+}
+
+// parseModuleState parses app_state.<module> for editing. Numbers are kept as json.Number so large values aren't rounded.
+func parseModuleState(gen *RawGenesis, module string) (*gabs.Container, error) {
+	decoder := json.NewDecoder(bytes.NewReader(gen.AppState[module]))
+	decoder.UseNumber()
+	state, err := gabs.ParseJSONDecoder(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal app_state.%s: %w", module, err)
+	}
+	return state, nil
+}
+
+// jsonString returns the string form of the string or number at path, or "" if there is none
+func jsonString(c *gabs.Container, path string) string {
+	switch v := c.Path(path).Data().(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
+
+// calcPowerDelta calculates the necessary total power increase that, when given to the replaced
+// validators, adjusts the total power such that the replaced validators control at least the
+// desired percentage
+func calcPowerDelta(initialTotalPower, initialValPower sdk.Int, desiredPercent float64) sdk.Int {
+	iTotalPower := new(big.Float).SetInt(initialTotalPower.BigInt())
+	iValPower := new(big.Float).SetInt(initialValPower.BigInt())
+	initialPercent := new(big.Float).Quo(iValPower, iTotalPower)
+
+	percentAfter := big.NewFloat(desiredPercent)
+	// if we already have enough power, no change is necessary
+	if initialPercent.Cmp(percentAfter) >= 0 {
+		return sdk.ZeroInt()
+	}
+
+	// a = (P + Δ) / (T + Δ) => Δ = (a*T - P) / (1 - a)
+	// Δ - total change in power given to replaced validators
+	// a - desired percentage of total power
+	// P - initial power of replaced validators
+	// T - initial total power of all validators
+	num := new(big.Float).Sub(new(big.Float).Mul(percentAfter, iTotalPower), iValPower)
+	den := new(big.Float).Sub(big.NewFloat(1), percentAfter)
+	delta := new(big.Float).Quo(num, den)
+
+	// convert the delta to a big int
+	roundedDelta := new(big.Int)
+	delta.Int(roundedDelta)
+	// add 1 to ensure any rounding is in our validators' favor
+	return sdk.NewIntFromBigInt(roundedDelta).AddRaw(1)
+}
@@ -0,0 +1,104 @@
+package generate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/otiai10/copy"
+
+	"github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+const (
+	// HermesMnemonicFile is the file of the hermes dir holding the mnemonic of the relayer's key
+	HermesMnemonicFile = "mnemonic.txt"
+	// HermesHdPath is the hd path of the relayer's key, the path of kava accounts
+	HermesHdPath = "m/44'/459'/0'/0/0"
+)
+
+// hermesChainConfig is the [[chains]] entry of a chain in the hermes config.toml
+const hermesChainConfig = `
+[[chains]]
+id = '%[1]s'
+type = 'CosmosSdk'
+rpc_addr = 'http://%[2]s:26657'
+grpc_addr = 'http://%[2]s:9090'
+event_source = { mode = 'push', url = 'ws://%[2]s:26657/websocket', batch_delay = '500ms' }
+rpc_timeout = '10s'
+trusted_node = true
+account_prefix = 'kava'
+key_name = 'testkey'
+key_store_type = 'Test'
+store_prefix = 'ibc'
+default_gas = 100000
+max_gas = 4000000
+gas_price = { price = 0.01, denom = '%[3]s' }
+gas_multiplier = 1.5
+max_msg_num = 30
+max_tx_size = 180000
+clock_drift = '5s'
+max_block_time = '30s'
+trust_threshold = { numerator = '1', denominator = '3' }
+address_type = { derivation = 'cosmos' }
+`
+
+// ChainID returns the chain id of a chain of the topology, the chain id of the kava template for kava
+func (t IbcTopology) ChainID(name string) string {
+	if name == KavaIbcChainName {
+		return genesis.DefaultChainID
+	}
+	for _, chain := range t.Chains {
+		if chain.Name == name {
+			return chain.ChainID
+		}
+	}
+	return ""
+}
+
+// GenerateHermesConfig generates the hermes config.toml with a chain for kava & each ibc chain. Hermes relays every
+// channel, so the paths are only used when they are opened. The keys are added by hermes from the mnemonic file.
+func GenerateHermesConfig(generatedConfigDir string, topology IbcTopology) error {
+	templateDir, err := findTemplate(HermesTemplate)
+	if err != nil {
+		return err
+	}
+	hermesDir := filepath.Join(generatedConfigDir, HermesTemplate)
+	if err := copy.Copy(templateDir, hermesDir); err != nil {
+		return err
+	}
+	// the image runs as the hermes user, which adds the keys to the dir
+	if err := os.Chmod(hermesDir, 0777); err != nil {
+		return err
+	}
+
+	var chains strings.Builder
+	fmt.Fprintf(&chains, hermesChainConfig, genesis.DefaultChainID, KavaValidatorServiceName, genesis.DefaultDenom)
+	for _, chain := range topology.Chains {
+		fmt.Fprintf(&chains, hermesChainConfig, chain.ChainID, chain.ServiceName(), chain.Denom)
+	}
+	configPath := filepath.Join(hermesDir, "config.toml")
+	config, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(configPath, append(config, chains.String()...), 0644); err != nil {
+		return err
+	}
+
+	common, err := commonDir()
+	if err != nil {
+		return err
+	}
+	addresses, err := gabs.ParseJSONFile(filepath.Join(common, "addresses.json"))
+	if err != nil {
+		return fmt.Errorf("failed to load addresses: %w", err)
+	}
+	mnemonic, ok := addresses.Path("kava.validators.0.mnemonic").Data().(string)
+	if !ok {
+		return fmt.Errorf("no validator mnemonic found in addresses.json")
+	}
+	return os.WriteFile(filepath.Join(hermesDir, HermesMnemonicFile), []byte(mnemonic), 0644)
+}
@@ -0,0 +1,153 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// testTwoChainTopology is kava with the default ibc chain & osmosis, with transfer paths from kava & between the chains
+func testTwoChainTopology(t *testing.T) IbcTopology {
+	t.Helper()
+	topology, err := IbcTopology{
+		Chains: []IbcChain{
+			{Name: DefaultIbcChainName},
+			{Name: "osmosis", ChainID: "osmosis_9000-1", Denom: "uosmo"},
+		},
+		Paths: []IbcPath{
+			{Name: "kava-ibcchain", Src: KavaIbcChainName, Dst: DefaultIbcChainName},
+			{Name: "ibcchain-osmosis", Src: DefaultIbcChainName, Dst: "osmosis"},
+		},
+	}.Resolve()
+	require.NoError(t, err)
+	return topology
+}
+
+func TestGenerateHermesConfig(t *testing.T) {
+	dir := t.TempDir()
+	topology := testTwoChainTopology(t)
+
+	require.NoError(t, GenerateHermesConfig(dir, topology))
+
+	hermesDir := filepath.Join(dir, "hermes")
+	bz, err := os.ReadFile(filepath.Join(hermesDir, "config.toml"))
+	require.NoError(t, err)
+	var config struct {
+		Global struct {
+			LogLevel string `toml:"log_level"`
+		} `toml:"global"`
+		Chains []struct {
+			ID       string `toml:"id"`
+			RpcAddr  string `toml:"rpc_addr"`
+			GrpcAddr string `toml:"grpc_addr"`
+			KeyName  string `toml:"key_name"`
+			GasPrice struct {
+				Price float64 `toml:"price"`
+				Denom string  `toml:"denom"`
+			} `toml:"gas_price"`
+		} `toml:"chains"`
+	}
+	require.NoError(t, toml.Unmarshal(bz, &config))
+
+	// the template's settings are kept & a chain is appended for kava & each ibc chain
+	assert.Equal(t, "info", config.Global.LogLevel)
+	require.Len(t, config.Chains, 3)
+	expected := []struct{ id, service, denom string }{
+		{genesis.DefaultChainID, "kavanode", genesis.DefaultDenom},
+		{DefaultIbcChainID, "ibcnode", DefaultIbcDenom},
+		{"osmosis_9000-1", "osmosisnode", "uosmo"},
+	}
+	for i, chain := range config.Chains {
+		assert.Equal(t, expected[i].id, chain.ID)
+		assert.Equal(t, "http://"+expected[i].service+":26657", chain.RpcAddr)
+		assert.Equal(t, "http://"+expected[i].service+":9090", chain.GrpcAddr)
+		assert.Equal(t, "testkey", chain.KeyName)
+		assert.Equal(t, expected[i].denom, chain.GasPrice.Denom)
+	}
+
+	// hermes relays every channel, the chains of each path only need to be configured
+	chainIDs := map[string]bool{}
+	for _, chain := range config.Chains {
+		chainIDs[chain.ID] = true
+	}
+	for _, path := range topology.Paths {
+		assert.True(t, chainIDs[topology.ChainID(path.Src)], path.Name)
+		assert.True(t, chainIDs[topology.ChainID(path.Dst)], path.Name)
+	}
+
+	// the relayer key is added from the mnemonic of the kava validator, which is funded on every chain
+	common, err := commonDir()
+	require.NoError(t, err)
+	addresses, err := gabs.ParseJSONFile(filepath.Join(common, "addresses.json"))
+	require.NoError(t, err)
+	mnemonic, err := os.ReadFile(filepath.Join(hermesDir, HermesMnemonicFile))
+	require.NoError(t, err)
+	assert.Equal(t, addresses.Path("kava.validators.0.mnemonic").Data(), string(mnemonic))
+
+	info, err := os.Stat(hermesDir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0777), info.Mode().Perm())
+}
+
+func TestGenerateRelayerConfig(t *testing.T) {
+	dir := t.TempDir()
+	topology := testTwoChainTopology(t)
+
+	require.NoError(t, GenerateRelayerConfig(dir, topology))
+
+	relayerDir := filepath.Join(dir, "relayer")
+	config, err := importYAML(filepath.Join(relayerDir, "config", "config.yaml"))
+	require.NoError(t, err)
+
+	chains := config.Search("chains").ChildrenMap()
+	require.Len(t, chains, 3)
+	assert.Equal(t, genesis.DefaultChainID, chains[KavaIbcChainName].Path("value.chain-id").Data())
+	assert.Equal(t, DefaultIbcChainID, chains[DefaultIbcChainName].Path("value.chain-id").Data())
+	assert.Equal(t, "http://ibcnode:26657", chains[DefaultIbcChainName].Path("value.rpc-addr").Data())
+	assert.Equal(t, "osmosis_9000-1", chains["osmosis"].Path("value.chain-id").Data())
+	assert.Equal(t, "http://osmosisnode:26657", chains["osmosis"].Path("value.rpc-addr").Data())
+	assert.Equal(t, "0.01uosmo", chains["osmosis"].Path("value.gas-prices").Data())
+	// the chains are copies of the template's chain
+	assert.Equal(t, "0.01uatom", chains[DefaultIbcChainName].Path("value.gas-prices").Data())
+
+	paths := config.Search("paths").ChildrenMap()
+	require.Len(t, paths, 2)
+	assert.Equal(t, genesis.DefaultChainID, paths["kava-ibcchain"].Path("src.chain-id").Data())
+	assert.Equal(t, DefaultIbcChainID, paths["kava-ibcchain"].Path("dst.chain-id").Data())
+	assert.Equal(t, DefaultIbcChainID, paths["ibcchain-osmosis"].Path("src.chain-id").Data())
+	assert.Equal(t, "osmosis_9000-1", paths["ibcchain-osmosis"].Path("dst.chain-id").Data())
+
+	// every chain has the relayer key
+	for _, chainID := range []string{genesis.DefaultChainID, DefaultIbcChainID, "osmosis_9000-1"} {
+		assert.DirExists(t, filepath.Join(relayerDir, "keys", chainID, "keyring-test"))
+	}
+}
+
+func TestGenerateRelayerConfigWithoutDefaultChain(t *testing.T) {
+	dir := t.TempDir()
+	topology, err := IbcTopology{Chains: []IbcChain{{Name: "osmosis", ChainID: "osmosis_9000-1", Denom: "uosmo"}}}.Resolve()
+	require.NoError(t, err)
+
+	require.NoError(t, GenerateRelayerConfig(dir, topology))
+
+	config, err := importYAML(filepath.Join(dir, "relayer", "config", "config.yaml"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{KavaIbcChainName, "osmosis"}, sortedKeys(config.Search("chains").ChildrenMap()))
+	assert.NoDirExists(t, filepath.Join(dir, "relayer", "keys", DefaultIbcChainID))
+	assert.DirExists(t, filepath.Join(dir, "relayer", "keys", "osmosis_9000-1"))
+}
+
+func TestIbcTopologyChainID(t *testing.T) {
+	topology := testTwoChainTopology(t)
+	assert.Equal(t, genesis.DefaultChainID, topology.ChainID(KavaIbcChainName))
+	assert.Equal(t, DefaultIbcChainID, topology.ChainID(DefaultIbcChainName))
+	assert.Equal(t, "osmosis_9000-1", topology.ChainID("osmosis"))
+	assert.Equal(t, "", topology.ChainID("juno"))
+}
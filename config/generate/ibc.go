@@ -0,0 +1,431 @@
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/otiai10/copy"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+const (
+	// DefaultIbcChainName is the name of the chain run by --ibc, it runs in the ibcnode service
+	DefaultIbcChainName = "ibcchain"
+	// DefaultIbcChainID & DefaultIbcDenom are the chain id & denom of the ibcchain template
+	DefaultIbcChainID = "kavalocalnet_8889-2"
+	DefaultIbcDenom   = "uatom"
+	// KavaIbcChainName is the name of the kava chain in the paths & the relayer config
+	KavaIbcChainName = "kava"
+	// RelayerTemplate & HermesTemplate are the templates of the relayers, their config is generated to the dir of
+	// the same name
+	RelayerTemplate = "relayer"
+	HermesTemplate  = "hermes"
+
+	// the ibcchain template's node service & the path opened by --ibc
+	ibcChainServiceName = "ibcnode"
+	defaultIbcPathName  = "transfer"
+
+	// host ports of each additional ibc chain are shifted by this amount per chain
+	ibcChainPortOffset = 10
+)
+
+// IBC path types, they set the ports, version & order of the channel
+const (
+	IbcPathTransfer = "transfer"
+	// IbcPathIca only opens a connection. The channel is opened by registering an interchain account on the
+	// controller chain & its handshake is completed by the relayer.
+	IbcPathIca    = "ica"
+	IbcPathCustom = "custom"
+)
+
+var (
+	ibcNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	// kava chain ids must be ethermint chain ids, eg. kavalocalnet_8889-2
+	ibcChainIDRegexp = regexp.MustCompile(`^[a-z]+_[1-9][0-9]*-[1-9][0-9]*$`)
+	// relayerTemplates are the templates of every relayer
+	relayerTemplates = []string{RelayerTemplate, HermesTemplate}
+	// reservedIbcChainNames are used by the generated dirs of the other services
+	reservedIbcChainNames = append([]string{"geth", "binance", "deputy"}, relayerTemplates...)
+)
+
+// IbcTopology describes the counterparty chains of kava & the relayer paths between the chains
+type IbcTopology struct {
+	Chains []IbcChain `yaml:"chains"`
+	// Paths default to a transfer path between kava & each chain
+	Paths []IbcPath `yaml:"paths"`
+}
+
+// IbcChain is a counterparty chain. It runs the ibcchain template, so its image must be a kava image.
+type IbcChain struct {
+	Name    string `yaml:"name"`
+	ChainID string `yaml:"chainId"`
+	Denom   string `yaml:"denom"`
+	// Image overrides the image of the template's node
+	Image string `yaml:"image"`
+	// Genesis is the path of a genesis.json used instead of the template's. It must include a gentx of the template's
+	// validator, eg. one built with 'kvtool genesis build'. The chain id & denom default to the genesis'.
+	Genesis string `yaml:"genesis"`
+}
+
+// IbcPath is a relayer path between two chains, kava is named "kava"
+type IbcPath struct {
+	Name string `yaml:"name"`
+	Src  string `yaml:"src"`
+	Dst  string `yaml:"dst"`
+	// Type is transfer (the default), ica or custom
+	Type    string `yaml:"type"`
+	SrcPort string `yaml:"srcPort"`
+	DstPort string `yaml:"dstPort"`
+	Version string `yaml:"version"`
+	// Order is ordered or unordered (the default)
+	Order string `yaml:"order"`
+}
+
+// DefaultIbcTopology is the single chain & transfer path run by --ibc
+func DefaultIbcTopology() IbcTopology {
+	return IbcTopology{
+		Chains: []IbcChain{{Name: DefaultIbcChainName, ChainID: DefaultIbcChainID, Denom: DefaultIbcDenom}},
+		Paths:  []IbcPath{{Name: defaultIbcPathName, Src: KavaIbcChainName, Dst: DefaultIbcChainName, Type: IbcPathTransfer}},
+	}
+}
+
+// ServiceName is the docker compose service of the chain's node: ibcnode for the default chain, otherwise <name>node
+func (c IbcChain) ServiceName() string {
+	if c.Name == DefaultIbcChainName {
+		return ibcChainServiceName
+	}
+	return c.Name + "node"
+}
+
+// Resolve sets the defaults of the topology, reading the chain id & denom of the chains' genesis files, & validates it.
+// It collects all problems found so they can be fixed in one go.
+func (t IbcTopology) Resolve() (IbcTopology, error) {
+	if len(t.Chains) == 0 && len(t.Paths) == 0 {
+		return DefaultIbcTopology(), nil
+	}
+	var errs []error
+
+	resolved := IbcTopology{}
+	names := map[string]bool{KavaIbcChainName: true}
+	chainIDs := map[string]bool{genesis.DefaultChainID: true}
+	for i, chain := range t.Chains {
+		field := fmt.Sprintf("ibc.chains[%d]", i)
+		if err := chain.resolve(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+		switch {
+		case !ibcNameRegexp.MatchString(chain.Name):
+			errs = append(errs, fmt.Errorf("%s.name: must match %s, found %q", field, ibcNameRegexp, chain.Name))
+		case strings.HasPrefix(chain.Name, KavaIbcChainName) || contains(reservedIbcChainNames, chain.Name):
+			errs = append(errs, fmt.Errorf("%s.name: %q is reserved for the other services", field, chain.Name))
+		case names[chain.Name]:
+			errs = append(errs, fmt.Errorf("%s.name: %q is used by another chain", field, chain.Name))
+		}
+		names[chain.Name] = true
+		if !ibcChainIDRegexp.MatchString(chain.ChainID) {
+			errs = append(errs, fmt.Errorf("%s.chainId: must be a kava chain id like %s, found %q", field, DefaultIbcChainID, chain.ChainID))
+		} else if chainIDs[chain.ChainID] {
+			errs = append(errs, fmt.Errorf("%s.chainId: %s is used by another chain", field, chain.ChainID))
+		}
+		chainIDs[chain.ChainID] = true
+		if err := sdk.ValidateDenom(chain.Denom); err != nil {
+			errs = append(errs, fmt.Errorf("%s.denom: %w", field, err))
+		}
+		resolved.Chains = append(resolved.Chains, chain)
+	}
+	if len(resolved.Chains) == 0 {
+		errs = append(errs, fmt.Errorf("ibc.chains: at least one chain is required"))
+	}
+
+	paths := t.Paths
+	if len(paths) == 0 {
+		for _, chain := range resolved.Chains {
+			paths = append(paths, IbcPath{Name: chain.Name + "-transfer", Src: KavaIbcChainName, Dst: chain.Name})
+		}
+	}
+	pathNames := map[string]bool{}
+	for i, path := range paths {
+		field := fmt.Sprintf("ibc.paths[%d]", i)
+		if err := path.resolve(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+		if !ibcNameRegexp.MatchString(path.Name) {
+			errs = append(errs, fmt.Errorf("%s.name: must match %s, found %q", field, ibcNameRegexp, path.Name))
+		} else if pathNames[path.Name] {
+			errs = append(errs, fmt.Errorf("%s.name: %q is used by another path", field, path.Name))
+		}
+		pathNames[path.Name] = true
+		if !names[path.Src] {
+			errs = append(errs, fmt.Errorf("%s.src: unknown chain %q", field, path.Src))
+		}
+		if !names[path.Dst] {
+			errs = append(errs, fmt.Errorf("%s.dst: unknown chain %q", field, path.Dst))
+		}
+		if path.Src == path.Dst {
+			errs = append(errs, fmt.Errorf("%s: src & dst must be different chains", field))
+		}
+		resolved.Paths = append(resolved.Paths, path)
+	}
+
+	return resolved, errors.Join(errs...)
+}
+
+// resolve reads the chain id & denom of the chain's genesis & defaults them to the template's
+func (c *IbcChain) resolve() error {
+	if c.Genesis == "" {
+		if c.ChainID == "" {
+			c.ChainID = DefaultIbcChainID
+		}
+		if c.Denom == "" {
+			c.Denom = DefaultIbcDenom
+		}
+		return nil
+	}
+
+	gen, err := genesis.ReadRawGenesis(c.Genesis)
+	if err != nil {
+		return fmt.Errorf("genesis: %w", err)
+	}
+	chainID, err := gen.ChainID()
+	if err != nil {
+		return fmt.Errorf("genesis: %w", err)
+	}
+	bondDenom, err := gen.BondDenom()
+	if err != nil {
+		return fmt.Errorf("genesis: %w", err)
+	}
+	if c.ChainID == "" {
+		c.ChainID = chainID
+	} else if c.ChainID != chainID {
+		return fmt.Errorf("chainId %s doesn't match the chain id of the genesis, %s", c.ChainID, chainID)
+	}
+	if c.Denom == "" {
+		c.Denom = bondDenom
+	}
+	return nil
+}
+
+// resolve sets the ports, version & order of the path type
+func (p *IbcPath) resolve() error {
+	switch p.Type {
+	case "", IbcPathTransfer:
+		p.Type = IbcPathTransfer
+		if p.SrcPort == "" {
+			p.SrcPort = "transfer"
+		}
+		if p.DstPort == "" {
+			p.DstPort = "transfer"
+		}
+		if p.Version == "" {
+			p.Version = "ics20-1"
+		}
+	case IbcPathIca:
+		if p.SrcPort != "" || p.DstPort != "" || p.Version != "" || p.Order != "" {
+			return fmt.Errorf("ica paths only open a connection, the ports, version & order are set when the interchain account is registered")
+		}
+		return nil
+	case IbcPathCustom:
+		if p.SrcPort == "" || p.DstPort == "" || p.Version == "" {
+			return fmt.Errorf("custom paths require srcPort, dstPort & version")
+		}
+	default:
+		return fmt.Errorf("type: must be %s, %s or %s, found %q", IbcPathTransfer, IbcPathIca, IbcPathCustom, p.Type)
+	}
+	if p.Order == "" {
+		p.Order = "unordered"
+	}
+	if p.Order != "ordered" && p.Order != "unordered" {
+		return fmt.Errorf("order: must be ordered or unordered, found %q", p.Order)
+	}
+	return nil
+}
+
+// GenerateIbcChainsConfig generates the node of each chain from the ibcchain template. Chains other than the
+// template's get their chain id & denom in genesis & config, the gentx signed for their chain id, their own
+// service & host ports shifted by 10 per chain.
+func GenerateIbcChainsConfig(generatedConfigDir string, topology IbcTopology, remapPorts bool) error {
+	templateDir, err := findTemplate(filepath.Join("ibcchain", "master"))
+	if err != nil {
+		return err
+	}
+	for i, chain := range topology.Chains {
+		if err := generateIbcChainConfig(generatedConfigDir, templateDir, chain, i*ibcChainPortOffset, remapPorts); err != nil {
+			return fmt.Errorf("failed to generate ibc chain %s: %w", chain.Name, err)
+		}
+	}
+	return nil
+}
+
+func generateIbcChainConfig(generatedConfigDir, templateDir string, chain IbcChain, portOffset int, remapPorts bool) error {
+	chainDir := filepath.Join(generatedConfigDir, chain.Name)
+	if err := copy.Copy(templateDir, chainDir); err != nil {
+		return err
+	}
+	homeDir := filepath.Join(chainDir, "initstate", ".kava")
+	configDir := filepath.Join(homeDir, "config")
+
+	// genesis
+	switch {
+	case chain.Genesis != "":
+		if err := copy.Copy(chain.Genesis, filepath.Join(configDir, "genesis.json")); err != nil {
+			return err
+		}
+	case chain.ChainID != DefaultIbcChainID || chain.Denom != DefaultIbcDenom:
+		common, err := commonDir()
+		if err != nil {
+			return err
+		}
+		gen, err := genesis.ReadRawGenesis(filepath.Join(configDir, "genesis.json"))
+		if err != nil {
+			return err
+		}
+		builder := genesis.Builder{
+			ChainID:       chain.ChainID,
+			Denom:         chain.Denom,
+			AddressesPath: filepath.Join(common, "addresses.json"),
+			HomeDir:       homeDir,
+		}
+		result, err := builder.Rechain(gen, DefaultIbcDenom)
+		if err != nil {
+			return err
+		}
+		if err := result.WriteHome(homeDir); err != nil {
+			return err
+		}
+	}
+
+	// config
+	if err := setTomlFileValues(filepath.Join(configDir, "client.toml"), []ConfigOverride{{Key: "chain-id", Value: chain.ChainID}}); err != nil {
+		return err
+	}
+	if chain.Denom != DefaultIbcDenom {
+		appTomlPath := filepath.Join(configDir, "app.toml")
+		appToml, err := os.ReadFile(appTomlPath)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(appTomlPath, bytes.ReplaceAll(appToml, []byte(DefaultIbcDenom), []byte(chain.Denom)), 0644); err != nil {
+			return err
+		}
+	}
+
+	// service
+	composePath := filepath.Join(chainDir, "docker-compose.yaml")
+	compose, err := importYAML(composePath)
+	if err != nil {
+		return err
+	}
+	service, ok := compose.Search("services", ibcChainServiceName).Data().(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("no %s service found in %s", ibcChainServiceName, composePath)
+	}
+	if chain.Image != "" {
+		service["image"] = chain.Image
+	}
+	if volumes, ok := service["volumes"].([]interface{}); ok {
+		for j, volume := range volumes {
+			if v, ok := volume.(string); ok {
+				volumes[j] = strings.Replace(v, "./ibcchain/", fmt.Sprintf("./%s/", chain.Name), 1)
+			}
+		}
+	}
+	if ports, ok := service["ports"].([]interface{}); ok {
+		for j, port := range ports {
+			if binding, ok := parseHostPort(port); ok {
+				ports[j], _ = withHostPort(port, binding.start+portOffset)
+			}
+		}
+	}
+	if _, err := compose.Set(map[string]interface{}{chain.ServiceName(): service}, "services"); err != nil {
+		return err
+	}
+	if err := exportYAML(composePath, compose); err != nil {
+		return err
+	}
+	return mergeComposeYAML(composePath, filepath.Join(generatedConfigDir, "docker-compose.yaml"), remapPorts)
+}
+
+// GenerateRelayerConfig generates the relayer's config.yaml with a chain for kava & each ibc chain & the paths of
+// the topology. The relayer's key is the same on every chain.
+func GenerateRelayerConfig(generatedConfigDir string, topology IbcTopology) error {
+	templateDir, err := findTemplate(RelayerTemplate)
+	if err != nil {
+		return err
+	}
+	relayerDir := filepath.Join(generatedConfigDir, RelayerTemplate)
+	if err := copy.Copy(templateDir, relayerDir, copy.Options{AddPermission: 0666}); err != nil {
+		return err
+	}
+
+	configPath := filepath.Join(relayerDir, "config", "config.yaml")
+	config, err := importYAML(configPath)
+	if err != nil {
+		return err
+	}
+	chainYAML, err := yaml.Marshal(config.Search("chains", DefaultIbcChainName).Data())
+	if err != nil {
+		return err
+	}
+
+	kavaChainID, ok := config.Search("chains", KavaIbcChainName, "value", "chain-id").Data().(string)
+	if !ok {
+		return fmt.Errorf("no %s chain found in %s", KavaIbcChainName, configPath)
+	}
+	chainIDs := map[string]string{KavaIbcChainName: kavaChainID}
+	chains := map[string]interface{}{KavaIbcChainName: config.Search("chains", KavaIbcChainName).Data()}
+	keysDir := filepath.Join(relayerDir, "keys")
+	for _, chain := range topology.Chains {
+		// unmarshal the template's chain each time to get a deep copy
+		relayerChain := map[string]interface{}{}
+		if err := yaml.Unmarshal(chainYAML, &relayerChain); err != nil {
+			return err
+		}
+		value, ok := relayerChain["value"].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("no %s chain found in %s", DefaultIbcChainName, configPath)
+		}
+		value["chain-id"] = chain.ChainID
+		value["rpc-addr"] = fmt.Sprintf("http://%s:26657", chain.ServiceName())
+		value["gas-prices"] = "0.01" + chain.Denom
+		chains[chain.Name] = relayerChain
+		chainIDs[chain.Name] = chain.ChainID
+
+		if chain.ChainID != DefaultIbcChainID {
+			if err := copy.Copy(filepath.Join(keysDir, DefaultIbcChainID), filepath.Join(keysDir, chain.ChainID)); err != nil {
+				return err
+			}
+		}
+	}
+	if _, ok := chainIDs[DefaultIbcChainName]; !ok {
+		if err := os.RemoveAll(filepath.Join(keysDir, DefaultIbcChainID)); err != nil {
+			return err
+		}
+	}
+
+	paths := map[string]interface{}{}
+	for _, path := range topology.Paths {
+		paths[path.Name] = map[string]interface{}{
+			"src": map[string]interface{}{"chain-id": chainIDs[path.Src]},
+			"dst": map[string]interface{}{"chain-id": chainIDs[path.Dst]},
+			"src-channel-filter": map[string]interface{}{
+				"rule":         "",
+				"channel-list": []interface{}{},
+			},
+		}
+	}
+	if _, err := config.Set(chains, "chains"); err != nil {
+		return err
+	}
+	if _, err := config.Set(paths, "paths"); err != nil {
+		return err
+	}
+	return exportYAML(configPath, config)
+}
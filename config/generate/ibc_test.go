@@ -0,0 +1,251 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+// writeTestIbcGenesis writes a copy of the ibcchain template's genesis with another chain id & bond denom
+func writeTestIbcGenesis(t *testing.T, chainID, denom string) string {
+	t.Helper()
+	gen, err := genesis.ReadRawGenesis(filepath.Join(ConfigTemplatesDir, "ibcchain", "master", "initstate", ".kava", "config", "genesis.json"))
+	require.NoError(t, err)
+	require.NoError(t, gen.SetChainID(chainID))
+	require.NoError(t, gen.SetAppStateValue("staking.params.bond_denom", []byte(`"`+denom+`"`)))
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.NoError(t, gen.WriteFile(path))
+	return path
+}
+
+func TestIbcTopologyResolve(t *testing.T) {
+	osmosisGenesis := writeTestIbcGenesis(t, "osmosis_9000-1", "uosmo")
+
+	testCases := []struct {
+		name     string
+		topology IbcTopology
+		expected IbcTopology
+	}{
+		{
+			name:     "empty topology is the default chain",
+			topology: IbcTopology{},
+			expected: DefaultIbcTopology(),
+		},
+		{
+			name:     "chain defaults to the template's chain id & denom, with a transfer path from kava",
+			topology: IbcTopology{Chains: []IbcChain{{Name: "osmosis"}}},
+			expected: IbcTopology{
+				Chains: []IbcChain{{Name: "osmosis", ChainID: DefaultIbcChainID, Denom: DefaultIbcDenom}},
+				Paths: []IbcPath{{
+					Name: "osmosis-transfer", Src: KavaIbcChainName, Dst: "osmosis", Type: IbcPathTransfer,
+					SrcPort: "transfer", DstPort: "transfer", Version: "ics20-1", Order: "unordered",
+				}},
+			},
+		},
+		{
+			name:     "chain id & denom are read from the genesis",
+			topology: IbcTopology{Chains: []IbcChain{{Name: "osmosis", Genesis: osmosisGenesis}}},
+			expected: IbcTopology{
+				Chains: []IbcChain{{Name: "osmosis", ChainID: "osmosis_9000-1", Denom: "uosmo", Genesis: osmosisGenesis}},
+				Paths: []IbcPath{{
+					Name: "osmosis-transfer", Src: KavaIbcChainName, Dst: "osmosis", Type: IbcPathTransfer,
+					SrcPort: "transfer", DstPort: "transfer", Version: "ics20-1", Order: "unordered",
+				}},
+			},
+		},
+		{
+			name: "paths between chains",
+			topology: IbcTopology{
+				Chains: []IbcChain{
+					{Name: "osmosis", ChainID: "osmosis_9000-1", Denom: "uosmo"},
+					{Name: "cosmoshub", ChainID: "cosmoshub_9001-1", Denom: "uatom"},
+				},
+				Paths: []IbcPath{
+					{Name: "hub-osmosis", Src: "cosmoshub", Dst: "osmosis"},
+					{Name: "kava-ica", Src: KavaIbcChainName, Dst: "osmosis", Type: IbcPathIca},
+					{Name: "kava-custom", Src: KavaIbcChainName, Dst: "cosmoshub", Type: IbcPathCustom, SrcPort: "a", DstPort: "b", Version: "v1", Order: "ordered"},
+				},
+			},
+			expected: IbcTopology{
+				Chains: []IbcChain{
+					{Name: "osmosis", ChainID: "osmosis_9000-1", Denom: "uosmo"},
+					{Name: "cosmoshub", ChainID: "cosmoshub_9001-1", Denom: "uatom"},
+				},
+				Paths: []IbcPath{
+					{Name: "hub-osmosis", Src: "cosmoshub", Dst: "osmosis", Type: IbcPathTransfer, SrcPort: "transfer", DstPort: "transfer", Version: "ics20-1", Order: "unordered"},
+					{Name: "kava-ica", Src: KavaIbcChainName, Dst: "osmosis", Type: IbcPathIca},
+					{Name: "kava-custom", Src: KavaIbcChainName, Dst: "cosmoshub", Type: IbcPathCustom, SrcPort: "a", DstPort: "b", Version: "v1", Order: "ordered"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, err := tc.topology.Resolve()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, resolved)
+		})
+	}
+}
+
+func TestIbcTopologyResolveErrors(t *testing.T) {
+	osmosisGenesis := writeTestIbcGenesis(t, "osmosis_9000-1", "uosmo")
+
+	testCases := []struct {
+		name         string
+		topology     IbcTopology
+		expectedErrs []string
+	}{
+		{
+			name:         "no chains",
+			topology:     IbcTopology{Paths: []IbcPath{{Name: "transfer", Src: KavaIbcChainName, Dst: "osmosis"}}},
+			expectedErrs: []string{"ibc.chains: at least one chain is required", `ibc.paths[0].dst: unknown chain "osmosis"`},
+		},
+		{
+			name: "duplicate chain ids",
+			topology: IbcTopology{Chains: []IbcChain{
+				{Name: "osmosis", ChainID: "osmosis_9000-1"},
+				{Name: "cosmoshub", ChainID: "osmosis_9000-1"},
+			}},
+			expectedErrs: []string{"ibc.chains[1].chainId: osmosis_9000-1 is used by another chain"},
+		},
+		{
+			name:         "chain id of kava",
+			topology:     IbcTopology{Chains: []IbcChain{{Name: "osmosis", ChainID: genesis.DefaultChainID}}},
+			expectedErrs: []string{"ibc.chains[0].chainId: " + genesis.DefaultChainID + " is used by another chain"},
+		},
+		{
+			name: "default chain ids of two chains",
+			topology: IbcTopology{Chains: []IbcChain{
+				{Name: "osmosis"},
+				{Name: "cosmoshub"},
+			}},
+			expectedErrs: []string{"ibc.chains[1].chainId: " + DefaultIbcChainID + " is used by another chain"},
+		},
+		{
+			name:         "invalid chain id",
+			topology:     IbcTopology{Chains: []IbcChain{{Name: "osmosis", ChainID: "osmosis-1"}}},
+			expectedErrs: []string{`ibc.chains[0].chainId: must be a kava chain id like kavalocalnet_8889-2, found "osmosis-1"`},
+		},
+		{
+			name: "duplicate & reserved chain names",
+			topology: IbcTopology{Chains: []IbcChain{
+				{Name: "osmosis", ChainID: "osmosis_9000-1"},
+				{Name: "osmosis", ChainID: "osmosis_9001-1"},
+				{Name: "relayer", ChainID: "relayer_9002-1"},
+				{Name: "kava2", ChainID: "kava_9003-1"},
+				{Name: "Hub", ChainID: "hub_9004-1"},
+				{Name: "hermes", ChainID: "hermes_9005-1"},
+			}},
+			expectedErrs: []string{
+				`ibc.chains[1].name: "osmosis" is used by another chain`,
+				`ibc.chains[2].name: "relayer" is reserved for the other services`,
+				`ibc.chains[3].name: "kava2" is reserved for the other services`,
+				`ibc.chains[4].name: must match`,
+				`ibc.chains[5].name: "hermes" is reserved for the other services`,
+			},
+		},
+		{
+			name:         "invalid denom",
+			topology:     IbcTopology{Chains: []IbcChain{{Name: "osmosis", Denom: "u"}}},
+			expectedErrs: []string{"ibc.chains[0].denom: invalid denom: u"},
+		},
+		{
+			name:         "chain id doesn't match the genesis",
+			topology:     IbcTopology{Chains: []IbcChain{{Name: "osmosis", ChainID: "osmosis_9001-1", Genesis: osmosisGenesis}}},
+			expectedErrs: []string{"ibc.chains[0]: chainId osmosis_9001-1 doesn't match the chain id of the genesis, osmosis_9000-1"},
+		},
+		{
+			name:         "missing genesis",
+			topology:     IbcTopology{Chains: []IbcChain{{Name: "osmosis", Genesis: filepath.Join(t.TempDir(), "missing.json")}}},
+			expectedErrs: []string{"ibc.chains[0]: genesis: failed to read genesis file"},
+		},
+		{
+			name: "paths referencing unknown chains",
+			topology: IbcTopology{
+				Chains: []IbcChain{{Name: "osmosis"}},
+				Paths: []IbcPath{
+					{Name: "a", Src: "cosmoshub", Dst: "osmosis"},
+					{Name: "b", Src: KavaIbcChainName, Dst: "juno"},
+				},
+			},
+			expectedErrs: []string{`ibc.paths[0].src: unknown chain "cosmoshub"`, `ibc.paths[1].dst: unknown chain "juno"`},
+		},
+		{
+			name: "invalid paths",
+			topology: IbcTopology{
+				Chains: []IbcChain{{Name: "osmosis"}},
+				Paths: []IbcPath{
+					{Name: "a", Src: "osmosis", Dst: "osmosis"},
+					{Name: "a", Src: KavaIbcChainName, Dst: "osmosis", Type: IbcPathIca, Order: "ordered"},
+					{Name: "c", Src: KavaIbcChainName, Dst: "osmosis", Type: IbcPathCustom},
+					{Name: "d", Src: KavaIbcChainName, Dst: "osmosis", Type: "nft"},
+					{Name: "e", Src: KavaIbcChainName, Dst: "osmosis", Order: "sorted"},
+				},
+			},
+			expectedErrs: []string{
+				"ibc.paths[0]: src & dst must be different chains",
+				`ibc.paths[1].name: "a" is used by another path`,
+				"ibc.paths[1]: ica paths only open a connection",
+				"ibc.paths[2]: custom paths require srcPort, dstPort & version",
+				`ibc.paths[3]: type: must be transfer, ica or custom, found "nft"`,
+				`ibc.paths[4]: order: must be ordered or unordered, found "sorted"`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.topology.Resolve()
+			require.Error(t, err)
+			for _, expected := range tc.expectedErrs {
+				assert.ErrorContains(t, err, expected)
+			}
+		})
+	}
+}
+
+func TestGenerateIbcChainsConfig(t *testing.T) {
+	dir := t.TempDir()
+	topology, err := IbcTopology{Chains: []IbcChain{
+		{Name: DefaultIbcChainName},
+		{Name: "osmosis", ChainID: "osmosis_9000-1", Denom: "uosmo"},
+	}}.Resolve()
+	require.NoError(t, err)
+
+	require.NoError(t, GenerateIbcChainsConfig(dir, topology, false))
+
+	// each chain gets its own service, shifted ports & genesis
+	compose, err := importYAML(filepath.Join(dir, "docker-compose.yaml"))
+	require.NoError(t, err)
+	ibcPorts, err := ComposeHostPorts(filepath.Join(dir, DefaultIbcChainName, "docker-compose.yaml"))
+	require.NoError(t, err)
+	require.True(t, compose.Exists("services", "ibcnode"))
+	require.True(t, compose.Exists("services", "osmosisnode"))
+	for _, volume := range compose.Search("services", "osmosisnode", "volumes").Children() {
+		assert.Contains(t, volume.Data(), "./osmosis/")
+	}
+	hostPorts, err := ComposeHostPorts(filepath.Join(dir, "docker-compose.yaml"))
+	require.NoError(t, err)
+	for _, port := range ibcPorts {
+		assert.Contains(t, hostPorts, port+ibcChainPortOffset)
+	}
+
+	gen, err := genesis.ReadRawGenesis(filepath.Join(dir, "osmosis", "initstate", ".kava", "config", "genesis.json"))
+	require.NoError(t, err)
+	chainID, err := gen.ChainID()
+	require.NoError(t, err)
+	assert.Equal(t, "osmosis_9000-1", chainID)
+	bondDenom, err := gen.BondDenom()
+	require.NoError(t, err)
+	assert.Equal(t, "uosmo", bondDenom)
+	clientToml, err := os.ReadFile(filepath.Join(dir, "osmosis", "initstate", ".kava", "config", "client.toml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(clientToml), `chain-id = "osmosis_9000-1"`)
+}
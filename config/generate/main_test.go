@@ -0,0 +1,26 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain runs the tests with the templates of the repo & a kvtool home that isn't the user's
+func TestMain(m *testing.M) {
+	templates, err := filepath.Abs("../templates")
+	if err != nil {
+		panic(err)
+	}
+	ConfigTemplatesDir = templates
+
+	home, err := os.MkdirTemp("", "kvtool-home")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv(KvtoolHomeEnv, home)
+
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
@@ -0,0 +1,223 @@
+package generate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kava-labs/kvtool/config"
+)
+
+const (
+	// TemplatesPathEnv is a list of user template directories, separated like PATH
+	TemplatesPathEnv = "KVTOOL_TEMPLATES_PATH"
+	// KvtoolHomeEnv overrides the directory of kvtool's config file, extracted templates & generated config
+	KvtoolHomeEnv = "KVTOOL_HOME"
+	// KvtoolConfigFile is the kvtool config file in KvtoolHome
+	KvtoolConfigFile = "config.yaml"
+)
+
+// TemplatesPath are the user template directories set with --templates-path. They take precedence over
+// KVTOOL_TEMPLATES_PATH, the templatesPath of the kvtool config file & the built-in templates.
+var TemplatesPath []string
+
+// kvtoolConfig is the kvtool config file, eg. ~/.kvtool/config.yaml
+type kvtoolConfig struct {
+	// TemplatesPath are user template directories. Relative paths are relative to the config file.
+	TemplatesPath []string `yaml:"templatesPath"`
+}
+
+var builtinTemplates struct {
+	once sync.Once
+	dir  string
+	err  error
+}
+
+// KvtoolHome is the directory of kvtool's config file, extracted templates & generated config when kvtool isn't run
+// from a checkout of its repo. Defaults to ~/.kvtool.
+func KvtoolHome() (string, error) {
+	if home := os.Getenv(KvtoolHomeEnv); home != "" {
+		return home, nil
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find kvtool home, set %s: %w", KvtoolHomeEnv, err)
+	}
+	return filepath.Join(userHome, ".kvtool"), nil
+}
+
+// BuiltinTemplatesDir returns the directory of the templates kvtool is built with. It's ConfigTemplatesDir when
+// set at build time, otherwise the embedded templates are extracted to the kvtool home.
+func BuiltinTemplatesDir() (string, error) {
+	if ConfigTemplatesDir != "" {
+		return ConfigTemplatesDir, nil
+	}
+	builtinTemplates.once.Do(func() {
+		builtinTemplates.dir, builtinTemplates.err = extractEmbeddedTemplates()
+	})
+	return builtinTemplates.dir, builtinTemplates.err
+}
+
+// TemplateSearchPath returns the template directories in order of precedence: --templates-path,
+// KVTOOL_TEMPLATES_PATH, the kvtool config file & the built-in templates
+func TemplateSearchPath() ([]string, error) {
+	dirs := append([]string{}, TemplatesPath...)
+	for _, dir := range filepath.SplitList(os.Getenv(TemplatesPathEnv)) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	configDirs, err := configTemplatesPath()
+	if err != nil {
+		return nil, err
+	}
+	dirs = append(dirs, configDirs...)
+
+	builtin, err := BuiltinTemplatesDir()
+	if err != nil {
+		return nil, err
+	}
+	return append(dirs, builtin), nil
+}
+
+// findTemplate returns the first directory of the search path containing the template at rel, eg. geth or kava/master
+func findTemplate(rel string) (string, error) {
+	dirs, err := TemplateSearchPath()
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, rel)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("template %s not found in %s", rel, strings.Join(dirs, ", "))
+}
+
+// commonDir returns the directory of the files shared by the built-in templates, eg. addresses.json
+func commonDir() (string, error) {
+	builtin, err := BuiltinTemplatesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(builtin, "..", "common"), nil
+}
+
+// configTemplatesPath returns the templatesPath of the kvtool config file, if there is one
+func configTemplatesPath() ([]string, error) {
+	home, err := KvtoolHome()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(home, KvtoolConfigFile)
+	bz, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kvtool config: %w", err)
+	}
+	var cfg kvtoolConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(bz))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse kvtool config %s: %w", path, err)
+	}
+	dirs := make([]string, len(cfg.TemplatesPath))
+	for i, dir := range cfg.TemplatesPath {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(home, dir)
+		}
+		dirs[i] = dir
+	}
+	return dirs, nil
+}
+
+// extractEmbeddedTemplates writes the embedded templates to the kvtool home, once for each version of the templates
+func extractEmbeddedTemplates() (string, error) {
+	hash, err := hashFS(config.FS)
+	if err != nil {
+		return "", fmt.Errorf("failed to read embedded templates: %w", err)
+	}
+	home, err := KvtoolHome()
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Join(home, "builtin")
+	dest := filepath.Join(parent, hash)
+	if _, err := os.Stat(dest); err == nil {
+		return filepath.Join(dest, "templates"), nil
+	}
+
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", parent, err)
+	}
+	// extract to a temporary directory first, so an interrupted extraction is never used
+	tmp, err := os.MkdirTemp(parent, hash+".tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if err := writeFS(config.FS, tmp); err != nil {
+		return "", fmt.Errorf("failed to extract embedded templates: %w", err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		// another kvtool may have extracted the same templates first
+		if _, statErr := os.Stat(dest); statErr != nil {
+			return "", fmt.Errorf("failed to extract embedded templates: %w", err)
+		}
+	}
+	return filepath.Join(dest, "templates"), nil
+}
+
+// writeFS copies the files of fsys to dir. Scripts are made executable, as embedded files have no permissions.
+func writeFS(fsys fs.FS, dir string) error {
+	return fs.WalkDir(fsys, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(path))
+		if entry.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+		bz, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if strings.HasSuffix(path, ".sh") || bytes.HasPrefix(bz, []byte("#!")) {
+			mode = 0755
+		}
+		return os.WriteFile(dest, bz, mode)
+	})
+}
+
+// hashFS returns a short hash of the paths & contents of the files of fsys
+func hashFS(fsys fs.FS) (string, error) {
+	h := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		bz, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s %d\n", path, len(bz))
+		h.Write(bz)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
@@ -0,0 +1,202 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTemplatesPath points the template search path at a new kvtool home & clears --templates-path & the
+// environment for the duration of the test. It returns the kvtool home.
+func useTemplatesPath(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv(KvtoolHomeEnv, home)
+	t.Setenv(TemplatesPathEnv, "")
+	original := TemplatesPath
+	TemplatesPath = nil
+	t.Cleanup(func() { TemplatesPath = original })
+	return home
+}
+
+// writeKvtoolConfig writes the kvtool config file to the kvtool home
+func writeKvtoolConfig(t *testing.T, home, contents string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(home, KvtoolConfigFile), []byte(contents), 0644))
+}
+
+func TestTemplateSearchPath(t *testing.T) {
+	home := useTemplatesPath(t)
+	flagDir, envDirA, envDirB, configDir := t.TempDir(), t.TempDir(), t.TempDir(), t.TempDir()
+
+	// without user template directories, only the built-in templates are searched
+	dirs, err := TemplateSearchPath()
+	require.NoError(t, err)
+	assert.Equal(t, []string{ConfigTemplatesDir}, dirs)
+
+	TemplatesPath = []string{flagDir}
+	t.Setenv(TemplatesPathEnv, strings.Join([]string{envDirA, "", envDirB}, string(os.PathListSeparator)))
+	// relative directories of the config file are relative to the kvtool home
+	writeKvtoolConfig(t, home, "templatesPath: [team-templates, "+configDir+"]\n")
+
+	dirs, err = TemplateSearchPath()
+	require.NoError(t, err)
+	assert.Equal(t, []string{flagDir, envDirA, envDirB, filepath.Join(home, "team-templates"), configDir, ConfigTemplatesDir}, dirs)
+}
+
+func TestTemplateSearchPathPrecedence(t *testing.T) {
+	testCases := []struct {
+		name string
+		// layers are the search path layers with a master template, of flag, env & config
+		layers   []string
+		expected string
+	}{
+		{name: "built-in", expected: "built-in"},
+		{name: "config file over built-in", layers: []string{"config"}, expected: "config"},
+		{name: "env over config file", layers: []string{"env", "config"}, expected: "env"},
+		{name: "flag over env", layers: []string{"flag", "env", "config"}, expected: "flag"},
+		{name: "flag over config file", layers: []string{"flag", "config"}, expected: "flag"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			home := useTemplatesPath(t)
+			// every layer is in the search path, but only some have a master template
+			dirs := map[string]string{"flag": t.TempDir(), "env": t.TempDir(), "config": t.TempDir()}
+			TemplatesPath = []string{dirs["flag"]}
+			t.Setenv(TemplatesPathEnv, dirs["env"])
+			writeKvtoolConfig(t, home, "templatesPath: ["+dirs["config"]+"]\n")
+			for _, layer := range tc.layers {
+				writeTestTemplate(t, dirs[layer], "master", strings.Replace(testManifest, "a test template", layer, 1))
+			}
+
+			template, err := LoadKavaTemplate("master")
+			require.NoError(t, err)
+			if tc.expected == "built-in" {
+				assert.Equal(t, filepath.Join(ConfigTemplatesDir, "kava", "master"), template.Dir)
+				return
+			}
+			assert.Equal(t, tc.expected, template.Description)
+			assert.Equal(t, filepath.Join(dirs[tc.expected], "kava", "master"), template.Dir)
+		})
+	}
+}
+
+func TestKavaTemplatesWithUserTemplates(t *testing.T) {
+	useTemplatesPath(t)
+	userDir := t.TempDir()
+	TemplatesPath = []string{userDir}
+	writeTestTemplate(t, userDir, "master", testManifest)
+	writeTestTemplate(t, userDir, "custom", testManifest)
+
+	templates, err := KavaTemplates()
+	require.NoError(t, err)
+	byName := map[string]KavaTemplate{}
+	for i, template := range templates {
+		_, duplicate := byName[template.Name]
+		assert.False(t, duplicate, template.Name)
+		byName[template.Name] = template
+		if i > 0 {
+			assert.Less(t, templates[i-1].Name, template.Name)
+		}
+	}
+	// user templates are listed alongside the built-in ones & hide the built-in templates of the same name
+	assert.Equal(t, filepath.Join(userDir, "kava", "custom"), byName["custom"].Dir)
+	assert.Equal(t, filepath.Join(userDir, "kava", "master"), byName["master"].Dir)
+	assert.Equal(t, filepath.Join(ConfigTemplatesDir, "kava", "v0.23"), byName["v0.23"].Dir)
+
+	// an invalid user template fails the listing rather than being silently skipped
+	writeTestTemplate(t, userDir, "broken", "kind: archive\n")
+	_, err = KavaTemplates()
+	require.ErrorContains(t, err, `invalid template.yaml of kava template "broken"`)
+}
+
+func TestTemplateSearchPathErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		errMsg string
+	}{
+		{
+			name:   "invalid yaml",
+			config: "templatesPath: [\n",
+			errMsg: "failed to parse kvtool config",
+		},
+		{
+			name:   "unknown field",
+			config: "templates: [team-templates]\n",
+			errMsg: "field templates not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			home := useTemplatesPath(t)
+			writeKvtoolConfig(t, home, tc.config)
+
+			_, err := TemplateSearchPath()
+			require.ErrorContains(t, err, tc.errMsg)
+		})
+	}
+
+	useTemplatesPath(t)
+	_, err := findTemplate(filepath.Join("kava", "v0.99"))
+	require.EqualError(t, err, "template kava/v0.99 not found in "+ConfigTemplatesDir)
+}
+
+func TestExtractEmbeddedTemplates(t *testing.T) {
+	home := useTemplatesPath(t)
+
+	dir, err := extractEmbeddedTemplates()
+	require.NoError(t, err)
+	assert.Equal(t, home, filepath.Dir(filepath.Dir(filepath.Dir(dir))))
+	assert.Equal(t, "templates", filepath.Base(dir))
+
+	// the extracted templates are a copy of the repo's, including the hidden node homes & the common files
+	for _, rel := range []string{
+		filepath.Join("kava", "master", TemplateManifestFile),
+		filepath.Join("kava", "master", "initstate", ".kava", "config", "genesis.json"),
+		filepath.Join("..", "common", "addresses.json"),
+	} {
+		expected, err := os.ReadFile(filepath.Join(ConfigTemplatesDir, rel))
+		require.NoError(t, err)
+		extracted, err := os.ReadFile(filepath.Join(dir, rel))
+		require.NoError(t, err)
+		assert.Equal(t, expected, extracted, rel)
+	}
+	// embedded files have no permissions, so scripts are made executable
+	info, err := os.Stat(filepath.Join(dir, "kava", "master", "initstate", ".kava", "config", "init-data-directory.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(dir, "kava", "master", "docker-compose.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// the same templates are extracted once, without leaving temporary directories behind
+	again, err := extractEmbeddedTemplates()
+	require.NoError(t, err)
+	assert.Equal(t, dir, again)
+	entries, err := os.ReadDir(filepath.Join(home, "builtin"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// the embedded templates can be used like a checkout's
+	template, err := loadKavaTemplate("master", filepath.Join(dir, "kava", "master"))
+	require.NoError(t, err)
+	assert.Equal(t, TemplateKindNode, template.Kind)
+}
+
+func TestBuiltinTemplatesDir(t *testing.T) {
+	// kvtool built in a checkout uses the checkout's templates rather than extracting them
+	dir, err := BuiltinTemplatesDir()
+	require.NoError(t, err)
+	assert.Equal(t, ConfigTemplatesDir, dir)
+
+	common, err := commonDir()
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(common, "addresses.json"))
+}
@@ -0,0 +1,253 @@
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateManifestFile is the file in a kava template's directory describing the template
+const TemplateManifestFile = "template.yaml"
+
+const (
+	// TemplateKindNode templates run the kava validators, they are selected with --kava.configTemplate
+	TemplateKindNode = "node"
+	// TemplateKindPruning templates run a pruning node alongside a node template, see --pruning
+	TemplateKindPruning = "pruning"
+)
+
+// Features a node template may support
+const (
+	// FeatureValidators is running more than one validator, which requires a gentx in the template's genesis
+	FeatureValidators = "validators"
+	// FeaturePruning is running a pruning node alongside the validator
+	FeaturePruning = "pruning"
+	// FeatureGenesis is replacing the template's genesis, eg. with --genesis or --validate-genesis
+	FeatureGenesis = "genesis"
+)
+
+var templateFeatures = []string{FeatureValidators, FeaturePruning, FeatureGenesis}
+
+// KavaTemplate is the manifest of a kava template, eg.
+//
+//	description: kava v0.23 with a single validator
+//	kind: node
+//	versions: [v0.23]
+//	image: kava/kava
+//	defaultTag: v0.23.1
+//	kavaTagOverride: true
+//	dbBackends: [goleveldb]
+//	chainId: kavalocalnet_8888-1
+//	home: .kava
+//	features: [validators, pruning, genesis]
+type KavaTemplate struct {
+	// Name is the name of the template's directory
+	Name string `yaml:"-"`
+	// Dir is the template's directory
+	Dir string `yaml:"-"`
+
+	Description string `yaml:"description"`
+	// Kind is node or pruning. Defaults to node.
+	Kind string `yaml:"kind"`
+	// Versions are the kava versions the template's config & genesis support
+	Versions []string `yaml:"versions"`
+	// Image is the docker image of the kava node, without the tag
+	Image string `yaml:"image"`
+	// DefaultTag is the image tag used when KAVA_TAG isn't set
+	DefaultTag string `yaml:"defaultTag"`
+	// KavaTagOverride is true if the template's docker-compose.yaml uses KAVA_TAG as the image tag when it's set
+	KavaTagOverride bool `yaml:"kavaTagOverride"`
+	// DbBackends are the db_backend values supported by the template's images
+	DbBackends []string `yaml:"dbBackends"`
+	// ChainID is the chain id of the template's genesis
+	ChainID string `yaml:"chainId"`
+	// Home is the name of the node's home directory in initstate, eg. .kava or .kvd
+	Home string `yaml:"home"`
+	// Features are the optional features of kvtool supported by the template
+	Features []string `yaml:"features"`
+}
+
+// TemplateUsage is how a node template is used by a command, checked with KavaTemplate.CheckUsage
+type TemplateUsage struct {
+	DbBackend  string
+	Validators int
+	Pruning    bool
+	Genesis    bool
+	// KavaTag is true if the image tag is overridden with KAVA_TAG, eg. by an upgrade
+	KavaTag bool
+}
+
+// LoadKavaTemplate reads the manifest of the kava template kava/<name> found first in the template search path
+func LoadKavaTemplate(name string) (KavaTemplate, error) {
+	dir, err := findTemplate(filepath.Join("kava", name))
+	if err != nil {
+		return KavaTemplate{Name: name}, fmt.Errorf("unknown kava template %q, see 'kvtool testnet templates list': %w", name, err)
+	}
+	return loadKavaTemplate(name, dir)
+}
+
+// loadKavaTemplate reads the manifest of the kava template in dir
+func loadKavaTemplate(name, dir string) (KavaTemplate, error) {
+	template := KavaTemplate{Name: name, Dir: dir}
+
+	bz, err := os.ReadFile(filepath.Join(dir, TemplateManifestFile))
+	if os.IsNotExist(err) {
+		return template, fmt.Errorf("kava template %q has no %s", name, TemplateManifestFile)
+	}
+	if err != nil {
+		return template, fmt.Errorf("failed to read kava template %q: %w", name, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(bz))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&template); err != nil {
+		return template, fmt.Errorf("failed to parse %s of kava template %q: %w", TemplateManifestFile, name, err)
+	}
+	if template.Kind == "" {
+		template.Kind = TemplateKindNode
+	}
+	if err := template.Validate(); err != nil {
+		return template, fmt.Errorf("invalid %s of kava template %q: %w", TemplateManifestFile, name, err)
+	}
+	return template, nil
+}
+
+// KavaTemplates returns the manifests of all kava templates in the template search path, sorted by name.
+// A template in a user template directory hides the templates of the same name in later directories.
+func KavaTemplates() ([]KavaTemplate, error) {
+	dirs, err := TemplateSearchPath()
+	if err != nil {
+		return nil, err
+	}
+	var templates []KavaTemplate
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(filepath.Join(dir, "kava"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list kava templates: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || seen[entry.Name()] {
+				continue
+			}
+			seen[entry.Name()] = true
+			template, err := loadKavaTemplate(entry.Name(), filepath.Join(dir, "kava", entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			templates = append(templates, template)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// Validate checks the manifest has the fields required by its kind
+func (t KavaTemplate) Validate() error {
+	var errs []error
+	switch t.Kind {
+	case TemplateKindPruning:
+		return nil
+	case TemplateKindNode:
+	default:
+		return fmt.Errorf("kind: must be %s or %s, found %q", TemplateKindNode, TemplateKindPruning, t.Kind)
+	}
+	if len(t.Versions) == 0 {
+		errs = append(errs, fmt.Errorf("versions: required"))
+	}
+	if t.Image == "" {
+		errs = append(errs, fmt.Errorf("image: required"))
+	}
+	if t.DefaultTag == "" {
+		errs = append(errs, fmt.Errorf("defaultTag: required"))
+	}
+	if len(t.DbBackends) == 0 {
+		errs = append(errs, fmt.Errorf("dbBackends: required"))
+	}
+	if t.ChainID == "" {
+		errs = append(errs, fmt.Errorf("chainId: required"))
+	}
+	if t.Home == "" {
+		errs = append(errs, fmt.Errorf("home: required"))
+	}
+	for _, feature := range t.Features {
+		if !contains(templateFeatures, feature) {
+			errs = append(errs, fmt.Errorf("features: unknown feature %q, must be one of %v", feature, templateFeatures))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Supports returns true if the template supports the feature
+func (t KavaTemplate) Supports(feature string) bool {
+	return contains(t.Features, feature)
+}
+
+// ImageRef returns the image of the template's kava node as it's used in docker-compose.yaml
+func (t KavaTemplate) ImageRef() string {
+	if t.KavaTagOverride {
+		return fmt.Sprintf("%s:${KAVA_TAG:-%s}", t.Image, t.DefaultTag)
+	}
+	return fmt.Sprintf("%s:%s", t.Image, t.DefaultTag)
+}
+
+// CheckUsage returns an error describing every way the usage isn't supported by the template
+func (t KavaTemplate) CheckUsage(usage TemplateUsage) error {
+	if t.Kind == TemplateKindPruning {
+		return fmt.Errorf("kava template %q runs a pruning node alongside a different template, see --pruning", t.Name)
+	}
+
+	var errs []error
+	if usage.DbBackend != "" && !contains(t.DbBackends, usage.DbBackend) {
+		errs = append(errs, fmt.Errorf("db backend %q isn't supported, must be one of %v", usage.DbBackend, t.DbBackends))
+	}
+	if usage.Validators > 1 && !t.Supports(FeatureValidators) {
+		errs = append(errs, t.unsupported(FeatureValidators, "multiple validators"))
+	}
+	if usage.Pruning && !t.Supports(FeaturePruning) {
+		errs = append(errs, t.unsupported(FeaturePruning, "a pruning node"))
+	}
+	if usage.Genesis && !t.Supports(FeatureGenesis) {
+		errs = append(errs, t.unsupported(FeatureGenesis, "replacing or validating its genesis"))
+	}
+	if usage.KavaTag && !t.KavaTagOverride {
+		errs = append(errs, fmt.Errorf("the image tag can't be overridden with KAVA_TAG, the template always runs %s", t.ImageRef()))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("kava template %q can't be used this way:\n%w", t.Name, err)
+	}
+	return nil
+}
+
+// unsupported returns an error for an unsupported feature, listing the templates that support it
+func (t KavaTemplate) unsupported(feature, description string) error {
+	var supported []string
+	if templates, err := KavaTemplates(); err == nil {
+		for _, template := range templates {
+			if template.Kind == TemplateKindNode && template.Supports(feature) {
+				supported = append(supported, template.Name)
+			}
+		}
+	}
+	if len(supported) == 0 {
+		return fmt.Errorf("%s isn't supported", description)
+	}
+	return fmt.Errorf("%s isn't supported, use one of the templates %s", description, strings.Join(supported, ", "))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
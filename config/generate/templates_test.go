@@ -0,0 +1,238 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestTemplate writes a kava template with the manifest to <dir>/kava/<name>
+func writeTestTemplate(t *testing.T, dir, name, manifest string) string {
+	t.Helper()
+	templateDir := filepath.Join(dir, "kava", name)
+	require.NoError(t, os.MkdirAll(templateDir, 0755))
+	if manifest != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(templateDir, TemplateManifestFile), []byte(manifest), 0644))
+	}
+	return templateDir
+}
+
+// testManifest is a valid node template manifest
+const testManifest = `description: a test template
+versions: [v0.23]
+image: kava/kava
+defaultTag: v0.23.1
+kavaTagOverride: true
+dbBackends: [goleveldb]
+chainId: kavalocalnet_8888-1
+home: .kava
+features: [validators]
+`
+
+func TestBuiltinKavaTemplates(t *testing.T) {
+	templates, err := KavaTemplates()
+	require.NoError(t, err)
+	require.NotEmpty(t, templates)
+
+	for _, template := range templates {
+		t.Run(template.Name, func(t *testing.T) {
+			assert.Equal(t, filepath.Join(ConfigTemplatesDir, "kava", template.Name), template.Dir)
+			if template.Kind == TemplateKindPruning {
+				return
+			}
+			assert.DirExists(t, filepath.Join(template.Dir, "initstate", template.Home))
+
+			// the manifest describes the image of the node, other services may run a different tag
+			compose, err := importYAML(filepath.Join(template.Dir, "docker-compose.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, template.ImageRef(), compose.Search("services", KavaValidatorServiceName, "image").Data())
+		})
+	}
+
+	master, err := LoadKavaTemplate("master")
+	require.NoError(t, err)
+	assert.Equal(t, TemplateKindNode, master.Kind)
+	assert.Equal(t, "kava/kava:${KAVA_TAG:-master-goleveldb}", master.ImageRef())
+	assert.True(t, master.Supports(FeaturePruning))
+
+	pruning, err := LoadKavaTemplate("pruning-node")
+	require.NoError(t, err)
+	assert.Equal(t, TemplateKindPruning, pruning.Kind)
+}
+
+func TestLoadKavaTemplate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		expected KavaTemplate
+		errMsgs  []string
+	}{
+		{
+			name:     "valid",
+			manifest: testManifest,
+			expected: KavaTemplate{
+				Description:     "a test template",
+				Kind:            TemplateKindNode,
+				Versions:        []string{"v0.23"},
+				Image:           "kava/kava",
+				DefaultTag:      "v0.23.1",
+				KavaTagOverride: true,
+				DbBackends:      []string{"goleveldb"},
+				ChainID:         "kavalocalnet_8888-1",
+				Home:            ".kava",
+				Features:        []string{FeatureValidators},
+			},
+		},
+		{
+			name:     "pruning templates only need a kind",
+			manifest: "kind: pruning\n",
+			expected: KavaTemplate{Kind: TemplateKindPruning},
+		},
+		{
+			name:    "missing manifest",
+			errMsgs: []string{`kava template "test" has no template.yaml`},
+		},
+		{
+			name:     "unknown field",
+			manifest: testManifest + "tag: v0.23.1\n",
+			errMsgs:  []string{`failed to parse template.yaml of kava template "test"`, "field tag not found"},
+		},
+		{
+			name:     "unknown kind",
+			manifest: "kind: archive\n",
+			errMsgs:  []string{`invalid template.yaml of kava template "test": kind: must be node or pruning, found "archive"`},
+		},
+		{
+			name:     "missing fields & unknown feature",
+			manifest: "description: incomplete\nfeatures: [ibc]\n",
+			errMsgs: []string{
+				"versions: required",
+				"image: required",
+				"defaultTag: required",
+				"dbBackends: required",
+				"chainId: required",
+				"home: required",
+				`features: unknown feature "ibc", must be one of [validators pruning genesis]`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeTestTemplate(t, t.TempDir(), "test", tc.manifest)
+
+			template, err := loadKavaTemplate("test", dir)
+			if len(tc.errMsgs) > 0 {
+				for _, errMsg := range tc.errMsgs {
+					require.ErrorContains(t, err, errMsg)
+				}
+				return
+			}
+			require.NoError(t, err)
+			tc.expected.Name, tc.expected.Dir = "test", dir
+			assert.Equal(t, tc.expected, template)
+		})
+	}
+
+	_, err := LoadKavaTemplate("v0.99")
+	require.ErrorContains(t, err, `unknown kava template "v0.99", see 'kvtool testnet templates list'`)
+}
+
+func TestKavaTemplateImageRef(t *testing.T) {
+	template := KavaTemplate{Image: "kava/kava", DefaultTag: "v0.23.1", KavaTagOverride: true}
+	assert.Equal(t, "kava/kava:${KAVA_TAG:-v0.23.1}", template.ImageRef())
+	template.KavaTagOverride = false
+	assert.Equal(t, "kava/kava:v0.23.1", template.ImageRef())
+}
+
+func TestKavaTemplateCheckUsage(t *testing.T) {
+	legacy := KavaTemplate{
+		Name:       "legacy",
+		Kind:       TemplateKindNode,
+		Image:      "kava/kava",
+		DefaultTag: "v0.14.1",
+		DbBackends: []string{"goleveldb"},
+	}
+	current := KavaTemplate{
+		Name:            "current",
+		Kind:            TemplateKindNode,
+		DbBackends:      []string{"goleveldb", "rocksdb"},
+		KavaTagOverride: true,
+		Features:        []string{FeatureValidators, FeaturePruning, FeatureGenesis},
+	}
+
+	testCases := []struct {
+		name     string
+		template KavaTemplate
+		usage    TemplateUsage
+		errMsgs  []string
+	}{
+		{
+			name:     "default usage",
+			template: legacy,
+			usage:    TemplateUsage{DbBackend: "goleveldb", Validators: 1},
+		},
+		{
+			name:     "every feature",
+			template: current,
+			usage:    TemplateUsage{DbBackend: "rocksdb", Validators: 3, Pruning: true, Genesis: true, KavaTag: true},
+		},
+		{
+			name:     "unsupported db backend",
+			template: legacy,
+			usage:    TemplateUsage{DbBackend: "rocksdb"},
+			errMsgs:  []string{`kava template "legacy" can't be used this way:`, `db backend "rocksdb" isn't supported, must be one of [goleveldb]`},
+		},
+		{
+			name:     "multiple validators",
+			template: legacy,
+			usage:    TemplateUsage{Validators: 2},
+			errMsgs:  []string{"multiple validators isn't supported, use one of the templates master, v0.16"},
+		},
+		{
+			name:     "pruning node",
+			template: legacy,
+			usage:    TemplateUsage{Pruning: true},
+			errMsgs:  []string{"a pruning node isn't supported, use one of the templates master, v0.16"},
+		},
+		{
+			name:     "genesis",
+			template: legacy,
+			usage:    TemplateUsage{Genesis: true},
+			errMsgs:  []string{"replacing or validating its genesis isn't supported, use one of the templates master, v0.16"},
+		},
+		{
+			name:     "kava tag",
+			template: legacy,
+			usage:    TemplateUsage{KavaTag: true},
+			errMsgs:  []string{"the image tag can't be overridden with KAVA_TAG, the template always runs kava/kava:v0.14.1"},
+		},
+		{
+			name:     "every failure is reported",
+			template: legacy,
+			usage:    TemplateUsage{DbBackend: "rocksdb", Validators: 2, Pruning: true},
+			errMsgs:  []string{"db backend", "multiple validators", "a pruning node"},
+		},
+		{
+			name:     "pruning template",
+			template: KavaTemplate{Name: "pruning-node", Kind: TemplateKindPruning},
+			errMsgs:  []string{`kava template "pruning-node" runs a pruning node alongside a different template, see --pruning`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.template.CheckUsage(tc.usage)
+			if len(tc.errMsgs) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			for _, errMsg := range tc.errMsgs {
+				require.ErrorContains(t, err, errMsg)
+			}
+		})
+	}
+}
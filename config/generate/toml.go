@@ -0,0 +1,343 @@
+package generate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// ConfigOverride sets Key, a dotted path like consensus.timeout_commit, of a node's config.toml or app.toml to Value
+type ConfigOverride struct {
+	Key   string
+	Value string
+}
+
+// NodeConfigOverrides are the settings changed in the config.toml & app.toml of every kava node
+type NodeConfigOverrides struct {
+	Config []ConfigOverride
+	App    []ConfigOverride
+}
+
+// ApplyNodeConfigOverrides applies the overrides to every generated kava node: the validators, the pruning node &
+// the ibc chains. Every override must set an existing key, so typos & settings of other kava versions are rejected.
+func ApplyNodeConfigOverrides(generatedConfigDir string, overrides NodeConfigOverrides) error {
+	if len(overrides.Config) == 0 && len(overrides.App) == 0 {
+		return nil
+	}
+	configDirs := kavaNodeConfigDirs(generatedConfigDir)
+	if len(configDirs) == 0 {
+		return fmt.Errorf("no kava nodes found in %s to apply config overrides to", generatedConfigDir)
+	}
+	for _, dir := range configDirs {
+		if err := setTomlFileValues(filepath.Join(dir, "config.toml"), overrides.Config); err != nil {
+			return err
+		}
+		if err := setTomlFileValues(filepath.Join(dir, "app.toml"), overrides.App); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kavaNodeConfigDirs returns the directories of the config.toml & app.toml of the generated kava nodes
+func kavaNodeConfigDirs(generatedConfigDir string) []string {
+	var candidates []string
+	for i := 1; ; i++ {
+		validatorDir := filepath.Join(generatedConfigDir, kavaValidatorDir(i))
+		if _, err := os.Stat(validatorDir); err != nil {
+			break
+		}
+		for _, home := range []string{".kava", ".kvd"} {
+			candidates = append(candidates, filepath.Join(validatorDir, "initstate", home, "config"))
+		}
+	}
+	candidates = append(candidates, filepath.Join(generatedConfigDir, "kava-pruning", "shared"))
+	// the ibc chains are named by the ibc topology
+	ibcChainDirs, _ := filepath.Glob(filepath.Join(generatedConfigDir, "*", "initstate", ".kava", "config"))
+	candidates = append(candidates, ibcChainDirs...)
+
+	var dirs []string
+	seen := map[string]bool{}
+	for _, dir := range candidates {
+		if _, err := os.Stat(filepath.Join(dir, "config.toml")); err == nil && !seen[dir] {
+			dirs = append(dirs, dir)
+			seen[dir] = true
+		}
+	}
+	return dirs
+}
+
+// ParseConfigOverrides parses key=value overrides, eg. from --kava.config consensus.timeout_commit=500ms
+func ParseConfigOverrides(values []string) ([]ConfigOverride, error) {
+	overrides := make([]ConfigOverride, 0, len(values))
+	for _, value := range values {
+		key, v, ok := strings.Cut(value, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid override %q, expected key=value", value)
+		}
+		overrides = append(overrides, ConfigOverride{Key: key, Value: strings.TrimSpace(v)})
+	}
+	return overrides, nil
+}
+
+// ConfigOverridesFromMap returns the overrides of a map, eg. from a topology file, sorted by key
+func ConfigOverridesFromMap(values map[string]string) []ConfigOverride {
+	overrides := make([]ConfigOverride, 0, len(values))
+	for key, value := range values {
+		overrides = append(overrides, ConfigOverride{Key: key, Value: value})
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Key < overrides[j].Key })
+	return overrides
+}
+
+// setTomlFileValues applies overrides to a toml file. The file's other lines are kept as they are, including comments,
+// see setTomlValue.
+func setTomlFileValues(path string, overrides []ConfigOverride) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, override := range overrides {
+		if content, err = setTomlValue(content, override.Key, override.Value); err != nil {
+			return fmt.Errorf("failed to set %s in %s: %w", override.Key, path, err)
+		}
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// setTomlValue replaces the value of an existing key in a toml document. key is the dotted path of the key, eg.
+// p2p.persistent_peers. The new value has the type of the replaced value: strings are used as is, other types must be
+// toml values, eg. true, 5000 or ["eth", "net"].
+//
+// The document is scanned line by line rather than decoded & encoded, so everything but the replaced value is kept as
+// is. The value is written on the key's line, followed by the comment that ended the replaced value, if any. Keys of
+// arrays of tables, eg. [[chains]], can't be set as they don't identify a single table.
+func setTomlValue(doc []byte, key, value string) ([]byte, error) {
+	path := splitTomlKey(key)
+	lines := strings.Split(string(doc), "\n")
+	var currentTable []string
+	arrayTable := false
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			arrayTable = strings.HasPrefix(line, "[[")
+			header, _, _ := cutOutsideQuotes(strings.TrimLeft(line, "["), ']')
+			currentTable = splitTomlKey(header)
+			continue
+		}
+		lineKey, rest, ok := cutOutsideQuotes(lines[i], '=')
+		if !ok {
+			continue
+		}
+
+		// values may span lines, eg. arrays, so lines are added until the value parses
+		var current interface{}
+		end := i
+		for ; end < len(lines); end++ {
+			snippet := "v =" + rest + "\n" + strings.Join(lines[i+1:end+1], "\n")
+			if current, ok = parseTomlValue(snippet); ok {
+				break
+			}
+		}
+		if end == len(lines) {
+			return nil, fmt.Errorf("failed to parse the value of %s", strings.TrimSpace(lineKey))
+		}
+
+		if !equalTomlKeys(append(append([]string{}, currentTable...), splitTomlKey(lineKey)...), path) {
+			// the value's other lines aren't keys, eg. the items of an array
+			i = end
+			continue
+		}
+		if arrayTable {
+			return nil, fmt.Errorf("key is in an array of tables [[%s]], which can't be set", strings.Join(currentTable, "."))
+		}
+
+		encoded, err := encodeTomlValue(current, value)
+		if err != nil {
+			return nil, err
+		}
+		replaced := fmt.Sprintf("%s = %s", strings.TrimSpace(lineKey), encoded)
+		if comment := tomlTrailingComment(strings.Join(append([]string{rest}, lines[i+1:end+1]...), "\n")); comment != "" {
+			replaced += " " + comment
+		}
+		updated := append(append(append([]string{}, lines[:i]...), replaced), lines[end+1:]...)
+		return []byte(strings.Join(updated, "\n")), nil
+	}
+	if len(path) == 1 {
+		return nil, fmt.Errorf("key not found")
+	}
+	return nil, fmt.Errorf("key %s not found in table [%s]", path[len(path)-1], strings.Join(path[:len(path)-1], "."))
+}
+
+// splitTomlKey splits a dotted toml key into its parts, eg. a."b.c" -> [a b.c]. Whitespace around the parts & the
+// quotes of quoted parts are removed.
+func splitTomlKey(key string) []string {
+	var parts []string
+	for {
+		part, rest, found := cutOutsideQuotes(key, '.')
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && (part[0] == '"' || part[0] == '\'') && part[len(part)-1] == part[0] {
+			part = part[1 : len(part)-1]
+		}
+		parts = append(parts, part)
+		if !found {
+			return parts
+		}
+		key = rest
+	}
+}
+
+func equalTomlKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// cutOutsideQuotes is strings.Cut for a separator that isn't within a quoted string
+func cutOutsideQuotes(s string, sep byte) (before, after string, found bool) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote == 0 && c == sep:
+			return s[:i], s[i+1:], true
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == '"' && c == '\\':
+			// skip the escaped character
+			i++
+		case c == quote:
+			quote = 0
+		}
+	}
+	return s, "", false
+}
+
+// tomlTrailingComment returns the comment at the end of a toml value that may span lines, eg. "# the peers", or "" if
+// the value's last line has no comment
+func tomlTrailingComment(value string) string {
+	comment := -1
+	var quote string
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != "":
+			if c == '\\' && quote[0] == '"' {
+				i++
+			} else if strings.HasPrefix(value[i:], quote) {
+				i += len(quote) - 1
+				quote = ""
+			}
+		case c == '"' || c == '\'':
+			quote = string(c)
+			if strings.HasPrefix(value[i:], strings.Repeat(quote, 3)) {
+				quote = strings.Repeat(quote, 3)
+				i += 2
+			}
+		case c == '#':
+			comment = i
+			// the comment runs to the end of the line
+			for i+1 < len(value) && value[i+1] != '\n' {
+				i++
+			}
+		case c == '\n':
+			comment = -1
+		}
+	}
+	if comment < 0 {
+		return ""
+	}
+	return strings.TrimRight(value[comment:], " \t\r")
+}
+
+// encodeTomlValue returns value as a toml value of the same type as current
+func encodeTomlValue(current interface{}, value string) (string, error) {
+	if _, ok := current.(string); ok {
+		// quoted strings are accepted too, eg. from a shell that kept the quotes
+		if parsed, ok := parseTomlValue("v = " + value); ok {
+			if s, ok := parsed.(string); ok {
+				return quoteTomlString(s), nil
+			}
+		}
+		return quoteTomlString(value), nil
+	}
+
+	parsed, ok := parseTomlValue("v = " + value)
+	if !ok || tomlType(parsed) != tomlType(current) && !(tomlType(current) == "float" && tomlType(parsed) == "integer") {
+		return "", fmt.Errorf("expected a toml %s, found %q", tomlType(current), value)
+	}
+	return value, nil
+}
+
+// parseTomlValue returns the value of v in a toml snippet
+func parseTomlValue(snippet string) (interface{}, bool) {
+	var parsed map[string]interface{}
+	if err := toml.Unmarshal([]byte(snippet), &parsed); err != nil {
+		return nil, false
+	}
+	value, ok := parsed["v"]
+	return value, ok
+}
+
+func tomlType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "table"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// quoteTomlString returns s as a toml basic string, the quoting used by tendermint & cosmos-sdk config files
+func quoteTomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,228 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetTomlValue(t *testing.T) {
+	testCases := []struct {
+		name        string
+		doc         string
+		key         string
+		value       string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "root string",
+			doc:      "# the db\ndb_backend = \"goleveldb\"\nmoniker = \"kava\"\n",
+			key:      "db_backend",
+			value:    "rocksdb",
+			expected: "# the db\ndb_backend = \"rocksdb\"\nmoniker = \"kava\"\n",
+		},
+		{
+			name:     "quoted string value",
+			doc:      "chain-id = \"kavalocalnet_8888-1\"\n",
+			key:      "chain-id",
+			value:    `"kavamirror_2221-1"`,
+			expected: "chain-id = \"kavamirror_2221-1\"\n",
+		},
+		{
+			name:     "string with quotes is escaped",
+			doc:      "moniker = \"kava\"\n",
+			key:      "moniker",
+			value:    `say "hi"`,
+			expected: "moniker = \"say \\\"hi\\\"\"\n",
+		},
+		{
+			name:     "table",
+			doc:      "[p2p]\nladdr = \"tcp://0.0.0.0:26656\"\npersistent_peers = \"\"\n\n[rpc]\npersistent_peers = \"other\"\n",
+			key:      "p2p.persistent_peers",
+			value:    "a@kavanode2:26656",
+			expected: "[p2p]\nladdr = \"tcp://0.0.0.0:26656\"\npersistent_peers = \"a@kavanode2:26656\"\n\n[rpc]\npersistent_peers = \"other\"\n",
+		},
+		{
+			name:     "nested table",
+			doc:      "[api]\nenable = false\n\n[api.swagger]\nenable = false\n",
+			key:      "api.swagger.enable",
+			value:    "true",
+			expected: "[api]\nenable = false\n\n[api.swagger]\nenable = true\n",
+		},
+		{
+			name:     "dotted key within a table",
+			doc:      "[json-rpc]\nws.address = \"0.0.0.0:8546\"\n",
+			key:      "json-rpc.ws.address",
+			value:    "0.0.0.0:9546",
+			expected: "[json-rpc]\nws.address = \"0.0.0.0:9546\"\n",
+		},
+		{
+			name:     "quoted keys",
+			doc:      "[\"state-sync\"]\n\"snapshot-interval\" = 0\n",
+			key:      "state-sync.snapshot-interval",
+			value:    "1000",
+			expected: "[\"state-sync\"]\n\"snapshot-interval\" = 1000\n",
+		},
+		{
+			name:     "quoted key with a dot",
+			doc:      "[a]\n\"b.c\" = 1\nb = 2\n",
+			key:      `a."b.c"`,
+			value:    "3",
+			expected: "[a]\n\"b.c\" = 3\nb = 2\n",
+		},
+		{
+			name:     "multi-line array",
+			doc:      "[json-rpc]\napi = [\n  \"eth\",\n  \"net\", # the net api\n]\nenable = true\n",
+			key:      "json-rpc.api",
+			value:    `["eth", "web3"]`,
+			expected: "[json-rpc]\napi = [\"eth\", \"web3\"]\nenable = true\n",
+		},
+		{
+			name:     "keys in a multi-line value are skipped",
+			doc:      "notes = \"\"\"\nenable = false\n\"\"\"\nenable = false\n",
+			key:      "enable",
+			value:    "true",
+			expected: "notes = \"\"\"\nenable = false\n\"\"\"\nenable = true\n",
+		},
+		{
+			name:     "inline comment is kept",
+			doc:      "[consensus]\ntimeout_commit = \"5s\" # how long to wait\n",
+			key:      "consensus.timeout_commit",
+			value:    "500ms",
+			expected: "[consensus]\ntimeout_commit = \"500ms\" # how long to wait\n",
+		},
+		{
+			name:     "inline comment after a multi-line array is kept",
+			doc:      "api = [\n  \"eth\", # first\n] # the apis\n",
+			key:      "api",
+			value:    `["net"]`,
+			expected: "api = [\"net\"] # the apis\n",
+		},
+		{
+			name:     "hash in a string isn't a comment",
+			doc:      "moniker = \"kava#1\"\n",
+			key:      "moniker",
+			value:    "kava#2",
+			expected: "moniker = \"kava#2\"\n",
+		},
+		{
+			name:     "table header with a comment",
+			doc:      "[mempool] # the mempool\nsize = 5000\n",
+			key:      "mempool.size",
+			value:    "10000",
+			expected: "[mempool] # the mempool\nsize = 10000\n",
+		},
+		{
+			name:     "integer for a float",
+			doc:      "ratio = 0.5\n",
+			key:      "ratio",
+			value:    "1",
+			expected: "ratio = 1\n",
+		},
+		{
+			name:        "bool type mismatch",
+			doc:         "[api]\nenable = false\n",
+			key:         "api.enable",
+			value:       "yes",
+			expectedErr: `expected a toml bool, found "yes"`,
+		},
+		{
+			name:        "integer type mismatch",
+			doc:         "[mempool]\nsize = 5000\n",
+			key:         "mempool.size",
+			value:       "5000.5",
+			expectedErr: `expected a toml integer, found "5000.5"`,
+		},
+		{
+			name:        "array type mismatch",
+			doc:         "api = [\"eth\"]\n",
+			key:         "api",
+			value:       "eth",
+			expectedErr: `expected a toml array, found "eth"`,
+		},
+		{
+			name:        "missing root key",
+			doc:         "moniker = \"kava\"\n",
+			key:         "monikr",
+			value:       "kava",
+			expectedErr: "key not found",
+		},
+		{
+			name:        "missing key in table",
+			doc:         "[p2p]\nladdr = \"\"\n",
+			key:         "p2p.peers",
+			value:       "a",
+			expectedErr: "key peers not found in table [p2p]",
+		},
+		{
+			name:        "key in another table",
+			doc:         "[rpc]\nladdr = \"\"\n",
+			key:         "p2p.laddr",
+			value:       "a",
+			expectedErr: "key laddr not found in table [p2p]",
+		},
+		{
+			name:        "array of tables",
+			doc:         "[[chains]]\nid = \"a\"\n\n[[chains]]\nid = \"b\"\n",
+			key:         "chains.id",
+			value:       "c",
+			expectedErr: "key is in an array of tables [[chains]], which can't be set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			updated, err := setTomlValue([]byte(tc.doc), tc.key, tc.value)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(updated))
+		})
+	}
+}
+
+func TestTomlTrailingComment(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{value: ` "5s"`, expected: ""},
+		{value: ` "5s" # wait`, expected: "# wait"},
+		{value: ` "a # b"`, expected: ""},
+		{value: ` 'a # b' # c`, expected: "# c"},
+		{value: ` "a \" # b"`, expected: ""},
+		{value: " [\n  1, # one\n]", expected: ""},
+		{value: " [\n  1, # one\n] # list  ", expected: "# list"},
+		{value: " \"\"\"\n# not a comment\n\"\"\"", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, tomlTrailingComment(tc.value), tc.value)
+	}
+}
+
+func TestSetTomlFileValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, []byte("# tendermint config\n\n[p2p]\n# peers to stay connected to\npersistent_peers = \"\"\n\n[consensus]\ntimeout_commit = \"5s\"\n"), 0644))
+
+	require.NoError(t, setTomlFileValues(path, []ConfigOverride{
+		{Key: "p2p.persistent_peers", Value: "a@kavanode2:26656"},
+		{Key: "consensus.timeout_commit", Value: "500ms"},
+	}))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# tendermint config\n\n[p2p]\n# peers to stay connected to\npersistent_peers = \"a@kavanode2:26656\"\n\n[consensus]\ntimeout_commit = \"500ms\"\n", string(content))
+
+	// the file isn't written if any override fails
+	err = setTomlFileValues(path, []ConfigOverride{{Key: "consensus.timeout_commit", Value: "1s"}, {Key: "consensus.missing", Value: "1"}})
+	require.ErrorContains(t, err, "failed to set consensus.missing in "+path)
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, after)
+}
@@ -0,0 +1,278 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/kava-labs/kava/app"
+	"github.com/otiai10/copy"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/privval"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+const (
+	// KavaValidatorServiceName is the docker compose service name of the primary kava validator
+	KavaValidatorServiceName = "kavanode"
+
+	// the self delegation of each additional validator, and the amount they are funded with
+	validatorSelfDelegation = 1_000_000_000
+	validatorFunds          = 2 * validatorSelfDelegation
+
+	// host ports of additional validators are shifted by this amount per validator
+	validatorPortOffset = 100
+)
+
+// KavaValidatorServiceNames returns the docker compose service names of numValidators validators.
+// The first validator is always the template's kavanode.
+func KavaValidatorServiceNames(numValidators int) []string {
+	names := []string{KavaValidatorServiceName}
+	for i := 2; i <= numValidators; i++ {
+		names = append(names, fmt.Sprintf("%s%d", KavaValidatorServiceName, i))
+	}
+	return names
+}
+
+// KavaValidatorKeyNames returns the keyring names of the operator keys of numValidators validators.
+// The first validator's key is the template's validator key.
+func KavaValidatorKeyNames(numValidators int) []string {
+	names := []string{"validator"}
+	for i := 2; i <= numValidators; i++ {
+		names = append(names, fmt.Sprintf("validator%d", i))
+	}
+	return names
+}
+
+// kavaValidatorDir returns the generated directory of the i-th validator (1-indexed)
+func kavaValidatorDir(i int) string {
+	if i == 1 {
+		return "kava"
+	}
+	return fmt.Sprintf("kava%d", i)
+}
+
+// kavaHomeDir returns the home directory of the i-th generated validator, home is the template's home, eg. .kava
+func kavaHomeDir(generatedConfigDir, home string, i int) string {
+	return filepath.Join(generatedConfigDir, kavaValidatorDir(i), "initstate", home)
+}
+
+// GenerateKavaValidatorsConfig extends an already generated kava config with additional validators so
+// that the network runs with numValidators validators. Each validator gets a fresh node key, consensus key
+// and operator account. The operator keys are added to the keyring as validator2, validator3, etc.
+// Every validator's genesis includes gentxs for all validators & they are all configured as persistent peers.
+func GenerateKavaValidatorsConfig(kavaConfigTemplate, generatedConfigDir string, numValidators int) error {
+	if numValidators <= 1 {
+		return nil
+	}
+	template, err := LoadKavaTemplate(kavaConfigTemplate)
+	if err != nil {
+		return err
+	}
+
+	homeDir := kavaHomeDir(generatedConfigDir, template.Home, 1)
+	genesisPath := filepath.Join(homeDir, "config", "genesis.json")
+
+	gen, err := genesis.ReadRawGenesis(genesisPath)
+	if err != nil {
+		return err
+	}
+	gentxs, err := gen.Gentxs()
+	if err != nil {
+		return err
+	}
+	if len(gentxs) == 0 {
+		return fmt.Errorf("kava template genesis has no gentxs, multiple validators are not supported")
+	}
+	chainID, err := gen.ChainID()
+	if err != nil {
+		return err
+	}
+	bondDenom, err := gen.BondDenom()
+	if err != nil {
+		return err
+	}
+
+	encodingConfig := app.MakeEncodingConfig()
+	kr, err := keyring.New("kava", keyring.BackendTest, homeDir, nil, encodingConfig.Marshaler)
+	if err != nil {
+		return fmt.Errorf("failed to open kava keyring: %w", err)
+	}
+	hdPath := hd.CreateHDPath(app.Bip44CoinType, 0, 0).String()
+
+	primaryNodeKey, err := p2p.LoadNodeKey(filepath.Join(homeDir, "config", "node_key.json"))
+	if err != nil {
+		return fmt.Errorf("failed to load kava node key: %w", err)
+	}
+
+	serviceNames := KavaValidatorServiceNames(numValidators)
+	keyNames := KavaValidatorKeyNames(numValidators)
+	peers := []string{peerAddress(primaryNodeKey, serviceNames[0])}
+	nodeKeys := make(map[int]*p2p.NodeKey, numValidators)
+	consensusKeys := make(map[int]ed25519.PrivKey, numValidators)
+
+	for i := 2; i <= numValidators; i++ {
+		nodeKeys[i] = &p2p.NodeKey{PrivKey: ed25519.GenPrivKey()}
+		consensusKeys[i] = ed25519.GenPrivKey()
+		consensusPubKey, err := cryptocodec.FromTmPubKeyInterface(consensusKeys[i].PubKey())
+		if err != nil {
+			return err
+		}
+
+		// create the operator account key in the keyring so it can be used from within the containers
+		keyName := keyNames[i-1]
+		_, mnemonic, err := kr.NewMnemonic(keyName, keyring.English, hdPath, "", hd.Secp256k1)
+		if err != nil {
+			return fmt.Errorf("failed to create %s key: %w", keyName, err)
+		}
+		derivedKey, err := hd.Secp256k1.Derive()(mnemonic, "", hdPath)
+		if err != nil {
+			return err
+		}
+
+		val := genesis.Validator{
+			Moniker:         keyName,
+			OperatorKey:     hd.Secp256k1.Generate()(derivedKey),
+			ConsensusPubKey: consensusPubKey,
+			PeerAddress:     peerAddress(nodeKeys[i], serviceNames[i-1]),
+			SelfDelegation:  sdk.NewInt64Coin(bondDenom, validatorSelfDelegation),
+		}
+		peers = append(peers, val.PeerAddress)
+
+		account := authtypes.NewBaseAccountWithAddress(val.OperatorAddress())
+		funds := sdk.NewCoins(sdk.NewInt64Coin(bondDenom, validatorFunds))
+		if err := gen.AddAccount(encodingConfig.Marshaler, account, funds); err != nil {
+			return err
+		}
+
+		gentx, err := genesis.NewGentx(encodingConfig, chainID, val)
+		if err != nil {
+			return err
+		}
+		if err := gen.AddGentx(gentx); err != nil {
+			return err
+		}
+
+		fmt.Printf("generated validator %s (%s)\n", keyName, val.OperatorAddress())
+	}
+
+	// the genesis & keyring are finalized before the home directory is copied to the other validators
+	if err := gen.WriteFile(genesisPath); err != nil {
+		return err
+	}
+
+	for i := 2; i <= numValidators; i++ {
+		validatorHomeDir := kavaHomeDir(generatedConfigDir, template.Home, i)
+		if err := copy.Copy(homeDir, validatorHomeDir); err != nil {
+			return err
+		}
+		if err := nodeKeys[i].SaveAs(filepath.Join(validatorHomeDir, "config", "node_key.json")); err != nil {
+			return err
+		}
+		privval.NewFilePV(consensusKeys[i], filepath.Join(validatorHomeDir, "config", "priv_validator_key.json"), "").Key.Save()
+	}
+
+	// every validator is configured to peer with all the others
+	for i := 1; i <= numValidators; i++ {
+		var otherPeers []string
+		for j, peer := range peers {
+			if j != i-1 {
+				otherPeers = append(otherPeers, peer)
+			}
+		}
+		configTomlPath := filepath.Join(kavaHomeDir(generatedConfigDir, template.Home, i), "config", "config.toml")
+		if err := setTomlFileValues(configTomlPath, []ConfigOverride{{Key: "p2p.persistent_peers", Value: strings.Join(otherPeers, ",")}}); err != nil {
+			return err
+		}
+	}
+
+	return addKavaValidatorServices(filepath.Join(generatedConfigDir, "docker-compose.yaml"), numValidators)
+}
+
+// addKavaValidatorServices adds a copy of the kavanode service for each additional validator.
+// The copies use their own home directories and their host ports are shifted to not conflict.
+func addKavaValidatorServices(dockerComposePath string, numValidators int) error {
+	compose, err := importYAML(dockerComposePath)
+	if err != nil {
+		return err
+	}
+	if !compose.Exists("services", KavaValidatorServiceName) {
+		return fmt.Errorf("no %s service found in %s", KavaValidatorServiceName, dockerComposePath)
+	}
+	serviceYAML, err := yaml.Marshal(compose.Search("services", KavaValidatorServiceName).Data())
+	if err != nil {
+		return err
+	}
+
+	serviceNames := KavaValidatorServiceNames(numValidators)
+	for i := 2; i <= numValidators; i++ {
+		// unmarshal the primary validator's service each time to get a deep copy
+		service := map[string]interface{}{}
+		if err := yaml.Unmarshal(serviceYAML, &service); err != nil {
+			return err
+		}
+
+		if volumes, ok := service["volumes"].([]interface{}); ok {
+			for j, volume := range volumes {
+				if v, ok := volume.(string); ok {
+					volumes[j] = strings.Replace(v, "./kava/", fmt.Sprintf("./%s/", kavaValidatorDir(i)), 1)
+				}
+			}
+		}
+		if ports, ok := service["ports"].([]interface{}); ok {
+			for j, port := range ports {
+				p, ok := port.(string)
+				if !ok {
+					continue
+				}
+				shifted, err := offsetHostPort(p, (i-1)*validatorPortOffset)
+				if err != nil {
+					return err
+				}
+				ports[j] = shifted
+			}
+		}
+
+		if _, err := compose.Set(service, "services", serviceNames[i-1]); err != nil {
+			return err
+		}
+	}
+
+	if composeMap, ok := compose.Data().(map[string]interface{}); ok {
+		conflicts, _ := composeConflicts(composeMap)
+		if err := errors.Join(conflicts...); err != nil {
+			return fmt.Errorf("additional validators conflict with %s:\n%w", dockerComposePath, err)
+		}
+	}
+	return exportYAML(dockerComposePath, compose)
+}
+
+// offsetHostPort shifts the host port of a docker compose port mapping, eg "26657:26657" -> "26757:26657"
+func offsetHostPort(mapping string, offset int) (string, error) {
+	pieces := strings.Split(mapping, ":")
+	if len(pieces) < 2 {
+		// the port is not published to the host
+		return mapping, nil
+	}
+	hostIdx := len(pieces) - 2
+	hostPort, err := strconv.Atoi(pieces[hostIdx])
+	if err != nil {
+		return "", fmt.Errorf("unsupported port mapping %q: %w", mapping, err)
+	}
+	pieces[hostIdx] = strconv.Itoa(hostPort + offset)
+	return strings.Join(pieces, ":"), nil
+}
+
+func peerAddress(nodeKey *p2p.NodeKey, host string) string {
+	return fmt.Sprintf("%s@%s:26656", nodeKey.ID(), host)
+}
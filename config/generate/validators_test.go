@@ -0,0 +1,139 @@
+package generate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/p2p"
+
+	"github.com/kava-labs/kvtool/config/generate/genesis"
+)
+
+func TestGenerateKavaValidatorsConfig(t *testing.T) {
+	const numValidators = 3
+	dir := t.TempDir()
+	require.NoError(t, GenerateKavaConfig("master", dir, "goleveldb", false))
+	require.NoError(t, GenerateKavaValidatorsConfig("master", dir, numValidators))
+
+	// every validator has its own node key & peers with all the others
+	serviceNames := KavaValidatorServiceNames(numValidators)
+	var peers []string
+	for i := 1; i <= numValidators; i++ {
+		nodeKey, err := p2p.LoadNodeKey(filepath.Join(kavaConfigDir(dir, ".kava", i), "node_key.json"))
+		require.NoError(t, err)
+		peers = append(peers, fmt.Sprintf("%s@%s:26656", nodeKey.ID(), serviceNames[i-1]))
+	}
+	for i := 1; i <= numValidators; i++ {
+		bz, err := os.ReadFile(filepath.Join(kavaConfigDir(dir, ".kava", i), "config.toml"))
+		require.NoError(t, err)
+		var config struct {
+			P2P struct {
+				PersistentPeers string `toml:"persistent_peers"`
+			} `toml:"p2p"`
+		}
+		require.NoError(t, toml.Unmarshal(bz, &config))
+
+		var expected []string
+		for j, peer := range peers {
+			if j != i-1 {
+				expected = append(expected, peer)
+			}
+		}
+		assert.Equal(t, strings.Join(expected, ","), config.P2P.PersistentPeers, "validator %d", i)
+	}
+
+	// every validator shares the genesis with a gentx per validator
+	primaryGenesis, err := os.ReadFile(filepath.Join(kavaConfigDir(dir, ".kava", 1), "genesis.json"))
+	require.NoError(t, err)
+	gen, err := genesis.NewRawGenesis(primaryGenesis)
+	require.NoError(t, err)
+	gentxs, err := gen.Gentxs()
+	require.NoError(t, err)
+	assert.Len(t, gentxs, numValidators)
+	for i := 2; i <= numValidators; i++ {
+		bz, err := os.ReadFile(filepath.Join(kavaConfigDir(dir, ".kava", i), "genesis.json"))
+		require.NoError(t, err)
+		assert.Equal(t, string(primaryGenesis), string(bz), "validator %d", i)
+	}
+
+	// the additional validators run in their own services with their own home & shifted host ports
+	compose, err := importYAML(filepath.Join(dir, "docker-compose.yaml"))
+	require.NoError(t, err)
+	for i := 2; i <= numValidators; i++ {
+		service := compose.Search("services", serviceNames[i-1])
+		require.True(t, service.Exists(), serviceNames[i-1])
+		assert.Contains(t, service.Search("volumes").Data(), fmt.Sprintf("./kava%d/initstate/.kava:/root/.kava", i))
+		assert.Contains(t, service.Search("ports").Data(), fmt.Sprintf("%d:26657", 26657+(i-1)*validatorPortOffset))
+		assert.Contains(t, service.Search("ports").Data(), fmt.Sprintf("%d:9090", 9090+(i-1)*validatorPortOffset))
+	}
+}
+
+func TestGenerateKavaValidatorsConfigSingleValidator(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, GenerateKavaConfig("master", dir, "goleveldb", false))
+	require.NoError(t, GenerateKavaValidatorsConfig("master", dir, 1))
+
+	compose, err := importYAML(filepath.Join(dir, "docker-compose.yaml"))
+	require.NoError(t, err)
+	assert.False(t, compose.Exists("services", "kavanode2"))
+	assert.NoDirExists(t, filepath.Join(dir, "kava2"))
+}
+
+func TestOffsetHostPort(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mapping  string
+		offset   int
+		expected string
+		errMsg   string
+	}{
+		{
+			name:     "host & container port",
+			mapping:  "26657:26657",
+			offset:   100,
+			expected: "26757:26657",
+		},
+		{
+			name:     "host ip",
+			mapping:  "127.0.0.1:1317:1317",
+			offset:   200,
+			expected: "127.0.0.1:1517:1317",
+		},
+		{
+			name:     "protocol",
+			mapping:  "26656:26656/tcp",
+			offset:   100,
+			expected: "26756:26656/tcp",
+		},
+		{
+			name:     "not published",
+			mapping:  "26657",
+			offset:   100,
+			expected: "26657",
+		},
+		{
+			name:    "port range",
+			mapping: "8000-8010:8000-8010",
+			offset:  100,
+			errMsg:  `unsupported port mapping "8000-8010:8000-8010"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := offsetHostPort(tc.mapping, tc.offset)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
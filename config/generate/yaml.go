@@ -2,38 +2,11 @@ package generate
 
 import (
 	"io/ioutil"
-	"os"
 
 	"github.com/Jeffail/gabs/v2"
 	"gopkg.in/yaml.v3"
 )
 
-func overwriteMergeYAML(sourceFileName, destinationFileName string) error {
-	source, err := importYAML(sourceFileName)
-	if err != nil {
-		return err
-	}
-	destination, err := importYAML(destinationFileName)
-	if err != nil {
-		if os.IsNotExist(err) {
-			destination = gabs.New()
-		} else {
-			return err
-		}
-	}
-	err = destination.MergeFn(source, func(destination, source interface{}) interface{} {
-		// overwrite any non-object values with the source's version
-		return source
-	})
-	if err != nil {
-		return err
-	}
-	if err := exportYAML(destinationFileName, destination); err != nil {
-		return err
-	}
-	return nil
-}
-
 func importYAML(filename string) (*gabs.Container, error) {
 	bz, err := ioutil.ReadFile(filename)
 	if err != nil {
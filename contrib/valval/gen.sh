@@ -43,7 +43,7 @@ for ((i = 1; i <= num_validators; i++)); do
   cp shared/init-data-directory.sh $home/config/init-data-directory.sh
   cp shared/priv_validator_state.json.example $home/config/priv_validator_state.json.example
 
-  # move keys to shared dir (for use in update-validator-genesis)
+  # move keys to shared dir (for use in kvtool genesis replace-validators)
   cp $home/config/priv_validator_key.json keys/priv_validator_key_$(($i - 1)).json
   peers+=("$(kava tendermint show-node-id --home $home)@$home:26656")
 
@@ -2,8 +2,10 @@ package kavaclient
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
@@ -15,6 +17,7 @@ import (
 )
 
 type Client struct {
+	conn       *grpc.ClientConn
 	bankClient banktypes.QueryClient
 	tmService  tmservice.ServiceClient
 }
@@ -26,11 +29,17 @@ func NewClient(grpcUrl string) (*Client, error) {
 	}
 
 	return &Client{
+		conn:       conn,
 		bankClient: banktypes.NewQueryClient(conn),
 		tmService:  tmservice.NewServiceClient(conn),
 	}, nil
 }
 
+// Close closes the underlying grpc connection
+func (c Client) Close() error {
+	return c.conn.Close()
+}
+
 func (c Client) GetBalance(address string, denom string, maxRetries int) (*sdk.Coin, error) {
 	res, err := c.bankClient.Balance(context.Background(), &banktypes.QueryBalanceRequest{
 		Address: address,
@@ -69,6 +78,21 @@ func (c Client) LatestBlock(maxRetries int) (*tmservice.Block, error) {
 	return res.SdkBlock, nil
 }
 
+// LatestHeight returns the height of the latest block. It supports nodes that only return the tendermint block.
+func (c Client) LatestHeight(ctx context.Context) (int64, error) {
+	res, err := c.tmService.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	if err != nil {
+		return 0, err
+	}
+	if res.SdkBlock != nil {
+		return res.SdkBlock.Header.Height, nil
+	}
+	if res.Block != nil {
+		return res.Block.Header.Height, nil
+	}
+	return 0, fmt.Errorf("latest block response contains no block")
+}
+
 func (c Client) Supply(height int64, maxRetries int) (sdk.Coin, error) {
 	res, err := c.bankClient.SupplyOf(ctxAtHeight(height), &banktypes.QuerySupplyOfRequest{
 		Denom: "ukava",